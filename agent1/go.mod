@@ -2,10 +2,18 @@ module github.com/rselbach/cc/lrucache
 
 go 1.25.1
 
-require github.com/stretchr/testify v1.11.1
+require (
+	github.com/rselbach/agent-comparison/clock v0.0.0
+	github.com/rselbach/agent-comparison/metrics v0.0.0
+	github.com/stretchr/testify v1.11.1
+)
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
+
+replace github.com/rselbach/agent-comparison/clock => ../clock
+
+replace github.com/rselbach/agent-comparison/metrics => ../metrics
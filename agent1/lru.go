@@ -5,6 +5,9 @@ import (
 	"container/list"
 	"sync"
 	"time"
+
+	"github.com/rselbach/agent-comparison/clock"
+	"github.com/rselbach/agent-comparison/metrics"
 )
 
 // entry represents an item in the cache with its expiration time.
@@ -23,11 +26,34 @@ type LRUCache struct {
 	items     map[string]*entry
 	evictList *list.List
 	stopChan  chan struct{}
+	clock     clock.Clock
+	metrics   metrics.Recorder
+}
+
+// Option configures an LRUCache at construction time.
+type Option func(*LRUCache)
+
+// WithClock sets the clock used to read the current time and to schedule the
+// background cleanup, so tests can drive both with a clock.Fake.
+func WithClock(clk clock.Clock) Option {
+	return func(c *LRUCache) {
+		if clk != nil {
+			c.clock = clk
+		}
+	}
+}
+
+// WithRecorder sets the recorder that receives the cache's hits, misses,
+// evictions, expirations and cleanup durations.
+func WithRecorder(r metrics.Recorder) Option {
+	return func(c *LRUCache) {
+		c.metrics = metrics.OrNop(r)
+	}
 }
 
 // New creates a new LRUCache with the specified capacity.
 // The cache starts a background goroutine to clean up expired items.
-func New(capacity int) *LRUCache {
+func New(capacity int, opts ...Option) *LRUCache {
 	if capacity <= 0 {
 		capacity = 1
 	}
@@ -37,6 +63,12 @@ func New(capacity int) *LRUCache {
 		items:     make(map[string]*entry),
 		evictList: list.New(),
 		stopChan:  make(chan struct{}),
+		clock:     clock.Real{},
+		metrics:   metrics.Nop{},
+	}
+
+	for _, opt := range opts {
+		opt(c)
 	}
 
 	// start cleanup goroutine
@@ -53,7 +85,7 @@ func (c *LRUCache) Set(key string, value any, ttl time.Duration) {
 	defer c.mu.Unlock()
 
 	// calculate expiration time
-	expiresAt := time.Now().Add(ttl)
+	expiresAt := c.clock.Now().Add(ttl)
 
 	// if key exists, update it
 	if ent, exists := c.items[key]; exists {
@@ -75,6 +107,7 @@ func (c *LRUCache) Set(key string, value any, ttl time.Duration) {
 	// check if we need to evict
 	if len(c.items) > c.capacity {
 		c.evictLRU()
+		c.metrics.Eviction()
 	}
 }
 
@@ -86,20 +119,45 @@ func (c *LRUCache) Get(key string) (any, bool) {
 
 	ent, exists := c.items[key]
 	if !exists {
+		c.metrics.Miss()
 		return nil, false
 	}
 
 	// check if expired
-	if time.Now().After(ent.expiresAt) {
+	if c.clock.Now().After(ent.expiresAt) {
 		c.removeEntry(ent)
+		c.metrics.Expiration()
+		c.metrics.Miss()
 		return nil, false
 	}
 
 	// move to front (most recently used)
 	c.evictList.MoveToFront(ent.element)
+	c.metrics.Hit()
 	return ent.value, true
 }
 
+// Peek retrieves a value from the cache without marking it as recently used.
+// It returns the value and a boolean indicating if the key was found and not expired.
+// Expired items are left for the cleanup goroutine.
+func (c *LRUCache) Peek(key string) (any, bool) {
+	value, _, ok := c.GetWithExpiry(key)
+	return value, ok
+}
+
+// GetWithExpiry is like Peek but also returns the time at which the item expires.
+func (c *LRUCache) GetWithExpiry(key string) (any, time.Time, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	ent, exists := c.items[key]
+	if !exists || c.clock.Now().After(ent.expiresAt) {
+		return nil, time.Time{}, false
+	}
+
+	return ent.value, ent.expiresAt, true
+}
+
 // Delete removes a key from the cache.
 // It returns true if the key was found and removed.
 func (c *LRUCache) Delete(key string) bool {
@@ -168,25 +226,27 @@ func (c *LRUCache) removeElement(element *list.Element) {
 
 // cleanupExpired runs in a goroutine and periodically removes expired items.
 func (c *LRUCache) cleanupExpired() {
-	ticker := time.NewTicker(time.Minute)
+	ticker := c.clock.NewTicker(time.Minute)
 	defer ticker.Stop()
 
 	for {
 		select {
-		case <-ticker.C:
-			c.removeExpired()
+		case <-ticker.C():
+			c.RemoveExpired()
 		case <-c.stopChan:
 			return
 		}
 	}
 }
 
-// removeExpired removes all expired items from the cache.
-func (c *LRUCache) removeExpired() {
+// RemoveExpired removes all expired items from the cache and returns how many were removed.
+// The background goroutine does this once a minute; call it directly to sweep on demand.
+func (c *LRUCache) RemoveExpired() int {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	now := time.Now()
+	now := c.clock.Now()
+	removed := 0
 	var next *list.Element
 
 	for element := c.evictList.Back(); element != nil; element = next {
@@ -195,6 +255,11 @@ func (c *LRUCache) removeExpired() {
 		ent := element.Value.(*entry)
 		if now.After(ent.expiresAt) {
 			c.removeElement(element)
+			c.metrics.Expiration()
+			removed++
 		}
 	}
+
+	c.metrics.SweepDuration(c.clock.Now().Sub(now))
+	return removed
 }
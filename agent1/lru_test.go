@@ -4,6 +4,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/rselbach/agent-comparison/clock"
+	"github.com/rselbach/agent-comparison/metrics"
 	"github.com/stretchr/testify/require"
 )
 
@@ -162,7 +164,8 @@ func TestEviction(t *testing.T) {
 
 func TestExpiration(t *testing.T) {
 	r := require.New(t)
-	c := New(5)
+	clk := clock.NewFake(time.Unix(0, 0))
+	c := New(5, WithClock(clk))
 
 	t.Run("items expire after ttl", func(t *testing.T) {
 		c.Set("key1", "value1", 10*time.Millisecond)
@@ -171,8 +174,7 @@ func TestExpiration(t *testing.T) {
 		_, ok := c.Get("key1")
 		r.True(ok)
 
-		// wait for expiration
-		time.Sleep(20 * time.Millisecond)
+		clk.Advance(20 * time.Millisecond)
 
 		// should be expired
 		_, ok = c.Get("key1")
@@ -185,12 +187,10 @@ func TestExpiration(t *testing.T) {
 
 		r.Equal(2, c.Len())
 
-		// wait for key2 to expire
-		time.Sleep(20 * time.Millisecond)
+		clk.Advance(20 * time.Millisecond)
 
-		// manually trigger cleanup by checking key3 (this doesn't trigger cleanup)
-		// the background cleanup runs every minute, so we'll trigger it manually
-		c.removeExpired()
+		// the background cleanup runs every minute, so trigger it manually
+		c.RemoveExpired()
 
 		// only key3 should remain
 		r.Equal(1, c.Len())
@@ -265,3 +265,109 @@ func TestEdgeCases(t *testing.T) {
 		r.Equal("empty_key_value", got)
 	})
 }
+
+func TestRemoveExpired(t *testing.T) {
+	r := require.New(t)
+	clk := clock.NewFake(time.Unix(0, 0))
+	c := New(5, WithClock(clk))
+	defer c.Close()
+
+	c.Set("short", "value1", time.Second)
+	c.Set("long", "value2", time.Hour)
+
+	clk.Advance(500 * time.Millisecond)
+	_, ok := c.Get("short")
+	r.True(ok)
+
+	clk.Advance(time.Second)
+	r.Equal(1, c.RemoveExpired())
+	r.Equal(1, c.Len())
+
+	_, ok = c.Get("short")
+	r.False(ok)
+
+	val, ok := c.Get("long")
+	r.True(ok)
+	r.Equal("value2", val)
+}
+
+func TestWithClock(t *testing.T) {
+	r := require.New(t)
+	clk := clock.NewFake(time.Unix(0, 0))
+	c := New(5, WithClock(clk))
+	defer c.Close()
+	// wait for the cleanup goroutine's ticker so the minute is counted from now
+	clk.BlockUntil(1)
+
+	c.Set("short", "value1", 30*time.Second)
+	c.Set("long", "value2", time.Hour)
+
+	clk.Advance(29 * time.Second)
+	_, ok := c.Get("short")
+	r.True(ok)
+
+	// the minute tick wakes the cleanup goroutine, which drops short
+	clk.Advance(31 * time.Second)
+	r.Eventually(func() bool { return c.Len() == 1 }, time.Second, time.Millisecond)
+
+	_, ok = c.Peek("long")
+	r.True(ok)
+}
+
+func TestWithRecorder(t *testing.T) {
+	r := require.New(t)
+	clk := clock.NewFake(time.Unix(0, 0))
+	rec := &metrics.Counters{}
+	c := New(2, WithClock(clk), WithRecorder(rec))
+	defer c.Close()
+
+	c.Set("a", 1, time.Second)
+	c.Set("b", 2, time.Hour)
+	c.Get("a")
+	c.Get("missing")
+	c.Peek("b")
+	c.Set("c", 3, time.Hour) // evicts b
+
+	clk.Advance(2 * time.Second)
+	c.Get("a")
+	c.Set("d", 4, time.Millisecond)
+	clk.Advance(time.Second)
+	c.RemoveExpired()
+
+	r.Equal(metrics.Snapshot{Hits: 1, Misses: 2, Evictions: 1, Expirations: 2, Sweeps: 1}, rec.Snapshot())
+}
+
+func TestPeekAndGetWithExpiry(t *testing.T) {
+	r := require.New(t)
+	clk := clock.NewFake(time.Unix(0, 0))
+	c := New(2, WithClock(clk))
+	defer c.Close()
+
+	c.Set("a", "value1", time.Minute)
+	c.Set("b", "value2", time.Second)
+
+	val, ok := c.Peek("a")
+	r.True(ok)
+	r.Equal("value1", val)
+
+	val, expiresAt, ok := c.GetWithExpiry("b")
+	r.True(ok)
+	r.Equal("value2", val)
+	r.Equal(clk.Now().Add(time.Second), expiresAt)
+
+	_, expiresAt, ok = c.GetWithExpiry("missing")
+	r.False(ok)
+	r.True(expiresAt.IsZero())
+
+	// neither call promoted a, so it is evicted first
+	c.Set("c", "value3", time.Minute)
+	_, ok = c.Peek("a")
+	r.False(ok)
+
+	clk.Advance(2 * time.Second)
+	_, ok = c.Peek("b")
+	r.False(ok)
+	_, _, ok = c.GetWithExpiry("b")
+	r.False(ok)
+	r.Equal(2, c.Len())
+}
@@ -0,0 +1,108 @@
+package lrucache
+
+import (
+	"hash/maphash"
+	"time"
+)
+
+// ShardedCache splits the keyspace across independent LRUCache shards so that
+// concurrent callers working on different keys rarely contend for the same lock.
+// Recency and eviction are tracked per shard, so the least recently used item
+// evicted is the oldest in its shard rather than in the whole cache.
+type ShardedCache struct {
+	seed   maphash.Seed
+	shards []*LRUCache
+}
+
+// NewSharded creates a ShardedCache holding roughly capacity items spread over
+// the given number of shards. Each shard gets capacity/shards items, rounded up.
+// If capacity is <= 0 it defaults to 1, and if shards is <= 0 it defaults to 1.
+// shards is capped at capacity so that every shard can hold at least one item.
+// opts are applied to every shard.
+func NewSharded(capacity, shards int, opts ...Option) *ShardedCache {
+	if capacity <= 0 {
+		capacity = 1
+	}
+	if shards <= 0 {
+		shards = 1
+	}
+	if shards > capacity {
+		shards = capacity
+	}
+
+	perShard := (capacity + shards - 1) / shards
+	c := &ShardedCache{
+		seed:   maphash.MakeSeed(),
+		shards: make([]*LRUCache, shards),
+	}
+	for i := range c.shards {
+		c.shards[i] = New(perShard, opts...)
+	}
+
+	return c
+}
+
+// shard returns the shard responsible for key.
+func (c *ShardedCache) shard(key string) *LRUCache {
+	return c.shards[maphash.String(c.seed, key)%uint64(len(c.shards))]
+}
+
+// Set adds a value to the cache with the specified TTL (time to live).
+// If the key's shard is full, it evicts that shard's least recently used item.
+func (c *ShardedCache) Set(key string, value any, ttl time.Duration) {
+	c.shard(key).Set(key, value, ttl)
+}
+
+// Get retrieves a value from the cache.
+// It returns the value and a boolean indicating if the key was found and not expired.
+func (c *ShardedCache) Get(key string) (any, bool) {
+	return c.shard(key).Get(key)
+}
+
+// Peek retrieves a value from the cache without marking it as recently used.
+func (c *ShardedCache) Peek(key string) (any, bool) {
+	return c.shard(key).Peek(key)
+}
+
+// GetWithExpiry is like Peek but also returns the time at which the item expires.
+func (c *ShardedCache) GetWithExpiry(key string) (any, time.Time, bool) {
+	return c.shard(key).GetWithExpiry(key)
+}
+
+// Delete removes a key from the cache.
+// It returns true if the key was found and removed.
+func (c *ShardedCache) Delete(key string) bool {
+	return c.shard(key).Delete(key)
+}
+
+// Clear removes all items from every shard.
+func (c *ShardedCache) Clear() {
+	for _, s := range c.shards {
+		s.Clear()
+	}
+}
+
+// Len returns the number of items across all shards.
+func (c *ShardedCache) Len() int {
+	n := 0
+	for _, s := range c.shards {
+		n += s.Len()
+	}
+	return n
+}
+
+// RemoveExpired removes all expired items from every shard and returns how many were removed.
+func (c *ShardedCache) RemoveExpired() int {
+	removed := 0
+	for _, s := range c.shards {
+		removed += s.RemoveExpired()
+	}
+	return removed
+}
+
+// Close stops every shard's cleanup goroutine and clears the cache.
+func (c *ShardedCache) Close() {
+	for _, s := range c.shards {
+		s.Close()
+	}
+}
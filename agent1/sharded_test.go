@@ -0,0 +1,146 @@
+package lrucache
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rselbach/agent-comparison/clock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSharded(t *testing.T) {
+	r := require.New(t)
+
+	t.Run("splits capacity across shards", func(t *testing.T) {
+		c := NewSharded(10, 4)
+		defer c.Close()
+		r.Len(c.shards, 4)
+		for _, s := range c.shards {
+			r.Equal(3, s.capacity)
+		}
+	})
+
+	t.Run("shards capped at capacity", func(t *testing.T) {
+		c := NewSharded(2, 8)
+		defer c.Close()
+		r.Len(c.shards, 2)
+	})
+
+	t.Run("non-positive arguments default to 1", func(t *testing.T) {
+		c := NewSharded(0, -1)
+		defer c.Close()
+		r.Len(c.shards, 1)
+		r.Equal(1, c.shards[0].capacity)
+	})
+}
+
+func TestShardedCache(t *testing.T) {
+	r := require.New(t)
+	clk := clock.NewFake(time.Unix(0, 0))
+	// Keys are spread with a random seed, so give every shard room for all 33
+	// keys the test sets; otherwise an unlucky seed evicts keys it expects.
+	c := NewSharded(8*33, 8, WithClock(clk))
+	defer c.Close()
+
+	for i := 0; i < 32; i++ {
+		c.Set(strconv.Itoa(i), i, time.Minute)
+	}
+	r.Equal(32, c.Len())
+
+	val, ok := c.Get("7")
+	r.True(ok)
+	r.Equal(7, val)
+
+	val, ok = c.Peek("8")
+	r.True(ok)
+	r.Equal(8, val)
+
+	_, expiresAt, ok := c.GetWithExpiry("9")
+	r.True(ok)
+	r.Equal(clk.Now().Add(time.Minute), expiresAt)
+
+	r.True(c.Delete("7"))
+	r.False(c.Delete("7"))
+	r.Equal(31, c.Len())
+
+	c.Set("short", "lived", time.Second)
+	clk.Advance(2 * time.Second)
+	r.Equal(1, c.RemoveExpired())
+	r.Equal(31, c.Len())
+
+	c.Clear()
+	r.Equal(0, c.Len())
+}
+
+func TestShardedCacheEvictsPerShard(t *testing.T) {
+	r := require.New(t)
+	c := NewSharded(16, 4)
+	defer c.Close()
+
+	for i := 0; i < 1000; i++ {
+		c.Set(strconv.Itoa(i), i, time.Hour)
+	}
+	r.Equal(16, c.Len())
+	for _, s := range c.shards {
+		r.Equal(4, s.Len())
+	}
+}
+
+func TestShardedCacheConcurrentAccess(t *testing.T) {
+	c := NewSharded(1000, 16)
+	defer c.Close()
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				key := strconv.Itoa(g*1000 + i)
+				c.Set(key, i, time.Minute)
+				c.Get(key)
+				if i%10 == 0 {
+					c.Delete(key)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	require.LessOrEqual(t, c.Len(), 1008)
+}
+
+func BenchmarkGetParallel(b *testing.B) {
+	keys := make([]string, 1024)
+	for i := range keys {
+		keys[i] = strconv.Itoa(i)
+	}
+
+	b.Run("single", func(b *testing.B) {
+		c := New(len(keys))
+		defer c.Close()
+		benchmarkGetParallel(b, c.Set, c.Get, keys)
+	})
+
+	b.Run("sharded", func(b *testing.B) {
+		c := NewSharded(len(keys), 32)
+		defer c.Close()
+		benchmarkGetParallel(b, c.Set, c.Get, keys)
+	})
+}
+
+func benchmarkGetParallel(b *testing.B, set func(string, any, time.Duration), get func(string) (any, bool), keys []string) {
+	for _, k := range keys {
+		set(k, k, time.Hour)
+	}
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			get(keys[i%len(keys)])
+			i++
+		}
+	})
+}
@@ -0,0 +1,156 @@
+package lru
+
+import (
+	"container/heap"
+	"time"
+)
+
+// expiryQueue is a min-heap of entries ordered by expiration time. The cache keeps a single scheduler timer armed
+// for the head of the queue instead of one timer per entry.
+type expiryQueue[K comparable, V any] []*entry[K, V]
+
+func (q expiryQueue[K, V]) Len() int { return len(q) }
+
+func (q expiryQueue[K, V]) Less(i, j int) bool { return q[i].expiresAt.Before(q[j].expiresAt) }
+
+func (q expiryQueue[K, V]) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *expiryQueue[K, V]) Push(x any) {
+	ent := x.(*entry[K, V])
+	ent.index = len(*q)
+	*q = append(*q, ent)
+}
+
+func (q *expiryQueue[K, V]) Pop() any {
+	old := *q
+	n := len(old)
+	ent := old[n-1]
+	old[n-1] = nil
+	ent.index = -1
+	*q = old[:n-1]
+	return ent
+}
+
+// trackExpiryLocked adds, repositions, or removes ent in the expiry queue to match its current expiration time and
+// re-arms the timer if the earliest deadline changed.
+func (c *Cache[K, V]) trackExpiryLocked(ent *entry[K, V]) {
+	switch {
+	case ent.expiresAt.IsZero() && ent.index >= 0:
+		heap.Remove(&c.expiries, ent.index)
+	case ent.expiresAt.IsZero():
+		return
+	case ent.index >= 0:
+		heap.Fix(&c.expiries, ent.index)
+	default:
+		heap.Push(&c.expiries, ent)
+	}
+	c.armLocked()
+}
+
+// untrackExpiryLocked removes ent from the expiry queue. The timer is left armed; if it fires early it finds nothing
+// due and re-arms for the new head.
+func (c *Cache[K, V]) untrackExpiryLocked(ent *entry[K, V]) {
+	if ent.index >= 0 {
+		heap.Remove(&c.expiries, ent.index)
+	}
+}
+
+// armLocked ensures the timer fires no later than the earliest expiration in the queue. It does nothing in sweep mode
+// or once the cache is closed.
+func (c *Cache[K, V]) armLocked() {
+	if c.closed || c.sweepEvery > 0 {
+		return
+	}
+	if len(c.expiries) == 0 {
+		c.stopTimerLocked()
+		return
+	}
+
+	next := c.expiries[0].expiresAt
+	if c.timer != nil && !c.timerAt.After(next) {
+		return
+	}
+
+	c.stopTimerLocked()
+	delay := next.Sub(c.sched.Now())
+	if delay < 0 {
+		delay = 0
+	}
+	seq := c.timerSeq
+	c.timerAt = next
+	c.timer = c.sched.AfterFunc(delay, func() {
+		c.expireDue(seq)
+	})
+}
+
+func (c *Cache[K, V]) stopTimerLocked() {
+	if c.timer == nil {
+		return
+	}
+	// if the timer already fired, its callback sees a stale sequence and leaves the new timer alone
+	c.timer.Stop()
+	c.timer = nil
+	c.timerAt = time.Time{}
+	c.timerSeq++
+}
+
+// expireDue removes every entry whose deadline has passed and re-arms the timer for the next one. seq identifies the
+// timer that triggered the call; callbacks from timers that were replaced in the meantime still reclaim due entries
+// but do not touch the current timer.
+func (c *Cache[K, V]) expireDue(seq uint64) {
+	now := c.sched.Now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.removeDueLocked(now)
+	if seq != c.timerSeq {
+		return
+	}
+	c.timer = nil
+	c.timerAt = time.Time{}
+	c.timerSeq++
+	c.armLocked()
+}
+
+// armSweepLocked schedules the next periodic sweep unless the cache is closed.
+func (c *Cache[K, V]) armSweepLocked() {
+	if c.closed {
+		return
+	}
+	seq := c.timerSeq
+	c.timer = c.sched.AfterFunc(c.sweepEvery, func() {
+		c.sweep(seq)
+	})
+}
+
+// sweep removes every entry due by now and schedules the next sweep. Like expireDue it ignores the timer state when
+// seq is stale.
+func (c *Cache[K, V]) sweep(seq uint64) {
+	now := c.sched.Now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.removeDueLocked(now)
+	if seq != c.timerSeq {
+		return
+	}
+	c.timer = nil
+	c.timerSeq++
+	c.armSweepLocked()
+}
+
+// removeDueLocked removes every entry whose deadline is not after now and records how long that took.
+func (c *Cache[K, V]) removeDueLocked(now time.Time) {
+	start := c.sched.Now()
+	for len(c.expiries) > 0 && !now.Before(c.expiries[0].expiresAt) {
+		c.removeElementLocked(c.expiries[0].elem)
+		c.metrics.Expiration()
+	}
+	c.metrics.SweepDuration(c.sched.Now().Sub(start))
+}
@@ -4,6 +4,9 @@ import (
 	"container/list"
 	"sync"
 	"time"
+
+	"github.com/rselbach/agent-comparison/clock"
+	"github.com/rselbach/agent-comparison/metrics"
 )
 
 // Cache stores values up to a fixed capacity using an LRU eviction policy and optional per-item expiration.
@@ -12,31 +15,101 @@ type Cache[K comparable, V any] struct {
 	capacity int
 	entries  map[K]*list.Element
 	order    *list.List
+	sched    Scheduler
+	metrics  metrics.Recorder
+	expiries expiryQueue[K, V]
+	timer    Timer
+	timerAt  time.Time
+	timerSeq uint64
+
+	// sweepEvery is the sweeper period, or zero when expirations are timed
+	// individually.
+	sweepEvery time.Duration
+	closed     bool
 }
 
 type entry[K comparable, V any] struct {
 	key       K
 	value     V
 	expiresAt time.Time
-	timer     *time.Timer
+	elem      *list.Element
+	index     int // position in the expiry queue, or -1 when not tracked
+}
+
+// Option configures a cache at construction time.
+type Option func(*options)
+
+type options struct {
+	sched      Scheduler
+	metrics    metrics.Recorder
+	sweepEvery time.Duration
+}
+
+// WithScheduler replaces the time source and timer implementation used for expiration.
+func WithScheduler(s Scheduler) Option {
+	return func(o *options) {
+		if s != nil {
+			o.sched = s
+		}
+	}
+}
+
+// WithRecorder reports hits, misses, evictions, expirations and the duration of each timer or sweeper pass to r.
+func WithRecorder(r metrics.Recorder) Option {
+	return func(o *options) {
+		o.metrics = r
+	}
+}
+
+// WithSweepInterval replaces the timer armed for the earliest expiration with a periodic sweep every d that removes
+// all entries due by then. Expired entries may then linger for up to d, but the timer is not re-armed as entries with
+// earlier deadlines arrive. A non-positive d keeps the default per-deadline timer.
+func WithSweepInterval(d time.Duration) Option {
+	return func(o *options) {
+		o.sweepEvery = d
+	}
 }
 
 // New constructs a cache with the provided capacity. Capacity must be greater than zero.
-func New[K comparable, V any](capacity int) *Cache[K, V] {
+func New[K comparable, V any](capacity int, opts ...Option) *Cache[K, V] {
 	if capacity <= 0 {
 		panic("lru: capacity must be greater than zero")
 	}
 
-	return &Cache[K, V]{
+	o := options{sched: clock.Real{}}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	c := &Cache[K, V]{
 		capacity: capacity,
 		entries:  make(map[K]*list.Element, capacity),
 		order:    list.New(),
+		sched:    o.sched,
+		metrics:  metrics.OrNop(o.metrics),
+	}
+	if o.sweepEvery > 0 {
+		c.sweepEvery = o.sweepEvery
+		c.mu.Lock()
+		c.armSweepLocked()
+		c.mu.Unlock()
 	}
+	return c
+}
+
+// Close stops the expiration timer or sweeper so the cache holds no pending callbacks. The cache stays usable
+// afterwards, but expired entries are only removed when Get finds them. Close is safe to call more than once.
+func (c *Cache[K, V]) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closed = true
+	c.stopTimerLocked()
 }
 
 // Set stores value for key with the provided ttl. A ttl of zero or less disables expiration.
 func (c *Cache[K, V]) Set(key K, value V, ttl time.Duration) {
-	now := time.Now()
+	now := c.sched.Now()
 
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -45,15 +118,7 @@ func (c *Cache[K, V]) Set(key K, value V, ttl time.Duration) {
 		ent := elem.Value.(*entry[K, V])
 		ent.value = value
 		ent.expiresAt = expirationTime(now, ttl)
-		if ent.timer != nil {
-			if !ent.timer.Stop() {
-				// timer already fired or is running; allow callback to observe updated expiration
-			}
-			ent.timer = nil
-		}
-		if ttl > 0 {
-			ent.timer = c.scheduleExpiration(key, ent.expiresAt)
-		}
+		c.trackExpiryLocked(ent)
 		c.order.MoveToFront(elem)
 		return
 	}
@@ -62,13 +127,12 @@ func (c *Cache[K, V]) Set(key K, value V, ttl time.Duration) {
 		key:       key,
 		value:     value,
 		expiresAt: expirationTime(now, ttl),
-	}
-	if ttl > 0 {
-		ent.timer = c.scheduleExpiration(key, ent.expiresAt)
+		index:     -1,
 	}
 
-	elem := c.order.PushFront(ent)
-	c.entries[key] = elem
+	ent.elem = c.order.PushFront(ent)
+	c.entries[key] = ent.elem
+	c.trackExpiryLocked(ent)
 	if c.order.Len() > c.capacity {
 		c.evictOldestLocked()
 	}
@@ -83,16 +147,20 @@ func (c *Cache[K, V]) Get(key K) (V, bool) {
 
 	elem, ok := c.entries[key]
 	if !ok {
+		c.metrics.Miss()
 		return zero, false
 	}
 
 	ent := elem.Value.(*entry[K, V])
-	if ent.expiresAt.IsZero() || time.Now().Before(ent.expiresAt) {
+	if ent.expiresAt.IsZero() || c.sched.Now().Before(ent.expiresAt) {
 		c.order.MoveToFront(elem)
+		c.metrics.Hit()
 		return ent.value, true
 	}
 
 	c.removeElementLocked(elem)
+	c.metrics.Expiration()
+	c.metrics.Miss()
 	return zero, false
 }
 
@@ -118,34 +186,6 @@ func (c *Cache[K, V]) Len() int {
 	return c.order.Len()
 }
 
-func (c *Cache[K, V]) scheduleExpiration(key K, expiresAt time.Time) *time.Timer {
-	delay := time.Until(expiresAt)
-	if delay < 0 {
-		delay = 0
-	}
-
-	return time.AfterFunc(delay, func() {
-		c.expire(key, expiresAt)
-	})
-}
-
-func (c *Cache[K, V]) expire(key K, expiresAt time.Time) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	elem, ok := c.entries[key]
-	if !ok {
-		return
-	}
-
-	ent := elem.Value.(*entry[K, V])
-	if !ent.expiresAt.Equal(expiresAt) {
-		return
-	}
-
-	c.removeElementLocked(elem)
-}
-
 func (c *Cache[K, V]) evictOldestLocked() {
 	elem := c.order.Back()
 	if elem == nil {
@@ -153,18 +193,14 @@ func (c *Cache[K, V]) evictOldestLocked() {
 	}
 
 	c.removeElementLocked(elem)
+	c.metrics.Eviction()
 }
 
 func (c *Cache[K, V]) removeElementLocked(elem *list.Element) {
 	c.order.Remove(elem)
 	ent := elem.Value.(*entry[K, V])
 	delete(c.entries, ent.key)
-	if ent.timer != nil {
-		if !ent.timer.Stop() {
-			// timer has already fired; allow callback to exit via expiration check
-		}
-		ent.timer = nil
-	}
+	c.untrackExpiryLocked(ent)
 }
 
 func expirationTime(now time.Time, ttl time.Duration) time.Time {
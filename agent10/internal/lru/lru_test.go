@@ -4,6 +4,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/rselbach/agent-comparison/clock"
 	"github.com/stretchr/testify/require"
 
 	"agent10/internal/lru"
@@ -37,12 +38,12 @@ func TestCacheSetGet(t *testing.T) {
 		t.Run(name, func(t *testing.T) {
 			r := require.New(t)
 
-			cache := lru.New[string, int](2)
+			sched := clock.NewFake(time.Unix(0, 0))
+			cache := lru.New[string, int](2, lru.WithScheduler(sched))
+			defer cache.Close()
 			cache.Set("alpha", 42, tc.ttl)
 
-			if tc.wait > 0 {
-				time.Sleep(tc.wait)
-			}
+			sched.Advance(tc.wait)
 
 			value, ok := cache.Get("alpha")
 			r.Equal(tc.wantOK, ok)
@@ -94,6 +95,7 @@ func TestCacheEviction(t *testing.T) {
 			r := require.New(t)
 
 			cache := lru.New[string, int](tc.capacity)
+			defer cache.Close()
 			tc.setup(cache)
 			tc.verify(r, cache)
 		})
@@ -120,10 +122,12 @@ func TestCacheExpirationRefresh(t *testing.T) {
 		t.Run(name, func(t *testing.T) {
 			r := require.New(t)
 
-			cache := lru.New[string, int](1)
+			sched := clock.NewFake(time.Unix(0, 0))
+			cache := lru.New[string, int](1, lru.WithScheduler(sched))
+			defer cache.Close()
 			cache.Set("token", 7, tc.initialTTL)
 
-			time.Sleep(tc.waits[0])
+			sched.Advance(tc.waits[0])
 			value, ok := cache.Get("token")
 			r.Equal(tc.expects[0], ok)
 			if ok {
@@ -132,14 +136,14 @@ func TestCacheExpirationRefresh(t *testing.T) {
 
 			cache.Set("token", 7, tc.refreshTTL)
 
-			time.Sleep(tc.waits[1])
+			sched.Advance(tc.waits[1])
 			value, ok = cache.Get("token")
 			r.Equal(tc.expects[1], ok)
 			if ok {
 				r.Equal(7, value)
 			}
 
-			time.Sleep(tc.waits[2])
+			sched.Advance(tc.waits[2])
 			_, ok = cache.Get("token")
 			r.Equal(tc.expects[2], ok)
 		})
@@ -171,6 +175,7 @@ func TestCacheDelete(t *testing.T) {
 			r := require.New(t)
 
 			cache := lru.New[string, int](2)
+			defer cache.Close()
 			tc.operations(r, cache)
 		})
 	}
@@ -0,0 +1,10 @@
+package lru
+
+import "github.com/rselbach/agent-comparison/clock"
+
+// Scheduler supplies the current time and runs expiration callbacks through AfterFunc. It is the shared clock.Clock:
+// the default is clock.Real, and tests can substitute a clock.Fake to fire expirations deterministically.
+type Scheduler = clock.Clock
+
+// Timer is a handle to a callback scheduled with a Scheduler.
+type Timer = clock.Timer
@@ -0,0 +1,148 @@
+package lru_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rselbach/agent-comparison/clock"
+	"github.com/rselbach/agent-comparison/metrics"
+	"github.com/stretchr/testify/require"
+
+	"agent10/internal/lru"
+)
+
+func newFakeScheduler() *clock.Fake {
+	return clock.NewFake(time.Unix(0, 0))
+}
+
+func TestCacheSchedulerExpiration(t *testing.T) {
+	r := require.New(t)
+	sched := newFakeScheduler()
+
+	cache := lru.New[string, int](4, lru.WithScheduler(sched))
+	cache.Set("short", 1, time.Second)
+	cache.Set("long", 2, time.Minute)
+	cache.Set("forever", 3, 0)
+
+	sched.Advance(999 * time.Millisecond)
+	r.Equal(3, cache.Len())
+
+	sched.Advance(time.Millisecond)
+	r.Equal(2, cache.Len())
+	_, ok := cache.Get("short")
+	r.False(ok)
+
+	cache.Set("long", 20, time.Hour)
+	sched.Advance(time.Minute)
+	value, ok := cache.Get("long")
+	r.True(ok)
+	r.Equal(20, value)
+
+	sched.Advance(time.Hour)
+	r.Equal(1, cache.Len())
+}
+
+func TestCacheSchedulerSingleTimer(t *testing.T) {
+	r := require.New(t)
+	sched := newFakeScheduler()
+
+	cache := lru.New[int, int](100, lru.WithScheduler(sched))
+	for i := 0; i < 50; i++ {
+		cache.Set(i, i, time.Duration(50-i)*time.Second)
+	}
+	r.Equal(1, sched.Pending())
+
+	cache.Delete(49)
+	r.Equal(1, sched.Pending())
+
+	sched.Advance(10 * time.Second)
+	r.Equal(40, cache.Len())
+	r.Equal(1, sched.Pending())
+
+	cache.Set(0, 0, 0)
+	sched.Advance(30 * time.Second)
+	r.Equal(10, cache.Len())
+	r.Equal(1, sched.Pending())
+
+	sched.Advance(10 * time.Second)
+	r.Equal(1, cache.Len())
+	r.Equal(0, sched.Pending())
+	_, ok := cache.Get(0)
+	r.True(ok)
+}
+
+func TestCacheRecorder(t *testing.T) {
+	r := require.New(t)
+	sched := newFakeScheduler()
+	rec := &metrics.Counters{}
+
+	cache := lru.New[string, int](2, lru.WithScheduler(sched), lru.WithRecorder(rec))
+	cache.Set("a", 1, time.Second)
+	cache.Set("b", 2, 0)
+	cache.Get("a")
+	cache.Get("missing")
+	cache.Set("c", 3, 0) // evicts b
+
+	sched.Advance(time.Second) // the timer expires a
+
+	cache.Set("d", 4, time.Second)
+	cache.Close()
+	sched.Advance(2 * time.Second)
+	_, ok := cache.Get("d")
+	r.False(ok)
+
+	r.Equal(metrics.Snapshot{Hits: 1, Misses: 2, Evictions: 1, Expirations: 2, Sweeps: 1}, rec.Snapshot())
+}
+
+func TestCacheCloseStopsTimer(t *testing.T) {
+	r := require.New(t)
+	sched := newFakeScheduler()
+
+	cache := lru.New[string, int](4, lru.WithScheduler(sched))
+	cache.Set("a", 1, time.Second)
+	r.Equal(1, sched.Pending())
+
+	cache.Close()
+	r.Equal(0, sched.Pending())
+	cache.Close()
+
+	// The cache stays usable; expiry becomes lazy.
+	cache.Set("b", 2, time.Second)
+	r.Equal(0, sched.Pending())
+	sched.Advance(2 * time.Second)
+	r.Equal(2, cache.Len())
+	_, ok := cache.Get("b")
+	r.False(ok)
+	r.Equal(1, cache.Len())
+}
+
+func TestCacheSweepInterval(t *testing.T) {
+	r := require.New(t)
+	sched := newFakeScheduler()
+
+	cache := lru.New[string, int](4, lru.WithScheduler(sched), lru.WithSweepInterval(10*time.Second))
+	r.Equal(1, sched.Pending())
+
+	cache.Set("short", 1, time.Second)
+	cache.Set("long", 2, 25*time.Second)
+	cache.Set("forever", 3, 0)
+	r.Equal(1, sched.Pending())
+
+	sched.Advance(5 * time.Second)
+	r.Equal(3, cache.Len(), "expired entries wait for the next sweep")
+
+	sched.Advance(5 * time.Second)
+	r.Equal(2, cache.Len())
+
+	sched.Advance(10 * time.Second)
+	r.Equal(2, cache.Len())
+
+	sched.Advance(10 * time.Second)
+	r.Equal(1, cache.Len())
+	r.Equal(1, sched.Pending())
+
+	cache.Close()
+	r.Equal(0, sched.Pending())
+	sched.Advance(time.Minute)
+	r.Equal(0, sched.Pending())
+}
@@ -0,0 +1,43 @@
+// Package lru makes agent10's cache, which lives in an internal package,
+// importable from other modules. Every identifier is an alias of, or forwards
+// to, the one in internal/lru; see there for the full documentation.
+package lru
+
+import (
+	"time"
+
+	"agent10/internal/lru"
+
+	"github.com/rselbach/agent-comparison/metrics"
+)
+
+// Cache stores values up to a fixed capacity using an LRU eviction policy and
+// optional per-item expiration.
+type Cache[K comparable, V any] = lru.Cache[K, V]
+
+// Option configures a cache at construction time.
+type Option = lru.Option
+
+// Scheduler supplies the current time and runs expiration callbacks.
+type Scheduler = lru.Scheduler
+
+// Timer is a handle to a callback scheduled with a Scheduler.
+type Timer = lru.Timer
+
+// New constructs a cache with the provided capacity. Capacity must be greater
+// than zero.
+func New[K comparable, V any](capacity int, opts ...Option) *Cache[K, V] {
+	return lru.New[K, V](capacity, opts...)
+}
+
+// WithScheduler replaces the time source and timer implementation used for
+// expiration.
+func WithScheduler(s Scheduler) Option { return lru.WithScheduler(s) }
+
+// WithRecorder reports hits, misses, evictions, expirations and the duration
+// of each timer or sweeper pass to r.
+func WithRecorder(r metrics.Recorder) Option { return lru.WithRecorder(r) }
+
+// WithSweepInterval replaces the per-deadline timer with a periodic sweep
+// every d.
+func WithSweepInterval(d time.Duration) Option { return lru.WithSweepInterval(d) }
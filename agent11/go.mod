@@ -1,3 +1,12 @@
 module agent11
 
 go 1.22.0
+
+require (
+	github.com/rselbach/agent-comparison/clock v0.0.0
+	github.com/rselbach/agent-comparison/metrics v0.0.0
+)
+
+replace github.com/rselbach/agent-comparison/clock => ../clock
+
+replace github.com/rselbach/agent-comparison/metrics => ../metrics
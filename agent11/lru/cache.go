@@ -1,9 +1,11 @@
 package lru
 
 import (
-	"container/list"
 	"sync"
 	"time"
+
+	"github.com/rselbach/agent-comparison/clock"
+	"github.com/rselbach/agent-comparison/metrics"
 )
 
 // Option configures cache behavior during construction.
@@ -12,6 +14,9 @@ type Option func(*options)
 type options struct {
 	defaultTTL      time.Duration
 	cleanupInterval time.Duration
+	clock           func() time.Time
+	source          clock.Clock
+	recorder        metrics.Recorder
 }
 
 // WithTTL sets a default time-to-live applied to entries inserted with Set.
@@ -38,21 +43,69 @@ func WithCleanupInterval(interval time.Duration) Option {
 	}
 }
 
+// WithClock overrides the time source used for expiry computation and checks.
+// It is primarily intended for tests that need to advance time without
+// sleeping. A nil clock leaves the default of time.Now in place.
+func WithClock(clock func() time.Time) Option {
+	return func(o *options) {
+		if clock != nil {
+			o.clock = clock
+		}
+	}
+}
+
+// WithClockSource uses clk both for expiry and for the background sweeper's
+// ticker, so tests can drive cleanup with a clock.Fake as well as expiry. A
+// nil clk leaves the defaults in place.
+func WithClockSource(clk clock.Clock) Option {
+	return func(o *options) {
+		if clk != nil {
+			o.source = clk
+			o.clock = clk.Now
+		}
+	}
+}
+
+// WithRecorder reports Get hits and misses, capacity evictions, TTL
+// expirations and Cleanup durations to r. Entries dropped because their
+// namespace was flushed or the cache cleared are not counted as expirations.
+// A nil r records nothing.
+func WithRecorder(r metrics.Recorder) Option {
+	return func(o *options) {
+		o.recorder = r
+	}
+}
+
 // Cache implements a size-bound least-recently-used cache with optional TTL
 // based expiration. Cache provides safe concurrent access.
 type Cache[K comparable, V any] struct {
 	mu              sync.Mutex
 	capacity        int
 	defaultTTL      time.Duration
-	items           map[K]*list.Element
-	evictionList    *list.List
+	items           map[scopedKey[K]]*entry[K, V]
+	evictionLists   [numPriorities]entryList[K, V]
 	cleanupInterval time.Duration
 	stopCh          chan struct{}
 	stopOnce        sync.Once
+	now             func() time.Time
+	source          clock.Clock
+	recorder        metrics.Recorder
+	generations     map[string]uint64
+}
+
+// scopedKey identifies an entry within its namespace. Entries stored through
+// the Cache methods live in the unnamed namespace.
+type scopedKey[K comparable] struct {
+	ns  string
+	key K
 }
 
 type entry[K comparable, V any] struct {
-	key     K
+	prev    *entry[K, V]
+	next    *entry[K, V]
+	key     scopedKey[K]
+	gen     uint64
+	prio    Priority
 	value   V
 	expires time.Time
 }
@@ -64,7 +117,7 @@ func New[K comparable, V any](capacity int, opts ...Option) *Cache[K, V] {
 		panic("lru: capacity must be greater than zero")
 	}
 
-	o := options{}
+	o := options{clock: time.Now}
 	for _, opt := range opts {
 		opt(&o)
 	}
@@ -72,9 +125,14 @@ func New[K comparable, V any](capacity int, opts ...Option) *Cache[K, V] {
 	c := &Cache[K, V]{
 		capacity:        capacity,
 		defaultTTL:      o.defaultTTL,
-		items:           make(map[K]*list.Element, capacity),
-		evictionList:    list.New(),
+		items:           make(map[scopedKey[K]]*entry[K, V], capacity),
 		cleanupInterval: o.cleanupInterval,
+		now:             o.clock,
+		source:          clock.OrReal(o.source),
+		recorder:        metrics.OrNop(o.recorder),
+	}
+	for i := range c.evictionLists {
+		c.evictionLists[i].init()
 	}
 
 	if c.cleanupInterval > 0 {
@@ -104,28 +162,61 @@ func (c *Cache[K, V]) Set(key K, value V) {
 func (c *Cache[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	c.setLocked(scopedKey[K]{key: key}, value, ttl, PriorityNormal)
+}
+
+// SetEvict is Set, additionally returning the entry the insert displaced to
+// make room, if any. Only capacity evictions are reported: expired entries
+// purged along the way and the previous value of an updated key are not. With
+// namespaces in use the evicted entry may belong to any namespace.
+func (c *Cache[K, V]) SetEvict(key K, value V) (evictedKey K, evictedValue V, evicted bool) {
+	return c.SetEvictWithTTL(key, value, c.defaultTTL)
+}
+
+// SetEvictWithTTL is SetWithTTL, reporting the displaced entry like SetEvict.
+func (c *Cache[K, V]) SetEvictWithTTL(key K, value V, ttl time.Duration) (evictedKey K, evictedValue V, evicted bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if ent := c.setLocked(scopedKey[K]{key: key}, value, ttl, PriorityNormal); ent != nil {
+		return ent.key.key, ent.value, true
+	}
+	return evictedKey, evictedValue, false
+}
 
-	c.purgeExpiredLocked(time.Now())
+// setLocked stores value under key and returns the entry evicted for capacity,
+// or nil.
+func (c *Cache[K, V]) setLocked(key scopedKey[K], value V, ttl time.Duration, prio Priority) *entry[K, V] {
+	c.purgeExpiredLocked(c.now())
 
-	if element, ok := c.items[key]; ok {
-		ent := element.Value.(*entry[K, V])
+	if ent, ok := c.items[key]; ok {
 		ent.value = value
 		ent.expires = c.expiryTime(ttl)
-		c.evictionList.MoveToFront(element)
-		return
+		if ent.prio != prio {
+			c.evictionLists[ent.prio].remove(ent)
+			ent.prio = prio
+			c.evictionLists[prio].pushFront(ent)
+		} else {
+			c.evictionLists[prio].moveToFront(ent)
+		}
+		return nil
 	}
 
-	for c.evictionList.Len() >= c.capacity {
-		c.removeOldestLocked()
+	var evicted *entry[K, V]
+	for len(c.items) >= c.capacity {
+		evicted = c.removeOldestLocked()
 	}
 
 	ent := &entry[K, V]{
 		key:     key,
+		gen:     c.generations[key.ns],
+		prio:    prio,
 		value:   value,
 		expires: c.expiryTime(ttl),
 	}
 
-	c.items[key] = c.evictionList.PushFront(ent)
+	c.evictionLists[prio].pushFront(ent)
+	c.items[key] = ent
+	return evicted
 }
 
 // Get returns the value associated with key. The boolean result indicates
@@ -133,42 +224,39 @@ func (c *Cache[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
 func (c *Cache[K, V]) Get(key K) (V, bool) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-
-	element, ok := c.items[key]
-	if !ok {
-		var zero V
-		return zero, false
-	}
-
-	ent := element.Value.(*entry[K, V])
-	if c.isExpired(ent, time.Now()) {
-		c.removeElementLocked(element)
-		var zero V
-		return zero, false
-	}
-
-	c.evictionList.MoveToFront(element)
-	return ent.value, true
+	return c.getLocked(scopedKey[K]{key: key}, true)
 }
 
 // Peek returns the value associated with key without updating its recency.
 func (c *Cache[K, V]) Peek(key K) (V, bool) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	return c.getLocked(scopedKey[K]{key: key}, false)
+}
 
-	element, ok := c.items[key]
+func (c *Cache[K, V]) getLocked(key scopedKey[K], touch bool) (V, bool) {
+	ent, ok := c.items[key]
 	if !ok {
+		if touch {
+			c.recorder.Miss()
+		}
 		var zero V
 		return zero, false
 	}
 
-	ent := element.Value.(*entry[K, V])
-	if c.isExpired(ent, time.Now()) {
-		c.removeElementLocked(element)
+	if now := c.now(); c.isExpired(ent, now) {
+		c.removeExpiredLocked(ent, now)
+		if touch {
+			c.recorder.Miss()
+		}
 		var zero V
 		return zero, false
 	}
 
+	if touch {
+		c.evictionLists[ent.prio].moveToFront(ent)
+		c.recorder.Hit()
+	}
 	return ent.value, true
 }
 
@@ -177,12 +265,15 @@ func (c *Cache[K, V]) Peek(key K) (V, bool) {
 func (c *Cache[K, V]) Delete(key K) bool {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	return c.deleteLocked(scopedKey[K]{key: key})
+}
 
-	element, ok := c.items[key]
+func (c *Cache[K, V]) deleteLocked(key scopedKey[K]) bool {
+	ent, ok := c.items[key]
 	if !ok {
 		return false
 	}
-	c.removeElementLocked(element)
+	c.removeEntryLocked(ent)
 	return true
 }
 
@@ -191,24 +282,66 @@ func (c *Cache[K, V]) Len() int {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	c.purgeExpiredLocked(time.Now())
-	return c.evictionList.Len()
+	c.purgeExpiredLocked(c.now())
+	return len(c.items)
+}
+
+// Size returns the number of stored entries, including expired ones that
+// have not been collected yet. Unlike Len it does not purge anything.
+func (c *Cache[K, V]) Size() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.items)
+}
+
+// ExpiredCount returns how many stored entries are past their TTL, or belong
+// to a flushed namespace, but have not been collected yet. It scans every
+// entry without removing any, and the count may be stale by the time it is
+// returned.
+func (c *Cache[K, V]) ExpiredCount() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	expired := 0
+	for _, ent := range c.items {
+		if c.isExpired(ent, now) {
+			expired++
+		}
+	}
+	return expired
 }
 
-// Cleanup removes expired entries immediately.
+// Cleanup removes expired entries, including those from flushed namespaces,
+// immediately.
 func (c *Cache[K, V]) Cleanup() int {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	return c.purgeExpiredLocked(time.Now())
+	now := c.now()
+	removed := c.purgeExpiredLocked(now)
+	c.recorder.SweepDuration(c.now().Sub(now))
+	return removed
+}
+
+// Clear removes every entry in every namespace in constant time. It swaps in
+// an empty map and eviction lists and leaves the old entries to the garbage
+// collector, so no later operation has to walk them.
+func (c *Cache[K, V]) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.items = make(map[scopedKey[K]]*entry[K, V])
+	for i := range c.evictionLists {
+		c.evictionLists[i].init()
+	}
 }
 
 func (c *Cache[K, V]) runCleanup() {
-	ticker := time.NewTicker(c.cleanupInterval)
+	ticker := c.source.NewTicker(c.cleanupInterval)
 	defer ticker.Stop()
 
 	for {
 		select {
-		case <-ticker.C:
+		case <-ticker.C():
 			c.Cleanup()
 		case <-c.stopCh:
 			return
@@ -220,10 +353,13 @@ func (c *Cache[K, V]) expiryTime(ttl time.Duration) time.Time {
 	if ttl <= 0 {
 		return time.Time{}
 	}
-	return time.Now().Add(ttl)
+	return c.now().Add(ttl)
 }
 
 func (c *Cache[K, V]) isExpired(ent *entry[K, V], now time.Time) bool {
+	if ent.gen != c.generations[ent.key.ns] {
+		return true
+	}
 	if ent.expires.IsZero() {
 		return false
 	}
@@ -232,31 +368,43 @@ func (c *Cache[K, V]) isExpired(ent *entry[K, V], now time.Time) bool {
 
 func (c *Cache[K, V]) purgeExpiredLocked(now time.Time) int {
 	removed := 0
-	for element := c.evictionList.Back(); element != nil; {
-		prev := element.Prev()
-		ent := element.Value.(*entry[K, V])
-		if !c.isExpired(ent, now) {
-			element = prev
-			continue
+	for i := range c.evictionLists {
+		l := &c.evictionLists[i]
+		for ent := l.back(); ent != nil; {
+			prev := l.prevOf(ent)
+			if c.isExpired(ent, now) {
+				c.removeExpiredLocked(ent, now)
+				removed++
+			}
+			ent = prev
 		}
-		c.evictionList.Remove(element)
-		delete(c.items, ent.key)
-		removed++
-		element = prev
 	}
 	return removed
 }
 
-func (c *Cache[K, V]) removeOldestLocked() {
-	element := c.evictionList.Back()
-	if element == nil {
-		return
+// removeOldestLocked evicts the least recently used entry of the lowest
+// priority that has any entries and returns it.
+func (c *Cache[K, V]) removeOldestLocked() *entry[K, V] {
+	for i := range c.evictionLists {
+		if ent := c.evictionLists[i].back(); ent != nil {
+			c.removeEntryLocked(ent)
+			c.recorder.Eviction()
+			return ent
+		}
+	}
+	return nil
+}
+
+// removeExpiredLocked removes an entry isExpired reported, recording an
+// expiration only if its TTL has passed.
+func (c *Cache[K, V]) removeExpiredLocked(ent *entry[K, V], now time.Time) {
+	c.removeEntryLocked(ent)
+	if !ent.expires.IsZero() && now.After(ent.expires) {
+		c.recorder.Expiration()
 	}
-	c.removeElementLocked(element)
 }
 
-func (c *Cache[K, V]) removeElementLocked(element *list.Element) {
-	c.evictionList.Remove(element)
-	ent := element.Value.(*entry[K, V])
+func (c *Cache[K, V]) removeEntryLocked(ent *entry[K, V]) {
+	c.evictionLists[ent.prio].remove(ent)
 	delete(c.items, ent.key)
 }
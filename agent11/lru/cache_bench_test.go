@@ -0,0 +1,62 @@
+package lru_test
+
+import (
+	"testing"
+
+	"agent11/lru"
+)
+
+const benchCapacity = 10_000
+
+func BenchmarkGetHit(b *testing.B) {
+	cache := lru.New[int, int](benchCapacity)
+	defer cache.Close()
+	for i := 0; i < benchCapacity; i++ {
+		cache.Set(i, i)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cache.Get(i % benchCapacity)
+	}
+}
+
+func BenchmarkSetUpdate(b *testing.B) {
+	cache := lru.New[int, int](benchCapacity)
+	defer cache.Close()
+	for i := 0; i < benchCapacity; i++ {
+		cache.Set(i, i)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cache.Set(i%benchCapacity, i)
+	}
+}
+
+func BenchmarkSetEvict(b *testing.B) {
+	cache := lru.New[int, int](benchCapacity)
+	defer cache.Close()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cache.Set(i, i)
+	}
+}
+
+func BenchmarkClear(b *testing.B) {
+	cache := lru.New[int, int](benchCapacity)
+	defer cache.Close()
+	for i := 0; i < benchCapacity; i++ {
+		cache.Set(i, i)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cache.Clear()
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"time"
 
 	"agent11/lru"
+	"github.com/rselbach/agent-comparison/clock"
+	"github.com/rselbach/agent-comparison/metrics"
 )
 
 func TestLRUEviction(t *testing.T) {
@@ -33,11 +35,12 @@ func TestLRUEviction(t *testing.T) {
 }
 
 func TestTTLExpiration(t *testing.T) {
-	cache := lru.New[string, int](2, lru.WithTTL(50*time.Millisecond))
+	clk := clock.NewFake(time.Unix(0, 0))
+	cache := lru.New[string, int](2, lru.WithTTL(50*time.Millisecond), lru.WithClockSource(clk))
 
 	cache.Set("a", 1)
 
-	time.Sleep(70 * time.Millisecond)
+	clk.Advance(70 * time.Millisecond)
 
 	if _, ok := cache.Get("a"); ok {
 		t.Fatalf("expected key a to expire")
@@ -49,15 +52,17 @@ func TestTTLExpiration(t *testing.T) {
 }
 
 func TestSetWithTTLOverridesDefault(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
 	cache := lru.New[string, int](
 		2,
 		lru.WithTTL(50*time.Millisecond),
+		lru.WithClockSource(clk),
 	)
 
 	cache.Set("short", 1)
 	cache.SetWithTTL("long", 2, 200*time.Millisecond)
 
-	time.Sleep(70 * time.Millisecond)
+	clk.Advance(70 * time.Millisecond)
 
 	if _, ok := cache.Get("short"); ok {
 		t.Fatalf("expected short to expire")
@@ -69,24 +74,26 @@ func TestSetWithTTLOverridesDefault(t *testing.T) {
 }
 
 func TestCleanupIntervalRemovesExpired(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
 	cache := lru.New[string, int](
 		2,
 		lru.WithTTL(30*time.Millisecond),
 		lru.WithCleanupInterval(10*time.Millisecond),
+		lru.WithClockSource(clk),
 	)
 	defer cache.Close()
+	clk.BlockUntil(1)
 
 	cache.Set("a", 1)
+	clk.Advance(40 * time.Millisecond)
 
-	deadline := time.Now().Add(250 * time.Millisecond)
-	for {
-		if _, ok := cache.Get("a"); !ok {
-			break
-		}
+	// Size counts expired entries, so it only drops once the sweeper has run.
+	deadline := time.Now().Add(time.Second)
+	for cache.Size() != 0 {
 		if time.Now().After(deadline) {
-			t.Fatalf("item did not expire within expected time")
+			t.Fatalf("sweeper did not remove the expired item")
 		}
-		time.Sleep(10 * time.Millisecond)
+		time.Sleep(time.Millisecond)
 	}
 }
 
@@ -107,3 +114,157 @@ func TestDelete(t *testing.T) {
 		t.Fatalf("expected delete on missing key to return false")
 	}
 }
+
+func TestWithClock(t *testing.T) {
+	now := time.Unix(1000, 0)
+	cache := lru.New[string, int](
+		4,
+		lru.WithTTL(time.Minute),
+		lru.WithClock(func() time.Time { return now }),
+	)
+
+	cache.Set("a", 1)
+	cache.SetWithTTL("b", 2, time.Hour)
+
+	now = now.Add(59 * time.Second)
+	if v, ok := cache.Peek("a"); !ok || v != 1 {
+		t.Fatalf("expected a to remain before TTL, got %v, %t", v, ok)
+	}
+
+	now = now.Add(2 * time.Second)
+	if _, ok := cache.Peek("a"); ok {
+		t.Fatalf("expected a to expire after advancing the clock")
+	}
+	if v, ok := cache.Get("b"); !ok || v != 2 {
+		t.Fatalf("expected b to remain, got %v, %t", v, ok)
+	}
+
+	now = now.Add(time.Hour)
+	if removed := cache.Cleanup(); removed != 1 {
+		t.Fatalf("expected cleanup to remove 1 entry, got %d", removed)
+	}
+}
+
+func TestSizeAndExpiredCountDoNotPurge(t *testing.T) {
+	now := time.Unix(0, 0)
+	cache := lru.New[string, int](8, lru.WithClock(func() time.Time { return now }))
+	defer cache.Close()
+
+	cache.SetWithTTL("a", 1, time.Second)
+	cache.SetWithTTL("b", 2, time.Minute)
+	cache.Set("c", 3)
+	cache.Namespace("ns").Set("d", 4)
+	cache.FlushNamespace("ns")
+
+	now = now.Add(2 * time.Second)
+
+	if got := cache.ExpiredCount(); got != 2 {
+		t.Fatalf("expected 2 expired entries, got %d", got)
+	}
+	if got := cache.Size(); got != 4 {
+		t.Fatalf("expected size 4 before purge, got %d", got)
+	}
+	if got := cache.Size(); got != 4 {
+		t.Fatalf("expected Size to leave entries in place, got %d", got)
+	}
+
+	if got := cache.Len(); got != 2 {
+		t.Fatalf("expected len 2, got %d", got)
+	}
+	if got := cache.Size(); got != 2 {
+		t.Fatalf("expected size 2 after purge, got %d", got)
+	}
+	if got := cache.ExpiredCount(); got != 0 {
+		t.Fatalf("expected no expired entries after purge, got %d", got)
+	}
+}
+
+func TestSetEvictReportsDisplacedEntry(t *testing.T) {
+	now := time.Unix(0, 0)
+	cache := lru.New[string, int](2, lru.WithClock(func() time.Time { return now }))
+
+	if _, _, evicted := cache.SetEvict("a", 1); evicted {
+		t.Fatalf("expected no eviction while below capacity")
+	}
+	cache.SetWithTTL("b", 2, time.Second)
+
+	if _, _, evicted := cache.SetEvict("a", 10); evicted {
+		t.Fatalf("expected update of existing key not to evict")
+	}
+
+	// b is now least recently used.
+	k, v, evicted := cache.SetEvict("c", 3)
+	if !evicted || k != "b" || v != 2 {
+		t.Fatalf("expected b=2 evicted, got %q=%d, %t", k, v, evicted)
+	}
+
+	// An expired entry is purged rather than reported as displaced.
+	cache.SetWithTTL("a", 1, time.Second)
+	now = now.Add(2 * time.Second)
+	if k, _, evicted := cache.SetEvictWithTTL("d", 4, time.Minute); evicted {
+		t.Fatalf("expected expired entries to be purged silently, got %q evicted", k)
+	}
+}
+
+func TestClear(t *testing.T) {
+	cache := lru.New[string, int](4)
+	defer cache.Close()
+
+	cache.Set("a", 1)
+	cache.Set("b", 2)
+	cache.Namespace("ns").Set("a", 3)
+
+	cache.Clear()
+
+	if _, ok := cache.Get("a"); ok {
+		t.Fatalf("expected a to be cleared")
+	}
+	if _, ok := cache.Namespace("ns").Peek("a"); ok {
+		t.Fatalf("expected ns a to be cleared")
+	}
+	// nothing is left for the next Set or Len to walk
+	if got := cache.Size(); got != 0 {
+		t.Fatalf("expected Clear to drop every entry, got size %d", got)
+	}
+	if got := cache.ExpiredCount(); got != 0 {
+		t.Fatalf("expected no cleared entry awaiting collection, got %d", got)
+	}
+
+	cache.Set("b", 4)
+	if v, ok := cache.Get("b"); !ok || v != 4 {
+		t.Fatalf("expected cache to accept writes after clear, got %v, %t", v, ok)
+	}
+	if got := cache.Size(); got != 1 {
+		t.Fatalf("expected size 1, got %d", got)
+	}
+	if got := cache.Len(); got != 1 {
+		t.Fatalf("expected len 1, got %d", got)
+	}
+}
+
+func TestWithRecorder(t *testing.T) {
+	now := time.Unix(0, 0)
+	rec := &metrics.Counters{}
+	cache := lru.New[string, int](2, lru.WithClock(func() time.Time { return now }), lru.WithRecorder(rec))
+
+	cache.SetWithTTL("a", 1, time.Second)
+	cache.Set("b", 2)
+	cache.Get("a")
+	cache.Get("missing")
+	cache.Peek("b")
+	cache.Set("c", 3) // evicts b
+
+	now = now.Add(2 * time.Second)
+	cache.Get("a")
+	cache.SetWithTTL("d", 4, time.Millisecond)
+	now = now.Add(time.Second)
+	if removed := cache.Cleanup(); removed != 1 {
+		t.Fatalf("expected cleanup to remove 1 entry, got %d", removed)
+	}
+	cache.Clear() // c is cleared, not expired
+
+	want := metrics.Snapshot{Hits: 1, Misses: 2, Evictions: 1, Expirations: 2, Sweeps: 1}
+	if got := rec.Snapshot(); got != want {
+		t.Fatalf("recorded %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,67 @@
+package lru
+
+// entryList is an intrusive doubly linked list of entries ordered from most
+// to least recently used. Entries carry their own links, so unlike
+// container/list there is no separate element allocation and no type
+// assertion to get from a node back to its entry.
+//
+// The list is circular around a sentinel root; the zero value must be
+// initialized with init before use.
+type entryList[K comparable, V any] struct {
+	root entry[K, V]
+	len  int
+}
+
+func (l *entryList[K, V]) init() {
+	l.root.next = &l.root
+	l.root.prev = &l.root
+	l.len = 0
+}
+
+// back returns the least recently used entry, or nil if the list is empty.
+func (l *entryList[K, V]) back() *entry[K, V] {
+	if l.len == 0 {
+		return nil
+	}
+	return l.root.prev
+}
+
+// prevOf returns the entry before e, toward the front, or nil at the front.
+func (l *entryList[K, V]) prevOf(e *entry[K, V]) *entry[K, V] {
+	if e.prev == &l.root {
+		return nil
+	}
+	return e.prev
+}
+
+func (l *entryList[K, V]) pushFront(e *entry[K, V]) {
+	l.insertAfter(e, &l.root)
+	l.len++
+}
+
+func (l *entryList[K, V]) moveToFront(e *entry[K, V]) {
+	if l.root.next == e {
+		return
+	}
+	l.unlink(e)
+	l.insertAfter(e, &l.root)
+}
+
+func (l *entryList[K, V]) remove(e *entry[K, V]) {
+	l.unlink(e)
+	e.prev = nil
+	e.next = nil
+	l.len--
+}
+
+func (l *entryList[K, V]) insertAfter(e, at *entry[K, V]) {
+	e.prev = at
+	e.next = at.next
+	at.next.prev = e
+	at.next = e
+}
+
+func (l *entryList[K, V]) unlink(e *entry[K, V]) {
+	e.prev.next = e.next
+	e.next.prev = e.prev
+}
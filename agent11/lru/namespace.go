@@ -0,0 +1,87 @@
+package lru
+
+import "time"
+
+// Namespace is a view on a Cache whose keys are scoped to a name. Keys in
+// different namespaces never collide, and a namespace can be flushed without
+// touching entries that belong to others. All namespaces share the parent
+// cache's capacity and LRU order.
+type Namespace[K comparable, V any] struct {
+	cache *Cache[K, V]
+	name  string
+}
+
+// Namespace returns a view scoped to name. The empty name refers to the
+// entries stored through the Cache methods directly.
+func (c *Cache[K, V]) Namespace(name string) *Namespace[K, V] {
+	return &Namespace[K, V]{cache: c, name: name}
+}
+
+// FlushNamespace invalidates every entry in the named namespace in constant
+// time. Flushed entries stop being visible immediately and are reclaimed by
+// the next cleanup pass or LRU eviction.
+func (c *Cache[K, V]) FlushNamespace(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.generations == nil {
+		c.generations = make(map[string]uint64)
+	}
+	c.generations[name]++
+}
+
+// Name returns the namespace name.
+func (n *Namespace[K, V]) Name() string {
+	return n.name
+}
+
+// Set inserts or updates the value for key, applying the cache default TTL.
+func (n *Namespace[K, V]) Set(key K, value V) {
+	n.SetWithTTL(key, value, n.cache.defaultTTL)
+}
+
+// SetWithTTL inserts or updates the value for key using the provided TTL.
+func (n *Namespace[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+	n.cache.mu.Lock()
+	defer n.cache.mu.Unlock()
+	n.cache.setLocked(n.scoped(key), value, ttl, PriorityNormal)
+}
+
+// SetWithPriority inserts or updates the value for key using the provided TTL
+// and eviction priority. See Cache.SetWithPriority.
+func (n *Namespace[K, V]) SetWithPriority(key K, value V, ttl time.Duration, prio Priority) {
+	n.cache.mu.Lock()
+	defer n.cache.mu.Unlock()
+	n.cache.setLocked(n.scoped(key), value, ttl, prio.normalize())
+}
+
+// Get returns the value associated with key in the namespace.
+func (n *Namespace[K, V]) Get(key K) (V, bool) {
+	n.cache.mu.Lock()
+	defer n.cache.mu.Unlock()
+	return n.cache.getLocked(n.scoped(key), true)
+}
+
+// Peek returns the value associated with key without updating its recency.
+func (n *Namespace[K, V]) Peek(key K) (V, bool) {
+	n.cache.mu.Lock()
+	defer n.cache.mu.Unlock()
+	return n.cache.getLocked(n.scoped(key), false)
+}
+
+// Delete removes key from the namespace, returning true when an entry was
+// removed.
+func (n *Namespace[K, V]) Delete(key K) bool {
+	n.cache.mu.Lock()
+	defer n.cache.mu.Unlock()
+	return n.cache.deleteLocked(n.scoped(key))
+}
+
+// Flush invalidates every entry in the namespace. See Cache.FlushNamespace.
+func (n *Namespace[K, V]) Flush() {
+	n.cache.FlushNamespace(n.name)
+}
+
+func (n *Namespace[K, V]) scoped(key K) scopedKey[K] {
+	return scopedKey[K]{ns: n.name, key: key}
+}
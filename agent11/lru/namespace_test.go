@@ -0,0 +1,75 @@
+package lru_test
+
+import (
+	"testing"
+
+	"agent11/lru"
+)
+
+func TestNamespaceIsolation(t *testing.T) {
+	cache := lru.New[string, int](8)
+	defer cache.Close()
+
+	acme := cache.Namespace("acme")
+	globex := cache.Namespace("globex")
+
+	cache.Set("user:1", 0)
+	acme.Set("user:1", 1)
+	globex.Set("user:1", 2)
+
+	if v, ok := cache.Get("user:1"); !ok || v != 0 {
+		t.Fatalf("expected root user:1=0, got %v, %t", v, ok)
+	}
+	if v, ok := acme.Get("user:1"); !ok || v != 1 {
+		t.Fatalf("expected acme user:1=1, got %v, %t", v, ok)
+	}
+	if v, ok := globex.Peek("user:1"); !ok || v != 2 {
+		t.Fatalf("expected globex user:1=2, got %v, %t", v, ok)
+	}
+
+	if !acme.Delete("user:1") {
+		t.Fatalf("expected acme delete to remove entry")
+	}
+	if _, ok := globex.Get("user:1"); !ok {
+		t.Fatalf("expected globex entry to survive acme delete")
+	}
+}
+
+func TestFlushNamespace(t *testing.T) {
+	cache := lru.New[string, int](8)
+	defer cache.Close()
+
+	acme := cache.Namespace("acme")
+	globex := cache.Namespace("globex")
+
+	acme.Set("a", 1)
+	acme.Set("b", 2)
+	globex.Set("a", 3)
+	cache.Set("a", 4)
+
+	cache.FlushNamespace("acme")
+
+	if _, ok := acme.Get("a"); ok {
+		t.Fatalf("expected acme a to be flushed")
+	}
+	if _, ok := acme.Peek("b"); ok {
+		t.Fatalf("expected acme b to be flushed")
+	}
+	if v, ok := globex.Get("a"); !ok || v != 3 {
+		t.Fatalf("expected globex a=3, got %v, %t", v, ok)
+	}
+	if got := cache.Len(); got != 2 {
+		t.Fatalf("expected 2 live entries after flush, got %d", got)
+	}
+
+	acme.Set("a", 5)
+	if v, ok := acme.Get("a"); !ok || v != 5 {
+		t.Fatalf("expected acme to accept writes after flush, got %v, %t", v, ok)
+	}
+
+	globex.Flush()
+	cache.Namespace("").Flush()
+	if got := cache.Len(); got != 1 {
+		t.Fatalf("expected only the new acme entry, got %d", got)
+	}
+}
@@ -0,0 +1,54 @@
+package lru
+
+import "time"
+
+// Priority ranks entries for capacity eviction. When the cache is full it
+// evicts from the lowest priority that has entries, falling back to least
+// recently used order within that priority. Priorities do not affect TTL
+// expiration.
+type Priority uint8
+
+const (
+	// PriorityLow marks entries that are cheap to recompute and should be
+	// sacrificed first.
+	PriorityLow Priority = iota
+	// PriorityNormal is the priority used by Set and SetWithTTL.
+	PriorityNormal
+	// PriorityHigh marks entries that are expensive to recompute and should
+	// be evicted last.
+	PriorityHigh
+
+	numPriorities = int(PriorityHigh) + 1
+)
+
+// String returns the lower-case name of the priority.
+func (p Priority) String() string {
+	switch p {
+	case PriorityLow:
+		return "low"
+	case PriorityNormal:
+		return "normal"
+	case PriorityHigh:
+		return "high"
+	default:
+		return "unknown"
+	}
+}
+
+// normalize clamps out-of-range priorities to PriorityHigh.
+func (p Priority) normalize() Priority {
+	if p > PriorityHigh {
+		return PriorityHigh
+	}
+	return p
+}
+
+// SetWithPriority inserts or updates the value for key using the provided TTL
+// and eviction priority. Updating an existing key replaces its priority; Set
+// and SetWithTTL reset it to PriorityNormal. Priorities above PriorityHigh are
+// treated as PriorityHigh.
+func (c *Cache[K, V]) SetWithPriority(key K, value V, ttl time.Duration, prio Priority) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.setLocked(scopedKey[K]{key: key}, value, ttl, prio.normalize())
+}
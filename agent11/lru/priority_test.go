@@ -0,0 +1,96 @@
+package lru_test
+
+import (
+	"testing"
+	"time"
+
+	"agent11/lru"
+)
+
+func TestPriorityEviction(t *testing.T) {
+	cache := lru.New[string, int](3)
+	defer cache.Close()
+
+	cache.SetWithPriority("expensive", 1, 0, lru.PriorityHigh)
+	cache.Set("normal", 2)
+	cache.SetWithPriority("cheap", 3, 0, lru.PriorityLow)
+
+	// cheap is the most recently used entry but the only low-priority one.
+	cache.Set("d", 4)
+	if _, ok := cache.Peek("cheap"); ok {
+		t.Fatalf("expected low priority entry to be evicted first")
+	}
+
+	cache.Set("e", 5)
+	if _, ok := cache.Peek("normal"); ok {
+		t.Fatalf("expected least recently used normal entry to be evicted")
+	}
+	if _, ok := cache.Peek("d"); !ok {
+		t.Fatalf("expected d to survive")
+	}
+
+	cache.Set("f", 6)
+	cache.Set("g", 7)
+	if v, ok := cache.Get("expensive"); !ok || v != 1 {
+		t.Fatalf("expected high priority entry to survive, got %v, %t", v, ok)
+	}
+	if cache.Len() != 3 {
+		t.Fatalf("expected len 3, got %d", cache.Len())
+	}
+}
+
+func TestPriorityUpdate(t *testing.T) {
+	cache := lru.New[string, int](2)
+	defer cache.Close()
+
+	cache.SetWithPriority("a", 1, 0, lru.PriorityHigh)
+	cache.Set("b", 2)
+	cache.Set("a", 10)
+
+	// a was reset to normal priority and b is now the older normal entry.
+	cache.Set("c", 3)
+	if _, ok := cache.Peek("b"); ok {
+		t.Fatalf("expected b to be evicted")
+	}
+
+	cache.SetWithPriority("c", 3, 0, lru.PriorityLow)
+	cache.Set("d", 4)
+	if _, ok := cache.Peek("c"); ok {
+		t.Fatalf("expected demoted c to be evicted")
+	}
+	if v, ok := cache.Peek("a"); !ok || v != 10 {
+		t.Fatalf("expected a=10, got %v, %t", v, ok)
+	}
+}
+
+func TestPriorityExpiration(t *testing.T) {
+	now := time.Unix(0, 0)
+	cache := lru.New[string, int](4, lru.WithClock(func() time.Time { return now }))
+	defer cache.Close()
+
+	cache.SetWithPriority("high", 1, time.Second, lru.PriorityHigh)
+	cache.SetWithPriority("low", 2, time.Minute, lru.PriorityLow)
+	cache.Namespace("ns").SetWithPriority("k", 3, time.Second, lru.PriorityLow)
+
+	now = now.Add(2 * time.Second)
+	if removed := cache.Cleanup(); removed != 2 {
+		t.Fatalf("expected 2 expired entries, got %d", removed)
+	}
+	if _, ok := cache.Get("low"); !ok {
+		t.Fatalf("expected low priority entry to outlive its TTL peers")
+	}
+}
+
+func TestPriorityString(t *testing.T) {
+	tests := map[lru.Priority]string{
+		lru.PriorityLow:    "low",
+		lru.PriorityNormal: "normal",
+		lru.PriorityHigh:   "high",
+		lru.Priority(9):    "unknown",
+	}
+	for p, want := range tests {
+		if got := p.String(); got != want {
+			t.Fatalf("Priority(%d).String() = %q, want %q", p, got, want)
+		}
+	}
+}
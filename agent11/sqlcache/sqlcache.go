@@ -0,0 +1,331 @@
+// Package sqlcache caches database/sql query results in an lru.Cache.
+//
+// Results are fully materialised before they are stored, so the wrapper is
+// meant for small, read-mostly lookups such as reference tables. Entries are
+// keyed by the normalised statement and its arguments, and are tracked by the
+// tables they read so writes issued through Exec can invalidate them.
+package sqlcache
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"agent11/lru"
+)
+
+// Conn is the subset of *sql.DB, *sql.Conn and *sql.Tx used by DB.
+type Conn interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// Result is a materialised query result. Callers must treat it as read-only
+// because the same value is returned to every caller that hits the cache.
+type Result struct {
+	Columns []string
+	Rows    [][]any
+}
+
+// Option configures a DB during construction.
+type Option func(*DB)
+
+// WithTTL sets the time-to-live applied to cached results. Zero uses the
+// cache's default TTL.
+func WithTTL(ttl time.Duration) Option {
+	return func(d *DB) {
+		if ttl < 0 {
+			ttl = 0
+		}
+		d.ttl = ttl
+	}
+}
+
+// WithTableExtractor overrides how the tables referenced by a statement are
+// determined. Tables drive invalidation by InvalidateTable and Exec.
+func WithTableExtractor(fn func(query string) []string) Option {
+	return func(d *DB) {
+		if fn != nil {
+			d.tables = fn
+		}
+	}
+}
+
+// WithInvalidateHook registers fn to be called with each table invalidated,
+// whether explicitly or by a write through Exec. It runs after the affected
+// entries have been removed and must not call back into DB.
+func WithInvalidateHook(fn func(table string)) Option {
+	return func(d *DB) {
+		d.onInvalidate = fn
+	}
+}
+
+// DB wraps a Conn and caches the results of Query.
+type DB struct {
+	conn         Conn
+	cache        *lru.Cache[string, *Result]
+	ttl          time.Duration
+	tables       func(query string) []string
+	onInvalidate func(table string)
+
+	// mu guards byTable, indexed and gens, and is held across storing a
+	// result and indexing it so InvalidateTable cannot slip in between.
+	mu      sync.Mutex
+	byTable map[string]map[string]struct{}
+	indexed int
+	// gens counts invalidations per table. Query skips storing a result if
+	// any of its tables was invalidated while the query ran.
+	gens map[string]uint64
+}
+
+// New wraps conn, storing query results in cache.
+func New(conn Conn, cache *lru.Cache[string, *Result], opts ...Option) *DB {
+	d := &DB{
+		conn:    conn,
+		cache:   cache,
+		tables:  ExtractTables,
+		byTable: make(map[string]map[string]struct{}),
+		gens:    make(map[string]uint64),
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// Query returns the cached result for query and args, running the query and
+// caching its result on a miss. A result is not cached if one of the tables it
+// reads is invalidated while the query runs, since it may predate the write.
+func (d *DB) Query(ctx context.Context, query string, args ...any) (*Result, error) {
+	key := Key(query, args...)
+	if res, ok := d.cache.Get(key); ok {
+		return res, nil
+	}
+
+	tables := d.tables(query)
+	for i, table := range tables {
+		tables[i] = strings.ToLower(table)
+	}
+	d.mu.Lock()
+	gens := make([]uint64, len(tables))
+	for i, table := range tables {
+		gens[i] = d.gens[table]
+	}
+	d.mu.Unlock()
+
+	rows, err := d.conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	res, err := scan(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for i, table := range tables {
+		if d.gens[table] != gens[i] {
+			return res, nil
+		}
+	}
+	if d.ttl > 0 {
+		d.cache.SetWithTTL(key, res, d.ttl)
+	} else {
+		d.cache.Set(key, res)
+	}
+	d.indexLocked(key, tables)
+	return res, nil
+}
+
+// Exec runs a statement on the underlying connection and, if it succeeds,
+// invalidates cached results for every table the statement references.
+func (d *DB) Exec(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	res, err := d.conn.ExecContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	for _, table := range d.tables(query) {
+		d.InvalidateTable(table)
+	}
+	return res, nil
+}
+
+// Invalidate removes the cached result for query and args, reporting whether
+// one was present.
+func (d *DB) Invalidate(query string, args ...any) bool {
+	return d.cache.Delete(Key(query, args...))
+}
+
+// InvalidateTable removes every cached result that reads table and returns the
+// number of entries removed.
+func (d *DB) InvalidateTable(table string) int {
+	table = strings.ToLower(table)
+
+	d.mu.Lock()
+	d.gens[table]++
+	keys := d.byTable[table]
+	delete(d.byTable, table)
+	d.indexed -= len(keys)
+	d.mu.Unlock()
+
+	removed := 0
+	for key := range keys {
+		if d.cache.Delete(key) {
+			removed++
+		}
+	}
+	if d.onInvalidate != nil {
+		d.onInvalidate(table)
+	}
+	return removed
+}
+
+// indexLocked records key under each lower-cased table. Keys dropped by the
+// LRU are pruned from the index once it grows well beyond the number of live
+// entries. The caller must hold d.mu.
+func (d *DB) indexLocked(key string, tables []string) {
+	for _, table := range tables {
+		set, ok := d.byTable[table]
+		if !ok {
+			set = make(map[string]struct{})
+			d.byTable[table] = set
+		}
+		if _, ok := set[key]; !ok {
+			set[key] = struct{}{}
+			d.indexed++
+		}
+	}
+
+	if limit := 2 * d.cache.Len(); d.indexed > limit && d.indexed > 64 {
+		d.pruneLocked()
+	}
+}
+
+func (d *DB) pruneLocked() {
+	d.indexed = 0
+	for table, set := range d.byTable {
+		for key := range set {
+			if _, ok := d.cache.Peek(key); !ok {
+				delete(set, key)
+			}
+		}
+		if len(set) == 0 {
+			delete(d.byTable, table)
+			continue
+		}
+		d.indexed += len(set)
+	}
+}
+
+func scan(rows *sql.Rows) (*Result, error) {
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	res := &Result{Columns: cols}
+	for rows.Next() {
+		values := make([]any, len(cols))
+		ptrs := make([]any, len(cols))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		// Scanning into *any copies []byte values, so rows stay valid after
+		// the driver reuses its buffers.
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+		res.Rows = append(res.Rows, values)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Key returns the cache key for query and args. Runs of whitespace outside
+// quoted literals and comments are collapsed and a trailing semicolon is
+// dropped; everything else, including the contents of literals, is left
+// untouched, so the statement is not case-folded.
+func Key(query string, args ...any) string {
+	var b strings.Builder
+	b.WriteString(strings.TrimSpace(strings.TrimSuffix(collapseWhitespace(query), ";")))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "\x00%T:%v", arg, arg)
+	}
+	return b.String()
+}
+
+// collapseWhitespace trims query and replaces each run of whitespace with a
+// single space, copying '…' and "…" literals, -- line comments and /* */
+// block comments verbatim.
+func collapseWhitespace(query string) string {
+	var b strings.Builder
+	pending := false
+	for i := 0; i < len(query); {
+		var end int
+		switch c := query[i]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f':
+			pending = true
+			i++
+			continue
+		case c == '\'' || c == '"':
+			// A doubled quote inside a literal closes and reopens it, which
+			// copies the same bytes.
+			end = closeAfter(query, i+1, string(c))
+		case strings.HasPrefix(query[i:], "--"):
+			end = strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				end = len(query)
+			} else {
+				end += i
+			}
+		case strings.HasPrefix(query[i:], "/*"):
+			end = closeAfter(query, i+2, "*/")
+		default:
+			end = i + 1
+		}
+		if pending && b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		pending = false
+		b.WriteString(query[i:end])
+		i = end
+	}
+	return b.String()
+}
+
+// closeAfter returns the index just past the first delim at or after start,
+// or len(query) if there is none.
+func closeAfter(query string, start int, delim string) int {
+	if n := strings.Index(query[start:], delim); n >= 0 {
+		return start + n + len(delim)
+	}
+	return len(query)
+}
+
+var tablePattern = regexp.MustCompile(`(?i)\b(?:from|join|into|update)\s+([a-zA-Z_][\w.]*)`)
+
+// ExtractTables is the default table extractor. It returns the identifiers
+// following FROM, JOIN, INTO and UPDATE, lower-cased and de-duplicated. It
+// does not understand subquery aliases or quoted identifiers; use
+// WithTableExtractor when that matters.
+func ExtractTables(query string) []string {
+	var tables []string
+	seen := make(map[string]bool)
+	for _, m := range tablePattern.FindAllStringSubmatch(query, -1) {
+		table := strings.ToLower(m[1])
+		if !seen[table] {
+			seen[table] = true
+			tables = append(tables, table)
+		}
+	}
+	return tables
+}
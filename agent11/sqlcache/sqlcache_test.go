@@ -0,0 +1,250 @@
+package sqlcache_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"agent11/lru"
+	"agent11/sqlcache"
+)
+
+// fakeDriver answers every query with a single row echoing the statement and
+// counts queries and execs.
+type fakeDriver struct {
+	queries atomic.Int32
+	execs   atomic.Int32
+	// onQuery, if set, runs while a query is in flight.
+	onQuery func()
+}
+
+func (d *fakeDriver) Open(string) (driver.Conn, error) { return &fakeConn{d: d}, nil }
+
+type fakeConn struct{ d *fakeDriver }
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{d: c.d, query: query}, nil
+}
+func (c *fakeConn) Close() error              { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) { return nil, driver.ErrSkip }
+
+type fakeStmt struct {
+	d     *fakeDriver
+	query string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Exec([]driver.Value) (driver.Result, error) {
+	s.d.execs.Add(1)
+	return driver.RowsAffected(1), nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.d.queries.Add(1)
+	if s.d.onQuery != nil {
+		s.d.onQuery()
+	}
+	row := []driver.Value{[]byte(s.query)}
+	row = append(row, args...)
+	return &fakeRows{row: row}, nil
+}
+
+type fakeRows struct {
+	row  []driver.Value
+	done bool
+}
+
+func (r *fakeRows) Columns() []string {
+	cols := []string{"query"}
+	for i := 1; i < len(r.row); i++ {
+		cols = append(cols, "arg")
+	}
+	return cols
+}
+func (r *fakeRows) Close() error { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	copy(dest, r.row)
+	return nil
+}
+
+var driverSeq atomic.Int32
+
+func openDB(t *testing.T) (*sql.DB, *fakeDriver) {
+	t.Helper()
+	d := &fakeDriver{}
+	name := fmt.Sprintf("sqlcache-fake-%d", driverSeq.Add(1))
+	sql.Register(name, d)
+	db, err := sql.Open(name, "")
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db, d
+}
+
+func TestQueryCachesResults(t *testing.T) {
+	db, drv := openDB(t)
+	cache := lru.New[string, *sqlcache.Result](10)
+	qc := sqlcache.New(db, cache)
+	ctx := context.Background()
+
+	first, err := qc.Query(ctx, "SELECT name FROM users WHERE id = ?", 1)
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	second, err := qc.Query(ctx, "SELECT name\n  FROM users   WHERE id = ?;", 1)
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	if first != second {
+		t.Fatalf("expected normalised statement to hit the cache")
+	}
+	if n := drv.queries.Load(); n != 1 {
+		t.Fatalf("expected 1 query, got %d", n)
+	}
+	if len(first.Rows) != 1 || len(first.Columns) != 2 {
+		t.Fatalf("unexpected result shape: %+v", first)
+	}
+
+	if _, err := qc.Query(ctx, "SELECT name FROM users WHERE id = ?", 2); err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	if n := drv.queries.Load(); n != 2 {
+		t.Fatalf("expected different args to miss, got %d queries", n)
+	}
+
+	if !qc.Invalidate("SELECT name FROM users WHERE id = ?", 1) {
+		t.Fatalf("expected invalidate to remove entry")
+	}
+	if _, err := qc.Query(ctx, "SELECT name FROM users WHERE id = ?", 1); err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	if n := drv.queries.Load(); n != 3 {
+		t.Fatalf("expected requery after invalidate, got %d queries", n)
+	}
+}
+
+func TestExecInvalidatesTables(t *testing.T) {
+	db, drv := openDB(t)
+	cache := lru.New[string, *sqlcache.Result](10)
+
+	var invalidated []string
+	qc := sqlcache.New(db, cache, sqlcache.WithInvalidateHook(func(table string) {
+		invalidated = append(invalidated, table)
+	}))
+	ctx := context.Background()
+
+	mustQuery := func(q string) {
+		t.Helper()
+		if _, err := qc.Query(ctx, q); err != nil {
+			t.Fatalf("query: %v", err)
+		}
+	}
+	mustQuery("SELECT * FROM users u JOIN roles r ON r.id = u.role_id")
+	mustQuery("SELECT * FROM countries")
+
+	if _, err := qc.Exec(ctx, "UPDATE roles SET name = 'x'"); err != nil {
+		t.Fatalf("exec: %v", err)
+	}
+	if drv.execs.Load() != 1 {
+		t.Fatalf("expected exec to reach the driver")
+	}
+	if strings.Join(invalidated, ",") != "roles" {
+		t.Fatalf("expected roles to be invalidated, got %v", invalidated)
+	}
+
+	mustQuery("SELECT * FROM users u JOIN roles r ON r.id = u.role_id")
+	mustQuery("SELECT * FROM countries")
+	if n := drv.queries.Load(); n != 3 {
+		t.Fatalf("expected only the join to be re-run, got %d queries", n)
+	}
+
+	if removed := qc.InvalidateTable("COUNTRIES"); removed != 1 {
+		t.Fatalf("expected 1 entry removed, got %d", removed)
+	}
+}
+
+func TestInvalidateDuringQuery(t *testing.T) {
+	db, drv := openDB(t)
+	cache := lru.New[string, *sqlcache.Result](10)
+	qc := sqlcache.New(db, cache)
+	ctx := context.Background()
+
+	// a write lands between the upstream query and storing its result
+	drv.onQuery = func() {
+		drv.onQuery = nil
+		qc.InvalidateTable("users")
+	}
+	if _, err := qc.Query(ctx, "SELECT * FROM users"); err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	if cache.Len() != 0 {
+		t.Fatalf("expected a result read before the invalidation not to be cached")
+	}
+
+	if _, err := qc.Query(ctx, "SELECT * FROM users"); err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	if _, err := qc.Query(ctx, "SELECT * FROM users"); err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	if n := drv.queries.Load(); n != 2 {
+		t.Fatalf("expected the second query to be cached, got %d queries", n)
+	}
+}
+
+func TestQueryTTL(t *testing.T) {
+	db, drv := openDB(t)
+	now := time.Unix(0, 0)
+	cache := lru.New[string, *sqlcache.Result](10, lru.WithClock(func() time.Time { return now }))
+	qc := sqlcache.New(db, cache, sqlcache.WithTTL(time.Minute))
+	ctx := context.Background()
+
+	if _, err := qc.Query(ctx, "SELECT 1"); err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	now = now.Add(2 * time.Minute)
+	if _, err := qc.Query(ctx, "SELECT 1"); err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	if n := drv.queries.Load(); n != 2 {
+		t.Fatalf("expected expired result to be re-queried, got %d queries", n)
+	}
+}
+
+func TestExtractTables(t *testing.T) {
+	got := sqlcache.ExtractTables("INSERT INTO Audit SELECT * FROM users JOIN users ON 1=1")
+	if strings.Join(got, ",") != "audit,users" {
+		t.Fatalf("unexpected tables: %v", got)
+	}
+}
+
+func TestKeyCollapsesWhitespaceOutsideLiterals(t *testing.T) {
+	if a, b := sqlcache.Key("SELECT  *\n FROM users ;"), sqlcache.Key("SELECT * FROM users"); a != b {
+		t.Fatalf("expected whitespace to be collapsed, got %q and %q", a, b)
+	}
+
+	for _, pair := range [][2]string{
+		{"SELECT * FROM users WHERE name = 'a  b'", "SELECT * FROM users WHERE name = 'a b'"},
+		{`SELECT * FROM "my  users"`, `SELECT * FROM "my users"`},
+		{"SELECT 1 /* a  b */", "SELECT 1 /* a b */"},
+		{"SELECT 'it''s  here'", "SELECT 'it''s here'"},
+	} {
+		if sqlcache.Key(pair[0]) == sqlcache.Key(pair[1]) {
+			t.Errorf("expected %q and %q to get different keys", pair[0], pair[1])
+		}
+	}
+}
@@ -1,10 +1,22 @@
-module github.com/rselbach/agent7
+module github.com/rselbach/agent12
 
 go 1.25.1
 
+require (
+	github.com/rselbach/agent-comparison/clock v0.0.0
+	github.com/rselbach/agent-comparison/flight v0.0.0
+	github.com/rselbach/agent-comparison/metrics v0.0.0
+	github.com/stretchr/testify v1.11.1
+)
+
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	github.com/stretchr/testify v1.11.1 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
+
+replace github.com/rselbach/agent-comparison/clock => ../clock
+
+replace github.com/rselbach/agent-comparison/metrics => ../metrics
+
+replace github.com/rselbach/agent-comparison/flight => ../flight
@@ -0,0 +1,10 @@
+package lru
+
+import "github.com/rselbach/agent-comparison/clock"
+
+// Clock abstracts the passage of time so expiration can be tested without sleeping.
+// It is the shared clock.Clock; clock.Real is the default and clock.Fake suits tests.
+type Clock = clock.Clock
+
+// Ticker is the ticker a Clock returns for the cleanup goroutine.
+type Ticker = clock.Ticker
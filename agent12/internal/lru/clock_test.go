@@ -0,0 +1,99 @@
+package lru
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rselbach/agent-comparison/clock"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock is a clock.Fake whose tickers only fire when Tick is called, so
+// tests can run a cleanup pass without moving time.
+type fakeClock struct {
+	*clock.Fake
+
+	mu      sync.Mutex
+	tickers []*fakeTicker
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{Fake: clock.NewFake(time.Unix(0, 0))}
+}
+
+func (f *fakeClock) NewTicker(d time.Duration) Ticker {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t := &fakeTicker{ch: make(chan time.Time)}
+	f.tickers = append(f.tickers, t)
+	return t
+}
+
+// Tick delivers a tick to every ticker and blocks until each is received.
+// It waits for at least one ticker to be created so callers need not race
+// the cleanup goroutine's startup.
+func (f *fakeClock) Tick() {
+	var tickers []*fakeTicker
+	for len(tickers) == 0 {
+		f.mu.Lock()
+		tickers = append([]*fakeTicker(nil), f.tickers...)
+		f.mu.Unlock()
+		runtime.Gosched()
+	}
+
+	now := f.Now()
+	for _, t := range tickers {
+		t.ch <- now
+	}
+}
+
+type fakeTicker struct {
+	ch chan time.Time
+}
+
+func (t *fakeTicker) C() <-chan time.Time { return t.ch }
+
+func (t *fakeTicker) Stop() {}
+
+func (t *fakeTicker) Reset(time.Duration) {}
+
+func TestCache_FakeClockExpiration(t *testing.T) {
+	r := require.New(t)
+	clock := newFakeClock()
+	cache := NewWithClock(10, time.Minute, clock)
+	defer cache.Close()
+
+	cache.Set("key1", "value1", time.Second)
+	cache.Set("key2", "value2", 0)
+
+	clock.Advance(500 * time.Millisecond)
+	val, ok := cache.Get("key1")
+	r.True(ok)
+	r.Equal("value1", val)
+
+	clock.Advance(time.Second)
+	_, ok = cache.Get("key1")
+	r.False(ok)
+	r.Equal(1, cache.Len())
+}
+
+func TestCache_FakeClockCleanup(t *testing.T) {
+	r := require.New(t)
+	clock := newFakeClock()
+	cache := NewWithClock(10, time.Minute, clock)
+	defer cache.Close()
+
+	cache.Set("key1", "value1", time.Second)
+	cache.Set("key2", "value2", 0)
+
+	clock.Advance(2 * time.Second)
+	clock.Tick()
+	// a second tick cannot be delivered until the first sweep has finished
+	clock.Tick()
+
+	cache.mu.RLock()
+	r.Len(cache.items, 1)
+	cache.mu.RUnlock()
+}
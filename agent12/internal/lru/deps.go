@@ -0,0 +1,88 @@
+package lru
+
+import (
+	"time"
+	"unique"
+)
+
+// SetWithDeps adds or updates a value like Set and records that it was derived
+// from deps. Deleting or setting any of the dependency keys later invalidates
+// this entry, and in turn anything that depends on it. A dependency that is
+// evicted or expires does not invalidate its dependents, since its value did
+// not change.
+func (c *Cache) SetWithDeps(key string, value interface{}, ttl time.Duration, deps ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.set(key, value, ttl, deps, nil)
+}
+
+// linkDeps records ent as a dependent of each of deps.
+// must be called with lock held.
+func (c *Cache) linkDeps(ent *entry, deps []string) {
+	if len(deps) == 0 {
+		return
+	}
+	if c.dependents == nil {
+		c.dependents = make(map[string]map[string]struct{})
+	}
+
+	for _, dep := range deps {
+		if dep == ent.key {
+			continue
+		}
+		if c.internKeys {
+			dep = unique.Make(dep).Value()
+		}
+		set, ok := c.dependents[dep]
+		if !ok {
+			set = make(map[string]struct{})
+			c.dependents[dep] = set
+		}
+		if _, dup := set[ent.key]; dup {
+			continue
+		}
+		set[ent.key] = struct{}{}
+		ent.deps = append(ent.deps, dep)
+	}
+}
+
+// unlinkDeps drops ent from the dependents of every key it depends on.
+// must be called with lock held.
+func (c *Cache) unlinkDeps(ent *entry) {
+	for _, dep := range ent.deps {
+		set := c.dependents[dep]
+		delete(set, ent.key)
+		if len(set) == 0 {
+			delete(c.dependents, dep)
+		}
+	}
+	ent.deps = nil
+}
+
+// invalidateDependents removes every entry that transitively depends on key.
+// key itself is left alone, which also breaks dependency cycles through it.
+// must be called with lock held.
+func (c *Cache) invalidateDependents(key string) {
+	visited := map[string]struct{}{key: {}}
+	queue := []string{key}
+
+	for len(queue) > 0 {
+		dep := queue[0]
+		queue = queue[1:]
+
+		for dependent := range c.dependents[dep] {
+			if _, seen := visited[dependent]; seen {
+				continue
+			}
+			visited[dependent] = struct{}{}
+			queue = append(queue, dependent)
+		}
+		if dep == key {
+			continue
+		}
+		if elem, exists := c.items[dep]; exists {
+			c.removeElement(elem)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package lru
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetWithDeps(t *testing.T) {
+	tests := map[string]struct {
+		mutate   func(c *Cache)
+		wantGone []string
+		wantKept []string
+	}{
+		"delete cascades transitively": {
+			mutate:   func(c *Cache) { c.Delete("user:1") },
+			wantGone: []string{"user:1", "profile:1", "dashboard:1"},
+			wantKept: []string{"user:2", "team"},
+		},
+		"update cascades": {
+			mutate:   func(c *Cache) { c.Set("user:2", "bob v2", 0) },
+			wantGone: []string{"team", "dashboard:1"},
+			wantKept: []string{"user:1", "user:2", "profile:1"},
+		},
+		"setting a missing dependency cascades": {
+			mutate:   func(c *Cache) { c.Set("config", "on", 0) },
+			wantGone: []string{"team", "dashboard:1"},
+			wantKept: []string{"user:1", "user:2", "profile:1", "config"},
+		},
+		"updating a dependent does not touch dependencies": {
+			mutate:   func(c *Cache) { c.SetWithDeps("profile:1", "p1 v2", 0, "user:1") },
+			wantGone: []string{"dashboard:1"},
+			wantKept: []string{"user:1", "profile:1", "team"},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := require.New(t)
+			c := New(10, 0)
+			defer c.Close()
+
+			c.Set("user:1", "alice", 0)
+			c.Set("user:2", "bob", 0)
+			c.SetWithDeps("profile:1", "p1", 0, "user:1")
+			c.SetWithDeps("team", "t", 0, "user:2", "config")
+			c.SetWithDeps("dashboard:1", "d1", 0, "profile:1", "team")
+
+			tt.mutate(c)
+
+			for _, key := range tt.wantGone {
+				_, ok := c.Get(key)
+				r.False(ok, "expected %s to be invalidated", key)
+			}
+			for _, key := range tt.wantKept {
+				_, ok := c.Get(key)
+				r.True(ok, "expected %s to be kept", key)
+			}
+		})
+	}
+}
+
+func TestDepsCycleAndBookkeeping(t *testing.T) {
+	r := require.New(t)
+	c := New(2, 0)
+	defer c.Close()
+
+	c.SetWithDeps("a", 1, 0, "b")
+	c.SetWithDeps("b", 2, 0, "a") // invalidates a, which depends on b
+
+	_, ok := c.Get("a")
+	r.False(ok)
+	v, ok := c.Get("b")
+	r.True(ok)
+	r.Equal(2, v)
+
+	// evicting an entry drops its edges
+	c.Set("x", 0, 0)
+	c.Set("y", 0, 0)
+	r.Empty(c.dependents)
+
+	c.SetWithDeps("z", 0, 0, "x")
+	c.Clear()
+	r.Nil(c.dependents)
+}
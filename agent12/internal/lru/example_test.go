@@ -4,7 +4,7 @@ import (
 	"fmt"
 	"time"
 
-	"github.com/rselbach/agent7/internal/lru"
+	"github.com/rselbach/agent12/internal/lru"
 )
 
 func Example() {
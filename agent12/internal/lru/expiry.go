@@ -0,0 +1,80 @@
+package lru
+
+import (
+	"container/heap"
+	"time"
+)
+
+// expiryHeap orders entries that have a TTL but have not yet been counted as
+// expired, soonest expiry first. It lets Len find newly expired entries
+// without walking the whole list.
+type expiryHeap []*entry
+
+func (h expiryHeap) Len() int           { return len(h) }
+func (h expiryHeap) Less(i, j int) bool { return h[i].expiresAt.Before(h[j].expiresAt) }
+
+func (h expiryHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *expiryHeap) Push(x any) {
+	ent := x.(*entry)
+	ent.index = len(*h)
+	*h = append(*h, ent)
+}
+
+func (h *expiryHeap) Pop() any {
+	old := *h
+	n := len(old)
+	ent := old[n-1]
+	old[n-1] = nil
+	ent.index = -1
+	*h = old[:n-1]
+	return ent
+}
+
+// Expired returns the number of entries that have expired but are still
+// stored because no lookup or cleanup pass has removed them yet. Len plus
+// Expired is the number of stored entries.
+func (c *Cache) Expired() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.countExpired(c.clock.Now())
+	return c.expired
+}
+
+// trackExpiry (re)registers ent after it was inserted or its expiry changed.
+// must be called with lock held.
+func (c *Cache) trackExpiry(ent *entry) {
+	c.untrackExpiry(ent)
+	if !ent.expiresAt.IsZero() {
+		heap.Push(&c.expiries, ent)
+	}
+}
+
+// untrackExpiry drops ent from the expiry bookkeeping.
+// must be called with lock held.
+func (c *Cache) untrackExpiry(ent *entry) {
+	if ent.index >= 0 {
+		heap.Remove(&c.expiries, ent.index)
+	}
+	if ent.counted {
+		ent.counted = false
+		c.expired--
+	}
+}
+
+// countExpired moves entries that have expired by now from the heap into the
+// expired count. Each entry is counted once, so the cost is amortised over
+// the entries that expire.
+// must be called with lock held.
+func (c *Cache) countExpired(now time.Time) {
+	for len(c.expiries) > 0 && now.After(c.expiries[0].expiresAt) {
+		ent := heap.Pop(&c.expiries).(*entry)
+		ent.counted = true
+		c.expired++
+	}
+}
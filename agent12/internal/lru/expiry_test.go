@@ -0,0 +1,84 @@
+package lru
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache_LenAndExpired(t *testing.T) {
+	r := require.New(t)
+	clock := newFakeClock()
+	cache := NewWithClock(10, time.Minute, clock)
+	defer cache.Close()
+
+	cache.Set("short", 1, time.Second)
+	cache.Set("medium", 2, 2*time.Second)
+	cache.Set("long", 3, time.Hour)
+	cache.Set("forever", 4, 0)
+	r.Equal(4, cache.Len())
+	r.Equal(0, cache.Expired())
+
+	// Get still returns an entry at its exact expiry, so Len counts it.
+	clock.Advance(time.Second)
+	r.Equal(4, cache.Len())
+	_, ok := cache.Peek("short")
+	r.True(ok)
+
+	clock.Advance(time.Nanosecond)
+	r.Equal(3, cache.Len())
+	r.Equal(1, cache.Expired())
+
+	// Refreshing an expired entry makes it live again.
+	cache.Set("short", 1, time.Minute)
+	r.Equal(4, cache.Len())
+	r.Equal(0, cache.Expired())
+
+	clock.Advance(5 * time.Second)
+	r.Equal(3, cache.Len())
+	r.Equal(1, cache.Expired())
+
+	// Removing an expired entry takes it out of the expired count.
+	_, ok = cache.Get("medium")
+	r.False(ok)
+	r.Equal(3, cache.Len())
+	r.Equal(0, cache.Expired())
+
+	// Dropping the TTL stops the entry from expiring.
+	cache.Set("long", 3, 0)
+	clock.Advance(2 * time.Hour)
+	r.Equal(2, cache.Len())
+	r.Equal(1, cache.Expired())
+
+	cache.Delete("short")
+	r.Equal(2, cache.Len())
+	r.Equal(0, cache.Expired())
+
+	cache.Set("x", 5, time.Second)
+	cache.Clear()
+	r.Equal(0, cache.Len())
+	r.Equal(0, cache.Expired())
+	r.Empty(cache.expiries)
+}
+
+func TestCache_LenAfterCleanupAndEviction(t *testing.T) {
+	r := require.New(t)
+	clock := newFakeClock()
+	cache := NewWithClock(2, time.Minute, clock)
+	defer cache.Close()
+
+	cache.Set("a", 1, time.Second)
+	cache.Set("b", 2, time.Second)
+	clock.Advance(2 * time.Second)
+	r.Equal(2, cache.Expired())
+
+	cache.Set("c", 3, time.Second) // evicts a
+	r.Equal(1, cache.Len())
+	r.Equal(1, cache.Expired())
+
+	cache.removeExpired()
+	r.Equal(1, cache.Len())
+	r.Equal(0, cache.Expired())
+	r.Len(cache.expiries, 1)
+}
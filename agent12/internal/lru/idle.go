@@ -0,0 +1,42 @@
+package lru
+
+import (
+	"container/list"
+	"time"
+)
+
+// SetMaxIdle makes the cleanup pass remove entries that have not been written
+// or read with Get or GetWithMeta within idle, even if their TTL has not
+// elapsed. Peek does not count as an access. Idle entries stay visible until
+// the next cleanup pass removes them. A non-positive idle turns the check off.
+func (c *Cache) SetMaxIdle(idle time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.maxIdle = idle
+}
+
+// touch records an access to elem at now and marks it most recently used.
+// must be called with lock held.
+func (c *Cache) touch(elem *list.Element, now time.Time) {
+	elem.Value.(*entry).accessedAt = now
+	c.list.MoveToFront(elem)
+}
+
+// removeIdle removes entries last accessed more than maxIdle before now.
+// The list is ordered by access time, so it stops at the first entry from the
+// back that is still fresh.
+// must be called with lock held.
+func (c *Cache) removeIdle(now time.Time) {
+	if c.maxIdle <= 0 {
+		return
+	}
+
+	cutoff := now.Add(-c.maxIdle)
+	for elem := c.list.Back(); elem != nil; elem = c.list.Back() {
+		if !elem.Value.(*entry).accessedAt.Before(cutoff) {
+			return
+		}
+		c.expire(elem)
+	}
+}
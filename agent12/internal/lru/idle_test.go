@@ -0,0 +1,65 @@
+package lru
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache_MaxIdle(t *testing.T) {
+	r := require.New(t)
+	clock := newFakeClock()
+	cache := NewWithClock(10, time.Minute, clock)
+	defer cache.Close()
+	cache.SetMaxIdle(10 * time.Second)
+
+	cache.Set("read", "value1", time.Hour)
+	cache.Set("peeked", "value2", time.Hour)
+	cache.Set("idle", "value3", 0)
+	cache.SetWithMeta("meta", "value4", time.Hour, map[string]string{"source": "db"})
+
+	clock.Advance(8 * time.Second)
+	_, ok := cache.Get("read")
+	r.True(ok)
+	_, ok = cache.Peek("peeked")
+	r.True(ok)
+	_, _, ok = cache.GetWithMeta("meta")
+	r.True(ok)
+
+	clock.Advance(5 * time.Second)
+	// idle entries stay visible until the cleanup pass runs
+	_, ok = cache.Peek("idle")
+	r.True(ok)
+
+	clock.Tick()
+	clock.Tick()
+
+	_, ok = cache.Get("peeked")
+	r.False(ok, "Peek does not count as an access")
+	_, ok = cache.Get("idle")
+	r.False(ok, "idle entries are removed even without a TTL")
+	_, ok = cache.Get("read")
+	r.True(ok)
+	_, ok = cache.Get("meta")
+	r.True(ok)
+	r.Equal(2, cache.Len())
+}
+
+func TestCache_MaxIdleDisabled(t *testing.T) {
+	r := require.New(t)
+	clock := newFakeClock()
+	cache := NewWithClock(10, time.Minute, clock)
+	defer cache.Close()
+
+	cache.SetMaxIdle(time.Second)
+	cache.SetMaxIdle(0)
+	cache.Set("key1", "value1", 0)
+
+	clock.Advance(time.Hour)
+	clock.Tick()
+	clock.Tick()
+
+	_, ok := cache.Get("key1")
+	r.True(ok)
+}
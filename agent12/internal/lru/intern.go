@@ -0,0 +1,14 @@
+package lru
+
+// SetKeyInterning turns key interning on or off for entries added afterwards.
+// With interning on, keys (and dependency names passed to SetWithDeps) are
+// replaced by a canonical copy shared with every other interned string of the
+// same content. Keys arriving as slices of larger buffers, e.g. from
+// deserialization, then no longer keep those buffers alive, and identical keys
+// held by several caches share one backing array.
+func (c *Cache) SetKeyInterning(enabled bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.internKeys = enabled
+}
@@ -0,0 +1,50 @@
+package lru
+
+import (
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyInterning(t *testing.T) {
+	r := require.New(t)
+
+	a := New(10, 0)
+	defer a.Close()
+	b := New(10, 0)
+	defer b.Close()
+	a.SetKeyInterning(true)
+	b.SetKeyInterning(true)
+
+	payload := []byte(`{"key":"user:42","pad":"` + string(make([]byte, 1024)) + `"}`)
+	key := string(payload)[8:15] // keeps the whole payload alive
+	r.Equal("user:42", key)
+
+	a.Set(key, 1, 0)
+	b.Set(string([]byte("user:42")), 2, 0)
+
+	ka := a.items["user:42"].Value.(*entry).key
+	kb := b.items["user:42"].Value.(*entry).key
+	r.True(sameData(ka, kb), "interned keys should share storage")
+	r.False(sameData(key, ka), "interned key should not alias the payload")
+
+	v, ok := a.Get("user:42")
+	r.True(ok)
+	r.Equal(1, v)
+}
+
+func TestKeyInterningDisabled(t *testing.T) {
+	r := require.New(t)
+
+	c := New(10, 0)
+	defer c.Close()
+
+	key := string([]byte("user:1"))
+	c.Set(key, 1, 0)
+	r.True(sameData(key, c.items["user:1"].Value.(*entry).key))
+}
+
+func sameData(a, b string) bool {
+	return unsafe.StringData(a) == unsafe.StringData(b)
+}
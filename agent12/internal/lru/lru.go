@@ -4,6 +4,10 @@ import (
 	"container/list"
 	"sync"
 	"time"
+	"unique"
+
+	"github.com/rselbach/agent-comparison/clock"
+	"github.com/rselbach/agent-comparison/metrics"
 )
 
 // Cache is an LRU cache with automatic expiration support.
@@ -15,6 +19,23 @@ type Cache struct {
 	stopCh    chan struct{}
 	wg        sync.WaitGroup
 	closeOnce sync.Once
+	clock     Clock
+	metrics   metrics.Recorder
+
+	// internKeys makes stored keys share canonical copies; see SetKeyInterning.
+	internKeys bool
+
+	// dependents maps a key to the keys whose entries were stored with it
+	// as a dependency.
+	dependents map[string]map[string]struct{}
+
+	// expiries holds entries not yet known to be expired; expired counts the
+	// stored entries that are. Together they make Len O(1) amortised.
+	expiries expiryHeap
+	expired  int
+
+	// maxIdle is the idle window enforced by the cleanup pass; see SetMaxIdle.
+	maxIdle time.Duration
 }
 
 // entry holds a cache value with its expiration time.
@@ -22,12 +43,32 @@ type entry struct {
 	key       string
 	value     interface{}
 	expiresAt time.Time
+	deps      []string
+	meta      map[string]string
+
+	// accessedAt is when the entry was last written or read by Get. Entries
+	// are ordered by it along the list, most recent at the front.
+	accessedAt time.Time
+
+	// index is the position in Cache.expiries, or -1 when not there.
+	index int
+	// counted reports whether the entry is included in Cache.expired.
+	counted bool
+
+	// interned keeps the canonical copy of key alive while interning is on.
+	interned unique.Handle[string]
 }
 
 // New creates a new LRU cache with the specified maximum size and cleanup interval.
 // The cache will automatically remove expired entries.
 // If cleanupInterval is 0, a default of 1 minute is used.
 func New(maxSize int, cleanupInterval time.Duration) *Cache {
+	return NewWithClock(maxSize, cleanupInterval, clock.Real{})
+}
+
+// NewWithClock is like New but uses clk for expiration checks and for the
+// cleanup ticker. A nil clk falls back to real time.
+func NewWithClock(maxSize int, cleanupInterval time.Duration, clk Clock) *Cache {
 	if maxSize <= 0 {
 		panic("lru: maxSize must be greater than 0")
 	}
@@ -41,6 +82,8 @@ func New(maxSize int, cleanupInterval time.Duration) *Cache {
 		items:   make(map[string]*list.Element),
 		list:    list.New(),
 		stopCh:  make(chan struct{}),
+		clock:   clock.OrReal(clk),
+		metrics: metrics.Nop{},
 	}
 
 	// start background cleanup goroutine
@@ -58,32 +101,63 @@ func (c *Cache) Get(key string) (interface{}, bool) {
 
 	elem, exists := c.items[key]
 	if !exists {
+		c.metrics.Miss()
 		return nil, false
 	}
 
 	ent := elem.Value.(*entry)
+	now := c.clock.Now()
 
 	// check if expired (skip check if expiresAt is zero, meaning no expiration)
-	if !ent.expiresAt.IsZero() && time.Now().After(ent.expiresAt) {
-		c.removeElement(elem)
+	if !ent.expiresAt.IsZero() && now.After(ent.expiresAt) {
+		c.expire(elem)
+		c.metrics.Miss()
 		return nil, false
 	}
 
 	// move to front (most recently used)
-	c.list.MoveToFront(elem)
+	c.touch(elem, now)
+	c.metrics.Hit()
+
+	return ent.value, true
+}
+
+// Peek retrieves a value like Get but without marking it as recently used.
+// Expired entries are reported absent but left for the cleanup goroutine.
+func (c *Cache) Peek(key string) (interface{}, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	elem, exists := c.items[key]
+	if !exists {
+		return nil, false
+	}
+
+	ent := elem.Value.(*entry)
+	if !ent.expiresAt.IsZero() && c.clock.Now().After(ent.expiresAt) {
+		return nil, false
+	}
 
 	return ent.value, true
 }
 
 // Set adds or updates a value in the cache with the specified TTL (time to live).
 // If TTL is 0 or negative, the item never expires.
+// Entries that depend on key are invalidated.
 func (c *Cache) Set(key string, value interface{}, ttl time.Duration) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	c.set(key, value, ttl, nil, nil)
+}
+
+// set stores the entry and cascades invalidation to its dependents.
+// must be called with lock held.
+func (c *Cache) set(key string, value interface{}, ttl time.Duration, deps []string, meta map[string]string) {
+	now := c.clock.Now()
 	var expiresAt time.Time
 	if ttl > 0 {
-		expiresAt = time.Now().Add(ttl)
+		expiresAt = now.Add(ttl)
 	}
 	// if ttl <= 0, leave expiresAt as zero value to indicate no expiration
 
@@ -91,28 +165,45 @@ func (c *Cache) Set(key string, value interface{}, ttl time.Duration) {
 	if elem, exists := c.items[key]; exists {
 		// update existing entry
 		ent := elem.Value.(*entry)
+		c.unlinkDeps(ent)
 		ent.value = value
 		ent.expiresAt = expiresAt
-		c.list.MoveToFront(elem)
+		c.trackExpiry(ent)
+		ent.meta = meta
+		c.linkDeps(ent, deps)
+		c.touch(elem, now)
+		c.invalidateDependents(key)
 		return
 	}
 
 	// add new entry
 	ent := &entry{
-		key:       key,
-		value:     value,
-		expiresAt: expiresAt,
+		key:        key,
+		value:      value,
+		expiresAt:  expiresAt,
+		meta:       meta,
+		accessedAt: now,
+		index:      -1,
+	}
+	if c.internKeys {
+		ent.interned = unique.Make(key)
+		ent.key = ent.interned.Value()
+		key = ent.key
 	}
 	elem := c.list.PushFront(ent)
 	c.items[key] = elem
+	c.trackExpiry(ent)
+	c.linkDeps(ent, deps)
 
 	// evict least recently used if over capacity
 	if c.list.Len() > c.maxSize {
 		c.evict()
 	}
+
+	c.invalidateDependents(key)
 }
 
-// Delete removes a value from the cache.
+// Delete removes a value from the cache along with every entry that depends on it.
 func (c *Cache) Delete(key string) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -120,6 +211,7 @@ func (c *Cache) Delete(key string) {
 	if elem, exists := c.items[key]; exists {
 		c.removeElement(elem)
 	}
+	c.invalidateDependents(key)
 }
 
 // Clear removes all items from the cache.
@@ -129,25 +221,19 @@ func (c *Cache) Clear() {
 
 	c.list.Init()
 	c.items = make(map[string]*list.Element)
+	c.dependents = nil
+	c.expiries = nil
+	c.expired = 0
 }
 
 // Len returns the current number of non-expired items in the cache.
+// It runs in amortised constant time; see Expired for the entries it excludes.
 func (c *Cache) Len() int {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	now := time.Now()
-	count := 0
-
-	for elem := c.list.Front(); elem != nil; elem = elem.Next() {
-		ent := elem.Value.(*entry)
-		// count items that never expire or haven't expired yet
-		if ent.expiresAt.IsZero() || now.Before(ent.expiresAt) {
-			count++
-		}
-	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-	return count
+	c.countExpired(c.clock.Now())
+	return c.list.Len() - c.expired
 }
 
 // Close stops the background cleanup goroutine and waits for it to finish.
@@ -165,6 +251,8 @@ func (c *Cache) removeElement(elem *list.Element) {
 	ent := elem.Value.(*entry)
 	delete(c.items, ent.key)
 	c.list.Remove(elem)
+	c.unlinkDeps(ent)
+	c.untrackExpiry(ent)
 }
 
 // evict removes the least recently used item from the cache.
@@ -173,32 +261,41 @@ func (c *Cache) evict() {
 	elem := c.list.Back()
 	if elem != nil {
 		c.removeElement(elem)
+		c.metrics.Eviction()
 	}
 }
 
+// expire removes an element whose TTL or idle window has passed.
+// must be called with lock held.
+func (c *Cache) expire(elem *list.Element) {
+	c.removeElement(elem)
+	c.metrics.Expiration()
+}
+
 // cleanup periodically removes expired entries from the cache.
 func (c *Cache) cleanup(interval time.Duration) {
 	defer c.wg.Done()
 
-	ticker := time.NewTicker(interval)
+	ticker := c.clock.NewTicker(interval)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-c.stopCh:
 			return
-		case <-ticker.C:
+		case <-ticker.C():
 			c.removeExpired()
 		}
 	}
 }
 
-// removeExpired removes all expired entries from the cache.
+// removeExpired removes all expired entries from the cache, and idle ones
+// when SetMaxIdle is in effect.
 func (c *Cache) removeExpired() {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	now := time.Now()
+	now := c.clock.Now()
 	var toRemove []*list.Element
 
 	// collect expired elements
@@ -212,6 +309,9 @@ func (c *Cache) removeExpired() {
 
 	// remove expired elements
 	for _, elem := range toRemove {
-		c.removeElement(elem)
+		c.expire(elem)
 	}
+
+	c.removeIdle(now)
+	c.metrics.SweepDuration(c.clock.Now().Sub(now))
 }
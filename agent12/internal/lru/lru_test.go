@@ -4,6 +4,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/rselbach/agent-comparison/clock"
 	"github.com/stretchr/testify/require"
 )
 
@@ -171,7 +172,8 @@ func TestCache_Clear(t *testing.T) {
 
 func TestCache_Expiration(t *testing.T) {
 	r := require.New(t)
-	cache := New(10, time.Minute)
+	clk := clock.NewFake(time.Unix(0, 0))
+	cache := NewWithClock(10, time.Minute, clk)
 	defer cache.Close()
 
 	// set item with short TTL
@@ -183,8 +185,7 @@ func TestCache_Expiration(t *testing.T) {
 	r.True(ok)
 	r.Equal("value1", val)
 
-	// wait for expiration
-	time.Sleep(150 * time.Millisecond)
+	clk.Advance(150 * time.Millisecond)
 
 	// expired item should return false
 	_, ok = cache.Get("key1")
@@ -198,9 +199,10 @@ func TestCache_Expiration(t *testing.T) {
 
 func TestCache_AutomaticCleanup(t *testing.T) {
 	r := require.New(t)
-	// use short cleanup interval for testing
-	cache := New(10, 100*time.Millisecond)
+	clk := clock.NewFake(time.Unix(0, 0))
+	cache := NewWithClock(10, 100*time.Millisecond, clk)
 	defer cache.Close()
+	clk.BlockUntil(1)
 
 	// add items with short TTL
 	cache.Set("key1", "value1", 50*time.Millisecond)
@@ -209,11 +211,14 @@ func TestCache_AutomaticCleanup(t *testing.T) {
 
 	r.Equal(3, cache.Len())
 
-	// wait for cleanup to run
-	time.Sleep(200 * time.Millisecond)
-
-	// expired items should be cleaned up
-	r.Equal(1, cache.Len())
+	// the tick wakes the cleanup goroutine, which removes the expired items;
+	// Len already hides them, so watch the list itself
+	clk.Advance(100 * time.Millisecond)
+	r.Eventually(func() bool {
+		cache.mu.Lock()
+		defer cache.mu.Unlock()
+		return cache.list.Len() == 1
+	}, time.Second, time.Millisecond)
 
 	_, ok := cache.Get("key3")
 	r.True(ok)
@@ -318,3 +323,31 @@ func TestCache_CloseIdempotent(t *testing.T) {
 		cache.Close()
 	})
 }
+
+func TestCache_Peek(t *testing.T) {
+	r := require.New(t)
+	clock := newFakeClock()
+	cache := NewWithClock(2, time.Hour, clock)
+	defer cache.Close()
+
+	cache.Set("key1", "value1", 0)
+	cache.Set("key2", "value2", time.Second)
+
+	val, ok := cache.Peek("key1")
+	r.True(ok)
+	r.Equal("value1", val)
+
+	_, ok = cache.Peek("missing")
+	r.False(ok)
+
+	// peeking key1 did not make it most recently used
+	cache.Set("key3", "value3", 0)
+	_, ok = cache.Peek("key1")
+	r.False(ok)
+
+	// expired entries are hidden but not removed
+	clock.Advance(2 * time.Second)
+	_, ok = cache.Peek("key2")
+	r.False(ok)
+	r.Len(cache.items, 2)
+}
@@ -0,0 +1,50 @@
+package lru
+
+import (
+	"maps"
+	"time"
+)
+
+// SetWithMeta adds or updates a value like Set and attaches meta to the entry,
+// for example the upstream source, version or trace ID that produced it. The
+// map is copied; metadata does not count toward capacity or affect eviction.
+// Setting the key again without metadata discards it.
+func (c *Cache) SetWithMeta(key string, value interface{}, ttl time.Duration, meta map[string]string) {
+	if len(meta) == 0 {
+		meta = nil
+	} else {
+		meta = maps.Clone(meta)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.set(key, value, ttl, nil, meta)
+}
+
+// GetWithMeta retrieves a value like Get together with the metadata it was
+// stored with, or nil if it had none. The returned map is shared with the
+// cache and must not be modified.
+func (c *Cache) GetWithMeta(key string) (interface{}, map[string]string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, exists := c.items[key]
+	if !exists {
+		c.metrics.Miss()
+		return nil, nil, false
+	}
+
+	ent := elem.Value.(*entry)
+	now := c.clock.Now()
+	if !ent.expiresAt.IsZero() && now.After(ent.expiresAt) {
+		c.expire(elem)
+		c.metrics.Miss()
+		return nil, nil, false
+	}
+
+	c.touch(elem, now)
+	c.metrics.Hit()
+
+	return ent.value, ent.meta, true
+}
@@ -0,0 +1,94 @@
+package lru
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetWithMeta(t *testing.T) {
+	tests := map[string]struct {
+		store    func(c *Cache)
+		wantVal  interface{}
+		wantMeta map[string]string
+		wantOK   bool
+	}{
+		"metadata round trips": {
+			store: func(c *Cache) {
+				c.SetWithMeta("k", "v", 0, map[string]string{"source": "db-1", "trace": "abc"})
+			},
+			wantVal:  "v",
+			wantMeta: map[string]string{"source": "db-1", "trace": "abc"},
+			wantOK:   true,
+		},
+		"plain set has no metadata": {
+			store:   func(c *Cache) { c.Set("k", "v", 0) },
+			wantVal: "v",
+			wantOK:  true,
+		},
+		"overwrite with set discards metadata": {
+			store: func(c *Cache) {
+				c.SetWithMeta("k", "v1", 0, map[string]string{"version": "1"})
+				c.Set("k", "v2", 0)
+			},
+			wantVal: "v2",
+			wantOK:  true,
+		},
+		"overwrite replaces metadata": {
+			store: func(c *Cache) {
+				c.SetWithMeta("k", "v1", 0, map[string]string{"version": "1", "source": "a"})
+				c.SetWithMeta("k", "v2", 0, map[string]string{"version": "2"})
+			},
+			wantVal:  "v2",
+			wantMeta: map[string]string{"version": "2"},
+			wantOK:   true,
+		},
+		"missing key": {
+			store: func(c *Cache) {},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := require.New(t)
+			c := New(4, time.Hour)
+			defer c.Close()
+
+			tc.store(c)
+			val, meta, ok := c.GetWithMeta("k")
+			r.Equal(tc.wantOK, ok)
+			r.Equal(tc.wantVal, val)
+			r.Equal(tc.wantMeta, meta)
+		})
+	}
+}
+
+func TestSetWithMetaCopiesMap(t *testing.T) {
+	r := require.New(t)
+	c := New(4, time.Hour)
+	defer c.Close()
+
+	meta := map[string]string{"source": "db-1"}
+	c.SetWithMeta("k", "v", 0, meta)
+	meta["source"] = "db-2"
+
+	_, got, ok := c.GetWithMeta("k")
+	r.True(ok)
+	r.Equal("db-1", got["source"])
+}
+
+func TestGetWithMetaExpired(t *testing.T) {
+	r := require.New(t)
+	clock := newFakeClock()
+	c := NewWithClock(4, time.Hour, clock)
+	defer c.Close()
+
+	c.SetWithMeta("k", "v", time.Second, map[string]string{"source": "db-1"})
+	clock.Advance(2 * time.Second)
+
+	_, meta, ok := c.GetWithMeta("k")
+	r.False(ok)
+	r.Nil(meta)
+	r.Equal(0, c.Len())
+}
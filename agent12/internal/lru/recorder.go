@@ -0,0 +1,14 @@
+package lru
+
+import "github.com/rselbach/agent-comparison/metrics"
+
+// SetRecorder makes the cache report to r: hits and misses of Get and
+// GetWithMeta, capacity evictions, entries removed because their TTL or idle
+// window passed, and the duration of each cleanup pass. Entries removed with
+// their dependencies are not counted. A nil r records nothing.
+func (c *Cache) SetRecorder(r metrics.Recorder) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.metrics = metrics.OrNop(r)
+}
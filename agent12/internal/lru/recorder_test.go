@@ -0,0 +1,38 @@
+package lru
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rselbach/agent-comparison/metrics"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache_Recorder(t *testing.T) {
+	r := require.New(t)
+	clock := newFakeClock()
+	rec := &metrics.Counters{}
+	cache := NewWithClock(2, time.Minute, clock)
+	defer cache.Close()
+	cache.SetRecorder(rec)
+
+	cache.Set("a", 1, time.Second)
+	cache.Set("b", 2, 0)
+	cache.Get("a")
+	cache.Get("missing")
+	cache.Peek("b")
+	cache.Set("c", 3, 0) // evicts b
+
+	clock.Advance(2 * time.Second)
+	cache.Get("a")
+	cache.Set("d", 4, time.Millisecond)
+	clock.Advance(time.Second)
+
+	// the second tick is only received once the first pass has finished
+	clock.Tick()
+	clock.Tick()
+
+	got := rec.Snapshot()
+	r.GreaterOrEqual(got.Sweeps, int64(1))
+	r.Equal(metrics.Snapshot{Hits: 1, Misses: 2, Evictions: 1, Expirations: 2, Sweeps: got.Sweeps}, got)
+}
@@ -0,0 +1,111 @@
+package lru
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/rselbach/agent-comparison/flight"
+)
+
+// ErrTokenExpired is returned when a refresh function yields a token that has already expired.
+var ErrTokenExpired = errors.New("lru: refreshed token is already expired")
+
+// Token is a credential together with the time it stops being valid.
+type Token struct {
+	Value     string
+	ExpiresAt time.Time
+}
+
+// RefreshFunc obtains a new token for key.
+type RefreshFunc func(ctx context.Context, key string) (Token, error)
+
+// TokenCache stores tokens in a Cache and refreshes them shortly before they expire.
+// Concurrent refreshes of the same key are collapsed into a single call.
+type TokenCache struct {
+	cache   *Cache
+	refresh RefreshFunc
+	skew    time.Duration
+
+	refreshes flight.Group[string, Token]
+}
+
+// tokenResult is the outcome of a refresh as seen by one caller.
+type tokenResult struct {
+	tok Token
+	err error
+}
+
+// NewTokenCache creates a TokenCache backed by cache. Tokens are refreshed once they
+// are within skew of their expiry: callers keep receiving the current token while a
+// background refresh runs, and only block when the token is missing or already expired.
+func NewTokenCache(cache *Cache, refresh RefreshFunc, skew time.Duration) *TokenCache {
+	if skew < 0 {
+		skew = 0
+	}
+
+	return &TokenCache{
+		cache:   cache,
+		refresh: refresh,
+		skew:    skew,
+	}
+}
+
+// Token returns a valid token for key, refreshing it when needed.
+func (tc *TokenCache) Token(ctx context.Context, key string) (Token, error) {
+	now := tc.cache.clock.Now()
+
+	if v, ok := tc.cache.Get(key); ok {
+		// The cache may be shared, so a value under key that is not a Token
+		// counts as a miss.
+		if tok, ok := v.(Token); ok && now.Before(tok.ExpiresAt) {
+			if !now.Before(tok.ExpiresAt.Add(-tc.skew)) {
+				// close to expiry: refresh in the background
+				tc.start(ctx, key)
+			}
+			return tok, nil
+		}
+	}
+
+	select {
+	case res := <-tc.start(ctx, key):
+		return res.tok, res.err
+	case <-ctx.Done():
+		return Token{}, ctx.Err()
+	}
+}
+
+// Invalidate drops the cached token for key so the next call to Token refreshes it.
+func (tc *TokenCache) Invalidate(key string) {
+	tc.cache.Delete(key)
+}
+
+// start joins the in-flight refresh for key, launching one if none is running,
+// and returns a channel that receives its result. The refresh is shared by every
+// caller that joins it, so it runs detached from ctx's cancellation; each caller
+// stops waiting on its own context instead.
+func (tc *TokenCache) start(ctx context.Context, key string) <-chan tokenResult {
+	ctx = context.WithoutCancel(ctx)
+	done := make(chan tokenResult, 1)
+	go func() {
+		tok, err := tc.refreshes.Do(key, func() (Token, error) {
+			return tc.load(ctx, key)
+		})
+		done <- tokenResult{tok, err}
+	}()
+	return done
+}
+
+// load calls the refresh function and stores a token that is still valid.
+func (tc *TokenCache) load(ctx context.Context, key string) (Token, error) {
+	tok, err := tc.refresh(ctx, key)
+	if err != nil {
+		return Token{}, err
+	}
+	ttl := tok.ExpiresAt.Sub(tc.cache.clock.Now())
+	if ttl <= 0 {
+		return Token{}, ErrTokenExpired
+	}
+	tc.cache.Set(key, tok, ttl)
+	return tok, nil
+}
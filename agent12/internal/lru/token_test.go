@@ -0,0 +1,217 @@
+package lru
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// waitForRefresh blocks until n callers are waiting on the refresh of key
+// besides the one running it.
+func waitForRefresh(tc *TokenCache, key string, n int) {
+	for tc.refreshes.Waiting(key) < n {
+		runtime.Gosched()
+	}
+}
+
+func TestTokenCache_FetchesAndCaches(t *testing.T) {
+	r := require.New(t)
+	clock := newFakeClock()
+	cache := NewWithClock(10, time.Minute, clock)
+	defer cache.Close()
+
+	var calls atomic.Int32
+	tc := NewTokenCache(cache, func(ctx context.Context, key string) (Token, error) {
+		n := calls.Add(1)
+		return Token{Value: fmt.Sprintf("%s-%d", key, n), ExpiresAt: clock.Now().Add(time.Hour)}, nil
+	}, 5*time.Minute)
+
+	tok, err := tc.Token(context.Background(), "svc")
+	r.NoError(err)
+	r.Equal("svc-1", tok.Value)
+
+	tok, err = tc.Token(context.Background(), "svc")
+	r.NoError(err)
+	r.Equal("svc-1", tok.Value)
+	r.Equal(int32(1), calls.Load())
+}
+
+func TestTokenCache_ForeignValueIsMiss(t *testing.T) {
+	r := require.New(t)
+	clock := newFakeClock()
+	cache := NewWithClock(10, time.Minute, clock)
+	defer cache.Close()
+
+	// another user of the shared cache stored something else under the key
+	cache.Set("svc", "not a token", time.Hour)
+
+	tc := NewTokenCache(cache, func(ctx context.Context, key string) (Token, error) {
+		return Token{Value: "fresh", ExpiresAt: clock.Now().Add(time.Hour)}, nil
+	}, 5*time.Minute)
+
+	tok, err := tc.Token(context.Background(), "svc")
+	r.NoError(err)
+	r.Equal("fresh", tok.Value)
+}
+
+func TestTokenCache_RefreshesBeforeExpiry(t *testing.T) {
+	r := require.New(t)
+	clock := newFakeClock()
+	cache := NewWithClock(10, time.Minute, clock)
+	defer cache.Close()
+
+	var calls atomic.Int32
+	refreshed := make(chan struct{}, 1)
+	tc := NewTokenCache(cache, func(ctx context.Context, key string) (Token, error) {
+		n := calls.Add(1)
+		if n > 1 {
+			defer func() { refreshed <- struct{}{} }()
+		}
+		return Token{Value: fmt.Sprintf("t%d", n), ExpiresAt: clock.Now().Add(time.Hour)}, nil
+	}, 5*time.Minute)
+
+	_, err := tc.Token(context.Background(), "svc")
+	r.NoError(err)
+
+	// inside the skew window the current token is still served while a refresh runs
+	clock.Advance(56 * time.Minute)
+	tok, err := tc.Token(context.Background(), "svc")
+	r.NoError(err)
+	r.Equal("t1", tok.Value)
+
+	<-refreshed
+	r.Eventually(func() bool {
+		tok, err := tc.Token(context.Background(), "svc")
+		return err == nil && tok.Value == "t2"
+	}, time.Second, time.Millisecond)
+}
+
+func TestTokenCache_BlocksWhenExpired(t *testing.T) {
+	r := require.New(t)
+	clock := newFakeClock()
+	cache := NewWithClock(10, time.Minute, clock)
+	defer cache.Close()
+
+	var calls atomic.Int32
+	tc := NewTokenCache(cache, func(ctx context.Context, key string) (Token, error) {
+		n := calls.Add(1)
+		return Token{Value: fmt.Sprintf("t%d", n), ExpiresAt: clock.Now().Add(time.Minute)}, nil
+	}, 0)
+
+	_, err := tc.Token(context.Background(), "svc")
+	r.NoError(err)
+
+	clock.Advance(2 * time.Minute)
+	tok, err := tc.Token(context.Background(), "svc")
+	r.NoError(err)
+	r.Equal("t2", tok.Value)
+}
+
+func TestTokenCache_SingleflightAndErrors(t *testing.T) {
+	r := require.New(t)
+	clock := newFakeClock()
+	cache := NewWithClock(10, time.Minute, clock)
+	defer cache.Close()
+
+	boom := errors.New("boom")
+	release := make(chan struct{})
+	var calls atomic.Int32
+	tc := NewTokenCache(cache, func(ctx context.Context, key string) (Token, error) {
+		calls.Add(1)
+		<-release
+		return Token{}, boom
+	}, time.Minute)
+
+	errs := make(chan error, 5)
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := tc.Token(context.Background(), "svc")
+			errs <- err
+		}()
+	}
+	waitForRefresh(tc, "svc", 4)
+	close(release)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		r.ErrorIs(err, boom)
+	}
+	r.Equal(int32(1), calls.Load())
+}
+
+func TestTokenCache_LeaderCancelDoesNotFailWaiters(t *testing.T) {
+	r := require.New(t)
+	clock := newFakeClock()
+	cache := NewWithClock(10, time.Minute, clock)
+	defer cache.Close()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var calls atomic.Int32
+	tc := NewTokenCache(cache, func(ctx context.Context, key string) (Token, error) {
+		if calls.Add(1) == 1 {
+			close(started)
+		}
+		<-release
+		if err := ctx.Err(); err != nil {
+			return Token{}, err
+		}
+		return Token{Value: "t1", ExpiresAt: clock.Now().Add(time.Hour)}, nil
+	}, 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	leader := make(chan error, 1)
+	go func() {
+		_, err := tc.Token(ctx, "svc")
+		leader <- err
+	}()
+	<-started
+
+	type result struct {
+		tok Token
+		err error
+	}
+	waiter := make(chan result, 1)
+	go func() {
+		tok, err := tc.Token(context.Background(), "svc")
+		waiter <- result{tok, err}
+	}()
+	waitForRefresh(tc, "svc", 1)
+
+	cancel()
+	r.ErrorIs(<-leader, context.Canceled)
+	close(release)
+
+	res := <-waiter
+	r.NoError(res.err)
+	r.Equal("t1", res.tok.Value)
+	r.Equal(int32(1), calls.Load())
+}
+
+func TestTokenCache_ContextCancelled(t *testing.T) {
+	r := require.New(t)
+	cache := New(10, time.Minute)
+	defer cache.Close()
+
+	release := make(chan struct{})
+	defer close(release)
+	tc := NewTokenCache(cache, func(ctx context.Context, key string) (Token, error) {
+		<-release
+		return Token{}, nil
+	}, 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := tc.Token(ctx, "svc")
+	r.ErrorIs(err, context.Canceled)
+}
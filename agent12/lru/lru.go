@@ -0,0 +1,52 @@
+// Package lru makes agent12's cache, which lives in an internal package,
+// importable from other modules. Every identifier is an alias of, or forwards
+// to, the one in internal/lru; see there for the full documentation.
+package lru
+
+import (
+	"time"
+
+	"github.com/rselbach/agent12/internal/lru"
+)
+
+// Cache is an LRU cache with automatic expiration support.
+type Cache = lru.Cache
+
+// Clock abstracts the passage of time so expiration can be tested without
+// sleeping.
+type Clock = lru.Clock
+
+// Ticker is the ticker a Clock returns for the cleanup goroutine.
+type Ticker = lru.Ticker
+
+// Token is a credential together with the time it stops being valid.
+type Token = lru.Token
+
+// RefreshFunc obtains a new token for key.
+type RefreshFunc = lru.RefreshFunc
+
+// TokenCache stores tokens in a Cache and refreshes them shortly before they
+// expire.
+type TokenCache = lru.TokenCache
+
+// ErrTokenExpired is returned when a refresh function yields a token that has
+// already expired.
+var ErrTokenExpired = lru.ErrTokenExpired
+
+// New creates a new LRU cache with the specified maximum size and cleanup
+// interval. If cleanupInterval is 0, a default of 1 minute is used.
+func New(maxSize int, cleanupInterval time.Duration) *Cache {
+	return lru.New(maxSize, cleanupInterval)
+}
+
+// NewWithClock is like New but uses clk for expiration checks and for the
+// cleanup ticker. A nil clk falls back to real time.
+func NewWithClock(maxSize int, cleanupInterval time.Duration, clk Clock) *Cache {
+	return lru.NewWithClock(maxSize, cleanupInterval, clk)
+}
+
+// NewTokenCache creates a TokenCache backed by cache that refreshes tokens
+// once they are within skew of their expiry.
+func NewTokenCache(cache *Cache, refresh RefreshFunc, skew time.Duration) *TokenCache {
+	return lru.NewTokenCache(cache, refresh, skew)
+}
@@ -0,0 +1,152 @@
+package agent13
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+)
+
+// Compressor compresses and decompresses cached values. Implementations must
+// be safe for concurrent use.
+type Compressor interface {
+	Compress(src []byte) ([]byte, error)
+	Decompress(src []byte) ([]byte, error)
+}
+
+// GzipCompressor is a Compressor backed by compress/gzip.
+type GzipCompressor struct {
+	// Level is the gzip compression level; zero means gzip.DefaultCompression.
+	Level int
+}
+
+func (g GzipCompressor) Compress(src []byte) ([]byte, error) {
+	level := g.Level
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+
+	var buf bytes.Buffer
+	w, err := gzip.NewWriterLevel(&buf, level)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(src); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (g GzipCompressor) Decompress(src []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(src))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// CompressionStats reports how much compression has saved since it was
+// enabled. Counts are cumulative over every compressed Set, including values
+// that have since been overwritten or evicted.
+type CompressionStats struct {
+	Values          uint64
+	OriginalBytes   uint64
+	CompressedBytes uint64
+}
+
+// Saved returns the number of bytes compression avoided storing.
+func (s CompressionStats) Saved() uint64 {
+	return s.OriginalBytes - s.CompressedBytes
+}
+
+type compression struct {
+	codec     Compressor
+	threshold int
+}
+
+// compressedValue is stored in place of a []byte or string value that was
+// compressed. It keeps the codec that produced it so changing the compressor
+// does not strand existing entries.
+type compressedValue struct {
+	data   []byte
+	codec  Compressor
+	isText bool
+}
+
+// SetCompression makes Set compress []byte and string values of at least
+// threshold bytes with codec, and Get decompress them transparently. Values
+// that do not shrink are stored as is. A nil codec disables compression for
+// subsequent writes; values already compressed stay readable.
+func (c *Cache) SetCompression(codec Compressor, threshold int) {
+	if codec == nil {
+		c.compression.Store(nil)
+		return
+	}
+	c.compression.Store(&compression{codec: codec, threshold: threshold})
+}
+
+// CompressionStats returns the compression counters.
+func (c *Cache) CompressionStats() CompressionStats {
+	return CompressionStats{
+		Values:          c.compressedValues.Load(),
+		OriginalBytes:   c.originalBytes.Load(),
+		CompressedBytes: c.compressedBytes.Load(),
+	}
+}
+
+// compress returns the value to store for value. It is called without the
+// lock held so large values do not block other callers.
+func (c *Cache) compress(value interface{}) interface{} {
+	cfg := c.compression.Load()
+	if cfg == nil {
+		return value
+	}
+
+	var (
+		raw    []byte
+		isText bool
+	)
+	switch v := value.(type) {
+	case []byte:
+		raw = v
+	case string:
+		raw = []byte(v)
+		isText = true
+	default:
+		return value
+	}
+	if len(raw) < cfg.threshold {
+		return value
+	}
+
+	data, err := cfg.codec.Compress(raw)
+	if err != nil || len(data) >= len(raw) {
+		return value
+	}
+
+	c.compressedValues.Add(1)
+	c.originalBytes.Add(uint64(len(raw)))
+	c.compressedBytes.Add(uint64(len(data)))
+	return compressedValue{data: data, codec: cfg.codec, isText: isText}
+}
+
+// decompress reverses compress. It reports false if the stored data cannot be
+// decoded.
+func decompress(value interface{}) (interface{}, bool) {
+	cv, ok := value.(compressedValue)
+	if !ok {
+		return value, true
+	}
+
+	raw, err := cv.codec.Decompress(cv.data)
+	if err != nil {
+		return nil, false
+	}
+	if cv.isText {
+		return string(raw), true
+	}
+	return raw, true
+}
@@ -0,0 +1,124 @@
+package agent13
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/rselbach/agent-comparison/metrics"
+)
+
+func TestCompression(t *testing.T) {
+	blob := strings.Repeat(`{"name":"alice","role":"admin"},`, 100)
+
+	tests := []struct {
+		name           string
+		value          interface{}
+		wantCompressed bool
+	}{
+		{"large string", blob, true},
+		{"large bytes", []byte(blob), true},
+		{"small string", "short", false},
+		{"non-byte value", 42, false},
+		{"incompressible", randomish(4096), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(10, 0)
+			c.SetCompression(GzipCompressor{}, 64)
+
+			c.Set("k", tt.value, 0)
+
+			_, compressed := c.items["k"].Value.(*entry).value.(compressedValue)
+			if compressed != tt.wantCompressed {
+				t.Errorf("compressed = %v, want %v", compressed, tt.wantCompressed)
+			}
+
+			got, ok := c.Get("k")
+			if !ok {
+				t.Fatal("expected value to be present")
+			}
+			if b, isBytes := tt.value.([]byte); isBytes {
+				if !bytes.Equal(got.([]byte), b) {
+					t.Errorf("Get returned different bytes")
+				}
+			} else if got != tt.value {
+				t.Errorf("Get = %v, want %v", got, tt.value)
+			}
+		})
+	}
+}
+
+func TestCompressionStats(t *testing.T) {
+	c := New(10, 0)
+	c.SetCompression(GzipCompressor{}, 64)
+
+	blob := strings.Repeat("a", 5000)
+	c.Set("a", blob, 0)
+	c.Set("b", blob, 0)
+	c.Set("c", "tiny", 0)
+
+	stats := c.CompressionStats()
+	if stats.Values != 2 {
+		t.Errorf("Values = %d, want 2", stats.Values)
+	}
+	if stats.OriginalBytes != 10000 {
+		t.Errorf("OriginalBytes = %d, want 10000", stats.OriginalBytes)
+	}
+	if stats.Saved() == 0 || stats.Saved() != stats.OriginalBytes-stats.CompressedBytes {
+		t.Errorf("Saved = %d with stats %+v", stats.Saved(), stats)
+	}
+}
+
+func TestCompressionDisable(t *testing.T) {
+	c := New(10, 0)
+	c.SetCompression(GzipCompressor{}, 0)
+
+	blob := strings.Repeat("x", 1000)
+	c.Set("old", blob, 0)
+	c.SetCompression(nil, 0)
+	c.Set("new", blob, 0)
+
+	if _, compressed := c.items["new"].Value.(*entry).value.(compressedValue); compressed {
+		t.Error("expected value stored after disabling to be uncompressed")
+	}
+	if got, ok := c.Get("old"); !ok || got != blob {
+		t.Error("expected value compressed earlier to stay readable")
+	}
+}
+
+type failingCompressor struct{ GzipCompressor }
+
+func (failingCompressor) Decompress([]byte) ([]byte, error) {
+	return nil, errors.New("corrupt")
+}
+
+func TestCompressionDecodeError(t *testing.T) {
+	c := New(10, 0)
+	c.SetCompression(failingCompressor{}, 0)
+	rec := &metrics.Counters{}
+	c.SetRecorder(rec)
+
+	c.Set("k", strings.Repeat("x", 1000), 0)
+	if _, ok := c.Get("k"); ok {
+		t.Error("expected undecodable value to be reported missing")
+	}
+	if got, want := rec.Snapshot(), (metrics.Snapshot{Misses: 1}); got != want {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+}
+
+// randomish returns n bytes that gzip cannot shrink.
+func randomish(n int) []byte {
+	b := make([]byte, n)
+	x := uint32(2463534242)
+	for i := range b {
+		x ^= x << 13
+		x ^= x >> 17
+		x ^= x << 5
+		b[i] = byte(x)
+	}
+	return b
+}
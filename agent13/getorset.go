@@ -0,0 +1,48 @@
+package agent13
+
+import "time"
+
+// GetOrSet returns the value for key, calling supplier and storing its result
+// with ttl on a miss. Concurrent misses for the same key wait for a single
+// supplier call and share its result. Errors are returned to every waiter and
+// not cached, so the next call tries again. If supplier panics, the panic
+// propagates to the caller that ran it and the other waiters get
+// flight.ErrPanicked.
+func (c *Cache) GetOrSet(key string, ttl time.Duration, supplier func() (interface{}, error)) (interface{}, error) {
+	if value, ok := c.Get(key); ok {
+		return value, nil
+	}
+
+	return c.flights.Do(key, func() (interface{}, error) {
+		// A call for key may have finished between the Get above and starting
+		// this one, in which case its value is already stored.
+		if value, ok := c.cached(key); ok {
+			return value, nil
+		}
+
+		value, err := supplier()
+		if err == nil {
+			c.Set(key, value, ttl)
+		}
+		return value, err
+	})
+}
+
+// cached returns the value of key if it is present and unexpired, without
+// counting a hit or miss or changing its recency.
+func (c *Cache) cached(key string) (interface{}, bool) {
+	c.mu.Lock()
+	elem, ok := c.items[key]
+	var value interface{}
+	if ok {
+		ent := elem.Value.(*entry)
+		ok = ent.expiration.IsZero() || !c.now().After(ent.expiration)
+		value = ent.value
+	}
+	c.mu.Unlock()
+
+	if !ok {
+		return nil, false
+	}
+	return decompress(value)
+}
@@ -0,0 +1,165 @@
+package agent13
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rselbach/agent-comparison/clock"
+	"github.com/rselbach/agent-comparison/flight"
+	"github.com/rselbach/agent-comparison/metrics"
+)
+
+func TestGetOrSet(t *testing.T) {
+	cache := New(10, 0)
+
+	calls := 0
+	supplier := func() (interface{}, error) {
+		calls++
+		return "computed", nil
+	}
+
+	for i := 0; i < 3; i++ {
+		val, err := cache.GetOrSet("key1", 0, supplier)
+		if err != nil || val != "computed" {
+			t.Fatalf("expected computed, got %v, err=%v", val, err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("expected supplier to run once, ran %d times", calls)
+	}
+
+	cache.Set("key2", "stored", 0)
+	if val, err := cache.GetOrSet("key2", 0, supplier); err != nil || val != "stored" {
+		t.Errorf("expected stored, got %v, err=%v", val, err)
+	}
+	if calls != 1 {
+		t.Errorf("supplier should not run on a hit, ran %d times", calls)
+	}
+}
+
+func TestGetOrSetTTL(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	cache := NewWithClock(10, 0, clk)
+
+	calls := 0
+	supplier := func() (interface{}, error) {
+		calls++
+		return calls, nil
+	}
+
+	cache.GetOrSet("key1", time.Minute, supplier)
+	clk.Advance(2 * time.Minute)
+	if val, _ := cache.GetOrSet("key1", time.Minute, supplier); val != 2 {
+		t.Errorf("expected expired entry to be recomputed, got %v", val)
+	}
+}
+
+func TestGetOrSetRecorder(t *testing.T) {
+	cache := New(10, 0)
+	rec := &metrics.Counters{}
+	cache.SetRecorder(rec)
+
+	supplier := func() (interface{}, error) { return "computed", nil }
+	cache.GetOrSet("key1", 0, supplier)
+	cache.GetOrSet("key1", 0, supplier)
+
+	want := metrics.Snapshot{Hits: 1, Misses: 1}
+	if got := rec.Snapshot(); got != want {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+}
+
+func TestGetOrSetError(t *testing.T) {
+	cache := New(10, 0)
+	boom := errors.New("boom")
+
+	if _, err := cache.GetOrSet("key1", 0, func() (interface{}, error) { return nil, boom }); err != boom {
+		t.Fatalf("expected boom, got %v", err)
+	}
+	if _, ok := cache.Get("key1"); ok {
+		t.Error("errors should not be cached")
+	}
+	if val, err := cache.GetOrSet("key1", 0, func() (interface{}, error) { return "ok", nil }); err != nil || val != "ok" {
+		t.Errorf("expected retry to succeed, got %v, err=%v", val, err)
+	}
+}
+
+func TestGetOrSetStampede(t *testing.T) {
+	cache := New(10, 0)
+	defer cache.Close()
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+	supplier := func() (interface{}, error) {
+		calls.Add(1)
+		<-release
+		return "computed", nil
+	}
+
+	const callers = 50
+	var started, wg sync.WaitGroup
+	started.Add(callers)
+	wg.Add(callers)
+	results := make([]interface{}, callers)
+	for i := 0; i < callers; i++ {
+		go func(i int) {
+			defer wg.Done()
+			started.Done()
+			results[i], _ = cache.GetOrSet("key1", 0, supplier)
+		}(i)
+	}
+	started.Wait()
+	for cache.flights.Waiting("key1") < callers-1 {
+		runtime.Gosched()
+	}
+	close(release)
+	wg.Wait()
+
+	if n := calls.Load(); n != 1 {
+		t.Errorf("expected supplier to run once, ran %d times", n)
+	}
+	for i, val := range results {
+		if val != "computed" {
+			t.Fatalf("caller %d got %v", i, val)
+		}
+	}
+}
+
+func TestGetOrSetPanic(t *testing.T) {
+	cache := New(10, 0)
+	defer cache.Close()
+
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	go func() {
+		defer func() { recover() }()
+		cache.GetOrSet("key1", 0, func() (interface{}, error) {
+			close(entered)
+			<-release
+			panic("supplier failed")
+		})
+	}()
+
+	<-entered
+	errc := make(chan error)
+	go func() {
+		_, err := cache.GetOrSet("key1", 0, func() (interface{}, error) { return "late", nil })
+		errc <- err
+	}()
+	for cache.flights.Waiting("key1") < 1 {
+		runtime.Gosched()
+	}
+	close(release)
+
+	// The waiter joined the flight before it panicked, so it shares the panic.
+	if err := <-errc; !errors.Is(err, flight.ErrPanicked) {
+		t.Errorf("expected %v, got %v", flight.ErrPanicked, err)
+	}
+	if val, err := cache.GetOrSet("key1", 0, func() (interface{}, error) { return "again", nil }); err != nil || val == nil {
+		t.Errorf("expected a value after the panic, got %v, err=%v", val, err)
+	}
+}
@@ -1,3 +1,18 @@
 module github.com/rselbach/agent13
 
 go 1.21
+
+require (
+	github.com/rselbach/agent-comparison/clock v0.0.0
+	github.com/rselbach/agent-comparison/flight v0.0.0
+	github.com/rselbach/agent-comparison/metrics v0.0.0
+	github.com/rselbach/agent-comparison/trie v0.0.0
+)
+
+replace github.com/rselbach/agent-comparison/clock => ../clock
+
+replace github.com/rselbach/agent-comparison/metrics => ../metrics
+
+replace github.com/rselbach/agent-comparison/flight => ../flight
+
+replace github.com/rselbach/agent-comparison/trie => ../trie
@@ -3,13 +3,20 @@ package agent13
 import (
 	"container/list"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/rselbach/agent-comparison/clock"
+	"github.com/rselbach/agent-comparison/flight"
+	"github.com/rselbach/agent-comparison/metrics"
+	"github.com/rselbach/agent-comparison/trie"
 )
 
 type entry struct {
 	key        string
 	value      interface{}
 	expiration time.Time
+	free       bool
 }
 
 type Cache struct {
@@ -18,18 +25,64 @@ type Cache struct {
 	items       map[string]*list.Element
 	evictList   *list.List
 	stopCleanup chan struct{}
+	now         func() time.Time
+	clock       clock.Clock
+	index       *trie.Trie[*list.Element]
+	prealloc    bool
+	metrics     metrics.Recorder
+
+	compression      atomic.Pointer[compression]
+	compressedValues atomic.Uint64
+	originalBytes    atomic.Uint64
+	compressedBytes  atomic.Uint64
+
+	flights flight.Group[string, interface{}]
 }
 
 func New(capacity int, cleanupInterval time.Duration) *Cache {
-	if capacity <= 0 {
-		capacity = 100
+	return NewWithClock(capacity, cleanupInterval, clock.Real{})
+}
+
+// NewWithClock is like New but reads time from clk and runs the cleanup
+// goroutine off clk's ticker, so a clock.Fake can drive both expiry and
+// cleanup in tests. A nil clk means real time. A non-positive cleanupInterval
+// starts no goroutine, so the cache changes only in response to calls and
+// expired entries are dropped on access or by RemoveExpired.
+func NewWithClock(capacity int, cleanupInterval time.Duration, clk clock.Clock) *Cache {
+	clk = clock.OrReal(clk)
+	c := newCache(capacity, clk.Now)
+	c.clock = clk
+
+	if cleanupInterval > 0 {
+		go c.cleanupExpired(cleanupInterval)
 	}
 
-	c := &Cache{
-		capacity:    capacity,
-		items:       make(map[string]*list.Element),
-		evictList:   list.New(),
-		stopCleanup: make(chan struct{}),
+	return c
+}
+
+// NewPreallocated creates a cache that allocates every entry and list node up
+// front and recycles them on eviction and removal, so steady-state Set, Get
+// and Delete do not allocate. It keeps no key index, so DeleteMatch always
+// scans.
+func NewPreallocated(capacity int, cleanupInterval time.Duration) *Cache {
+	return NewPreallocatedWithClock(capacity, cleanupInterval, clock.Real{})
+}
+
+// NewPreallocatedWithClock is like NewPreallocated but reads time from clk and
+// runs the cleanup goroutine off clk's ticker, as NewWithClock does. A nil clk
+// means real time.
+func NewPreallocatedWithClock(capacity int, cleanupInterval time.Duration, clk clock.Clock) *Cache {
+	clk = clock.OrReal(clk)
+	c := newCache(capacity, clk.Now)
+	c.clock = clk
+	c.prealloc = true
+	c.index = nil
+	c.items = make(map[string]*list.Element, c.capacity)
+
+	entries := make([]entry, c.capacity)
+	for i := range entries {
+		entries[i].free = true
+		c.evictList.PushBack(&entries[i])
 	}
 
 	if cleanupInterval > 0 {
@@ -39,13 +92,32 @@ func New(capacity int, cleanupInterval time.Duration) *Cache {
 	return c
 }
 
+func newCache(capacity int, now func() time.Time) *Cache {
+	if capacity <= 0 {
+		capacity = 100
+	}
+
+	return &Cache{
+		capacity:    capacity,
+		items:       make(map[string]*list.Element),
+		evictList:   list.New(),
+		stopCleanup: make(chan struct{}),
+		now:         now,
+		clock:       clock.Real{},
+		index:       &trie.Trie[*list.Element]{},
+		metrics:     metrics.Nop{},
+	}
+}
+
 func (c *Cache) Set(key string, value interface{}, ttl time.Duration) {
+	value = c.compress(value)
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	expiration := time.Time{}
 	if ttl > 0 {
-		expiration = time.Now().Add(ttl)
+		expiration = c.now().Add(ttl)
 	}
 
 	if elem, exists := c.items[key]; exists {
@@ -55,6 +127,11 @@ func (c *Cache) Set(key string, value interface{}, ttl time.Duration) {
 		return
 	}
 
+	if c.prealloc {
+		c.reuseOldest(key, value, expiration)
+		return
+	}
+
 	ent := &entry{
 		key:        key,
 		value:      value,
@@ -62,6 +139,7 @@ func (c *Cache) Set(key string, value interface{}, ttl time.Duration) {
 	}
 	elem := c.evictList.PushFront(ent)
 	c.items[key] = elem
+	c.index.Insert(key, elem)
 
 	if c.evictList.Len() > c.capacity {
 		c.removeOldest()
@@ -69,21 +147,59 @@ func (c *Cache) Set(key string, value interface{}, ttl time.Duration) {
 }
 
 func (c *Cache) Get(key string) (interface{}, bool) {
+	value, ok := c.get(key, true)
+	if !ok {
+		return nil, false
+	}
+	// The hit is only counted once the value decompresses, so data the codec
+	// rejects is recorded as the miss the caller sees.
+	value, ok = decompress(value)
+	if !ok {
+		c.metrics.Miss()
+		return nil, false
+	}
+	c.metrics.Hit()
+	return value, true
+}
+
+// Peek returns the value for key like Get but without moving it to the front
+// of the LRU list. Expired entries are removed and reported absent.
+func (c *Cache) Peek(key string) (interface{}, bool) {
+	value, ok := c.get(key, false)
+	if !ok {
+		return nil, false
+	}
+	return decompress(value)
+}
+
+// get returns the stored value for key, which may still be compressed, and
+// marks it most recently used if touch is set. When touch is set it records a
+// miss for an absent or expired key; the hit is left to the caller.
+func (c *Cache) get(key string, touch bool) (interface{}, bool) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	elem, exists := c.items[key]
 	if !exists {
+		if touch {
+			c.metrics.Miss()
+		}
 		return nil, false
 	}
 
 	ent := elem.Value.(*entry)
-	if !ent.expiration.IsZero() && time.Now().After(ent.expiration) {
+	if !ent.expiration.IsZero() && c.now().After(ent.expiration) {
 		c.removeElement(elem)
+		c.metrics.Expiration()
+		if touch {
+			c.metrics.Miss()
+		}
 		return nil, false
 	}
 
-	c.evictList.MoveToFront(elem)
+	if touch {
+		c.evictList.MoveToFront(elem)
+	}
 	return ent.value, true
 }
 
@@ -103,15 +219,24 @@ func (c *Cache) Delete(key string) bool {
 func (c *Cache) Len() int {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
-	return c.evictList.Len()
+	return len(c.items)
 }
 
 func (c *Cache) Clear() {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	if c.prealloc {
+		for elem := c.evictList.Front(); elem != nil; elem = elem.Next() {
+			*elem.Value.(*entry) = entry{free: true}
+		}
+		clear(c.items)
+		return
+	}
+
 	c.items = make(map[string]*list.Element)
 	c.evictList.Init()
+	c.index = &trie.Trie[*list.Element]{}
 }
 
 func (c *Cache) Close() {
@@ -122,44 +247,76 @@ func (c *Cache) removeOldest() {
 	elem := c.evictList.Back()
 	if elem != nil {
 		c.removeElement(elem)
+		c.metrics.Eviction()
 	}
 }
 
 func (c *Cache) removeElement(elem *list.Element) {
-	c.evictList.Remove(elem)
 	ent := elem.Value.(*entry)
 	delete(c.items, ent.key)
+
+	if c.prealloc {
+		// Free entries are kept behind every live one so reuseOldest finds them first.
+		*ent = entry{free: true}
+		c.evictList.MoveToBack(elem)
+		return
+	}
+
+	c.evictList.Remove(elem)
+	c.index.Remove(ent.key)
+}
+
+// reuseOldest stores a new key in the least recently used slot, which is
+// either free or the live entry to evict.
+func (c *Cache) reuseOldest(key string, value interface{}, expiration time.Time) {
+	elem := c.evictList.Back()
+	ent := elem.Value.(*entry)
+	if !ent.free {
+		delete(c.items, ent.key)
+		c.metrics.Eviction()
+	}
+
+	*ent = entry{key: key, value: value, expiration: expiration}
+	c.evictList.MoveToFront(elem)
+	c.items[key] = elem
 }
 
 func (c *Cache) cleanupExpired(interval time.Duration) {
-	ticker := time.NewTicker(interval)
+	ticker := c.clock.NewTicker(interval)
 	defer ticker.Stop()
 
 	for {
 		select {
-		case <-ticker.C:
-			c.removeExpiredItems()
+		case <-ticker.C():
+			c.RemoveExpired()
 		case <-c.stopCleanup:
 			return
 		}
 	}
 }
 
-func (c *Cache) removeExpiredItems() {
+// RemoveExpired removes every expired entry and returns how many it removed.
+// The cleanup goroutine calls it on each tick; caches without one, such as
+// those from NewWithClock with a zero cleanupInterval, call it directly.
+func (c *Cache) RemoveExpired() int {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	now := time.Now()
+	now := c.now()
 	var toRemove []*list.Element
 
 	for elem := c.evictList.Back(); elem != nil; elem = elem.Prev() {
 		ent := elem.Value.(*entry)
-		if !ent.expiration.IsZero() && now.After(ent.expiration) {
+		if !ent.free && !ent.expiration.IsZero() && now.After(ent.expiration) {
 			toRemove = append(toRemove, elem)
 		}
 	}
 
 	for _, elem := range toRemove {
 		c.removeElement(elem)
+		c.metrics.Expiration()
 	}
+	c.metrics.SweepDuration(c.now().Sub(now))
+
+	return len(toRemove)
 }
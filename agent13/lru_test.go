@@ -3,6 +3,8 @@ package agent13
 import (
 	"testing"
 	"time"
+
+	"github.com/rselbach/agent-comparison/clock"
 )
 
 func TestNew(t *testing.T) {
@@ -58,7 +60,8 @@ func TestLRUEviction(t *testing.T) {
 }
 
 func TestExpiration(t *testing.T) {
-	cache := New(10, 0)
+	clk := clock.NewFake(time.Unix(0, 0))
+	cache := NewWithClock(10, 0, clk)
 	defer cache.Close()
 
 	cache.Set("key1", "value1", 100*time.Millisecond)
@@ -68,7 +71,7 @@ func TestExpiration(t *testing.T) {
 		t.Error("expected key1 to exist")
 	}
 
-	time.Sleep(150 * time.Millisecond)
+	clk.Advance(150 * time.Millisecond)
 
 	if _, ok := cache.Get("key1"); ok {
 		t.Error("expected key1 to be expired")
@@ -80,8 +83,10 @@ func TestExpiration(t *testing.T) {
 }
 
 func TestAutoCleanup(t *testing.T) {
-	cache := New(10, 50*time.Millisecond)
+	clk := clock.NewFake(time.Unix(0, 0))
+	cache := NewWithClock(10, 50*time.Millisecond, clk)
 	defer cache.Close()
+	clk.BlockUntil(1)
 
 	cache.Set("key1", "value1", 100*time.Millisecond)
 	cache.Set("key2", "value2", 100*time.Millisecond)
@@ -91,10 +96,15 @@ func TestAutoCleanup(t *testing.T) {
 		t.Errorf("expected len 3, got %d", cache.Len())
 	}
 
-	time.Sleep(200 * time.Millisecond)
+	clk.Advance(150 * time.Millisecond)
 
-	if cache.Len() != 1 {
-		t.Errorf("expected len 1 after cleanup, got %d", cache.Len())
+	// the cleanup goroutine sweeps once it receives the tick
+	deadline := time.Now().Add(time.Second)
+	for cache.Len() != 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected len 1 after cleanup, got %d", cache.Len())
+		}
+		time.Sleep(time.Millisecond)
 	}
 
 	if _, ok := cache.Get("key3"); !ok {
@@ -181,3 +191,78 @@ func TestConcurrency(t *testing.T) {
 		<-done
 	}
 }
+
+func TestDeterministic(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	cache := NewWithClock(2, 0, clk)
+	defer cache.Close()
+
+	cache.Set("key1", "value1", time.Second)
+	cache.Set("key2", "value2", 0)
+
+	clk.Advance(500 * time.Millisecond)
+	if val, ok := cache.Get("key1"); !ok || val != "value1" {
+		t.Errorf("expected value1, got %v, ok=%v", val, ok)
+	}
+
+	clk.Advance(time.Second)
+	if cache.Len() != 2 {
+		t.Errorf("expected expired entry to remain until swept, got len %d", cache.Len())
+	}
+
+	if removed := cache.RemoveExpired(); removed != 1 {
+		t.Errorf("expected 1 expired entry removed, got %d", removed)
+	}
+
+	if _, ok := cache.Get("key1"); ok {
+		t.Error("expected key1 to be removed")
+	}
+
+	if val, ok := cache.Get("key2"); !ok || val != "value2" {
+		t.Errorf("expected value2, got %v, ok=%v", val, ok)
+	}
+}
+
+func TestPeek(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	cache := NewWithClock(2, 0, clk)
+	defer cache.Close()
+
+	cache.Set("key1", "value1", 0)
+	cache.Set("key2", "value2", time.Second)
+
+	if val, ok := cache.Peek("key1"); !ok || val != "value1" {
+		t.Errorf("expected value1, got %v, ok=%v", val, ok)
+	}
+	if _, ok := cache.Peek("missing"); ok {
+		t.Error("expected missing key to be absent")
+	}
+
+	clk.Advance(2 * time.Second)
+	if _, ok := cache.Peek("key2"); ok {
+		t.Error("expected key2 to be expired")
+	}
+	if cache.Len() != 1 {
+		t.Errorf("expected expired entry to be removed, got len %d", cache.Len())
+	}
+
+	cache.Set("key3", "value3", 0)
+	cache.Peek("key1")
+	cache.Set("key4", "value4", 0)
+	if _, ok := cache.Peek("key1"); ok {
+		t.Error("expected peeked key1 to be evicted as least recently used")
+	}
+}
+
+func TestDeterministicNilClock(t *testing.T) {
+	cache := NewWithClock(2, 0, nil)
+	defer cache.Close()
+
+	cache.Set("key1", "value1", time.Hour)
+	if val, ok := cache.Get("key1"); !ok || val != "value1" {
+		t.Errorf("expected value1, got %v, ok=%v", val, ok)
+	}
+	if removed := cache.RemoveExpired(); removed != 0 {
+		t.Errorf("expected nothing expired, got %d", removed)
+	}
+}
@@ -0,0 +1,97 @@
+package agent13
+
+import "container/list"
+
+// DeleteMatch removes every entry whose key matches the glob pattern and
+// returns how many were removed. '*' matches any run of characters, '?'
+// matches a single character and '\' escapes the next character. When the
+// pattern starts with a literal prefix only keys under that prefix are
+// examined.
+func (c *Cache) DeleteMatch(pattern string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var candidates []*list.Element
+	if prefix := literalPrefix(pattern); prefix != "" && c.index != nil {
+		candidates = c.index.Collect(prefix)
+	} else {
+		candidates = make([]*list.Element, 0, len(c.items))
+		for _, elem := range c.items {
+			candidates = append(candidates, elem)
+		}
+	}
+
+	removed := 0
+	for _, elem := range candidates {
+		if matchGlob(pattern, elem.Value.(*entry).key) {
+			c.removeElement(elem)
+			removed++
+		}
+	}
+	return removed
+}
+
+func literalPrefix(pattern string) string {
+	prefix := make([]byte, 0, len(pattern))
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '*', '?':
+			return string(prefix)
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+			}
+		}
+		prefix = append(prefix, pattern[i])
+	}
+	return string(prefix)
+}
+
+// matchGlob reports whether name matches pattern. It backtracks only to the
+// most recent '*', which keeps matching linear in practice.
+func matchGlob(pattern, name string) bool {
+	p, n := 0, 0
+	starP, starN := -1, 0
+
+	for n < len(name) {
+		if p < len(pattern) {
+			switch c := pattern[p]; c {
+			case '*':
+				starP, starN = p, n
+				p++
+				continue
+			case '?':
+				p++
+				n++
+				continue
+			case '\\':
+				if p+1 < len(pattern) {
+					c = pattern[p+1]
+					if c == name[n] {
+						p += 2
+						n++
+						continue
+					}
+					break
+				}
+				fallthrough
+			default:
+				if c == name[n] {
+					p++
+					n++
+					continue
+				}
+			}
+		}
+		if starP < 0 {
+			return false
+		}
+		starN++
+		p, n = starP+1, starN
+	}
+
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
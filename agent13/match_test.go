@@ -0,0 +1,83 @@
+package agent13
+
+import "testing"
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"user:*:profile", "user:42:profile", true},
+		{"user:*:profile", "user:42:settings", false},
+		{"user:*:profile", "user::profile", true},
+		{"user:*", "user:", true},
+		{"user:*", "users", false},
+		{"*", "", true},
+		{"a?c", "abc", true},
+		{"a?c", "ac", false},
+		{"*:b:*", "x:b:y:b:z", true},
+		{`a\*b`, "a*b", true},
+		{`a\*b`, "axb", false},
+		{"a*b*c", "aXbYbZc", true},
+		{"a*b*c", "aXbYbZ", false},
+		{"exact", "exact", true},
+		{"exact", "exactly", false},
+	}
+
+	for _, tt := range tests {
+		if got := matchGlob(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("matchGlob(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLiteralPrefix(t *testing.T) {
+	tests := map[string]string{
+		"user:*:profile": "user:",
+		"*:profile":      "",
+		"abc":            "abc",
+		`a\*b*`:          "a*b",
+		"a?":             "a",
+	}
+
+	for pattern, want := range tests {
+		if got := literalPrefix(pattern); got != want {
+			t.Errorf("literalPrefix(%q) = %q, want %q", pattern, got, want)
+		}
+	}
+}
+
+func TestDeleteMatch(t *testing.T) {
+	cache := New(10, 0)
+	defer cache.Close()
+
+	for _, key := range []string{"user:1:profile", "user:2:profile", "user:1:settings", "org:1:profile", "user"} {
+		cache.Set(key, key, 0)
+	}
+
+	if removed := cache.DeleteMatch("user:*:profile"); removed != 2 {
+		t.Errorf("expected 2 removed, got %d", removed)
+	}
+	if _, ok := cache.Get("user:1:settings"); !ok {
+		t.Error("expected user:1:settings to remain")
+	}
+
+	if removed := cache.DeleteMatch("*:profile"); removed != 1 {
+		t.Errorf("expected 1 removed without a literal prefix, got %d", removed)
+	}
+
+	if removed := cache.DeleteMatch("nothing*"); removed != 0 {
+		t.Errorf("expected 0 removed, got %d", removed)
+	}
+
+	if cache.Len() != 2 {
+		t.Errorf("expected len 2, got %d", cache.Len())
+	}
+
+	cache.Delete("user")
+	cache.Set("user:3:profile", 3, 0)
+	if removed := cache.DeleteMatch("user*"); removed != 2 {
+		t.Errorf("expected index to track deletes and inserts, got %d", removed)
+	}
+}
@@ -0,0 +1,124 @@
+package agent13
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/rselbach/agent-comparison/clock"
+)
+
+func TestPreallocatedBehavesLikeLRU(t *testing.T) {
+	cache := NewPreallocated(3, 0)
+	defer cache.Close()
+
+	if cache.Len() != 0 {
+		t.Fatalf("expected empty cache, got len %d", cache.Len())
+	}
+
+	cache.Set("key1", "value1", 0)
+	cache.Set("key2", "value2", 0)
+	cache.Set("key3", "value3", 0)
+	cache.Get("key1")
+	cache.Set("key4", "value4", 0)
+
+	if _, ok := cache.Get("key2"); ok {
+		t.Error("expected key2 to be evicted")
+	}
+	if val, ok := cache.Get("key1"); !ok || val != "value1" {
+		t.Errorf("expected value1, got %v, ok=%v", val, ok)
+	}
+
+	if !cache.Delete("key3") {
+		t.Error("expected delete to return true")
+	}
+	if cache.Len() != 2 {
+		t.Errorf("expected len 2, got %d", cache.Len())
+	}
+
+	// The freed slot is used before evicting a live entry.
+	cache.Set("key5", "value5", 0)
+	for _, key := range []string{"key1", "key4", "key5"} {
+		if _, ok := cache.Get(key); !ok {
+			t.Errorf("expected %s to exist", key)
+		}
+	}
+
+	if removed := cache.DeleteMatch("key*"); removed != 3 {
+		t.Errorf("expected 3 removed, got %d", removed)
+	}
+
+	cache.Set("a", 1, 0)
+	cache.Clear()
+	if cache.Len() != 0 || cache.evictList.Len() != 3 {
+		t.Errorf("expected clear to keep 3 free slots, got len %d, slots %d", cache.Len(), cache.evictList.Len())
+	}
+}
+
+func TestPreallocatedExpiration(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	cache := NewPreallocatedWithClock(3, 0, clk)
+	defer cache.Close()
+
+	cache.Set("short", 1, time.Millisecond)
+	cache.Set("long", 2, 0)
+	clk.Advance(5 * time.Millisecond)
+
+	if removed := cache.RemoveExpired(); removed != 1 {
+		t.Errorf("expected 1 expired entry removed, got %d", removed)
+	}
+	if _, ok := cache.Get("long"); !ok {
+		t.Error("expected long to exist")
+	}
+}
+
+func TestPreallocatedAutoCleanup(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	cache := NewPreallocatedWithClock(3, 50*time.Millisecond, clk)
+	defer cache.Close()
+	clk.BlockUntil(1)
+
+	cache.Set("short", 1, 10*time.Millisecond)
+	cache.Set("long", 2, 0)
+	clk.Advance(50 * time.Millisecond)
+
+	// the cleanup goroutine sweeps once it receives the tick
+	deadline := time.Now().Add(time.Second)
+	for cache.Len() != 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected len 1 after cleanup, got %d", cache.Len())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestPreallocatedDoesNotAllocate(t *testing.T) {
+	const capacity = 64
+	cache := NewPreallocated(capacity, 0)
+	defer cache.Close()
+
+	keys := make([]string, 4*capacity)
+	for i := range keys {
+		keys[i] = "key:" + strconv.Itoa(i)
+	}
+	var value interface{} = "value"
+
+	// Warm up so the map has grown to its steady-state size.
+	for _, key := range keys {
+		cache.Set(key, value, 0)
+	}
+
+	i := 0
+	allocs := testing.AllocsPerRun(1000, func() {
+		key := keys[i%len(keys)]
+		cache.Set(key, value, 0)
+		cache.Get(key)
+		if i%3 == 0 {
+			cache.Delete(key)
+		}
+		i++
+	})
+	if allocs != 0 {
+		t.Errorf("expected no allocations, got %v per run", allocs)
+	}
+}
@@ -0,0 +1,123 @@
+// Package ratelimit implements per-key token-bucket rate limiting using an
+// agent13 cache as the state store.
+//
+// Each key's bucket is stored as a cache entry whose TTL is the time it takes
+// to refill completely; once it expires the key is indistinguishable from one
+// never seen, so idle keys cost nothing. Buckets evicted for capacity are
+// likewise reset to full, so size the cache for the number of active keys.
+package ratelimit
+
+import (
+	"sync"
+	"time"
+
+	"github.com/rselbach/agent-comparison/clock"
+	"github.com/rselbach/agent13"
+)
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Limiter limits events per key with a token bucket for each key. It is safe
+// for concurrent use.
+type Limiter struct {
+	mu     sync.Mutex
+	cache  *agent13.Cache
+	burst  float64
+	rate   float64 // tokens per nanosecond
+	now    func() time.Time
+	prefix string
+}
+
+// Option configures a Limiter.
+type Option func(*Limiter)
+
+// WithClock reads time from clk. Pair it with agent13.NewWithClock using the
+// same clock so bucket expiry follows the same time. A nil clk keeps real
+// time.
+func WithClock(clk clock.Clock) Option {
+	return func(l *Limiter) {
+		if clk != nil {
+			l.now = clk.Now
+		}
+	}
+}
+
+// WithPrefix namespaces the limiter's keys so the cache can be shared.
+func WithPrefix(prefix string) Option {
+	return func(l *Limiter) {
+		l.prefix = prefix
+	}
+}
+
+// New returns a limiter allowing limit events per window for each key, with
+// bursts of up to limit events. It panics if limit or window is not positive.
+func New(cache *agent13.Cache, limit int, window time.Duration, opts ...Option) *Limiter {
+	if limit <= 0 || window <= 0 {
+		panic("ratelimit: limit and window must be positive")
+	}
+
+	l := &Limiter{
+		cache: cache,
+		burst: float64(limit),
+		rate:  float64(limit) / float64(window),
+		now:   time.Now,
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// Allow is AllowN(key, 1).
+func (l *Limiter) Allow(key string) bool {
+	return l.AllowN(key, 1)
+}
+
+// AllowN reports whether n events may happen for key now, consuming n tokens
+// if so. A denied call consumes nothing. A non-positive n is always denied, so
+// it cannot add tokens. A value under key in the cache that is not a bucket,
+// such as one stored by another user of a shared cache without WithPrefix, is
+// treated as a full bucket and replaced.
+func (l *Limiter) AllowN(key string, n int) bool {
+	if n <= 0 {
+		return false
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	key = l.prefix + key
+	b := bucket{tokens: l.burst, last: now}
+	if v, ok := l.cache.Get(key); ok {
+		if stored, ok := v.(bucket); ok {
+			b = stored
+		}
+		b.tokens += float64(now.Sub(b.last)) * l.rate
+		if b.tokens > l.burst {
+			b.tokens = l.burst
+		}
+		b.last = now
+	}
+
+	allowed := b.tokens >= float64(n)
+	if allowed {
+		b.tokens -= float64(n)
+	}
+
+	refill := time.Duration((l.burst - b.tokens) / l.rate)
+	if refill <= 0 {
+		l.cache.Delete(key)
+		return allowed
+	}
+	l.cache.Set(key, b, refill)
+	return allowed
+}
+
+// Reset forgets the state for key, restoring its full burst.
+func (l *Limiter) Reset(key string) {
+	l.cache.Delete(l.prefix + key)
+}
@@ -0,0 +1,143 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rselbach/agent-comparison/clock"
+	"github.com/rselbach/agent13"
+)
+
+func newLimiter(limit int, window time.Duration) (*Limiter, *agent13.Cache, *clock.Fake) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	cache := agent13.NewWithClock(100, 0, clk)
+	return New(cache, limit, window, WithClock(clk)), cache, clk
+}
+
+func TestAllowBurstThenDeny(t *testing.T) {
+	l, cache, _ := newLimiter(3, time.Second)
+	defer cache.Close()
+
+	for i := 0; i < 3; i++ {
+		if !l.Allow("a") {
+			t.Fatalf("expected request %d to be allowed", i)
+		}
+	}
+	if l.Allow("a") {
+		t.Error("expected request beyond burst to be denied")
+	}
+	if !l.Allow("b") {
+		t.Error("expected other keys to be independent")
+	}
+}
+
+func TestAllowRefills(t *testing.T) {
+	l, cache, clk := newLimiter(2, time.Second)
+	defer cache.Close()
+
+	l.Allow("a")
+	l.Allow("a")
+	if l.Allow("a") {
+		t.Fatal("expected bucket to be empty")
+	}
+
+	clk.Advance(500 * time.Millisecond)
+	if !l.Allow("a") {
+		t.Error("expected one token after half a window")
+	}
+	if l.Allow("a") {
+		t.Error("expected bucket to be empty again")
+	}
+}
+
+func TestStateExpiresWhenFull(t *testing.T) {
+	l, cache, clk := newLimiter(2, time.Second)
+	defer cache.Close()
+
+	l.Allow("a")
+	if cache.Len() != 1 {
+		t.Fatalf("expected bucket state to be stored, got len %d", cache.Len())
+	}
+
+	clk.Advance(time.Second)
+	if removed := cache.RemoveExpired(); removed != 1 {
+		t.Errorf("expected refilled bucket to expire, removed %d", removed)
+	}
+}
+
+func TestAllowNDeniedConsumesNothing(t *testing.T) {
+	l, cache, _ := newLimiter(5, time.Second)
+	defer cache.Close()
+
+	if !l.AllowN("a", 4) {
+		t.Fatal("expected 4 tokens to be allowed")
+	}
+	if l.AllowN("a", 2) {
+		t.Fatal("expected 2 tokens to be denied")
+	}
+	if !l.Allow("a") {
+		t.Error("expected the remaining token to still be available")
+	}
+}
+
+func TestResetAndPrefix(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	cache := agent13.NewWithClock(100, 0, clk)
+	defer cache.Close()
+
+	login := New(cache, 1, time.Minute, WithClock(clk), WithPrefix("login:"))
+	api := New(cache, 1, time.Minute, WithClock(clk), WithPrefix("api:"))
+
+	if !login.Allow("u1") || !api.Allow("u1") {
+		t.Fatal("expected prefixed limiters not to share state")
+	}
+	if login.Allow("u1") {
+		t.Fatal("expected login limiter to be exhausted")
+	}
+	login.Reset("u1")
+	if !login.Allow("u1") {
+		t.Error("expected reset to restore the burst")
+	}
+}
+
+func TestWithNilClock(t *testing.T) {
+	cache := agent13.New(100, 0)
+	defer cache.Close()
+
+	l := New(cache, 1, time.Hour, WithClock(nil))
+	if !l.Allow("a") {
+		t.Fatal("expected the first request to be allowed")
+	}
+	if l.Allow("a") {
+		t.Error("expected the second request to be denied")
+	}
+}
+
+func TestAllowNNonPositive(t *testing.T) {
+	l, cache, _ := newLimiter(2, time.Second)
+	defer cache.Close()
+
+	l.Allow("a")
+	l.Allow("a")
+	for _, n := range []int{0, -1, -100} {
+		if l.AllowN("a", n) {
+			t.Errorf("expected AllowN(%d) to be denied", n)
+		}
+	}
+	if l.Allow("a") {
+		t.Error("expected non-positive AllowN not to refill the bucket")
+	}
+}
+
+func TestForeignValueInSharedCache(t *testing.T) {
+	l, cache, _ := newLimiter(1, time.Second)
+	defer cache.Close()
+
+	cache.Set("a", "not a bucket", time.Hour)
+	if !l.Allow("a") {
+		t.Fatal("expected a foreign value to count as a full bucket")
+	}
+	if l.Allow("a") {
+		t.Error("expected the bucket that replaced it to be drained")
+	}
+}
@@ -0,0 +1,14 @@
+package agent13
+
+import "github.com/rselbach/agent-comparison/metrics"
+
+// SetRecorder makes the cache report to r: hits and misses of Get, capacity
+// evictions, expired entries dropped on access or by RemoveExpired, and the
+// duration of each RemoveExpired pass. Peek is not counted as a hit or miss.
+// A nil r records nothing.
+func (c *Cache) SetRecorder(r metrics.Recorder) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.metrics = metrics.OrNop(r)
+}
@@ -0,0 +1,53 @@
+package agent13
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rselbach/agent-comparison/clock"
+	"github.com/rselbach/agent-comparison/metrics"
+)
+
+func TestSetRecorder(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	cache := NewWithClock(2, 0, clk)
+	defer cache.Close()
+	rec := &metrics.Counters{}
+	cache.SetRecorder(rec)
+
+	cache.Set("key1", "value1", time.Second)
+	cache.Set("key2", "value2", 0)
+	cache.Get("key1")
+	cache.Get("missing")
+	cache.Peek("key2")
+	cache.Set("key3", "value3", 0) // evicts key2
+
+	clk.Advance(2 * time.Second)
+	cache.Get("key1")
+	cache.Set("key4", "value4", time.Millisecond)
+	clk.Advance(time.Second)
+	if removed := cache.RemoveExpired(); removed != 1 {
+		t.Errorf("expected 1 expired entry removed, got %d", removed)
+	}
+
+	want := metrics.Snapshot{Hits: 1, Misses: 2, Evictions: 1, Expirations: 2, Sweeps: 1}
+	if got := rec.Snapshot(); got != want {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+}
+
+func TestSetRecorderPreallocated(t *testing.T) {
+	cache := NewPreallocated(1, 0)
+	defer cache.Close()
+	rec := &metrics.Counters{}
+	cache.SetRecorder(rec)
+
+	cache.Set("key1", "value1", 0)
+	cache.Set("key2", "value2", 0)
+	cache.Delete("key2")
+	cache.Set("key3", "value3", 0)
+
+	if got := rec.Snapshot().Evictions; got != 1 {
+		t.Errorf("expected 1 eviction, got %d", got)
+	}
+}
@@ -0,0 +1,101 @@
+package agent14
+
+import "time"
+
+type EventOp string
+
+const (
+	OpSet    EventOp = "set"
+	OpGet    EventOp = "get"
+	OpDelete EventOp = "delete"
+	OpEvict  EventOp = "evict"
+	OpExpire EventOp = "expire"
+	OpClear  EventOp = "clear"
+)
+
+type EventResult string
+
+const (
+	ResultHit      EventResult = "hit"
+	ResultMiss     EventResult = "miss"
+	ResultExpired  EventResult = "expired"
+	ResultInserted EventResult = "inserted"
+	ResultUpdated  EventResult = "updated"
+	ResultRemoved  EventResult = "removed"
+)
+
+type Event struct {
+	Op     EventOp
+	Key    string
+	Result EventResult
+	Time   time.Time
+}
+
+// eventRing keeps the most recent events in a fixed-size circular buffer.
+// It is not safe for concurrent use; callers hold the cache lock.
+type eventRing struct {
+	buf  []Event
+	next int
+	full bool
+}
+
+func newEventRing(size int) *eventRing {
+	if size <= 0 {
+		return nil
+	}
+	return &eventRing{buf: make([]Event, size)}
+}
+
+func (r *eventRing) add(ev Event) {
+	r.buf[r.next] = ev
+	r.next++
+	if r.next == len(r.buf) {
+		r.next = 0
+		r.full = true
+	}
+}
+
+func (r *eventRing) len() int {
+	if r.full {
+		return len(r.buf)
+	}
+	return r.next
+}
+
+// last returns up to n of the most recent events, oldest first.
+func (r *eventRing) last(n int) []Event {
+	size := r.len()
+	if n <= 0 || n > size {
+		n = size
+	}
+
+	out := make([]Event, n)
+	start := r.next - n
+	if start < 0 {
+		start += len(r.buf)
+	}
+	for i := 0; i < n; i++ {
+		out[i] = r.buf[(start+i)%len(r.buf)]
+	}
+	return out
+}
+
+func (c *Cache) recordLocked(op EventOp, key string, result EventResult) {
+	if c.events == nil {
+		return
+	}
+	c.events.add(Event{Op: op, Key: key, Result: result, Time: c.clock.Now()})
+}
+
+// RecentEvents returns up to n of the most recently recorded events, oldest
+// first. A non-positive n returns everything currently buffered. It returns
+// nil when Config.EventBufferSize is zero.
+func (c *Cache) RecentEvents(n int) []Event {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.events == nil {
+		return nil
+	}
+	return c.events.last(n)
+}
@@ -0,0 +1,95 @@
+package agent14
+
+import "sync"
+
+// InvalidationKind says what the Value of an Invalidation names.
+type InvalidationKind string
+
+const (
+	// InvalidateKey invalidates the entry stored under Value.
+	InvalidateKey InvalidationKind = "key"
+	// InvalidateTag invalidates every entry the subscriber associates with
+	// the tag Value.
+	InvalidateTag InvalidationKind = "tag"
+)
+
+// Invalidation is a message published on an Invalidator.
+type Invalidation struct {
+	Kind  InvalidationKind
+	Value string
+}
+
+// Invalidator broadcasts invalidations to every subscriber, so code that
+// changes an entity does not need a reference to each cache holding it.
+type Invalidator interface {
+	Publish(inv Invalidation)
+	Subscribe(fn func(Invalidation)) (unsubscribe func())
+}
+
+// LocalInvalidator is an in-process Invalidator. Publish calls every
+// subscriber synchronously, in subscription order, before returning.
+type LocalInvalidator struct {
+	mu   sync.RWMutex
+	subs []*subscription
+}
+
+type subscription struct {
+	fn func(Invalidation)
+}
+
+// NewLocalInvalidator returns a LocalInvalidator with no subscribers.
+func NewLocalInvalidator() *LocalInvalidator {
+	return &LocalInvalidator{}
+}
+
+// Publish calls every current subscriber with inv. Subscribers added or
+// removed while it runs are not affected until the next Publish.
+func (l *LocalInvalidator) Publish(inv Invalidation) {
+	l.mu.RLock()
+	subs := l.subs
+	l.mu.RUnlock()
+
+	for _, sub := range subs {
+		sub.fn(inv)
+	}
+}
+
+// Subscribe registers fn to receive every later invalidation and returns a
+// function that removes it. Calling the returned function more than once is
+// harmless.
+func (l *LocalInvalidator) Subscribe(fn func(Invalidation)) func() {
+	sub := &subscription{fn: fn}
+
+	l.mu.Lock()
+	// Copy on write so Publish can iterate without holding the lock.
+	subs := make([]*subscription, len(l.subs), len(l.subs)+1)
+	copy(subs, l.subs)
+	l.subs = append(subs, sub)
+	l.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+
+			subs := make([]*subscription, 0, len(l.subs))
+			for _, s := range l.subs {
+				if s != sub {
+					subs = append(subs, s)
+				}
+			}
+			l.subs = subs
+		})
+	}
+}
+
+// ListenTo deletes entries from c as key invalidations are published on inv.
+// Tag invalidations are ignored; subscribe directly to map tags to keys.
+func (c *Cache) ListenTo(inv Invalidator) (unsubscribe func()) {
+	return inv.Subscribe(func(ev Invalidation) {
+		if ev.Kind == InvalidateKey {
+			c.Delete(ev.Value)
+		}
+	})
+}
@@ -0,0 +1,74 @@
+package agent14
+
+import "testing"
+
+func TestLocalInvalidatorFansOut(t *testing.T) {
+	bus := NewLocalInvalidator()
+
+	users := New(Config{Capacity: 4})
+	defer users.Close()
+	profiles := New(Config{Capacity: 4})
+	defer profiles.Close()
+
+	users.Set("user:1", "alice")
+	users.Set("user:2", "bob")
+	profiles.Set("user:1", "profile")
+
+	stopUsers := users.ListenTo(bus)
+	defer stopUsers()
+	stopProfiles := profiles.ListenTo(bus)
+
+	var tags []string
+	stopTags := bus.Subscribe(func(inv Invalidation) {
+		if inv.Kind == InvalidateTag {
+			tags = append(tags, inv.Value)
+		}
+	})
+	defer stopTags()
+
+	bus.Publish(Invalidation{Kind: InvalidateKey, Value: "user:1"})
+
+	if _, err := users.Get("user:1"); err == nil {
+		t.Fatal("expected user:1 to be invalidated in users")
+	}
+	if _, err := profiles.Get("user:1"); err == nil {
+		t.Fatal("expected user:1 to be invalidated in profiles")
+	}
+	if _, err := users.Get("user:2"); err != nil {
+		t.Fatalf("expected user:2 to remain, err=%v", err)
+	}
+
+	bus.Publish(Invalidation{Kind: InvalidateTag, Value: "team:7"})
+	if len(tags) != 1 || tags[0] != "team:7" {
+		t.Fatalf("expected tag subscriber to see team:7, got %v", tags)
+	}
+	if users.Len() != 1 {
+		t.Fatalf("expected tag invalidation to leave caches alone, got len %d", users.Len())
+	}
+
+	stopProfiles()
+	stopProfiles()
+	profiles.Set("user:2", "profile")
+	bus.Publish(Invalidation{Kind: InvalidateKey, Value: "user:2"})
+	if _, err := profiles.Get("user:2"); err != nil {
+		t.Fatalf("expected unsubscribed cache to keep user:2, err=%v", err)
+	}
+	if _, err := users.Get("user:2"); err == nil {
+		t.Fatal("expected subscribed cache to drop user:2")
+	}
+}
+
+func TestLocalInvalidatorSubscribeDuringPublish(t *testing.T) {
+	bus := NewLocalInvalidator()
+
+	calls := 0
+	bus.Subscribe(func(Invalidation) {
+		calls++
+		bus.Subscribe(func(Invalidation) { calls++ })
+	})
+
+	bus.Publish(Invalidation{Kind: InvalidateKey, Value: "a"})
+	if calls != 1 {
+		t.Fatalf("expected new subscriber to miss the in-flight publish, got %d calls", calls)
+	}
+}
@@ -5,6 +5,9 @@ import (
 	"errors"
 	"sync"
 	"time"
+
+	"github.com/rselbach/agent-comparison/clock"
+	"github.com/rselbach/agent-comparison/metrics"
 )
 
 var ErrNotFound = errors.New("key not found")
@@ -21,11 +24,43 @@ type Cache struct {
 	items    map[string]*list.Element
 	order    *list.List
 	stopCh   chan struct{}
+	events   *eventRing
+	cloner   Cloner
+	onEvict  func(key string, value interface{}, reason EventOp)
+	clock    clock.Clock
+	metrics  metrics.Recorder
+
+	defaultTTL time.Duration
 }
 
+// Cloner returns a deep copy of a cached value. It is called on every hit,
+// outside the cache lock, and must return values of types it does not
+// recognise unchanged.
+type Cloner func(value interface{}) interface{}
+
 type Config struct {
 	Capacity        int
 	CleanupInterval time.Duration
+	EventBufferSize int
+	// Cloner, if set, makes Get return a copy of the cached value so callers
+	// cannot mutate the shared instance.
+	Cloner Cloner
+	// DefaultTTL is the expiry applied by Set. Zero means entries written by
+	// Set never expire.
+	DefaultTTL time.Duration
+	// OnEvict, if set, is called with OpEvict for every entry dropped to make
+	// room, with OpExpire for every expired entry removed, by Get or by the
+	// cleanup goroutine, and with OpSet for the previous value of a key that
+	// Set or SetWithTTL overwrites, even if the new value is the same one. It
+	// runs outside the cache lock. Delete and Clear do not call it.
+	OnEvict func(key string, value interface{}, reason EventOp)
+	// Clock supplies the time for expiry and event timestamps and drives the
+	// cleanup ticker. Nil means real time; tests can pass a clock.Fake.
+	Clock clock.Clock
+	// Recorder, if set, is told about Get hits and misses, entries evicted
+	// or expired, and how long each cleanup pass took. It is called under the
+	// cache lock.
+	Recorder metrics.Recorder
 }
 
 func New(cfg Config) *Cache {
@@ -39,6 +74,13 @@ func New(cfg Config) *Cache {
 		items:    make(map[string]*list.Element, capacity),
 		order:    list.New(),
 		stopCh:   make(chan struct{}),
+		events:   newEventRing(cfg.EventBufferSize),
+		cloner:   cfg.Cloner,
+		onEvict:  cfg.OnEvict,
+		clock:    clock.OrReal(cfg.Clock),
+		metrics:  metrics.OrNop(cfg.Recorder),
+
+		defaultTTL: cfg.DefaultTTL,
 	}
 
 	if cfg.CleanupInterval > 0 {
@@ -48,49 +90,96 @@ func New(cfg Config) *Cache {
 	return c
 }
 
-func (c *Cache) Set(key string, value interface{}, ttl time.Duration) {
+// Set stores value under key using Config.DefaultTTL.
+func (c *Cache) Set(key string, value interface{}) {
+	c.SetWithTTL(key, value, c.defaultTTL)
+}
+
+// SetWithTTL stores value under key, expiring it after ttl. A ttl of zero or
+// less means the entry never expires, regardless of Config.DefaultTTL.
+func (c *Cache) SetWithTTL(key string, value interface{}, ttl time.Duration) {
 	c.mu.Lock()
-	defer c.mu.Unlock()
+	replaced, evicted := c.setLocked(key, value, ttl)
+	c.mu.Unlock()
 
+	if replaced != nil {
+		c.notifyEvicted(replaced, OpSet)
+	}
+	if evicted != nil {
+		c.notifyEvicted(evicted, OpEvict)
+	}
+}
+
+// setLocked stores value under key. It returns the previous value of key as
+// an entry if it overwrote one, and the entry evicted to make room, if any.
+func (c *Cache) setLocked(key string, value interface{}, ttl time.Duration) (replaced, evicted *entry) {
 	expiresAt := time.Time{}
 	if ttl > 0 {
-		expiresAt = time.Now().Add(ttl)
+		expiresAt = c.clock.Now().Add(ttl)
 	}
 
 	if elem, ok := c.items[key]; ok {
 		ent := elem.Value.(*entry)
+		replaced = &entry{key: key, value: ent.value}
 		ent.value = value
 		ent.expiresAt = expiresAt
 		c.order.MoveToFront(elem)
-		return
+		c.recordLocked(OpSet, key, ResultUpdated)
+		return replaced, nil
 	}
 
 	ent := &entry{key: key, value: value, expiresAt: expiresAt}
 	elem := c.order.PushFront(ent)
 	c.items[key] = elem
+	c.recordLocked(OpSet, key, ResultInserted)
 
 	if len(c.items) > c.capacity {
-		c.removeOldestLocked()
+		return nil, c.removeOldestLocked()
 	}
+	return nil, nil
 }
 
 func (c *Cache) Get(key string) (interface{}, error) {
+	value, err := c.get(key)
+	if err != nil || c.cloner == nil {
+		return value, err
+	}
+	return c.cloner(value), nil
+}
+
+func (c *Cache) get(key string) (interface{}, error) {
 	c.mu.Lock()
-	defer c.mu.Unlock()
+	value, expired, err := c.getLocked(key)
+	c.mu.Unlock()
+
+	if expired != nil {
+		c.notifyEvicted(expired, OpExpire)
+	}
+	return value, err
+}
 
+// getLocked looks up key and returns the entry it removed if key had expired.
+func (c *Cache) getLocked(key string) (interface{}, *entry, error) {
 	elem, ok := c.items[key]
 	if !ok {
-		return nil, ErrNotFound
+		c.recordLocked(OpGet, key, ResultMiss)
+		c.metrics.Miss()
+		return nil, nil, ErrNotFound
 	}
 
 	ent := elem.Value.(*entry)
-	if ent.expiresAt.IsZero() || time.Now().Before(ent.expiresAt) {
+	if ent.expiresAt.IsZero() || c.clock.Now().Before(ent.expiresAt) {
 		c.order.MoveToFront(elem)
-		return ent.value, nil
+		c.recordLocked(OpGet, key, ResultHit)
+		c.metrics.Hit()
+		return ent.value, nil, nil
 	}
 
 	c.removeElementLocked(elem)
-	return nil, ErrNotFound
+	c.recordLocked(OpGet, key, ResultExpired)
+	c.metrics.Expiration()
+	c.metrics.Miss()
+	return nil, ent, ErrNotFound
 }
 
 func (c *Cache) Delete(key string) bool {
@@ -99,10 +188,12 @@ func (c *Cache) Delete(key string) bool {
 
 	elem, ok := c.items[key]
 	if !ok {
+		c.recordLocked(OpDelete, key, ResultMiss)
 		return false
 	}
 
 	c.removeElementLocked(elem)
+	c.recordLocked(OpDelete, key, ResultRemoved)
 	return true
 }
 
@@ -118,6 +209,7 @@ func (c *Cache) Clear() {
 
 	c.items = make(map[string]*list.Element, c.capacity)
 	c.order.Init()
+	c.recordLocked(OpClear, "", ResultRemoved)
 }
 
 func (c *Cache) Close() {
@@ -125,12 +217,12 @@ func (c *Cache) Close() {
 }
 
 func (c *Cache) startCleanup(interval time.Duration) {
-	ticker := time.NewTicker(interval)
+	ticker := c.clock.NewTicker(interval)
 	defer ticker.Stop()
 
 	for {
 		select {
-		case <-ticker.C:
+		case <-ticker.C():
 			c.removeExpired()
 		case <-c.stopCh:
 			return
@@ -140,24 +232,37 @@ func (c *Cache) startCleanup(interval time.Duration) {
 
 func (c *Cache) removeExpired() {
 	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	now := time.Now()
+	var expired []*entry
+	now := c.clock.Now()
 	for elem := c.order.Back(); elem != nil; {
 		prev := elem.Prev()
 		ent := elem.Value.(*entry)
 		if !ent.expiresAt.IsZero() && now.After(ent.expiresAt) {
 			c.removeElementLocked(elem)
+			c.recordLocked(OpExpire, ent.key, ResultRemoved)
+			c.metrics.Expiration()
+			expired = append(expired, ent)
 		}
 		elem = prev
 	}
+	c.metrics.SweepDuration(c.clock.Now().Sub(now))
+	c.mu.Unlock()
+
+	for _, ent := range expired {
+		c.notifyEvicted(ent, OpExpire)
+	}
 }
 
-func (c *Cache) removeOldestLocked() {
+func (c *Cache) removeOldestLocked() *entry {
 	elem := c.order.Back()
-	if elem != nil {
-		c.removeElementLocked(elem)
+	if elem == nil {
+		return nil
 	}
+	c.removeElementLocked(elem)
+	ent := elem.Value.(*entry)
+	c.recordLocked(OpEvict, ent.key, ResultRemoved)
+	c.metrics.Eviction()
+	return ent
 }
 
 func (c *Cache) removeElementLocked(elem *list.Element) {
@@ -165,3 +270,9 @@ func (c *Cache) removeElementLocked(elem *list.Element) {
 	ent := elem.Value.(*entry)
 	delete(c.items, ent.key)
 }
+
+func (c *Cache) notifyEvicted(ent *entry, reason EventOp) {
+	if c.onEvict != nil {
+		c.onEvict(ent.key, ent.value, reason)
+	}
+}
@@ -3,14 +3,17 @@ package agent14
 import (
 	"testing"
 	"time"
+
+	"github.com/rselbach/agent-comparison/clock"
+	"github.com/rselbach/agent-comparison/metrics"
 )
 
 func TestSetGet(t *testing.T) {
 	cache := New(Config{Capacity: 2})
 	defer cache.Close()
 
-	cache.Set("a", 1, 0)
-	cache.Set("b", 2, 0)
+	cache.Set("a", 1)
+	cache.Set("b", 2)
 
 	v, err := cache.Get("a")
 	if err != nil || v.(int) != 1 {
@@ -27,12 +30,12 @@ func TestLRUEviction(t *testing.T) {
 	cache := New(Config{Capacity: 2})
 	defer cache.Close()
 
-	cache.Set("a", 1, 0)
-	cache.Set("b", 2, 0)
+	cache.Set("a", 1)
+	cache.Set("b", 2)
 
 	cache.Get("a")
 
-	cache.Set("c", 3, 0)
+	cache.Set("c", 3)
 
 	if _, err := cache.Get("b"); err == nil {
 		t.Fatal("expected b to be evicted")
@@ -44,17 +47,18 @@ func TestLRUEviction(t *testing.T) {
 }
 
 func TestExpiration(t *testing.T) {
-	cache := New(Config{Capacity: 2})
+	clk := clock.NewFake(time.Unix(0, 0))
+	cache := New(Config{Capacity: 2, Clock: clk})
 	defer cache.Close()
 
-	cache.Set("a", 1, 50*time.Millisecond)
-	cache.Set("b", 2, 0)
+	cache.SetWithTTL("a", 1, 50*time.Millisecond)
+	cache.Set("b", 2)
 
 	if _, err := cache.Get("a"); err != nil {
 		t.Fatalf("expected a before expiration, got err=%v", err)
 	}
 
-	time.Sleep(80 * time.Millisecond)
+	clk.Advance(80 * time.Millisecond)
 
 	if _, err := cache.Get("a"); err == nil {
 		t.Fatal("expected a to expire")
@@ -66,16 +70,23 @@ func TestExpiration(t *testing.T) {
 }
 
 func TestAutoCleanup(t *testing.T) {
-	cache := New(Config{Capacity: 10, CleanupInterval: 30 * time.Millisecond})
+	clk := clock.NewFake(time.Unix(0, 0))
+	cache := New(Config{Capacity: 10, CleanupInterval: 30 * time.Millisecond, Clock: clk})
 	defer cache.Close()
+	clk.BlockUntil(1)
 
-	cache.Set("a", 1, 30*time.Millisecond)
-	cache.Set("b", 2, 0)
+	cache.SetWithTTL("a", 1, 30*time.Millisecond)
+	cache.Set("b", 2)
 
-	time.Sleep(80 * time.Millisecond)
+	clk.Advance(80 * time.Millisecond)
 
-	if _, err := cache.Get("a"); err == nil {
-		t.Fatal("expected a to be cleaned up")
+	// Len counts expired entries too, so it drops only once the cleanup has run
+	deadline := time.Now().Add(time.Second)
+	for cache.Len() != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected a to be cleaned up")
+		}
+		time.Sleep(time.Millisecond)
 	}
 
 	if v, err := cache.Get("b"); err != nil || v.(int) != 2 {
@@ -87,8 +98,8 @@ func TestDelete(t *testing.T) {
 	cache := New(Config{Capacity: 5})
 	defer cache.Close()
 
-	cache.Set("a", 1, 0)
-	cache.Set("b", 2, 0)
+	cache.Set("a", 1)
+	cache.Set("b", 2)
 
 	if !cache.Delete("a") {
 		t.Fatal("expected delete to succeed")
@@ -107,8 +118,8 @@ func TestClearLen(t *testing.T) {
 	cache := New(Config{Capacity: 5})
 	defer cache.Close()
 
-	cache.Set("a", 1, 0)
-	cache.Set("b", 2, 0)
+	cache.Set("a", 1)
+	cache.Set("b", 2)
 
 	if cache.Len() != 2 {
 		t.Fatalf("expected len 2, got %d", cache.Len())
@@ -124,3 +135,230 @@ func TestClearLen(t *testing.T) {
 		t.Fatal("expected a to be cleared")
 	}
 }
+
+func TestRecentEvents(t *testing.T) {
+	cache := New(Config{Capacity: 1, EventBufferSize: 4})
+	defer cache.Close()
+
+	cache.Set("a", 1)
+	cache.Get("a")
+	cache.Get("missing")
+	cache.Set("b", 2)
+	cache.Delete("b")
+
+	events := cache.RecentEvents(0)
+	want := []Event{
+		{Op: OpGet, Key: "missing", Result: ResultMiss},
+		{Op: OpSet, Key: "b", Result: ResultInserted},
+		{Op: OpEvict, Key: "a", Result: ResultRemoved},
+		{Op: OpDelete, Key: "b", Result: ResultRemoved},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("expected %d events, got %d: %+v", len(want), len(events), events)
+	}
+	for i, ev := range events {
+		if ev.Op != want[i].Op || ev.Key != want[i].Key || ev.Result != want[i].Result {
+			t.Fatalf("event %d: expected %+v, got %+v", i, want[i], ev)
+		}
+		if ev.Time.IsZero() {
+			t.Fatalf("event %d: expected timestamp", i)
+		}
+	}
+
+	last := cache.RecentEvents(2)
+	if len(last) != 2 || last[1].Op != OpDelete {
+		t.Fatalf("expected last two events ending in delete, got %+v", last)
+	}
+
+	disabled := New(Config{Capacity: 1})
+	defer disabled.Close()
+	disabled.Set("a", 1)
+	if events := disabled.RecentEvents(10); events != nil {
+		t.Fatalf("expected no events when buffer disabled, got %+v", events)
+	}
+}
+
+func TestClonerIsolatesValues(t *testing.T) {
+	cloneSlice := func(v interface{}) interface{} {
+		if s, ok := v.([]string); ok {
+			return append([]string(nil), s...)
+		}
+		return v
+	}
+
+	tests := map[string]struct {
+		cloner    Cloner
+		wantFirst string
+	}{
+		"without cloner callers share the value": {
+			wantFirst: "mutated",
+		},
+		"with cloner callers get copies": {
+			cloner:    cloneSlice,
+			wantFirst: "a",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			cache := New(Config{Capacity: 2, Cloner: tc.cloner})
+			defer cache.Close()
+
+			cache.Set("roles", []string{"a", "b"})
+			cache.Set("n", 1)
+
+			v, err := cache.Get("roles")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			v.([]string)[0] = "mutated"
+
+			v, err = cache.Get("roles")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := v.([]string)[0]; got != tc.wantFirst {
+				t.Fatalf("expected %q, got %q", tc.wantFirst, got)
+			}
+
+			if v, err := cache.Get("n"); err != nil || v.(int) != 1 {
+				t.Fatalf("expected 1, got %v, err=%v", v, err)
+			}
+		})
+	}
+}
+
+func TestClonerNotCalledOnMiss(t *testing.T) {
+	calls := 0
+	cache := New(Config{Capacity: 2, Cloner: func(v interface{}) interface{} {
+		calls++
+		return v
+	}})
+	defer cache.Close()
+
+	if _, err := cache.Get("missing"); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if calls != 0 {
+		t.Fatalf("expected no cloner calls, got %d", calls)
+	}
+}
+
+func TestDefaultTTL(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	cache := New(Config{Capacity: 4, DefaultTTL: 30 * time.Millisecond, Clock: clk})
+	defer cache.Close()
+
+	cache.Set("default", 1)
+	cache.SetWithTTL("long", 2, time.Hour)
+	cache.SetWithTTL("forever", 3, 0)
+
+	clk.Advance(60 * time.Millisecond)
+
+	if _, err := cache.Get("default"); err != ErrNotFound {
+		t.Fatalf("expected default-TTL entry to expire, got err=%v", err)
+	}
+	if _, err := cache.Get("long"); err != nil {
+		t.Fatalf("expected per-call TTL to override default, got err=%v", err)
+	}
+	if _, err := cache.Get("forever"); err != nil {
+		t.Fatalf("expected zero TTL to never expire, got err=%v", err)
+	}
+}
+
+func TestOnEvict(t *testing.T) {
+	type eviction struct {
+		key    string
+		value  interface{}
+		reason EventOp
+	}
+	var evicted []eviction
+	clk := clock.NewFake(time.Unix(0, 0))
+	var cache *Cache
+	cache = New(Config{
+		Capacity: 2,
+		Clock:    clk,
+		OnEvict: func(key string, value interface{}, reason EventOp) {
+			// runs outside the lock, so touching the cache must not deadlock
+			cache.Len()
+			evicted = append(evicted, eviction{key, value, reason})
+		},
+	})
+	defer cache.Close()
+
+	cache.Set("a", 1)
+	cache.SetWithTTL("b", 2, 20*time.Millisecond)
+	cache.Set("c", 3)
+	clk.Advance(40 * time.Millisecond)
+	cache.Get("b")
+	cache.Set("c", 4)
+	cache.Delete("c")
+	cache.Clear()
+
+	want := []eviction{{"a", 1, OpEvict}, {"b", 2, OpExpire}, {"c", 3, OpSet}}
+	if len(evicted) != len(want) {
+		t.Fatalf("expected %v, got %v", want, evicted)
+	}
+	for i := range want {
+		if evicted[i] != want[i] {
+			t.Fatalf("eviction %d: expected %v, got %v", i, want[i], evicted[i])
+		}
+	}
+}
+
+func TestOnEvictCleanup(t *testing.T) {
+	done := make(chan string, 1)
+	cache := New(Config{
+		Capacity:        2,
+		CleanupInterval: 10 * time.Millisecond,
+		OnEvict: func(key string, value interface{}, reason EventOp) {
+			if reason == OpExpire {
+				done <- key
+			}
+		},
+	})
+	defer cache.Close()
+
+	cache.SetWithTTL("a", 1, 20*time.Millisecond)
+
+	select {
+	case key := <-done:
+		if key != "a" {
+			t.Fatalf("expected a to expire, got %s", key)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("cleanup did not report the expired entry")
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	rec := &metrics.Counters{}
+	cache := New(Config{Capacity: 2, CleanupInterval: time.Minute, Clock: clk, Recorder: rec})
+	defer cache.Close()
+	clk.BlockUntil(1)
+
+	cache.SetWithTTL("a", 1, time.Second)
+	cache.Set("b", 2)
+	cache.Get("a")
+	cache.Get("missing")
+	cache.Set("c", 3) // evicts b
+
+	clk.Advance(2 * time.Second)
+	cache.Get("a")
+	cache.SetWithTTL("d", 4, time.Millisecond)
+	clk.Advance(time.Minute)
+
+	deadline := time.Now().Add(time.Second)
+	for rec.Snapshot().Sweeps == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected a cleanup pass")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	want := metrics.Snapshot{Hits: 1, Misses: 2, Evictions: 1, Expirations: 2, Sweeps: 1}
+	if got := rec.Snapshot(); got != want {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+}
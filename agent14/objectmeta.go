@@ -0,0 +1,82 @@
+package agent14
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrUnexpectedNotModified = errors.New("not modified response without cached metadata")
+
+type ObjectMeta struct {
+	ETag         string
+	Size         int64
+	LastModified time.Time
+}
+
+// FetchResult is what a Validate fetch reports back. NotModified mirrors a
+// 304 response to a conditional request; Meta is ignored in that case.
+type FetchResult struct {
+	Meta        ObjectMeta
+	NotModified bool
+}
+
+type FetchFunc func(ifNoneMatch string) (FetchResult, error)
+
+type metaEntry struct {
+	meta       ObjectMeta
+	validUntil time.Time
+}
+
+// MetaCache caches object metadata in front of an object store. Entries are
+// served without a round trip for ttl; after that they are revalidated with
+// the cached ETag so an unchanged object costs a 304 instead of a full HEAD.
+type MetaCache struct {
+	cache *Cache
+	ttl   time.Duration
+}
+
+// NewMetaCache stores metadata in cache. Entries are written without an
+// expiry so their ETag outlives the freshness window; capacity bounds them.
+// A non-positive ttl revalidates on every call.
+func NewMetaCache(cache *Cache, ttl time.Duration) *MetaCache {
+	return &MetaCache{cache: cache, ttl: ttl}
+}
+
+func (m *MetaCache) Validate(key string, fetch FetchFunc) (ObjectMeta, error) {
+	var cached *metaEntry
+	if v, err := m.cache.Get(key); err == nil {
+		if ent, ok := v.(*metaEntry); ok {
+			cached = ent
+		}
+	}
+
+	now := m.cache.clock.Now()
+	if cached != nil && now.Before(cached.validUntil) {
+		return cached.meta, nil
+	}
+
+	ifNoneMatch := ""
+	if cached != nil {
+		ifNoneMatch = cached.meta.ETag
+	}
+
+	res, err := fetch(ifNoneMatch)
+	if err != nil {
+		return ObjectMeta{}, err
+	}
+
+	meta := res.Meta
+	if res.NotModified {
+		if cached == nil {
+			return ObjectMeta{}, ErrUnexpectedNotModified
+		}
+		meta = cached.meta
+	}
+
+	m.cache.SetWithTTL(key, &metaEntry{meta: meta, validUntil: now.Add(m.ttl)}, 0)
+	return meta, nil
+}
+
+func (m *MetaCache) Invalidate(key string) bool {
+	return m.cache.Delete(key)
+}
@@ -0,0 +1,94 @@
+package agent14
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type fakeStore struct {
+	meta  ObjectMeta
+	calls []string
+}
+
+func (s *fakeStore) head(ifNoneMatch string) (FetchResult, error) {
+	s.calls = append(s.calls, ifNoneMatch)
+	if ifNoneMatch != "" && ifNoneMatch == s.meta.ETag {
+		return FetchResult{NotModified: true}, nil
+	}
+	return FetchResult{Meta: s.meta}, nil
+}
+
+func TestMetaCacheServesFreshEntries(t *testing.T) {
+	cache := New(Config{Capacity: 4})
+	defer cache.Close()
+
+	store := &fakeStore{meta: ObjectMeta{ETag: `"v1"`, Size: 10}}
+	mc := NewMetaCache(cache, time.Hour)
+
+	for i := 0; i < 3; i++ {
+		meta, err := mc.Validate("bucket/obj", store.head)
+		if err != nil || meta.ETag != `"v1"` {
+			t.Fatalf("expected v1, got %+v, err=%v", meta, err)
+		}
+	}
+	if len(store.calls) != 1 {
+		t.Fatalf("expected a single fetch, got %d", len(store.calls))
+	}
+
+	mc.Invalidate("bucket/obj")
+	mc.Validate("bucket/obj", store.head)
+	if len(store.calls) != 2 || store.calls[1] != "" {
+		t.Fatalf("expected unconditional fetch after invalidate, got %q", store.calls)
+	}
+}
+
+func TestMetaCacheRevalidates(t *testing.T) {
+	cache := New(Config{Capacity: 4})
+	defer cache.Close()
+
+	store := &fakeStore{meta: ObjectMeta{ETag: `"v1"`, Size: 10}}
+	mc := NewMetaCache(cache, 0)
+
+	if _, err := mc.Validate("obj", store.head); err != nil {
+		t.Fatalf("unexpected err=%v", err)
+	}
+
+	meta, err := mc.Validate("obj", store.head)
+	if err != nil || meta.Size != 10 {
+		t.Fatalf("expected cached meta after 304, got %+v, err=%v", meta, err)
+	}
+	if store.calls[1] != `"v1"` {
+		t.Fatalf("expected If-None-Match v1, got %q", store.calls[1])
+	}
+
+	store.meta = ObjectMeta{ETag: `"v2"`, Size: 20}
+	meta, err = mc.Validate("obj", store.head)
+	if err != nil || meta.ETag != `"v2"` || meta.Size != 20 {
+		t.Fatalf("expected v2, got %+v, err=%v", meta, err)
+	}
+}
+
+func TestMetaCacheErrors(t *testing.T) {
+	cache := New(Config{Capacity: 4})
+	defer cache.Close()
+
+	mc := NewMetaCache(cache, time.Hour)
+
+	_, err := mc.Validate("obj", func(string) (FetchResult, error) {
+		return FetchResult{NotModified: true}, nil
+	})
+	if !errors.Is(err, ErrUnexpectedNotModified) {
+		t.Fatalf("expected ErrUnexpectedNotModified, got %v", err)
+	}
+
+	boom := errors.New("boom")
+	if _, err := mc.Validate("obj", func(string) (FetchResult, error) {
+		return FetchResult{}, boom
+	}); !errors.Is(err, boom) {
+		t.Fatalf("expected fetch error, got %v", err)
+	}
+	if cache.Len() != 0 {
+		t.Fatalf("expected nothing cached after errors, got %d", cache.Len())
+	}
+}
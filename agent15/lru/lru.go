@@ -1,53 +1,160 @@
 package lru
 
 import (
+	"container/heap"
 	"container/list"
 	"sync"
 	"time"
+
+	"github.com/rselbach/agent-comparison/clock"
+	"github.com/rselbach/agent-comparison/metrics"
 )
 
 // LRU implements a least recently used cache with automatic expiration.
 type LRU struct {
-	capacity int
-	items    map[string]*list.Element
-	l        *list.List
-	mu       sync.RWMutex
+	capacity   int
+	defaultTTL time.Duration
+	items      map[string]*list.Element
+	l          *list.List
+	expiries   expiryHeap
+	mu         sync.RWMutex
+	clock      clock.Clock
+	metrics    metrics.Recorder
+
+	// stop and done are nil unless background cleanup runs.
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
 }
 
 type entry struct {
 	key    string
 	value  any
-	expire time.Time
+	expire time.Time // zero means the entry never expires
+	index  int       // position in expiries, or -1 if it never expires
+}
+
+func (ent *entry) expired(now time.Time) bool {
+	return !ent.expire.IsZero() && now.After(ent.expire)
 }
 
-// New creates a new LRU cache with the given capacity.
+// expiryHeap orders entries by expiration time, soonest first.
+type expiryHeap []*entry
+
+func (h expiryHeap) Len() int           { return len(h) }
+func (h expiryHeap) Less(i, j int) bool { return h[i].expire.Before(h[j].expire) }
+func (h expiryHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *expiryHeap) Push(x any) {
+	ent := x.(*entry)
+	ent.index = len(*h)
+	*h = append(*h, ent)
+}
+
+func (h *expiryHeap) Pop() any {
+	old := *h
+	n := len(old)
+	ent := old[n-1]
+	ent.index = -1
+	old[n-1] = nil
+	*h = old[:n-1]
+	return ent
+}
+
+// CleanupBatchSize is the number of entries Cleanup removes before briefly
+// releasing the lock so that readers and writers are not starved.
+const CleanupBatchSize = 128
+
+// DefaultCleanupInterval is the interval used by New for background cleanup.
+const DefaultCleanupInterval = time.Minute
+
+// New creates a new LRU cache with the given capacity. Its background
+// cleanup runs until Close is called.
+// A capacity that is not positive is not rejected, but such a cache keeps
+// nothing; use NewWithOptions to get an error instead.
 func New(capacity int) *LRU {
-	lru := &LRU{
-		capacity: capacity,
-		items:    make(map[string]*list.Element),
-		l:        list.New(),
-	}
-	go lru.cleanup()
-	return lru
+	return NewWithCleanupInterval(capacity, DefaultCleanupInterval)
+}
+
+// NewWithCleanupInterval creates a new LRU cache that removes expired entries
+// every interval. A non-positive interval disables background cleanup; expired
+// entries are then removed on access or by calling Cleanup. Call Close to stop
+// background cleanup. Like New, it does not validate capacity.
+func NewWithCleanupInterval(capacity int, interval time.Duration) *LRU {
+	return newLRU(capacity, options{cleanupInterval: interval})
 }
 
-func (lru *LRU) cleanup() {
-	ticker := time.NewTicker(time.Minute)
+func (lru *LRU) cleanup(ticker clock.Ticker) {
+	defer close(lru.done)
 	defer ticker.Stop()
-	for range ticker.C {
-		lru.mu.Lock()
-		for e := lru.l.Back(); e != nil; {
-			ent := e.Value.(*entry)
-			if time.Now().After(ent.expire) {
-				delete(lru.items, ent.key)
-				prev := e.Prev()
-				lru.l.Remove(e)
-				e = prev
-			} else {
-				break
-			}
+	for {
+		select {
+		case <-ticker.C():
+			lru.Cleanup()
+		case <-lru.stop:
+			return
 		}
-		lru.mu.Unlock()
+	}
+}
+
+// Close stops background cleanup and waits for it to exit. The cache remains
+// usable afterwards; expired entries are then removed on access or by calling
+// Cleanup. Close may be called more than once.
+func (lru *LRU) Close() {
+	if lru.stop == nil {
+		return
+	}
+	lru.closeOnce.Do(func() {
+		close(lru.stop)
+	})
+	<-lru.done
+}
+
+// Cleanup removes every expired entry and returns the number removed.
+// Entries are taken from an expiration-ordered index, so only expired entries
+// are visited, and the lock is released after every CleanupBatchSize removals.
+func (lru *LRU) Cleanup() int {
+	start := lru.clock.Now()
+	removed := 0
+	for {
+		n, more := lru.cleanupBatch(lru.clock.Now())
+		removed += n
+		if !more {
+			lru.metrics.SweepDuration(lru.clock.Now().Sub(start))
+			return removed
+		}
+	}
+}
+
+// cleanupBatch removes up to CleanupBatchSize expired entries and reports
+// whether more may remain.
+func (lru *LRU) cleanupBatch(now time.Time) (int, bool) {
+	lru.mu.Lock()
+	defer lru.mu.Unlock()
+	removed := 0
+	for len(lru.expiries) > 0 && lru.expiries[0].expired(now) {
+		if removed == CleanupBatchSize {
+			return removed, true
+		}
+		lru.remove(lru.items[lru.expiries[0].key])
+		lru.metrics.Expiration()
+		removed++
+	}
+	return removed, false
+}
+
+// remove deletes elem from the list, map and expiration index.
+// The caller must hold the write lock.
+func (lru *LRU) remove(elem *list.Element) {
+	ent := elem.Value.(*entry)
+	delete(lru.items, ent.key)
+	lru.l.Remove(elem)
+	if ent.index >= 0 {
+		heap.Remove(&lru.expiries, ent.index)
 	}
 }
 
@@ -58,25 +165,50 @@ func (lru *LRU) Get(key string) (any, bool) {
 	elem, ok := lru.items[key]
 	lru.mu.RUnlock()
 	if !ok {
+		lru.metrics.Miss()
 		return nil, false
 	}
 	lru.mu.Lock()
+	if lru.items[key] != elem {
+		// Removed or replaced between the read and write lock.
+		lru.mu.Unlock()
+		lru.metrics.Miss()
+		return nil, false
+	}
 	ent := elem.Value.(*entry)
-	if time.Now().After(ent.expire) {
-		delete(lru.items, key)
-		lru.l.Remove(elem)
+	if ent.expired(lru.clock.Now()) {
+		lru.remove(elem)
+		lru.metrics.Expiration()
 		lru.mu.Unlock()
+		lru.metrics.Miss()
 		return nil, false
 	}
 	lru.l.MoveToFront(elem)
+	value := ent.value // a concurrent Put may overwrite it once unlocked
 	lru.mu.Unlock()
-	return ent.value, true
+	lru.metrics.Hit()
+	return value, true
 }
 
 // Put adds or updates the value for the given key with the specified TTL.
 // If the key already exists, it updates the value and resets the expiration.
 func (lru *LRU) Put(key string, value interface{}, ttl time.Duration) {
-	expire := time.Now().Add(ttl)
+	lru.put(key, value, lru.clock.Now().Add(ttl))
+}
+
+// Set adds or updates the value for the given key using the default TTL set
+// by WithDefaultTTL. Without a default TTL the entry never expires.
+func (lru *LRU) Set(key string, value any) {
+	var expire time.Time
+	if lru.defaultTTL > 0 {
+		expire = lru.clock.Now().Add(lru.defaultTTL)
+	}
+	lru.put(key, value, expire)
+}
+
+// put stores value under key, expiring it at expire; a zero expire means
+// never.
+func (lru *LRU) put(key string, value any, expire time.Time) {
 	lru.mu.Lock()
 	defer lru.mu.Unlock()
 	if elem, ok := lru.items[key]; ok {
@@ -84,15 +216,54 @@ func (lru *LRU) Put(key string, value interface{}, ttl time.Duration) {
 		ent := elem.Value.(*entry)
 		ent.value = value
 		ent.expire = expire
+		switch {
+		case ent.index >= 0 && expire.IsZero():
+			heap.Remove(&lru.expiries, ent.index)
+		case ent.index >= 0:
+			heap.Fix(&lru.expiries, ent.index)
+		case !expire.IsZero():
+			heap.Push(&lru.expiries, ent)
+		}
 		return
 	}
-	ent := &entry{key: key, value: value, expire: expire}
+	ent := &entry{key: key, value: value, expire: expire, index: -1}
 	elem := lru.l.PushFront(ent)
 	lru.items[key] = elem
+	if !expire.IsZero() {
+		heap.Push(&lru.expiries, ent)
+	}
 	if lru.l.Len() > lru.capacity {
-		elem = lru.l.Back()
-		ent = elem.Value.(*entry)
-		delete(lru.items, ent.key)
-		lru.l.Remove(elem)
+		lru.remove(lru.l.Back())
+		lru.metrics.Eviction()
 	}
 }
+
+// Delete removes the entry for the given key.
+// It returns true if the key was present, even if it had already expired.
+func (lru *LRU) Delete(key string) bool {
+	lru.mu.Lock()
+	defer lru.mu.Unlock()
+	elem, ok := lru.items[key]
+	if !ok {
+		return false
+	}
+	lru.remove(elem)
+	return true
+}
+
+// Clear removes all entries.
+func (lru *LRU) Clear() {
+	lru.mu.Lock()
+	defer lru.mu.Unlock()
+	lru.items = make(map[string]*list.Element)
+	lru.l.Init()
+	lru.expiries = nil
+}
+
+// Len returns the number of entries in the cache.
+// Expired entries are counted until they are removed by Get or Cleanup.
+func (lru *LRU) Len() int {
+	lru.mu.RLock()
+	defer lru.mu.RUnlock()
+	return lru.l.Len()
+}
@@ -1,9 +1,11 @@
 package lru
 
 import (
+	"strconv"
 	"testing"
 	"time"
 
+	"github.com/rselbach/agent-comparison/clock"
 	"github.com/stretchr/testify/require"
 )
 
@@ -53,7 +55,10 @@ func TestLRU_Put(t *testing.T) {
 
 func TestLRU_Expiration(t *testing.T) {
 	r := require.New(t)
-	lru := New(2)
+	clk := clock.NewFake(time.Unix(0, 0))
+	lru, err := NewWithOptions(2, WithClock(clk))
+	r.NoError(err)
+	defer lru.Close()
 
 	// Put with short TTL
 	lru.Put("key1", "value1", time.Millisecond*10)
@@ -63,8 +68,8 @@ func TestLRU_Expiration(t *testing.T) {
 	r.True(ok)
 	r.Equal("value1", val)
 
-	// Wait for expiration
-	time.Sleep(time.Millisecond * 20)
+	// Move past expiration
+	clk.Advance(time.Millisecond * 20)
 
 	// Now should be expired
 	_, ok = lru.Get("key1")
@@ -86,3 +91,120 @@ func TestLRU_Update(t *testing.T) {
 	r.True(ok)
 	r.Equal("value2", val)
 }
+
+func TestLRU_Cleanup(t *testing.T) {
+	r := require.New(t)
+	clk := clock.NewFake(time.Unix(0, 0))
+	lru, err := NewWithOptions(3, WithCleanupInterval(0), WithClock(clk))
+	r.NoError(err)
+
+	lru.Put("key1", "value1", time.Millisecond*10)
+	lru.Put("key2", "value2", time.Millisecond*10)
+	lru.Put("key3", "value3", time.Minute)
+
+	clk.Advance(time.Millisecond * 20)
+
+	// Nothing runs in the background, so entries stay until swept
+	r.Equal(3, lru.l.Len())
+
+	r.Equal(2, lru.Cleanup())
+	r.Equal(1, lru.l.Len())
+
+	val, ok := lru.Get("key3")
+	r.True(ok)
+	r.Equal("value3", val)
+}
+
+func TestLRU_CleanupInterval(t *testing.T) {
+	r := require.New(t)
+	clk := clock.NewFake(time.Unix(0, 0))
+	lru, err := NewWithOptions(2, WithCleanupInterval(time.Millisecond*10), WithClock(clk))
+	r.NoError(err)
+	defer lru.Close()
+
+	lru.Put("key1", "value1", time.Millisecond*10)
+	clk.Advance(time.Millisecond * 20)
+
+	r.Eventually(func() bool {
+		lru.mu.RLock()
+		defer lru.mu.RUnlock()
+		return lru.l.Len() == 0
+	}, time.Second, time.Millisecond*5)
+}
+
+func TestLRU_CleanupFindsExpiredAnywhere(t *testing.T) {
+	r := require.New(t)
+	clk := clock.NewFake(time.Unix(0, 0))
+	lru, err := NewWithOptions(4, WithCleanupInterval(0), WithClock(clk))
+	r.NoError(err)
+	lru.Put("short1", "value1", time.Millisecond*10)
+	lru.Put("long", "value2", time.Minute)
+	lru.Put("short2", "value3", time.Millisecond*10)
+	lru.Put("short3", "value4", time.Millisecond*10)
+	// long is now at the tail, where the old scan stopped without removing anything
+	_, _ = lru.Get("short1")
+	_, _ = lru.Get("short2")
+	_, _ = lru.Get("short3")
+	clk.Advance(time.Millisecond * 20)
+	r.Equal(3, lru.Cleanup())
+	r.Equal(1, lru.l.Len())
+	r.Len(lru.expiries, 1)
+	_, ok := lru.Get("long")
+	r.True(ok)
+}
+
+func TestLRU_CleanupBatches(t *testing.T) {
+	r := require.New(t)
+	n := CleanupBatchSize*2 + 5
+	clk := clock.NewFake(time.Unix(0, 0))
+	lru, err := NewWithOptions(n, WithCleanupInterval(0), WithClock(clk))
+	r.NoError(err)
+	for i := 0; i < n; i++ {
+		lru.Put(strconv.Itoa(i), i, time.Millisecond)
+	}
+	clk.Advance(time.Millisecond * 5)
+	removed, more := lru.cleanupBatch(clk.Now())
+	r.Equal(CleanupBatchSize, removed)
+	r.True(more)
+	r.Equal(n-CleanupBatchSize, lru.Cleanup())
+	r.Equal(0, lru.l.Len())
+	r.Empty(lru.expiries)
+}
+
+func TestLRU_Delete(t *testing.T) {
+	r := require.New(t)
+	lru := New(2)
+	defer lru.Close()
+	lru.Put("key1", "value1", time.Minute)
+	lru.Put("key2", "value2", time.Minute)
+
+	r.True(lru.Delete("key1"))
+	r.False(lru.Delete("key1"))
+	_, ok := lru.Get("key1")
+	r.False(ok)
+	r.Equal(1, lru.Len())
+	r.Len(lru.expiries, 1)
+
+	// The freed slot is reused without evicting key2
+	lru.Put("key3", "value3", time.Minute)
+	_, ok = lru.Get("key2")
+	r.True(ok)
+}
+
+func TestLRU_Clear(t *testing.T) {
+	r := require.New(t)
+	lru := New(2)
+	defer lru.Close()
+	lru.Put("key1", "value1", time.Minute)
+	lru.Put("key2", "value2", time.Minute)
+
+	lru.Clear()
+	r.Equal(0, lru.Len())
+	r.Empty(lru.expiries)
+	_, ok := lru.Get("key1")
+	r.False(ok)
+
+	lru.Put("key3", "value3", time.Minute)
+	r.Equal(1, lru.Len())
+	r.Equal(0, lru.Cleanup())
+}
@@ -0,0 +1,96 @@
+package lru
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rselbach/agent-comparison/clock"
+	"github.com/rselbach/agent-comparison/metrics"
+)
+
+// ErrInvalidCapacity is returned by NewWithOptions for a capacity that is not
+// positive.
+var ErrInvalidCapacity = errors.New("lru: capacity must be positive")
+
+// Option configures an LRU created by NewWithOptions.
+type Option func(*options)
+
+type options struct {
+	cleanupInterval time.Duration
+	defaultTTL      time.Duration
+	clock           clock.Clock
+	recorder        metrics.Recorder
+}
+
+// WithCleanupInterval sets how often expired entries are removed in the
+// background. A non-positive interval disables background cleanup. The
+// default is DefaultCleanupInterval.
+func WithCleanupInterval(interval time.Duration) Option {
+	return func(o *options) {
+		o.cleanupInterval = interval
+	}
+}
+
+// WithDefaultTTL sets the TTL applied by Set. Zero, the default, means entries
+// written by Set never expire.
+func WithDefaultTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		o.defaultTTL = ttl
+	}
+}
+
+// WithClock sets the clock used for expiration and for the background
+// cleanup ticker, so tests can drive both with a clock.Fake. A nil clock, the
+// default, means real time.
+func WithClock(clk clock.Clock) Option {
+	return func(o *options) {
+		o.clock = clk
+	}
+}
+
+// WithRecorder makes the cache report Get hits and misses, capacity
+// evictions, expired entries removed by Get or Cleanup, and the duration of
+// each Cleanup to r. A nil recorder, the default, records nothing.
+func WithRecorder(r metrics.Recorder) Option {
+	return func(o *options) {
+		o.recorder = r
+	}
+}
+
+// NewWithOptions creates a new LRU cache with the given capacity, configured
+// by opts. It returns an error if capacity is not positive or the default TTL
+// is negative.
+func NewWithOptions(capacity int, opts ...Option) (*LRU, error) {
+	if capacity <= 0 {
+		return nil, fmt.Errorf("%w, got %d", ErrInvalidCapacity, capacity)
+	}
+	o := options{cleanupInterval: DefaultCleanupInterval}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.defaultTTL < 0 {
+		return nil, fmt.Errorf("lru: default TTL must not be negative, got %v", o.defaultTTL)
+	}
+
+	return newLRU(capacity, o), nil
+}
+
+// newLRU builds a cache from capacity and o without validating either.
+func newLRU(capacity int, o options) *LRU {
+	lru := &LRU{
+		capacity:   capacity,
+		defaultTTL: o.defaultTTL,
+		items:      make(map[string]*list.Element),
+		l:          list.New(),
+		clock:      clock.OrReal(o.clock),
+		metrics:    metrics.OrNop(o.recorder),
+	}
+	if o.cleanupInterval > 0 {
+		lru.stop = make(chan struct{})
+		lru.done = make(chan struct{})
+		go lru.cleanup(lru.clock.NewTicker(o.cleanupInterval))
+	}
+	return lru
+}
@@ -0,0 +1,144 @@
+package lru
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rselbach/agent-comparison/clock"
+	"github.com/rselbach/agent-comparison/metrics"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewWithOptions_InvalidCapacity(t *testing.T) {
+	r := require.New(t)
+	for _, capacity := range []int{0, -1} {
+		lru, err := NewWithOptions(capacity)
+		r.Nil(lru)
+		r.True(errors.Is(err, ErrInvalidCapacity))
+	}
+}
+
+func TestNew_ZeroCapacity(t *testing.T) {
+	r := require.New(t)
+	lru := NewWithCleanupInterval(0, 0)
+	lru.Put("key1", "value1", time.Minute)
+	_, ok := lru.Get("key1")
+	r.False(ok)
+	r.Equal(0, lru.Len())
+}
+
+func TestNewWithOptions_InvalidDefaultTTL(t *testing.T) {
+	r := require.New(t)
+	_, err := NewWithOptions(2, WithDefaultTTL(-time.Second))
+	r.Error(err)
+}
+
+func TestNewWithOptions_DefaultTTL(t *testing.T) {
+	r := require.New(t)
+	clk := clock.NewFake(time.Unix(0, 0))
+	lru, err := NewWithOptions(3, WithCleanupInterval(0), WithDefaultTTL(time.Millisecond*10), WithClock(clk))
+	r.NoError(err)
+
+	lru.Set("key1", "value1")
+	lru.Put("key2", "value2", time.Minute)
+
+	clk.Advance(time.Millisecond * 20)
+
+	_, ok := lru.Get("key1")
+	r.False(ok)
+	val, ok := lru.Get("key2")
+	r.True(ok)
+	r.Equal("value2", val)
+}
+
+func TestSet_NoDefaultTTL(t *testing.T) {
+	r := require.New(t)
+	clk := clock.NewFake(time.Unix(0, 0))
+	lru, err := NewWithOptions(2, WithCleanupInterval(0), WithClock(clk))
+	r.NoError(err)
+
+	lru.Set("key1", "value1")
+	lru.Put("key2", "value2", time.Millisecond*10)
+	r.Len(lru.expiries, 1)
+
+	clk.Advance(time.Millisecond * 20)
+	r.Equal(1, lru.Cleanup())
+
+	val, ok := lru.Get("key1")
+	r.True(ok)
+	r.Equal("value1", val)
+
+	// switching between expiring and non-expiring keeps the index in step
+	lru.Put("key1", "value2", time.Minute)
+	r.Len(lru.expiries, 1)
+	lru.Set("key1", "value3")
+	r.Empty(lru.expiries)
+	r.True(lru.Delete("key1"))
+	r.Equal(0, lru.Len())
+}
+
+func TestWithClock_Cleanup(t *testing.T) {
+	r := require.New(t)
+	clk := clock.NewFake(time.Unix(0, 0))
+	lru, err := NewWithOptions(2, WithCleanupInterval(time.Minute), WithClock(clk))
+	r.NoError(err)
+	clk.BlockUntil(1)
+
+	lru.Put("key1", "value1", time.Second)
+	lru.Put("key2", "value2", time.Hour)
+
+	// the background cleanup runs on the minute tick, not before
+	clk.Advance(time.Second * 30)
+	r.Equal(2, lru.Len())
+
+	clk.Advance(time.Second * 30)
+	r.Eventually(func() bool { return lru.Len() == 1 }, time.Second, time.Millisecond)
+	_, ok := lru.Get("key2")
+	r.True(ok)
+}
+
+func TestClose(t *testing.T) {
+	r := require.New(t)
+	clk := clock.NewFake(time.Unix(0, 0))
+	lru, err := NewWithOptions(2, WithCleanupInterval(time.Minute), WithClock(clk))
+	r.NoError(err)
+	r.Equal(1, clk.Pending())
+
+	lru.Close()
+	r.Zero(clk.Pending())
+	lru.Close()
+
+	// the cache stays usable, with expired entries removed on access
+	lru.Put("key1", "value1", time.Second)
+	clk.Advance(time.Minute)
+	r.Equal(1, lru.Len())
+	_, ok := lru.Get("key1")
+	r.False(ok)
+	r.Zero(lru.Len())
+
+	// a cache without background cleanup closes too
+	NewWithCleanupInterval(2, 0).Close()
+}
+
+func TestWithRecorder(t *testing.T) {
+	r := require.New(t)
+	clk := clock.NewFake(time.Unix(0, 0))
+	rec := &metrics.Counters{}
+	lru, err := NewWithOptions(2, WithCleanupInterval(0), WithClock(clk), WithRecorder(rec))
+	r.NoError(err)
+
+	lru.Put("key1", "value1", time.Second)
+	lru.Set("key2", "value2")
+	lru.Get("key1")
+	lru.Get("missing")
+	lru.Set("key3", "value3") // evicts key2
+
+	clk.Advance(time.Second * 2)
+	lru.Get("key1")
+	lru.Put("key4", "value4", time.Millisecond)
+	clk.Advance(time.Second)
+	r.Equal(1, lru.Cleanup())
+
+	r.Equal(metrics.Snapshot{Hits: 1, Misses: 2, Evictions: 1, Expirations: 2, Sweeps: 1}, rec.Snapshot())
+}
@@ -1,9 +1,14 @@
 package lru
 
 import (
+	"container/heap"
 	"errors"
 	"sync"
 	"time"
+
+	"github.com/rselbach/agent-comparison/clock"
+	"github.com/rselbach/agent-comparison/flight"
+	"github.com/rselbach/agent-comparison/metrics"
 )
 
 // ErrInvalidCapacity is returned when New is called with a non-positive capacity.
@@ -21,14 +26,36 @@ type Cache[K comparable, V any] struct {
 	stopCh          chan struct{}
 	doneCh          chan struct{}
 	now             func() time.Time
+	clock           clock.Clock
+	// metrics is counters followed by the recorder passed to WithRecorder.
+	metrics         metrics.Recorder
+	counters        metrics.Counters
+	sets            uint64
+	tags            map[string]map[K]struct{}
+	pinned          int
+	maxSweepEntries int
+	maxWeight       int64
+	weight          int64
+	// pinnedWeight is the part of weight that eviction cannot free.
+	pinnedWeight int64
+	// expiries indexes the entries that have a TTL by expiry time.
+	expiries expiryHeap[K, V]
+
+	computing flight.Group[K, V]
 }
 
 type entry[K comparable, V any] struct {
 	key       K
 	value     V
 	expiresAt time.Time
-	prev      *entry[K, V]
-	next      *entry[K, V]
+	tags      []string
+	pinned    bool
+	cost      int64
+	// expiryIndex is the entry's position in the expiry heap, or -1 if it
+	// never expires.
+	expiryIndex int
+	prev        *entry[K, V]
+	next        *entry[K, V]
 }
 
 // Option configures cache behaviour.
@@ -38,6 +65,10 @@ type options struct {
 	defaultTTL      time.Duration
 	cleanupInterval time.Duration
 	now             func() time.Time
+	clock           clock.Clock
+	metrics         metrics.Recorder
+	maxSweepEntries int
+	maxWeight       int64
 }
 
 // WithDefaultTTL sets the default TTL applied when using Set.
@@ -64,6 +95,22 @@ func WithNow(now func() time.Time) Option {
 	}
 }
 
+// WithClock sets the clock used for expiry and for the background sweeper's
+// ticker. WithNow, if also given, still overrides how expiry reads the time.
+func WithClock(clk clock.Clock) Option {
+	return func(opt *options) {
+		opt.clock = clk
+	}
+}
+
+// WithRecorder sets the recorder that receives the cache's hits, misses,
+// evictions, expirations and background sweep durations.
+func WithRecorder(r metrics.Recorder) Option {
+	return func(opt *options) {
+		opt.metrics = r
+	}
+}
+
 // New constructs an LRU cache with the provided capacity.
 func New[K comparable, V any](capacity int, opts ...Option) (*Cache[K, V], error) {
 	if capacity <= 0 {
@@ -73,11 +120,14 @@ func New[K comparable, V any](capacity int, opts ...Option) (*Cache[K, V], error
 	cfg := options{
 		defaultTTL:      0,
 		cleanupInterval: 0,
-		now:             time.Now,
 	}
 	for _, opt := range opts {
 		opt(&cfg)
 	}
+	cfg.clock = clock.OrReal(cfg.clock)
+	if cfg.now == nil {
+		cfg.now = cfg.clock.Now
+	}
 
 	cache := &Cache[K, V]{
 		capacity:        capacity,
@@ -85,7 +135,11 @@ func New[K comparable, V any](capacity int, opts ...Option) (*Cache[K, V], error
 		defaultTTL:      cfg.defaultTTL,
 		cleanupInterval: cfg.cleanupInterval,
 		now:             cfg.now,
+		clock:           cfg.clock,
+		maxSweepEntries: cfg.maxSweepEntries,
+		maxWeight:       cfg.maxWeight,
 	}
+	cache.metrics = metrics.Tee(&cache.counters, metrics.OrNop(cfg.metrics))
 
 	// Default cleanup interval if TTL is enabled but no interval configured.
 	if cache.defaultTTL > 0 && cache.cleanupInterval <= 0 {
@@ -117,35 +171,69 @@ func (c *Cache[K, V]) Close() {
 }
 
 // Set stores value under the provided key using the cache's default TTL.
-func (c *Cache[K, V]) Set(key K, value V) {
-	c.SetWithTTL(key, value, c.defaultTTL)
+func (c *Cache[K, V]) Set(key K, value V) error {
+	return c.SetWithTTL(key, value, c.defaultTTL)
 }
 
 // SetWithTTL stores value under key applying ttl. Non-positive ttl disables expiry for that entry.
-func (c *Cache[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+// It returns ErrAllPinned, storing nothing, if key is new and every slot is pinned.
+func (c *Cache[K, V]) SetWithTTL(key K, value V, ttl time.Duration) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	return c.setLocked(key, value, ttl, nil, 1)
+}
 
-	c.removeExpiredLocked()
+// setLocked stores value under key, or returns why it could not.
+func (c *Cache[K, V]) setLocked(key K, value V, ttl time.Duration, tags []string, cost int64) error {
+	// Reclaim expired entries. Only expired entries are visited and each is removed at most once,
+	// so this stays amortized O(log n).
+	c.removeExpiredLocked(0)
+	c.sets++
 
-	if existing, ok := c.entries[key]; ok {
+	if cost < 0 {
+		cost = 0
+	}
+
+	existing := c.entries[key]
+	if !c.fitsLocked(existing, cost) {
+		return ErrOverweight
+	}
+
+	if existing != nil {
+		c.untagLocked(existing)
 		existing.value = value
-		existing.expiresAt = c.computeExpiry(ttl)
+		c.setExpiryLocked(existing, c.computeExpiry(ttl))
+		c.weight += cost - existing.cost
+		if existing.pinned {
+			c.pinnedWeight += cost - existing.cost
+		}
+		existing.cost = cost
+		c.tagLocked(existing, tags)
 		c.moveToFront(existing)
-		return
+		c.fitLocked(existing)
+		return nil
 	}
 
 	if len(c.entries) >= c.capacity {
 		c.evictLRU()
+		if len(c.entries) >= c.capacity {
+			return ErrAllPinned
+		}
 	}
 
 	item := &entry[K, V]{
-		key:       key,
-		value:     value,
-		expiresAt: c.computeExpiry(ttl),
+		key:         key,
+		value:       value,
+		cost:        cost,
+		expiryIndex: -1,
 	}
+	c.setExpiryLocked(item, c.computeExpiry(ttl))
 	c.insertAtFront(item)
 	c.entries[key] = item
+	c.weight += cost
+	c.tagLocked(item, tags)
+	c.fitLocked(item)
+	return nil
 }
 
 // Get retrieves the value associated with key.
@@ -156,13 +244,14 @@ func (c *Cache[K, V]) Get(key K) (V, bool) {
 	if item, ok := c.entries[key]; ok {
 		if item.expiresAt.IsZero() || !c.now().After(item.expiresAt) {
 			c.moveToFront(item)
+			c.metrics.Hit()
 			return item.value, true
 		}
 
-		c.removeEntry(item)
-		delete(c.entries, key)
+		c.expireLocked(item)
 	}
 
+	c.metrics.Miss()
 	var zero V
 	return zero, false
 }
@@ -173,8 +262,7 @@ func (c *Cache[K, V]) Delete(key K) bool {
 	defer c.mu.Unlock()
 
 	if item, ok := c.entries[key]; ok {
-		c.removeEntry(item)
-		delete(c.entries, key)
+		c.dropLocked(item)
 		return true
 	}
 	return false
@@ -184,79 +272,81 @@ func (c *Cache[K, V]) Delete(key K) bool {
 func (c *Cache[K, V]) Len() int {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	c.removeExpiredLocked()
+	c.removeExpiredLocked(0)
 	return len(c.entries)
 }
 
+// TriggerCleanup synchronously removes every expired entry and reports how many were dropped.
+// Combined with WithNow it lets tests advance a fake clock and force a sweep without waiting on the
+// background ticker.
+func (c *Cache[K, V]) TriggerCleanup() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	start := c.now()
+	removed := c.removeExpiredLocked(0)
+	c.metrics.SweepDuration(c.now().Sub(start))
+	return removed
+}
+
 func (c *Cache[K, V]) startCleaner() {
-	c.stopCh = make(chan struct{})
-	c.doneCh = make(chan struct{})
+	// The goroutine keeps its own references: Close clears the fields under the lock.
+	stopCh := make(chan struct{})
+	doneCh := make(chan struct{})
+	c.stopCh = stopCh
+	c.doneCh = doneCh
 
-	ticker := time.NewTicker(c.cleanupInterval)
+	ticker := c.clock.NewTicker(c.cleanupInterval)
 	go func() {
-		defer close(c.doneCh)
+		defer close(doneCh)
 		defer ticker.Stop()
 		for {
 			select {
-			case <-ticker.C:
-				c.cleanupExpired()
-			case <-c.stopCh:
+			case <-ticker.C():
+				c.sweep()
+			case <-stopCh:
 				return
 			}
 		}
 	}()
 }
 
-func (c *Cache[K, V]) cleanupExpired() {
-	c.mu.Lock()
-	c.removeExpiredLocked()
-	c.mu.Unlock()
-}
-
-func (c *Cache[K, V]) removeExpiredLocked() {
-	if len(c.entries) == 0 {
-		return
-	}
-
-	now := c.now()
-	for key, item := range c.entries {
-		if !item.expiresAt.IsZero() && now.After(item.expiresAt) {
-			c.removeEntry(item)
-			delete(c.entries, key)
-		}
-	}
-}
-
 func (c *Cache[K, V]) evictLRU() {
 	// Attempt to drop expired items first.
-	if c.removeTailExpired() {
+	if c.removeExpiredLocked(0) > 0 {
 		return
 	}
 
 	if c.tail == nil {
+		// Only pinned entries remain.
 		return
 	}
 
 	evicted := c.tail
-	c.removeEntry(evicted)
-	delete(c.entries, evicted.key)
+	c.dropLocked(evicted)
+	c.metrics.Eviction()
 }
 
-func (c *Cache[K, V]) removeTailExpired() bool {
-	now := c.now()
-	cursor := c.tail
-	evicted := false
-	for cursor != nil {
-		if cursor.expiresAt.IsZero() || !now.After(cursor.expiresAt) {
-			break
-		}
-		prev := cursor.prev
-		c.removeEntry(cursor)
-		delete(c.entries, cursor.key)
-		cursor = prev
-		evicted = true
+// expireLocked drops item because its TTL has passed.
+func (c *Cache[K, V]) expireLocked(item *entry[K, V]) {
+	c.dropLocked(item)
+	c.metrics.Expiration()
+}
+
+// dropLocked unlinks item and removes it from the index and any tag sets.
+func (c *Cache[K, V]) dropLocked(item *entry[K, V]) {
+	if item.pinned {
+		item.pinned = false
+		c.pinned--
+		c.pinnedWeight -= item.cost
+	} else {
+		c.removeEntry(item)
+	}
+	if item.expiryIndex >= 0 {
+		heap.Remove(&c.expiries, item.expiryIndex)
 	}
-	return evicted
+	delete(c.entries, item.key)
+	c.weight -= item.cost
+	c.untagLocked(item)
 }
 
 func (c *Cache[K, V]) computeExpiry(ttl time.Duration) time.Time {
@@ -278,7 +368,7 @@ func (c *Cache[K, V]) insertAtFront(item *entry[K, V]) {
 }
 
 func (c *Cache[K, V]) moveToFront(item *entry[K, V]) {
-	if c.head == item {
+	if item.pinned || c.head == item {
 		return
 	}
 	c.removeEntry(item)
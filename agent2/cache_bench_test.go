@@ -0,0 +1,195 @@
+package lru
+
+import (
+	"math/rand/v2"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func BenchmarkSetWithTTL(b *testing.B) {
+	for _, size := range []int{1_000, 100_000, 1_000_000} {
+		b.Run(strconv.Itoa(size), func(b *testing.B) {
+			cache, err := New[int, int](size, WithDefaultTTL(time.Hour), WithCleanupInterval(time.Hour))
+			if err != nil {
+				b.Fatalf("unexpected error: %v", err)
+			}
+			b.Cleanup(cache.Close)
+
+			for i := 0; i < size; i++ {
+				cache.Set(i, i)
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				cache.Set(i%(2*size), i)
+			}
+		})
+
+		// The clock moves one tick per Set and entries live for one tick less
+		// than it takes to fill the cache, so once it is full every Set
+		// reclaims the one entry that has just expired. The cost per Set should not grow with size.
+		b.Run(strconv.Itoa(size)+"/expiring", func(b *testing.B) {
+			now := time.Unix(0, 0)
+			cache, err := New[int, int](size, WithDefaultTTL(time.Duration(size-1)), WithCleanupInterval(time.Hour),
+				WithNow(func() time.Time { return now }))
+			if err != nil {
+				b.Fatalf("unexpected error: %v", err)
+			}
+			b.Cleanup(cache.Close)
+
+			for i := 0; i < size; i++ {
+				now = now.Add(1)
+				cache.Set(i, i)
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				now = now.Add(1)
+				cache.Set(size+i, i)
+			}
+			b.StopTimer()
+			if s := cache.Stats(); s.Evictions != 0 {
+				b.Fatalf("expected expired entries to be reclaimed before evicting, got %d evictions", s.Evictions)
+			}
+		})
+	}
+}
+
+var benchSizes = []int{1_000, 100_000, 1_000_000}
+
+// newFullCache returns a cache of capacity size filled with keys 0..size-1.
+// Without options the entries never expire and no background sweeper runs.
+func newFullCache(b *testing.B, size int, opts ...Option) *Cache[int, int] {
+	b.Helper()
+	cache, err := New[int, int](size, opts...)
+	if err != nil {
+		b.Fatalf("unexpected error: %v", err)
+	}
+	b.Cleanup(cache.Close)
+
+	for i := 0; i < size; i++ {
+		cache.Set(i, i)
+	}
+	return cache
+}
+
+func BenchmarkGetHit(b *testing.B) {
+	for _, size := range benchSizes {
+		b.Run(strconv.Itoa(size), func(b *testing.B) {
+			cache := newFullCache(b, size)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, ok := cache.Get(i % size); !ok {
+					b.Fatal("unexpected miss")
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkGetMiss(b *testing.B) {
+	for _, size := range benchSizes {
+		b.Run(strconv.Itoa(size), func(b *testing.B) {
+			cache := newFullCache(b, size)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, ok := cache.Get(size + i); ok {
+					b.Fatal("unexpected hit")
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkSetEvict(b *testing.B) {
+	for _, size := range benchSizes {
+		b.Run(strconv.Itoa(size), func(b *testing.B) {
+			cache := newFullCache(b, size)
+
+			// every key is new, so each Set evicts the least recently used entry
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				cache.Set(size+i, i)
+			}
+		})
+	}
+}
+
+func BenchmarkMixedParallel(b *testing.B) {
+	for _, size := range benchSizes {
+		b.Run(strconv.Itoa(size), func(b *testing.B) {
+			cache := newFullCache(b, size)
+
+			// keys span twice the capacity so reads miss about half the time and
+			// writes keep evicting; one operation in ten is a write
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				r := rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))
+				for pb.Next() {
+					key := r.IntN(2 * size)
+					if r.IntN(10) == 0 {
+						cache.Set(key, key)
+					} else {
+						cache.Get(key)
+					}
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkSweep(b *testing.B) {
+	for _, size := range benchSizes {
+		b.Run(strconv.Itoa(size), func(b *testing.B) {
+			// nothing has expired, so this is the cost of checking the soonest expiry
+			cache := newFullCache(b, size, WithDefaultTTL(time.Hour), WithCleanupInterval(time.Hour))
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				cache.sweep()
+			}
+		})
+
+		b.Run(strconv.Itoa(size)+"/bounded", func(b *testing.B) {
+			cache := newFullCache(b, size, WithDefaultTTL(time.Hour), WithCleanupInterval(time.Hour), WithMaxSweepEntries(1024))
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				cache.sweep()
+			}
+		})
+
+		b.Run(strconv.Itoa(size)+"/expired", func(b *testing.B) {
+			now := time.Unix(0, 0)
+			cache := newFullCache(b, size, WithDefaultTTL(time.Minute), WithCleanupInterval(time.Hour), WithNow(func() time.Time { return now }))
+			now = now.Add(time.Hour)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				now = now.Add(-time.Hour)
+				for k := 0; k < size; k++ {
+					cache.Set(k, k)
+				}
+				now = now.Add(time.Hour)
+				b.StartTimer()
+
+				if n := cache.TriggerCleanup(); n != size {
+					b.Fatalf("removed %d entries, want %d", n, size)
+				}
+			}
+		})
+	}
+}
@@ -3,6 +3,9 @@ package lru
 import (
 	"testing"
 	"time"
+
+	"github.com/rselbach/agent-comparison/clock"
+	"github.com/rselbach/agent-comparison/metrics"
 )
 
 func TestCacheSetGet(t *testing.T) {
@@ -51,7 +54,8 @@ func TestLRUEviction(t *testing.T) {
 }
 
 func TestExpiration(t *testing.T) {
-	cache, err := New[string, int](2, WithDefaultTTL(40*time.Millisecond), WithCleanupInterval(20*time.Millisecond))
+	clk := clock.NewFake(time.Unix(0, 0))
+	cache, err := New[string, int](2, WithDefaultTTL(40*time.Millisecond), WithCleanupInterval(20*time.Millisecond), WithClock(clk))
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -59,28 +63,91 @@ func TestExpiration(t *testing.T) {
 
 	cache.Set("x", 10)
 
-	time.Sleep(60 * time.Millisecond)
+	clk.Advance(60 * time.Millisecond)
 
 	if _, ok := cache.Get("x"); ok {
 		t.Fatalf("expected x to be expired")
 	}
 }
 
+func TestSetReclaimsExpiredBeforeEvicting(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	cache, err := New[string, int](3, WithCleanupInterval(time.Hour), WithClock(clk))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(cache.Close)
+
+	// b expires while sitting between a, the least recently used, and c
+	cache.Set("a", 1)
+	cache.SetWithTTL("b", 2, time.Second)
+	cache.Set("c", 3)
+	clk.Advance(2 * time.Second)
+
+	cache.Set("d", 4)
+	if _, ok := cache.Get("a"); !ok {
+		t.Fatalf("expected a to survive: the expired b should make room")
+	}
+	if s := cache.Stats(); s.Evictions != 0 || s.Expirations != 1 {
+		t.Fatalf("expected 0 evictions and 1 expiration, got %+v", s)
+	}
+}
+
+func TestWithRecorder(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	rec := &metrics.Counters{}
+	cache, err := New[string, int](2, WithClock(clk), WithRecorder(rec))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(cache.Close)
+
+	cache.SetWithTTL("a", 1, time.Second)
+	cache.Set("b", 2)
+	cache.Get("a")
+	cache.Get("missing")
+	cache.Set("c", 3) // evicts b
+
+	clk.Advance(2 * time.Second)
+	cache.Get("a")
+	cache.SetWithTTL("d", 4, time.Millisecond)
+	clk.Advance(time.Second)
+	cache.TriggerCleanup()
+
+	want := metrics.Snapshot{Hits: 1, Misses: 2, Evictions: 1, Expirations: 2, Sweeps: 1}
+	if got := rec.Snapshot(); got != want {
+		t.Fatalf("recorded %+v, want %+v", got, want)
+	}
+}
+
 func TestAutomaticCleanupRemovesExpiredEntries(t *testing.T) {
-	cache, err := New[string, int](2, WithCleanupInterval(15*time.Millisecond))
+	clk := clock.NewFake(time.Unix(0, 0))
+	cache, err := New[string, int](2, WithCleanupInterval(15*time.Millisecond), WithClock(clk))
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 	t.Cleanup(cache.Close)
 
 	cache.SetWithTTL("temp", 42, 20*time.Millisecond)
-	time.Sleep(70 * time.Millisecond)
+	clk.Advance(30 * time.Millisecond)
 
-	if length := cache.Len(); length != 0 {
-		t.Fatalf("expected cache to be empty after cleanup, got len=%d", length)
+	// Len would purge on its own, so watch the index until the sweeper empties it.
+	deadline := time.Now().Add(time.Second)
+	for stored(cache) != 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the sweeper to remove the expired entry")
+		}
+		time.Sleep(time.Millisecond)
 	}
 }
 
+// stored returns how many entries the cache holds, expired or not.
+func stored[K comparable, V any](c *Cache[K, V]) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
 func TestDelete(t *testing.T) {
 	cache, err := New[string, int](1)
 	if err != nil {
@@ -105,3 +172,31 @@ func TestNewInvalidCapacity(t *testing.T) {
 		t.Fatalf("expected error for negative capacity")
 	}
 }
+
+func TestTriggerCleanupWithFakeClock(t *testing.T) {
+	now := time.Unix(0, 0)
+	cache, err := New[string, int](4, WithNow(func() time.Time { return now }))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(cache.Close)
+
+	cache.SetWithTTL("short", 1, time.Second)
+	cache.SetWithTTL("long", 2, time.Minute)
+	cache.Set("forever", 3)
+
+	if removed := cache.TriggerCleanup(); removed != 0 {
+		t.Fatalf("expected nothing removed before expiry, got %d", removed)
+	}
+
+	now = now.Add(2 * time.Second)
+	if removed := cache.TriggerCleanup(); removed != 1 {
+		t.Fatalf("expected one entry removed, got %d", removed)
+	}
+	if _, ok := cache.Get("short"); ok {
+		t.Fatalf("expected short to be swept")
+	}
+	if v, ok := cache.Get("long"); !ok || v != 2 {
+		t.Fatalf("expected long=2, got %v, %t", v, ok)
+	}
+}
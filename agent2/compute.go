@@ -0,0 +1,46 @@
+package lru
+
+import "github.com/rselbach/agent-comparison/flight"
+
+// ErrComputePanicked is returned to callers waiting on a GetOrCompute loader
+// that panicked. The caller that ran the loader sees the panic itself.
+var ErrComputePanicked = flight.ErrPanicked
+
+// GetOrCompute returns the value cached under key, calling load on a miss and
+// caching its result with the default TTL. Concurrent misses for the same key
+// wait for a single load call and share its result. Errors from load are
+// returned to every waiting caller and are not cached, so the next call loads
+// again. If the loaded value cannot be stored, for example with ErrAllPinned,
+// every caller receives it together with the cache's error.
+func (c *Cache[K, V]) GetOrCompute(key K, load func() (V, error)) (V, error) {
+	if value, ok := c.Get(key); ok {
+		return value, nil
+	}
+
+	return c.computing.Do(key, func() (V, error) {
+		// Another caller may have finished loading between the miss and starting this call.
+		if value, ok := c.cached(key); ok {
+			return value, nil
+		}
+
+		value, err := load()
+		if err != nil {
+			var zero V
+			return zero, err
+		}
+		return value, c.Set(key, value)
+	})
+}
+
+// cached returns the value of key if it is present and unexpired, without
+// counting a hit or miss or changing its recency.
+func (c *Cache[K, V]) cached(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if item, ok := c.entries[key]; ok && (item.expiresAt.IsZero() || !c.now().After(item.expiresAt)) {
+		return item.value, true
+	}
+	var zero V
+	return zero, false
+}
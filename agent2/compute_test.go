@@ -0,0 +1,157 @@
+package lru
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGetOrComputeCachesWithDefaultTTL(t *testing.T) {
+	now := time.Unix(0, 0)
+	cache, err := New[string, int](4, WithDefaultTTL(time.Minute), WithCleanupInterval(time.Hour),
+		WithNow(func() time.Time { return now }))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(cache.Close)
+
+	var calls int
+	load := func() (int, error) {
+		calls++
+		return calls, nil
+	}
+
+	for i := 0; i < 3; i++ {
+		if v, err := cache.GetOrCompute("a", load); err != nil || v != 1 {
+			t.Fatalf("expected 1, got %d, %v", v, err)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("expected 1 load, got %d", calls)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if v, err := cache.GetOrCompute("a", load); err != nil || v != 2 {
+		t.Fatalf("expected expired value to be reloaded as 2, got %d, %v", v, err)
+	}
+}
+
+func TestGetOrComputeErrorNotCached(t *testing.T) {
+	cache, err := New[string, int](4)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	boom := errors.New("boom")
+	if _, err := cache.GetOrCompute("a", func() (int, error) { return 7, boom }); !errors.Is(err, boom) {
+		t.Fatalf("expected boom, got %v", err)
+	}
+	if _, ok := cache.Get("a"); ok {
+		t.Fatalf("expected failed load not to be cached")
+	}
+	if v, err := cache.GetOrCompute("a", func() (int, error) { return 1, nil }); err != nil || v != 1 {
+		t.Fatalf("expected 1 after failed load, got %d, %v", v, err)
+	}
+}
+
+func TestGetOrComputeAllPinned(t *testing.T) {
+	cache, err := New[string, int](1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(cache.Close)
+	cache.Set("pinned", 0)
+	if err := cache.Pin("pinned"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	v, err := cache.GetOrCompute("a", func() (int, error) { return 7, nil })
+	if !errors.Is(err, ErrAllPinned) || v != 7 {
+		t.Fatalf("expected 7 with ErrAllPinned, got %d, %v", v, err)
+	}
+	if _, ok := cache.Get("a"); ok {
+		t.Fatal("expected nothing stored while every slot is pinned")
+	}
+}
+
+func TestGetOrComputeSingleflight(t *testing.T) {
+	cache, err := New[string, int](4)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+	load := func() (int, error) {
+		calls.Add(1)
+		<-release
+		return 42, nil
+	}
+
+	const callers = 10
+	var started, done sync.WaitGroup
+	started.Add(callers)
+	done.Add(callers)
+	for i := 0; i < callers; i++ {
+		go func() {
+			defer done.Done()
+			started.Done()
+			if v, err := cache.GetOrCompute("slow", load); err != nil || v != 42 {
+				t.Errorf("expected 42, got %d, %v", v, err)
+			}
+		}()
+	}
+	started.Wait()
+	waitForCompute(cache, "slow", callers-1)
+	close(release)
+	done.Wait()
+
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("expected a single load, got %d", n)
+	}
+}
+
+func TestGetOrComputePanic(t *testing.T) {
+	cache, err := New[string, int](4)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	go func() {
+		defer func() { recover() }()
+		cache.GetOrCompute("a", func() (int, error) {
+			close(entered)
+			<-release
+			panic("boom")
+		})
+	}()
+	<-entered
+
+	waited := make(chan error)
+	go func() {
+		_, err := cache.GetOrCompute("a", func() (int, error) { return 1, nil })
+		waited <- err
+	}()
+	waitForCompute(cache, "a", 1)
+	close(release)
+
+	if err := <-waited; !errors.Is(err, ErrComputePanicked) {
+		t.Fatalf("expected ErrComputePanicked, got %v", err)
+	}
+	if v, err := cache.GetOrCompute("a", func() (int, error) { return 1, nil }); err != nil || v != 1 {
+		t.Fatalf("expected a later call to load again, got %d, %v", v, err)
+	}
+}
+
+// waitForCompute blocks until n callers are waiting on the GetOrCompute call
+// for key.
+func waitForCompute[K comparable, V any](cache *Cache[K, V], key K, n int) {
+	for cache.computing.Waiting(key) < n {
+		runtime.Gosched()
+	}
+}
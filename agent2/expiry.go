@@ -0,0 +1,68 @@
+package lru
+
+import (
+	"container/heap"
+	"time"
+)
+
+// expiryHeap orders the entries that have a TTL by expiry time, soonest
+// first, so expired entries can be found without scanning the others.
+type expiryHeap[K comparable, V any] []*entry[K, V]
+
+func (h expiryHeap[K, V]) Len() int           { return len(h) }
+func (h expiryHeap[K, V]) Less(i, j int) bool { return h[i].expiresAt.Before(h[j].expiresAt) }
+func (h expiryHeap[K, V]) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].expiryIndex = i
+	h[j].expiryIndex = j
+}
+
+func (h *expiryHeap[K, V]) Push(x any) {
+	item := x.(*entry[K, V])
+	item.expiryIndex = len(*h)
+	*h = append(*h, item)
+}
+
+func (h *expiryHeap[K, V]) Pop() any {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	item.expiryIndex = -1
+	old[n-1] = nil
+	*h = old[:n-1]
+	return item
+}
+
+// setExpiryLocked sets item's expiry time, zero meaning never, and keeps the
+// expiry heap in step.
+func (c *Cache[K, V]) setExpiryLocked(item *entry[K, V], expiresAt time.Time) {
+	item.expiresAt = expiresAt
+	switch {
+	case item.expiryIndex >= 0 && expiresAt.IsZero():
+		heap.Remove(&c.expiries, item.expiryIndex)
+	case item.expiryIndex >= 0:
+		heap.Fix(&c.expiries, item.expiryIndex)
+	case !expiresAt.IsZero():
+		heap.Push(&c.expiries, item)
+	}
+}
+
+// removeExpiredLocked drops up to limit expired entries, soonest expiry first,
+// and returns how many it dropped. A non-positive limit drops them all. Only
+// expired entries are visited.
+func (c *Cache[K, V]) removeExpiredLocked(limit int) int {
+	if len(c.expiries) == 0 {
+		return 0
+	}
+
+	now := c.now()
+	removed := 0
+	for len(c.expiries) > 0 && now.After(c.expiries[0].expiresAt) {
+		if limit > 0 && removed == limit {
+			break
+		}
+		c.expireLocked(c.expiries[0])
+		removed++
+	}
+	return removed
+}
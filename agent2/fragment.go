@@ -0,0 +1,74 @@
+package lru
+
+import (
+	"encoding/hex"
+	"fmt"
+	"hash/fnv"
+	"time"
+
+	"github.com/rselbach/agent-comparison/flight"
+)
+
+// Fragment caches rendered fragments such as HTML snippets on top of a Cache.
+// Concurrent renders of the same fragment are collapsed so only one caller runs
+// the render function while the others wait for its result.
+type Fragment struct {
+	cache   *Cache[string, []byte]
+	renders flight.Group[string, []byte]
+}
+
+// NewFragment returns a Fragment that stores rendered output in cache.
+func NewFragment(cache *Cache[string, []byte]) *Fragment {
+	return &Fragment{cache: cache}
+}
+
+// Render returns the cached fragment identified by name and params, invoking render on a miss
+// and caching its output for ttl. A non-positive ttl uses the cache's default TTL.
+// Errors from render are returned to every waiting caller and are not cached.
+// If the output cannot be stored, for example with ErrAllPinned, Render returns it
+// together with the cache's error. The returned slice is shared between callers and
+// must not be modified.
+func (f *Fragment) Render(name string, params []any, ttl time.Duration, render func() ([]byte, error)) ([]byte, error) {
+	key := FragmentKey(name, params...)
+	if body, ok := f.cache.Get(key); ok {
+		return body, nil
+	}
+
+	return f.renders.Do(key, func() ([]byte, error) {
+		// Another caller may have finished rendering between the miss and starting this call.
+		if body, ok := f.cache.cached(key); ok {
+			return body, nil
+		}
+
+		body, err := render()
+		if err != nil {
+			return nil, err
+		}
+
+		if ttl <= 0 {
+			err = f.cache.Set(key, body)
+		} else {
+			err = f.cache.SetWithTTL(key, body, ttl)
+		}
+		return body, err
+	})
+}
+
+// Invalidate removes the cached fragment identified by name and params.
+func (f *Fragment) Invalidate(name string, params ...any) bool {
+	return f.cache.Delete(FragmentKey(name, params...))
+}
+
+// FragmentKey returns the cache key for a fragment: its name followed by a hash of params.
+// Params are formatted with their types, so 1 and "1" produce different keys; maps are
+// formatted with sorted keys and therefore hash deterministically.
+func FragmentKey(name string, params ...any) string {
+	if len(params) == 0 {
+		return name
+	}
+	h := fnv.New64a()
+	for _, p := range params {
+		fmt.Fprintf(h, "%T=%v\x00", p, p)
+	}
+	return name + "#" + hex.EncodeToString(h.Sum(nil))
+}
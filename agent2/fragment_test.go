@@ -0,0 +1,151 @@
+package lru
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rselbach/agent-comparison/metrics"
+)
+
+func TestFragmentRenderCaches(t *testing.T) {
+	cache, err := New[string, []byte](8)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(cache.Close)
+	frag := NewFragment(cache)
+
+	var calls int
+	render := func() ([]byte, error) {
+		calls++
+		return []byte("<nav>"), nil
+	}
+
+	params := []any{map[string]string{"user": "1", "lang": "en"}}
+	for i := 0; i < 3; i++ {
+		body, err := frag.Render("nav", params, time.Minute, render)
+		if err != nil || string(body) != "<nav>" {
+			t.Fatalf("expected <nav>, got %q, %v", body, err)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("expected 1 render, got %d", calls)
+	}
+
+	if _, err := frag.Render("nav", []any{map[string]string{"user": "2", "lang": "en"}}, time.Minute, render); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls != 2 {
+		t.Fatalf("expected different params to render again, got %d renders", calls)
+	}
+
+	if !frag.Invalidate("nav", params...) {
+		t.Fatalf("expected invalidate to remove fragment")
+	}
+	if _, err := frag.Render("nav", params, time.Minute, render); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls != 3 {
+		t.Fatalf("expected render after invalidate, got %d renders", calls)
+	}
+}
+
+func TestFragmentRenderErrorNotCached(t *testing.T) {
+	cache, err := New[string, []byte](8)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(cache.Close)
+	frag := NewFragment(cache)
+
+	boom := errors.New("boom")
+	if _, err := frag.Render("x", nil, 0, func() ([]byte, error) { return nil, boom }); !errors.Is(err, boom) {
+		t.Fatalf("expected boom, got %v", err)
+	}
+	body, err := frag.Render("x", nil, 0, func() ([]byte, error) { return []byte("ok"), nil })
+	if err != nil || string(body) != "ok" {
+		t.Fatalf("expected ok after failed render, got %q, %v", body, err)
+	}
+}
+
+func TestFragmentRenderStoreError(t *testing.T) {
+	cache, err := New[string, []byte](1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(cache.Close)
+	cache.Set("pinned", nil)
+	if err := cache.Pin("pinned"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	frag := NewFragment(cache)
+
+	body, err := frag.Render("x", nil, 0, func() ([]byte, error) { return []byte("ok"), nil })
+	if !errors.Is(err, ErrAllPinned) || string(body) != "ok" {
+		t.Fatalf("expected ok with ErrAllPinned, got %q, %v", body, err)
+	}
+}
+
+func TestFragmentRenderRecorder(t *testing.T) {
+	rec := &metrics.Counters{}
+	cache, err := New[string, []byte](8, WithRecorder(rec))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(cache.Close)
+	frag := NewFragment(cache)
+
+	render := func() ([]byte, error) { return []byte("ok"), nil }
+	frag.Render("x", nil, 0, render)
+	frag.Render("x", nil, 0, render)
+
+	if got := rec.Snapshot(); got.Hits != 1 || got.Misses != 1 {
+		t.Fatalf("expected 1 hit and 1 miss, got %+v", got)
+	}
+}
+
+func TestFragmentRenderSingleflight(t *testing.T) {
+	cache, err := New[string, []byte](8)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(cache.Close)
+	frag := NewFragment(cache)
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+	render := func() ([]byte, error) {
+		calls.Add(1)
+		<-release
+		return []byte("slow"), nil
+	}
+
+	const callers = 10
+	var started, done sync.WaitGroup
+	started.Add(callers)
+	done.Add(callers)
+	for i := 0; i < callers; i++ {
+		go func() {
+			defer done.Done()
+			started.Done()
+			body, err := frag.Render("slow", nil, time.Minute, render)
+			if err != nil || string(body) != "slow" {
+				t.Errorf("expected slow, got %q, %v", body, err)
+			}
+		}()
+	}
+	started.Wait()
+	for frag.renders.Waiting(FragmentKey("slow")) < callers-1 {
+		runtime.Gosched()
+	}
+	close(release)
+	done.Wait()
+
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("expected a single render, got %d", n)
+	}
+}
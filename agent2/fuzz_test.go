@@ -0,0 +1,179 @@
+package lru
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// checkInvariants verifies that the recency list, the entry map, the pin
+// count and weight, the total weight, the expiry heap and the tag index all agree.
+func checkInvariants[K comparable, V any](c *Cache[K, V]) error {
+	listed := make(map[*entry[K, V]]bool)
+	var prev *entry[K, V]
+	for item := c.head; item != nil; item = item.next {
+		if listed[item] {
+			return fmt.Errorf("recency list has a cycle at %v", item.key)
+		}
+		listed[item] = true
+		if item.prev != prev {
+			return fmt.Errorf("%v has a stale prev link", item.key)
+		}
+		if item.pinned {
+			return fmt.Errorf("pinned %v is on the recency list", item.key)
+		}
+		if c.entries[item.key] != item {
+			return fmt.Errorf("listed %v is not the entry mapped under its key", item.key)
+		}
+		prev = item
+	}
+	if c.tail != prev {
+		return fmt.Errorf("tail does not end the recency list")
+	}
+	for i, item := range c.expiries {
+		if item.expiryIndex != i {
+			return fmt.Errorf("%v is at heap position %d but records %d", item.key, i, item.expiryIndex)
+		}
+		if c.entries[item.key] != item {
+			return fmt.Errorf("heap holds %v, which is not the entry mapped under its key", item.key)
+		}
+		if item.expiresAt.IsZero() {
+			return fmt.Errorf("%v never expires but is on the expiry heap", item.key)
+		}
+		if i > 0 && item.expiresAt.Before(c.expiries[(i-1)/2].expiresAt) {
+			return fmt.Errorf("%v expires before its heap parent", item.key)
+		}
+	}
+
+	pinned := 0
+	var weight, pinnedWeight int64
+	for key, item := range c.entries {
+		weight += item.cost
+		if item.expiresAt.IsZero() != (item.expiryIndex < 0) {
+			return fmt.Errorf("%v has expiry %v but heap position %d", key, item.expiresAt, item.expiryIndex)
+		}
+		if item.key != key {
+			return fmt.Errorf("entry for %v is mapped under %v", item.key, key)
+		}
+		if item.pinned {
+			pinned++
+			pinnedWeight += item.cost
+		} else if !listed[item] {
+			return fmt.Errorf("unpinned %v is missing from the recency list", key)
+		}
+		for _, tag := range item.tags {
+			if _, ok := c.tags[tag][key]; !ok {
+				return fmt.Errorf("%v carries tag %q but the index does not list it", key, tag)
+			}
+		}
+	}
+	if pinned != c.pinned {
+		return fmt.Errorf("pin count is %d, %d entries are pinned", c.pinned, pinned)
+	}
+	if pinnedWeight != c.pinnedWeight {
+		return fmt.Errorf("pinned weight is %d, pinned entries cost %d", c.pinnedWeight, pinnedWeight)
+	}
+	if n := len(c.entries); n > c.capacity {
+		return fmt.Errorf("%d entries exceed capacity %d", n, c.capacity)
+	}
+	if weight != c.weight {
+		return fmt.Errorf("weight is %d, entries cost %d", c.weight, weight)
+	}
+	if c.maxWeight > 0 && c.weight > c.maxWeight {
+		return fmt.Errorf("weight %d exceeds maximum %d", c.weight, c.maxWeight)
+	}
+
+	for tag, keys := range c.tags {
+		if len(keys) == 0 {
+			return fmt.Errorf("tag %q has an empty key set", tag)
+		}
+		for key := range keys {
+			item, ok := c.entries[key]
+			if !ok {
+				return fmt.Errorf("tag %q lists absent key %v", tag, key)
+			}
+			found := false
+			for _, t := range item.tags {
+				found = found || t == tag
+			}
+			if !found {
+				return fmt.Errorf("tag %q lists %v, which does not carry it", tag, key)
+			}
+		}
+	}
+	return nil
+}
+
+// FuzzOps applies byte-encoded operations, two bytes each, and checks the
+// cache's internal invariants after every one.
+func FuzzOps(f *testing.F) {
+	f.Add([]byte{0, 1, 0, 2, 5, 1, 0, 3, 6, 1, 0, 4})
+	f.Add([]byte{1, 1, 2, 2, 9, 30, 8, 3, 4, 2, 3, 1})
+	f.Add([]byte{2, 0x11, 2, 0x21, 7, 1, 5, 0x21, 0, 5, 8, 2})
+
+	tags := []string{"red", "green", "blue"}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		now := time.Unix(0, 0)
+		cache, err := New[int, int](4, WithNow(func() time.Time { return now }), WithMaxWeight(6))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		for i := 0; i+1 < len(data); i += 2 {
+			arg := int(data[i+1])
+			key := arg % 8
+			ttl := time.Duration(arg%4) * time.Second
+			var op string
+			switch data[i] % 10 {
+			case 0:
+				cost := int64(arg >> 3 % 4)
+				op = fmt.Sprintf("SetWithCost(%d, %d)", key, cost)
+				cache.SetWithCost(key, i, 0, cost)
+			case 1:
+				op = fmt.Sprintf("SetWithTTL(%d, %v)", key, ttl)
+				cache.SetWithTTL(key, i, ttl)
+			case 2:
+				tag := tags[arg>>4%len(tags)]
+				op = fmt.Sprintf("SetWithTags(%d, %v, %s)", key, ttl, tag)
+				cache.SetWithTags(key, i, ttl, tag)
+			case 3:
+				op = fmt.Sprintf("Get(%d)", key)
+				cache.Get(key)
+			case 4:
+				op = fmt.Sprintf("Delete(%d)", key)
+				cache.Delete(key)
+			case 5:
+				op = fmt.Sprintf("Pin(%d)", key)
+				cache.Pin(key)
+			case 6:
+				op = fmt.Sprintf("Unpin(%d)", key)
+				cache.Unpin(key)
+			case 7:
+				tag := tags[arg%len(tags)]
+				op = fmt.Sprintf("InvalidateTag(%s)", tag)
+				cache.InvalidateTag(tag)
+			case 8:
+				n := 1 + arg%3
+				op = fmt.Sprintf("sweep(%d)", n)
+				cache.mu.Lock()
+				cache.removeExpiredLocked(n)
+				cache.mu.Unlock()
+			case 9:
+				d := time.Duration(arg%3) * time.Second
+				op = fmt.Sprintf("advance(%v)", d)
+				now = now.Add(d)
+			}
+
+			if err := checkInvariants(cache); err != nil {
+				t.Fatalf("after op %d %s: %v", i/2, op, err)
+			}
+		}
+
+		if n := cache.Len(); n > 4 {
+			t.Fatalf("Len() = %d exceeds capacity", n)
+		}
+		if err := checkInvariants(cache); err != nil {
+			t.Fatalf("after Len: %v", err)
+		}
+	})
+}
@@ -1,3 +1,15 @@
 module lru
 
-go 1.21
+go 1.23
+
+require (
+	github.com/rselbach/agent-comparison/clock v0.0.0
+	github.com/rselbach/agent-comparison/flight v0.0.0
+	github.com/rselbach/agent-comparison/metrics v0.0.0
+)
+
+replace github.com/rselbach/agent-comparison/clock => ../clock
+
+replace github.com/rselbach/agent-comparison/metrics => ../metrics
+
+replace github.com/rselbach/agent-comparison/flight => ../flight
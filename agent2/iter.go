@@ -0,0 +1,81 @@
+package lru
+
+import "iter"
+
+// All returns an iterator over the unexpired entries, most recently used
+// first. It ranges over a snapshot taken when iteration starts, so the cache
+// may be used, even modified, from within the loop. Pinned entries do not
+// take part in recency tracking and follow the others in no particular order.
+func (c *Cache[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for _, item := range c.snapshot() {
+			if !yield(item.key, item.value) {
+				return
+			}
+		}
+	}
+}
+
+// Values returns an iterator over the values of the unexpired entries in the
+// same order and with the same snapshot semantics as All.
+func (c *Cache[K, V]) Values() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		for _, item := range c.snapshot() {
+			if !yield(item.value) {
+				return
+			}
+		}
+	}
+}
+
+// Keys returns the keys of the unexpired entries in the same order as All.
+func (c *Cache[K, V]) Keys() []K {
+	items := c.snapshot()
+	keys := make([]K, len(items))
+	for i, item := range items {
+		keys[i] = item.key
+	}
+	return keys
+}
+
+// Items returns a copy of the unexpired entries. Changes to the map do not
+// affect the cache.
+func (c *Cache[K, V]) Items() map[K]V {
+	items := c.snapshot()
+	m := make(map[K]V, len(items))
+	for _, item := range items {
+		m[item.key] = item.value
+	}
+	return m
+}
+
+type snapshotItem[K comparable, V any] struct {
+	key   K
+	value V
+}
+
+// snapshot copies the unexpired entries in recency order, then pinned ones.
+func (c *Cache[K, V]) snapshot() []snapshotItem[K, V] {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	live := func(item *entry[K, V]) bool {
+		return item.expiresAt.IsZero() || !now.After(item.expiresAt)
+	}
+
+	items := make([]snapshotItem[K, V], 0, len(c.entries))
+	for item := c.head; item != nil; item = item.next {
+		if live(item) {
+			items = append(items, snapshotItem[K, V]{key: item.key, value: item.value})
+		}
+	}
+	if c.pinned > 0 {
+		for _, item := range c.entries {
+			if item.pinned && live(item) {
+				items = append(items, snapshotItem[K, V]{key: item.key, value: item.value})
+			}
+		}
+	}
+	return items
+}
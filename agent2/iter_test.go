@@ -0,0 +1,80 @@
+package lru
+
+import (
+	"maps"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestAllRecencyOrder(t *testing.T) {
+	now := time.Unix(0, 0)
+	cache, err := New[string, int](8, WithNow(func() time.Time { return now }))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(cache.Close)
+
+	cache.Set("a", 1)
+	cache.Set("b", 2)
+	cache.SetWithTTL("short", 9, time.Second)
+	cache.Set("c", 3)
+	cache.Get("a")
+	now = now.Add(2 * time.Second)
+
+	var keys []string
+	var values []int
+	for k, v := range cache.All() {
+		keys = append(keys, k)
+		values = append(values, v)
+	}
+	if want := []string{"a", "c", "b"}; !slices.Equal(keys, want) {
+		t.Fatalf("expected keys %v, got %v", want, keys)
+	}
+	if want := []int{1, 3, 2}; !slices.Equal(values, want) {
+		t.Fatalf("expected values %v, got %v", want, values)
+	}
+	if got := slices.Collect(cache.Values()); !slices.Equal(got, values) {
+		t.Fatalf("expected Values %v, got %v", values, got)
+	}
+	if got := cache.Keys(); !slices.Equal(got, keys) {
+		t.Fatalf("expected Keys %v, got %v", keys, got)
+	}
+	if got, want := cache.Items(), map[string]int{"a": 1, "b": 2, "c": 3}; !maps.Equal(got, want) {
+		t.Fatalf("expected Items %v, got %v", want, got)
+	}
+}
+
+func TestAllSnapshot(t *testing.T) {
+	cache, err := New[int, int](8)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(cache.Close)
+
+	for i := 0; i < 4; i++ {
+		cache.Set(i, i)
+	}
+	if err := cache.Pin(0); err != nil {
+		t.Fatalf("pin: %v", err)
+	}
+
+	seen := 0
+	for k := range cache.All() {
+		cache.Delete(k)
+		cache.Set(k+100, k)
+		seen++
+	}
+	if seen != 4 {
+		t.Fatalf("expected to visit the 4 snapshotted entries, got %d", seen)
+	}
+
+	count := 0
+	for range cache.Values() {
+		count++
+		break
+	}
+	if count != 1 {
+		t.Fatalf("expected early break to stop iteration, got %d", count)
+	}
+}
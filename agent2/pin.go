@@ -0,0 +1,70 @@
+package lru
+
+import "errors"
+
+var (
+	// ErrNotFound is returned by Pin when the key is absent or expired.
+	ErrNotFound = errors.New("lru: key not found")
+	// ErrPinLimit is returned by Pin when every slot of the cache is already pinned,
+	// and by Resize when more entries are pinned than the new capacity allows.
+	ErrPinLimit = errors.New("lru: pinned entries would exceed capacity")
+	// ErrAllPinned is returned by writes of new keys while every slot of the
+	// cache is pinned, since nothing can be evicted to make room.
+	ErrAllPinned = errors.New("lru: every slot is pinned")
+)
+
+// Pin exempts key from capacity eviction until Unpin is called. Pinned entries
+// still expire according to their TTL and are still removed by Delete and
+// InvalidateTag. At most Capacity entries may be pinned; once every slot is
+// pinned, writes of new keys fail with ErrAllPinned.
+func (c *Cache[K, V]) Pin(key K) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	item, ok := c.entries[key]
+	if !ok {
+		return ErrNotFound
+	}
+	if !item.expiresAt.IsZero() && c.now().After(item.expiresAt) {
+		c.expireLocked(item)
+		return ErrNotFound
+	}
+	if item.pinned {
+		return nil
+	}
+	if c.pinned >= c.capacity {
+		return ErrPinLimit
+	}
+
+	// Pinned entries are kept out of the recency list so eviction never sees them.
+	c.removeEntry(item)
+	item.pinned = true
+	c.pinned++
+	c.pinnedWeight += item.cost
+	return nil
+}
+
+// Unpin makes key evictable again, treating it as most recently used. It reports
+// whether key was pinned.
+func (c *Cache[K, V]) Unpin(key K) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	item, ok := c.entries[key]
+	if !ok || !item.pinned {
+		return false
+	}
+
+	item.pinned = false
+	c.pinned--
+	c.pinnedWeight -= item.cost
+	c.insertAtFront(item)
+	return true
+}
+
+// Pinned reports the number of pinned entries.
+func (c *Cache[K, V]) Pinned() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.pinned
+}
@@ -0,0 +1,125 @@
+package lru
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPinExemptsFromEviction(t *testing.T) {
+	cache, err := New[string, int](2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(cache.Close)
+
+	cache.Set("config", 1)
+	if err := cache.Pin("config"); err != nil {
+		t.Fatalf("pin: %v", err)
+	}
+	cache.Set("a", 2)
+	cache.Set("b", 3)
+	cache.Set("c", 4)
+
+	if v, ok := cache.Get("config"); !ok || v != 1 {
+		t.Fatalf("expected pinned config=1, got %v, %t", v, ok)
+	}
+	if _, ok := cache.Get("b"); ok {
+		t.Fatalf("expected b to be evicted")
+	}
+	if v, ok := cache.Get("c"); !ok || v != 4 {
+		t.Fatalf("expected c=4, got %v, %t", v, ok)
+	}
+
+	if !cache.Unpin("config") {
+		t.Fatalf("expected config to be pinned")
+	}
+	if cache.Unpin("config") {
+		t.Fatalf("expected second unpin to report false")
+	}
+	cache.Get("c")
+	cache.Set("d", 5)
+	if _, ok := cache.Get("config"); ok {
+		t.Fatalf("expected unpinned config to be evicted")
+	}
+}
+
+func TestPinLimit(t *testing.T) {
+	cache, err := New[string, int](2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(cache.Close)
+
+	if err := cache.Pin("missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	cache.Set("a", 1)
+	cache.Set("b", 2)
+	for _, key := range []string{"a", "b", "a"} {
+		if err := cache.Pin(key); err != nil {
+			t.Fatalf("pin %s: %v", key, err)
+		}
+	}
+	if cache.Pinned() != 2 {
+		t.Fatalf("expected 2 pinned, got %d", cache.Pinned())
+	}
+
+	if err := cache.Set("c", 3); !errors.Is(err, ErrAllPinned) {
+		t.Fatalf("expected ErrAllPinned while every slot is pinned, got %v", err)
+	}
+	if _, ok := cache.Get("c"); ok {
+		t.Fatalf("expected the rejected write not to be stored")
+	}
+	if err := cache.Set("a", 10); err != nil {
+		t.Fatalf("expected a pinned key to stay writable, got %v", err)
+	}
+	if cache.Len() != 2 {
+		t.Fatalf("expected len 2, got %d", cache.Len())
+	}
+
+	cache.Delete("a")
+	if cache.Pinned() != 1 {
+		t.Fatalf("expected delete to release pin, got %d pinned", cache.Pinned())
+	}
+	cache.Set("c", 3)
+	if err := cache.Pin("c"); err != nil {
+		t.Fatalf("pin c: %v", err)
+	}
+	cache.Set("d", 4)
+	if err := cache.Pin("d"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected d to be rejected, got %v", err)
+	}
+}
+
+func TestPinnedEntriesExpire(t *testing.T) {
+	now := time.Unix(0, 0)
+	cache, err := New[string, int](2, WithNow(func() time.Time { return now }))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(cache.Close)
+
+	cache.SetWithTTL("a", 1, time.Second)
+	cache.SetWithTTL("b", 2, time.Minute)
+	if err := cache.Pin("a"); err != nil {
+		t.Fatalf("pin a: %v", err)
+	}
+	if err := cache.Pin("b"); err != nil {
+		t.Fatalf("pin b: %v", err)
+	}
+
+	now = now.Add(2 * time.Second)
+	if _, ok := cache.Get("a"); ok {
+		t.Fatalf("expected pinned a to expire")
+	}
+	if cache.Pinned() != 1 {
+		t.Fatalf("expected 1 pinned, got %d", cache.Pinned())
+	}
+
+	cache.Set("c", 3)
+	if v, ok := cache.Get("c"); !ok || v != 3 {
+		t.Fatalf("expected c=3, got %v, %t", v, ok)
+	}
+}
@@ -0,0 +1,31 @@
+package lru
+
+// Resize changes the capacity to newCapacity. Shrinking drops expired entries
+// first and then evicts the least recently used ones until the cache fits.
+// It returns ErrInvalidCapacity for a non-positive capacity and ErrPinLimit
+// if more entries are pinned than newCapacity allows, leaving the cache
+// unchanged in both cases.
+func (c *Cache[K, V]) Resize(newCapacity int) error {
+	if newCapacity <= 0 {
+		return ErrInvalidCapacity
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.pinned > newCapacity {
+		return ErrPinLimit
+	}
+	c.capacity = newCapacity
+	for len(c.entries) > c.capacity {
+		c.evictLRU()
+	}
+	return nil
+}
+
+// Capacity reports the maximum number of entries the cache holds.
+func (c *Cache[K, V]) Capacity() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.capacity
+}
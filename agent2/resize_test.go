@@ -0,0 +1,88 @@
+package lru
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestResizeShrinkEvictsLRU(t *testing.T) {
+	now := time.Unix(0, 0)
+	cache, err := New[string, int](5, WithNow(func() time.Time { return now }))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cache.Set("a", 1)
+	cache.SetWithTTL("b", 2, time.Second)
+	cache.Set("c", 3)
+	cache.Set("d", 4)
+	cache.Set("e", 5)
+	cache.Get("a")
+	now = now.Add(2 * time.Second)
+
+	if err := cache.Resize(2); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, want := cache.Keys(), []string{"a", "e"}; !slices.Equal(got, want) {
+		t.Fatalf("expected %v to survive, got %v", want, got)
+	}
+	if got := cache.Stats(); got.Expirations != 1 || got.Evictions != 2 {
+		t.Fatalf("expected 1 expiration and 2 evictions, got %+v", got)
+	}
+	if got := cache.Capacity(); got != 2 {
+		t.Fatalf("expected capacity 2, got %d", got)
+	}
+
+	cache.Set("f", 6)
+	if _, ok := cache.Get("e"); ok {
+		t.Fatalf("expected the new capacity to apply to later writes")
+	}
+}
+
+func TestResizeGrow(t *testing.T) {
+	cache, err := New[int, int](2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := cache.Resize(4); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i := 0; i < 4; i++ {
+		cache.Set(i, i)
+	}
+	if n := cache.Len(); n != 4 {
+		t.Fatalf("expected 4 entries after growing, got %d", n)
+	}
+}
+
+func TestResizeErrors(t *testing.T) {
+	cache, err := New[int, int](3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		cache.Set(i, i)
+	}
+	cache.Pin(0)
+	cache.Pin(1)
+
+	if err := cache.Resize(0); !errors.Is(err, ErrInvalidCapacity) {
+		t.Fatalf("expected ErrInvalidCapacity, got %v", err)
+	}
+	if err := cache.Resize(1); !errors.Is(err, ErrPinLimit) {
+		t.Fatalf("expected ErrPinLimit, got %v", err)
+	}
+	if got := cache.Capacity(); got != 3 || cache.Len() != 3 {
+		t.Fatalf("expected a failed resize to leave the cache unchanged, got capacity %d len %d", got, cache.Len())
+	}
+
+	if err := cache.Resize(2); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := cache.Keys(); len(got) != 2 || got[0] == 2 || got[1] == 2 {
+		t.Fatalf("expected only the pinned entries to remain, got %v", got)
+	}
+}
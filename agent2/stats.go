@@ -0,0 +1,59 @@
+package lru
+
+import "github.com/rselbach/agent-comparison/metrics"
+
+// Stats holds the cache's counters since it was created or last reset.
+type Stats struct {
+	// Hits and Misses count Get calls that found and did not find a live
+	// entry. Other lookups, such as Pin and iteration, are not counted.
+	Hits   uint64
+	Misses uint64
+	// Sets counts calls to Set, SetWithTTL and SetWithTags, including writes
+	// rejected because every slot is pinned.
+	Sets uint64
+	// Evictions counts live entries dropped to make room for a new key.
+	Evictions uint64
+	// Expirations counts entries removed because their TTL had passed,
+	// whether by a lookup, a write or a sweep.
+	Expirations uint64
+}
+
+// HitRatio returns Hits / (Hits + Misses), or zero before the first Get.
+func (s Stats) HitRatio() float64 {
+	total := s.Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(total)
+}
+
+// Stats returns a copy of the cache's counters.
+func (c *Cache[K, V]) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return statsOf(c.counters.Snapshot(), c.sets)
+}
+
+// ResetStats zeroes the counters and returns their values before the reset,
+// so periodic reporting can take per-interval figures without losing counts
+// between reading and resetting.
+func (c *Cache[K, V]) ResetStats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := statsOf(c.counters.Reset(), c.sets)
+	c.sets = 0
+	return s
+}
+
+// statsOf builds Stats from the counters the cache records its events to. The
+// counters are only written under c.mu, so a snapshot taken under it is
+// consistent.
+func statsOf(s metrics.Snapshot, sets uint64) Stats {
+	return Stats{
+		Hits:        uint64(s.Hits),
+		Misses:      uint64(s.Misses),
+		Sets:        sets,
+		Evictions:   uint64(s.Evictions),
+		Expirations: uint64(s.Expirations),
+	}
+}
@@ -0,0 +1,44 @@
+package lru
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	now := time.Unix(0, 0)
+	cache, err := New[string, int](2, WithNow(func() time.Time { return now }))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cache.SetWithTTL("a", 1, time.Second)
+	cache.Set("b", 2)
+	cache.Get("b")
+	cache.Get("missing")
+	cache.Set("b", 3)
+	cache.Set("c", 4) // evicts a, the least recently used
+
+	now = now.Add(time.Hour)
+	cache.SetWithTTL("d", 5, time.Second) // evicts b
+	now = now.Add(2 * time.Second)
+	cache.Get("d")
+
+	want := Stats{Hits: 1, Misses: 2, Sets: 5, Evictions: 2, Expirations: 1}
+	if got := cache.Stats(); got != want {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+	if ratio := want.HitRatio(); ratio != 1.0/3 {
+		t.Fatalf("expected hit ratio 1/3, got %v", ratio)
+	}
+
+	if got := cache.ResetStats(); got != want {
+		t.Fatalf("expected reset to return %+v, got %+v", want, got)
+	}
+	if got := cache.Stats(); got != (Stats{}) {
+		t.Fatalf("expected zeroed stats after reset, got %+v", got)
+	}
+	if ratio := cache.Stats().HitRatio(); ratio != 0 {
+		t.Fatalf("expected zero hit ratio without lookups, got %v", ratio)
+	}
+}
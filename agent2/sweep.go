@@ -0,0 +1,21 @@
+package lru
+
+// WithMaxSweepEntries bounds each background sweep to removing n expired
+// entries so the lock is not held for long when many expire at once. Entries
+// are removed soonest expiry first and whatever a sweep leaves is removed by
+// the next one, or earlier by Get, Len, writes and TriggerCleanup. A
+// non-positive n (the default) removes every expired entry.
+func WithMaxSweepEntries(n int) Option {
+	return func(opt *options) {
+		opt.maxSweepEntries = n
+	}
+}
+
+// sweep runs one background sweep.
+func (c *Cache[K, V]) sweep() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	start := c.now()
+	c.removeExpiredLocked(c.maxSweepEntries)
+	c.metrics.SweepDuration(c.now().Sub(start))
+}
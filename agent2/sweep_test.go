@@ -0,0 +1,97 @@
+package lru
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestBoundedSweepResumes(t *testing.T) {
+	now := time.Unix(0, 0)
+	cache, err := New[string, int](10, WithNow(func() time.Time { return now }), WithMaxSweepEntries(3))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i := 0; i < 8; i++ {
+		cache.SetWithTTL(strconv.Itoa(i), i, time.Second)
+	}
+	now = now.Add(2 * time.Second)
+
+	for _, want := range []int{3, 3, 2, 0} {
+		cache.mu.Lock()
+		removed := cache.removeExpiredLocked(cache.maxSweepEntries)
+		cache.mu.Unlock()
+		if removed != want {
+			t.Fatalf("expected sweep to remove %d, got %d", want, removed)
+		}
+	}
+	if n := len(cache.entries); n != 0 {
+		t.Fatalf("expected every entry swept, %d left", n)
+	}
+}
+
+func TestBoundedSweepSoonestFirst(t *testing.T) {
+	now := time.Unix(0, 0)
+	cache, err := New[string, int](10, WithNow(func() time.Time { return now }))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cache.SetWithTTL("late", 0, 3*time.Second)
+	cache.Set("forever", 0)
+	cache.SetWithTTL("early", 0, time.Second)
+	cache.SetWithTTL("pinned", 0, 2*time.Second)
+	cache.SetWithTTL("fresh", 0, time.Hour)
+	if err := cache.Pin("pinned"); err != nil {
+		t.Fatalf("pin: %v", err)
+	}
+	// Moving a deadline reorders the entry.
+	cache.SetWithTTL("late", 0, 5*time.Second)
+	now = now.Add(10 * time.Second)
+
+	for _, want := range []string{"early", "pinned", "late"} {
+		cache.mu.Lock()
+		removed := cache.removeExpiredLocked(1)
+		cache.mu.Unlock()
+		if removed != 1 {
+			t.Fatalf("expected one entry swept, got %d", removed)
+		}
+		if _, ok := cache.entries[want]; ok {
+			t.Fatalf("expected %s to be swept next", want)
+		}
+	}
+
+	cache.mu.Lock()
+	removed := cache.removeExpiredLocked(1)
+	cache.mu.Unlock()
+	if removed != 0 || len(cache.entries) != 2 {
+		t.Fatalf("expected only unexpired entries left, removed %d with %d left", removed, len(cache.entries))
+	}
+	if len(cache.expiries) != 1 {
+		t.Fatalf("expected only fresh on the expiry heap, got %d entries", len(cache.expiries))
+	}
+}
+
+func TestBackgroundBoundedSweep(t *testing.T) {
+	cache, err := New[int, int](100, WithCleanupInterval(5*time.Millisecond), WithMaxSweepEntries(10))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(cache.Close)
+	for i := 0; i < 50; i++ {
+		cache.SetWithTTL(i, i, time.Millisecond)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		cache.mu.Lock()
+		n := len(cache.entries)
+		cache.mu.Unlock()
+		if n == 0 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected background sweeps to drain the cache, %d left", n)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
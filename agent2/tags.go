@@ -0,0 +1,64 @@
+package lru
+
+import "time"
+
+// SetWithTags stores value under key applying ttl, like SetWithTTL, and
+// associates the entry with tags so it can later be dropped by InvalidateTag.
+// Overwriting a key replaces its previous tags. Like SetWithTTL it returns
+// ErrAllPinned if key is new and every slot is pinned.
+func (c *Cache[K, V]) SetWithTags(key K, value V, ttl time.Duration, tags ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.setLocked(key, value, ttl, tags, 1)
+}
+
+// InvalidateTag removes every entry tagged with tag and reports how many were removed.
+func (c *Cache[K, V]) InvalidateTag(tag string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	keys := c.tags[tag]
+	removed := 0
+	for key := range keys {
+		if item, ok := c.entries[key]; ok {
+			c.dropLocked(item)
+			removed++
+		}
+	}
+	delete(c.tags, tag)
+	return removed
+}
+
+func (c *Cache[K, V]) tagLocked(item *entry[K, V], tags []string) {
+	if len(tags) == 0 {
+		return
+	}
+	if c.tags == nil {
+		c.tags = make(map[string]map[K]struct{})
+	}
+
+	item.tags = item.tags[:0]
+	for _, tag := range tags {
+		keys, ok := c.tags[tag]
+		if !ok {
+			keys = make(map[K]struct{})
+			c.tags[tag] = keys
+		}
+		if _, dup := keys[item.key]; dup {
+			continue
+		}
+		keys[item.key] = struct{}{}
+		item.tags = append(item.tags, tag)
+	}
+}
+
+func (c *Cache[K, V]) untagLocked(item *entry[K, V]) {
+	for _, tag := range item.tags {
+		keys := c.tags[tag]
+		delete(keys, item.key)
+		if len(keys) == 0 {
+			delete(c.tags, tag)
+		}
+	}
+	item.tags = nil
+}
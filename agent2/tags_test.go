@@ -0,0 +1,70 @@
+package lru
+
+import (
+	"testing"
+	"time"
+)
+
+func TestInvalidateTag(t *testing.T) {
+	cache, err := New[string, int](8)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(cache.Close)
+
+	cache.SetWithTags("q1", 1, 0, "users")
+	cache.SetWithTags("q2", 2, 0, "users", "orders")
+	cache.SetWithTags("q3", 3, 0, "orders")
+	cache.Set("plain", 4)
+
+	if removed := cache.InvalidateTag("users"); removed != 2 {
+		t.Fatalf("expected 2 removed, got %d", removed)
+	}
+	for _, key := range []string{"q1", "q2"} {
+		if _, ok := cache.Get(key); ok {
+			t.Fatalf("expected %s to be invalidated", key)
+		}
+	}
+	if v, ok := cache.Get("q3"); !ok || v != 3 {
+		t.Fatalf("expected q3=3, got %v, %t", v, ok)
+	}
+	if removed := cache.InvalidateTag("orders"); removed != 1 {
+		t.Fatalf("expected 1 removed, got %d", removed)
+	}
+	if removed := cache.InvalidateTag("missing"); removed != 0 {
+		t.Fatalf("expected 0 removed, got %d", removed)
+	}
+	if cache.Len() != 1 {
+		t.Fatalf("expected only plain to remain, got len=%d", cache.Len())
+	}
+}
+
+func TestTagIndexFollowsRemovals(t *testing.T) {
+	now := time.Unix(0, 0)
+	cache, err := New[string, int](2, WithNow(func() time.Time { return now }))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(cache.Close)
+
+	cache.SetWithTags("a", 1, 0, "t")
+	cache.SetWithTags("b", 2, time.Second, "t")
+	cache.SetWithTags("c", 3, 0, "other") // evicts a
+
+	now = now.Add(2 * time.Second)
+	cache.TriggerCleanup() // expires b
+
+	if len(cache.tags["t"]) != 0 {
+		t.Fatalf("expected tag index for t to be empty, got %v", cache.tags["t"])
+	}
+
+	// Overwriting replaces tags.
+	cache.SetWithTags("c", 4, 0, "t")
+	if _, ok := cache.tags["other"]; ok {
+		t.Fatalf("expected old tag to be dropped on overwrite")
+	}
+	cache.Delete("c")
+	if len(cache.tags) != 0 {
+		t.Fatalf("expected tag index to be empty, got %v", cache.tags)
+	}
+}
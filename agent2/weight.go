@@ -0,0 +1,66 @@
+package lru
+
+import (
+	"errors"
+	"time"
+)
+
+// WithMaxWeight bounds the total cost of the stored entries, in addition to
+// the entry count given to New. Writes that take the total over maxWeight
+// evict least recently used entries until it fits. Entries written with Set,
+// SetWithTTL and SetWithTags cost 1; use SetWithCost to give an entry its own
+// cost, such as its size in bytes. A non-positive maxWeight (the default)
+// leaves the weight unbounded.
+func WithMaxWeight(maxWeight int64) Option {
+	return func(opt *options) {
+		opt.maxWeight = maxWeight
+	}
+}
+
+// SetWithCost stores value under key applying ttl, like SetWithTTL, and
+// counts cost towards the maximum weight. Negative costs count as zero. It
+// returns ErrOverweight if the entry cannot fit even after evicting every
+// unpinned entry, and ErrAllPinned under the same conditions as SetWithTTL;
+// either way the cache is left unchanged, including any previous value.
+func (c *Cache[K, V]) SetWithCost(key K, value V, ttl time.Duration, cost int64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.setLocked(key, value, ttl, nil, cost)
+}
+
+// ErrOverweight is returned by SetWithCost for an entry that does not fit
+// within the maximum weight beside the pinned entries.
+var ErrOverweight = errors.New("lru: entry exceeds the maximum weight")
+
+// Weight reports the total cost of the stored entries, including expired
+// entries that have not been removed yet.
+func (c *Cache[K, V]) Weight() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.weight
+}
+
+// fitsLocked reports whether an entry of the given cost can be stored once
+// every unpinned entry other than item, which may be nil, is evicted.
+func (c *Cache[K, V]) fitsLocked(item *entry[K, V], cost int64) bool {
+	if c.maxWeight <= 0 {
+		return true
+	}
+	pinned := c.pinnedWeight
+	if item != nil && item.pinned {
+		pinned -= item.cost
+	}
+	return pinned+cost <= c.maxWeight
+}
+
+// fitLocked evicts least recently used entries other than keep until the total
+// weight is within the maximum. Callers check fitsLocked first, so evicting
+// every unpinned entry is always enough.
+func (c *Cache[K, V]) fitLocked(keep *entry[K, V]) {
+	if c.maxWeight <= 0 {
+		return
+	}
+	for c.weight > c.maxWeight && c.tail != nil && c.tail != keep {
+		c.evictLRU()
+	}
+}
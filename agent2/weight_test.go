@@ -0,0 +1,134 @@
+package lru
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestMaxWeightEvictsLRU(t *testing.T) {
+	cache, err := New[string, string](10, WithMaxWeight(10))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cache.SetWithCost("a", "a", 0, 4)
+	cache.SetWithCost("b", "b", 0, 4)
+	cache.Set("c", "c") // cost 1
+	cache.Get("a")
+	if err := cache.SetWithCost("d", "d", 0, 5); err != nil {
+		t.Fatalf("expected d to be stored, got %v", err)
+	}
+
+	// b is least recently used and enough on its own; c, the next, stays.
+	if got, want := cache.Keys(), []string{"d", "a", "c"}; !slices.Equal(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	if got := cache.Weight(); got != 10 {
+		t.Fatalf("expected weight 10, got %d", got)
+	}
+	if got := cache.Stats().Evictions; got != 1 {
+		t.Fatalf("expected 1 eviction, got %d", got)
+	}
+
+	// Growing an existing entry evicts others, never the entry itself.
+	if err := cache.SetWithCost("c", "c", 0, 2); err != nil {
+		t.Fatalf("expected c to be stored, got %v", err)
+	}
+	if got, want := cache.Keys(), []string{"c", "d"}; !slices.Equal(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+
+	cache.Delete("d")
+	if got := cache.Weight(); got != 2 {
+		t.Fatalf("expected delete to release weight, got %d", got)
+	}
+}
+
+func TestMaxWeightRejects(t *testing.T) {
+	cache, err := New[string, int](10, WithMaxWeight(10))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cache.SetWithCost("a", 1, 0, 3)
+	if err := cache.SetWithCost("a", 2, 0, 11); !errors.Is(err, ErrOverweight) {
+		t.Fatalf("expected an entry heavier than the maximum to be rejected, got %v", err)
+	}
+	if v, ok := cache.Get("a"); !ok || v != 1 {
+		t.Fatalf("expected rejected write to keep the previous value, got %d, %v", v, ok)
+	}
+
+	if err := cache.Pin("a"); err != nil {
+		t.Fatalf("pin: %v", err)
+	}
+	cache.SetWithCost("b", 2, 0, 5)
+	if err := cache.SetWithCost("c", 3, 0, 8); !errors.Is(err, ErrOverweight) {
+		t.Fatalf("expected c not to fit beside the pinned entry, got %v", err)
+	}
+	if _, ok := cache.Get("c"); ok {
+		t.Fatalf("expected c not to be stored")
+	}
+	if v, ok := cache.Get("b"); !ok || v != 2 {
+		t.Fatalf("expected the rejected write to evict nothing, got %d, %v", v, ok)
+	}
+	if got := cache.Weight(); got != 8 {
+		t.Fatalf("expected weight 8, got %d", got)
+	}
+}
+
+func TestMaxWeightKeepsPinnedEntry(t *testing.T) {
+	cache, err := New[string, int](10, WithMaxWeight(10))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cache.SetWithCost("a", 1, 0, 4)
+	cache.SetWithCost("b", 2, 0, 5)
+	for _, key := range []string{"a", "b"} {
+		if err := cache.Pin(key); err != nil {
+			t.Fatalf("pin %s: %v", key, err)
+		}
+	}
+
+	// a can grow to 5 beside b, but not to 6.
+	if err := cache.SetWithCost("a", 3, 0, 6); !errors.Is(err, ErrOverweight) {
+		t.Fatalf("expected growing a past the pinned weight to fail, got %v", err)
+	}
+	if v, ok := cache.Get("a"); !ok || v != 1 {
+		t.Fatalf("expected pinned a to keep its value, got %d, %v", v, ok)
+	}
+	if got := cache.Pinned(); got != 2 {
+		t.Fatalf("expected 2 pinned entries, got %d", got)
+	}
+	if got := cache.Weight(); got != 9 {
+		t.Fatalf("expected weight 9, got %d", got)
+	}
+
+	if err := cache.SetWithCost("a", 4, 0, 5); err != nil {
+		t.Fatalf("expected a to fit at cost 5, got %v", err)
+	}
+	if v, ok := cache.Get("a"); !ok || v != 4 {
+		t.Fatalf("expected a to be updated, got %d, %v", v, ok)
+	}
+	if got := cache.Weight(); got != 10 {
+		t.Fatalf("expected weight 10, got %d", got)
+	}
+}
+
+func TestWeightUnbounded(t *testing.T) {
+	cache, err := New[int, int](3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := cache.SetWithCost(i, i, 0, 1<<40); err != nil {
+			t.Fatalf("expected %d to be stored without a maximum weight, got %v", i, err)
+		}
+	}
+	cache.SetWithCost(0, 0, 0, -5)
+	if got := cache.Weight(); got != 2<<40 {
+		t.Fatalf("expected weight %d, got %d", int64(2<<40), got)
+	}
+}
@@ -4,12 +4,15 @@ import (
 	"container/list"
 	"sync"
 	"time"
+
+	"github.com/rselbach/agent-comparison/clock"
+	"github.com/rselbach/agent-comparison/metrics"
 )
 
 // entry is used to hold a value in the cache.
 type entry struct {
-	key      interface{}
-	value    interface{}
+	key       interface{}
+	value     interface{}
 	expiresAt time.Time
 }
 
@@ -19,15 +22,90 @@ type Cache struct {
 	ll         *list.List
 	cache      map[interface{}]*list.Element
 	mu         sync.Mutex
+	stop       chan struct{}
+	done       chan struct{}
+	closeOnce  sync.Once
+	clock      clock.Clock
+	metrics    metrics.Recorder
+	interval   time.Duration // janitor period, or zero for none
+}
+
+// Option configures a Cache created by New.
+type Option func(*Cache)
+
+// WithJanitor starts a janitor goroutine that calls RemoveExpired every
+// interval. Call Close to stop it. A non-positive interval means no janitor.
+func WithJanitor(interval time.Duration) Option {
+	return func(c *Cache) {
+		c.interval = interval
+	}
+}
+
+// WithClock makes the cache read the time from clk and run the janitor off
+// clk's ticker. A nil clk means real time.
+func WithClock(clk clock.Clock) Option {
+	return func(c *Cache) {
+		c.clock = clk
+	}
 }
 
-// New creates a new Cache.
-func New(maxEntries int) *Cache {
-	return &Cache{
+// WithRecorder reports hits, misses, evictions, expirations and janitor sweep
+// durations to r. A nil r records nothing.
+func WithRecorder(r metrics.Recorder) Option {
+	return func(c *Cache) {
+		c.metrics = r
+	}
+}
+
+// New creates a new Cache. Without WithJanitor, expired entries are removed
+// when they are looked up or by RemoveExpired.
+func New(maxEntries int, opts ...Option) *Cache {
+	c := &Cache{
 		maxEntries: maxEntries,
 		ll:         list.New(),
 		cache:      make(map[interface{}]*list.Element),
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.interval <= 0 {
+		return c
+	}
+
+	c.stop = make(chan struct{})
+	c.done = make(chan struct{})
+	go c.janitor(c.interval)
+	return c
+}
+
+// Close stops the janitor, if any, and waits for it to exit. The cache stays
+// usable afterwards. It is safe to call Close more than once.
+func (c *Cache) Close() {
+	c.closeOnce.Do(func() {
+		if c.stop == nil {
+			return
+		}
+		close(c.stop)
+		<-c.done
+	})
+}
+
+func (c *Cache) janitor(interval time.Duration) {
+	defer close(c.done)
+
+	ticker := clock.OrReal(c.clock).NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C():
+			start := c.now()
+			c.RemoveExpired()
+			c.recorder().SweepDuration(c.now().Sub(start))
+		case <-c.stop:
+			return
+		}
+	}
 }
 
 // Add adds a value to the cache.
@@ -43,15 +121,16 @@ func (c *Cache) Add(key, value interface{}, ttl time.Duration) {
 	if ee, ok := c.cache[key]; ok {
 		c.ll.MoveToFront(ee)
 		ee.Value.(*entry).value = value
-		ee.Value.(*entry).expiresAt = time.Now().Add(ttl)
+		ee.Value.(*entry).expiresAt = c.now().Add(ttl)
 		return
 	}
 
-	ele := c.ll.PushFront(&entry{key, value, time.Now().Add(ttl)})
+	ele := c.ll.PushFront(&entry{key, value, c.now().Add(ttl)})
 	c.cache[key] = ele
 
 	if c.maxEntries != 0 && c.ll.Len() > c.maxEntries {
 		c.removeOldest()
+		c.recorder().Eviction()
 	}
 }
 
@@ -61,17 +140,22 @@ func (c *Cache) Get(key interface{}) (value interface{}, ok bool) {
 	defer c.mu.Unlock()
 
 	if c.cache == nil {
+		c.recorder().Miss()
 		return
 	}
 
 	if ele, hit := c.cache[key]; hit {
-		if time.Now().After(ele.Value.(*entry).expiresAt) {
+		if c.now().After(ele.Value.(*entry).expiresAt) {
 			c.removeElement(ele)
+			c.recorder().Expiration()
+			c.recorder().Miss()
 			return nil, false
 		}
 		c.ll.MoveToFront(ele)
+		c.recorder().Hit()
 		return ele.Value.(*entry).value, true
 	}
+	c.recorder().Miss()
 	return
 }
 
@@ -106,7 +190,44 @@ func (c *Cache) removeElement(e *list.Element) {
 	delete(c.cache, kv.key)
 }
 
-// Len returns the number of items in the cache.
+// Clear removes all items from the cache.
+func (c *Cache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.cache == nil {
+		return
+	}
+	c.ll.Init()
+	c.cache = make(map[interface{}]*list.Element)
+}
+
+// RemoveExpired removes all expired items and returns how many were removed.
+func (c *Cache) RemoveExpired() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.cache == nil {
+		return 0
+	}
+
+	now := c.now()
+	removed := 0
+	for ele := c.ll.Back(); ele != nil; {
+		prev := ele.Prev()
+		if now.After(ele.Value.(*entry).expiresAt) {
+			c.removeElement(ele)
+			c.recorder().Expiration()
+			removed++
+		}
+		ele = prev
+	}
+	return removed
+}
+
+// Len returns the number of items in the cache, including expired items that
+// have not been removed yet. It takes constant time; use LiveLen to leave the
+// expired items out.
 func (c *Cache) Len() int {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -115,3 +236,36 @@ func (c *Cache) Len() int {
 	}
 	return c.ll.Len()
 }
+
+// LiveLen returns the number of unexpired items in the cache. Unlike Len it
+// looks at every item, so it takes time proportional to the cache's size.
+func (c *Cache) LiveLen() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.cache == nil {
+		return 0
+	}
+
+	now := c.now()
+	n := 0
+	for ele := c.ll.Front(); ele != nil; ele = ele.Next() {
+		if !now.After(ele.Value.(*entry).expiresAt) {
+			n++
+		}
+	}
+	return n
+}
+
+// now reads the cache's clock, which is real time unless set by WithClock.
+func (c *Cache) now() time.Time {
+	if c.clock == nil {
+		return time.Now()
+	}
+	return c.clock.Now()
+}
+
+// recorder returns the cache's recorder, which discards everything unless set
+// by WithRecorder.
+func (c *Cache) recorder() metrics.Recorder {
+	return metrics.OrNop(c.metrics)
+}
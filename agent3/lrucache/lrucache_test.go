@@ -3,6 +3,9 @@ package lrucache
 import (
 	"testing"
 	"time"
+
+	"github.com/rselbach/agent-comparison/clock"
+	"github.com/rselbach/agent-comparison/metrics"
 )
 
 func TestCache_Get(t *testing.T) {
@@ -32,14 +35,15 @@ func TestCache_Eviction(t *testing.T) {
 }
 
 func TestCache_Expiration(t *testing.T) {
-	c := New(1)
+	clk := clock.NewFake(time.Unix(0, 0))
+	c := New(1, WithClock(clk))
 	c.Add("key", "value", time.Millisecond*100)
 
 	if _, ok := c.Get("key"); !ok {
 		t.Fatal("key should be present")
 	}
 
-	time.Sleep(time.Millisecond * 200)
+	clk.Advance(time.Millisecond * 200)
 
 	if _, ok := c.Get("key"); ok {
 		t.Fatal("key should have expired")
@@ -55,3 +59,102 @@ func TestCache_Remove(t *testing.T) {
 		t.Fatal("key should have been removed")
 	}
 }
+
+func TestCache_Clear(t *testing.T) {
+	c := New(2)
+	c.Add("key1", "value1", time.Second)
+	c.Add("key2", "value2", time.Second)
+	c.Clear()
+
+	if n := c.Len(); n != 0 {
+		t.Fatalf("expected empty cache, got len %d", n)
+	}
+	if _, ok := c.Get("key1"); ok {
+		t.Fatal("key1 should have been cleared")
+	}
+
+	var zero Cache
+	zero.Clear()
+}
+
+func TestCache_LiveLenExcludesExpired(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	c := New(2, WithClock(clk))
+	c.Add("short", "value", time.Millisecond)
+	c.Add("long", "value", time.Second)
+	clk.Advance(5 * time.Millisecond)
+
+	if n := c.LiveLen(); n != 1 {
+		t.Fatalf("expected live len 1, got %d", n)
+	}
+	if n := c.Len(); n != 2 {
+		t.Fatalf("expected len 2, got %d", n)
+	}
+	if n := c.RemoveExpired(); n != 1 {
+		t.Fatalf("expected 1 expired item removed, got %d", n)
+	}
+	if n := c.Len(); n != 1 {
+		t.Fatalf("expected len 1, got %d", n)
+	}
+}
+
+func TestCache_Janitor(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	c := New(2, WithJanitor(5*time.Millisecond), WithClock(clk))
+	defer c.Close()
+
+	c.Add("key", "value", time.Millisecond)
+	clk.BlockUntil(1)
+	clk.Advance(5 * time.Millisecond)
+
+	// the tick is delivered at once but the janitor sweeps on its own goroutine
+	deadline := time.Now().Add(time.Second)
+	for {
+		c.mu.Lock()
+		n := c.ll.Len()
+		c.mu.Unlock()
+		if n == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected janitor to remove expired item, %d left", n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	c.Close()
+	c.Close()
+	New(1).Close()
+}
+
+func TestCache_Recorder(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	rec := &metrics.Counters{}
+	c := New(2, WithJanitor(time.Hour), WithClock(clk), WithRecorder(rec))
+	defer c.Close()
+	clk.BlockUntil(1)
+
+	c.Add("a", 1, time.Second)
+	c.Add("b", 2, time.Hour)
+	c.Get("a")
+	c.Get("missing")
+	c.Add("c", 3, time.Hour) // evicts b
+
+	clk.Advance(2 * time.Second)
+	c.Get("a")
+	c.Add("d", 4, time.Millisecond)
+	clk.Advance(time.Hour)
+
+	deadline := time.Now().Add(time.Second)
+	for rec.Snapshot().Sweeps == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the janitor to record a sweep")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	want := metrics.Snapshot{Hits: 1, Misses: 2, Evictions: 1, Expirations: 3, Sweeps: 1}
+	if got := rec.Snapshot(); got != want {
+		t.Fatalf("recorded %+v, want %+v", got, want)
+	}
+}
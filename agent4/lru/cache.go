@@ -1,10 +1,13 @@
 package lru
 
 import (
-	"container/list"
 	"errors"
 	"sync"
 	"time"
+
+	"github.com/rselbach/agent-comparison/clock"
+	"github.com/rselbach/agent-comparison/metrics"
+	"github.com/rselbach/agent-comparison/trie"
 )
 
 var (
@@ -16,16 +19,27 @@ var (
 
 const defaultCleanupInterval = time.Second
 
-type entry[K comparable, V any] struct {
-	key       K
-	value     V
-	expiresAt time.Time
-}
+// cleanupMode says when the background cleanup goroutine starts.
+type cleanupMode int
+
+const (
+	// cleanupEager starts it in New.
+	cleanupEager cleanupMode = iota
+	// cleanupLazy starts it on the first write with a TTL.
+	cleanupLazy
+	// cleanupOff never starts it.
+	cleanupOff
+)
 
 type config struct {
 	defaultTTL      time.Duration
 	cleanupInterval time.Duration
+	cleanup         cleanupMode
 	clock           func() time.Time
+	source          clock.Clock
+	recorder        metrics.Recorder
+	prefixIndex     bool
+	tombstoneWindow time.Duration
 }
 
 // Option configures cache construction.
@@ -45,6 +59,27 @@ func WithCleanupInterval(interval time.Duration) Option {
 	}
 }
 
+// WithoutCleanup disables the background cleanup goroutine. Expired entries
+// are then removed only when they are accessed, when Len is called or when
+// they reach the least recently used end, and Close is not needed to avoid
+// leaking a goroutine.
+func WithoutCleanup() Option {
+	return func(cfg *config) {
+		cfg.cleanup = cleanupOff
+	}
+}
+
+// WithLazyCleanup defers starting the background cleanup goroutine until the
+// first write with a TTL, so a cache whose entries never expire does not run
+// one. Close still stops it once started. WithoutCleanup takes precedence.
+func WithLazyCleanup() Option {
+	return func(cfg *config) {
+		if cfg.cleanup == cleanupEager {
+			cfg.cleanup = cleanupLazy
+		}
+	}
+}
+
 // WithClock overrides the clock used to make expiration decisions.
 func WithClock(clock func() time.Time) Option {
 	return func(cfg *config) {
@@ -54,18 +89,49 @@ func WithClock(clock func() time.Time) Option {
 	}
 }
 
-// Cache implements an LRU cache with TTL-based expiration.
+// WithClockSource uses clk both for expiration decisions and for the cleanup
+// ticker, so a clock.Fake can drive background sweeps as well as expiry.
+func WithClockSource(clk clock.Clock) Option {
+	return func(cfg *config) {
+		if clk != nil {
+			cfg.source = clk
+			cfg.clock = clk.Now
+		}
+	}
+}
+
+// WithRecorder reports the cache's hits, misses, evictions, expirations and
+// cleanup sweep durations to r.
+func WithRecorder(r metrics.Recorder) Option {
+	return func(cfg *config) {
+		cfg.recorder = r
+	}
+}
+
+// Cache implements an LRU cache with TTL-based expiration. Entries live in
+// slab-allocated slots referenced by integer handles rather than as
+// individual heap objects, which keeps GC scan work low for large caches.
 type Cache[K comparable, V any] struct {
 	mu         sync.Mutex
 	capacity   int
-	entries    map[K]*list.Element
-	order      *list.List
+	entries    map[K]handle
+	store      *slabStore[K, V]
 	defaultTTL time.Duration
 
 	cleanupInterval time.Duration
+	cleanup         cleanupMode
+	cleanupStarted  bool
 	clock           func() time.Time
+	source          clock.Clock
+	recorder        metrics.Recorder
 	stopOnce        sync.Once
 	stopCh          chan struct{}
+	keyString       func(K) string
+	prefixes        *trie.Trie[handle]
+
+	tombstoneWindow time.Duration
+	tombstones      map[K]int64
+	graveyard       []tombstone[K]
 }
 
 // New constructs a Cache with the provided capacity and options.
@@ -74,11 +140,7 @@ func New[K comparable, V any](capacity int, opts ...Option) (*Cache[K, V], error
 		return nil, ErrInvalidCapacity
 	}
 
-	cfg := config{
-		cleanupInterval: defaultCleanupInterval,
-		clock:           time.Now,
-	}
-
+	cfg := defaultConfig()
 	for _, opt := range opts {
 		opt(&cfg)
 	}
@@ -95,19 +157,53 @@ func New[K comparable, V any](capacity int, opts ...Option) (*Cache[K, V], error
 		cfg.clock = time.Now
 	}
 
+	if cfg.prefixIndex && stringKeyFunc[K]() == nil {
+		return nil, ErrPrefixUnsupported
+	}
+
+	return newCache[K, V](capacity, cfg), nil
+}
+
+// defaultConfig returns the configuration New starts from before applying
+// options.
+func defaultConfig() config {
+	return config{
+		cleanupInterval: defaultCleanupInterval,
+		clock:           time.Now,
+	}
+}
+
+// newCache builds a cache from a configuration New has already validated.
+func newCache[K comparable, V any](capacity int, cfg config) *Cache[K, V] {
 	cache := &Cache[K, V]{
 		capacity:        capacity,
-		entries:         make(map[K]*list.Element, capacity),
-		order:           list.New(),
+		entries:         make(map[K]handle, capacity),
+		store:           newSlabStore[K, V](capacity),
 		defaultTTL:      cfg.defaultTTL,
 		cleanupInterval: cfg.cleanupInterval,
+		cleanup:         cfg.cleanup,
 		clock:           cfg.clock,
+		source:          clock.OrReal(cfg.source),
+		recorder:        metrics.OrNop(cfg.recorder),
 		stopCh:          make(chan struct{}),
+		keyString:       stringKeyFunc[K](),
 	}
 
-	go cache.runCleanup()
+	if cfg.prefixIndex {
+		cache.prefixes = &trie.Trie[handle]{}
+	}
 
-	return cache, nil
+	if cfg.tombstoneWindow > 0 {
+		cache.tombstoneWindow = cfg.tombstoneWindow
+		cache.tombstones = make(map[K]int64)
+	}
+
+	if cache.cleanup == cleanupEager {
+		cache.cleanupStarted = true
+		go cache.runCleanup()
+	}
+
+	return cache
 }
 
 // Set inserts or updates the value for key using the default TTL if configured.
@@ -115,7 +211,8 @@ func (c *Cache[K, V]) Set(key K, value V) error {
 	return c.SetWithTTL(key, value, 0)
 }
 
-// SetWithTTL inserts or updates key with an explicit TTL.
+// SetWithTTL inserts or updates key with an explicit TTL. With WithTombstones
+// it returns ErrRecentlyDeleted for keys deleted within the window.
 func (c *Cache[K, V]) SetWithTTL(key K, value V, ttl time.Duration) error {
 	if ttl < 0 {
 		return ErrNegativeTTL
@@ -124,33 +221,47 @@ func (c *Cache[K, V]) SetWithTTL(key K, value V, ttl time.Duration) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	now := c.now()
+	if c.buriedLocked(key, now) {
+		return ErrRecentlyDeleted
+	}
+	c.setLocked(key, value, ttl, now)
+	return nil
+}
+
+// setLocked inserts or updates key, applying the default TTL if ttl is zero.
+func (c *Cache[K, V]) setLocked(key K, value V, ttl time.Duration, now time.Time) {
 	ttlToUse := ttl
 	if ttlToUse == 0 {
 		ttlToUse = c.defaultTTL
 	}
 
-	var expiresAt time.Time
+	var expiresAt int64
 	if ttlToUse > 0 {
-		expiresAt = c.now().Add(ttlToUse)
+		expiresAt = now.Add(ttlToUse).UnixNano()
+		c.startCleanupLocked()
 	}
 
-	if elem, ok := c.entries[key]; ok {
-		ent := elem.Value.(*entry[K, V])
-		ent.value = value
-		ent.expiresAt = expiresAt
-		c.order.MoveToFront(elem)
-		return nil
+	if h, ok := c.entries[key]; ok {
+		sl := c.store.at(h)
+		sl.value = value
+		sl.expiresAt = expiresAt
+		c.store.moveToFront(h)
+		return
 	}
 
-	ent := &entry[K, V]{
-		key:       key,
-		value:     value,
-		expiresAt: expiresAt,
+	h := c.store.alloc()
+	sl := c.store.at(h)
+	sl.key = key
+	sl.value = value
+	sl.expiresAt = expiresAt
+	sl.insertedAt = now.UnixNano()
+	c.store.pushFront(h)
+	c.entries[key] = h
+	if c.prefixes != nil {
+		c.prefixes.Insert(c.keyString(key), h)
 	}
-	elem := c.order.PushFront(ent)
-	c.entries[key] = elem
 	c.enforceCapacityLocked()
-	return nil
 }
 
 // Get retrieves the value for key if present and not expired.
@@ -158,35 +269,65 @@ func (c *Cache[K, V]) Get(key K) (V, bool) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	var zero V
+	sl := c.getLocked(key, c.now())
+	if sl == nil {
+		var zero V
+		return zero, false
+	}
+	return sl.value, true
+}
 
-	elem, ok := c.entries[key]
+// getLocked returns the live slot for key, marking it as recently used and
+// recording the access, or nil if key is absent or expired.
+func (c *Cache[K, V]) getLocked(key K, now time.Time) *slot[K, V] {
+	h, ok := c.entries[key]
 	if !ok {
-		return zero, false
+		c.recorder.Miss()
+		return nil
 	}
 
-	ent := elem.Value.(*entry[K, V])
-	now := c.now()
-	if c.isExpired(ent, now) {
-		c.removeElementLocked(elem)
-		return zero, false
+	sl := c.store.at(h)
+	if c.isExpired(sl, now) {
+		c.expireLocked(h)
+		c.recorder.Miss()
+		return nil
 	}
 
-	c.order.MoveToFront(elem)
-	return ent.value, true
+	c.store.moveToFront(h)
+	sl.accessedAt = now.UnixNano()
+	sl.accesses++
+	c.recorder.Hit()
+	return sl
 }
 
-// Delete removes key if it exists.
+// Peek retrieves the value for key like Get but without marking it as
+// recently used. Expired entries are removed and reported absent.
+func (c *Cache[K, V]) Peek(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	sl := c.liveLocked(key, c.now())
+	if sl == nil {
+		var zero V
+		return zero, false
+	}
+	return sl.value, true
+}
+
+// Delete removes key if it exists. With WithTombstones it also leaves a
+// tombstone for key, whether or not it was present.
 func (c *Cache[K, V]) Delete(key K) bool {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	elem, ok := c.entries[key]
+	c.buryLocked(key, c.now())
+
+	h, ok := c.entries[key]
 	if !ok {
 		return false
 	}
 
-	c.removeElementLocked(elem)
+	c.removeLocked(h)
 	return true
 }
 
@@ -196,7 +337,7 @@ func (c *Cache[K, V]) Len() int {
 	defer c.mu.Unlock()
 
 	c.removeExpiredLocked(c.now())
-	return c.order.Len()
+	return c.store.len
 }
 
 // Capacity returns the cache capacity.
@@ -204,7 +345,8 @@ func (c *Cache[K, V]) Capacity() int {
 	return c.capacity
 }
 
-// Close stops the background cleanup goroutine.
+// Close stops the background cleanup goroutine if one is running. A cache
+// created with WithLazyCleanup does not start one after Close.
 func (c *Cache[K, V]) Close() {
 	c.stopOnce.Do(func() {
 		close(c.stopCh)
@@ -218,13 +360,28 @@ func (c *Cache[K, V]) now() time.Time {
 	return c.clock()
 }
 
+// startCleanupLocked starts the cleanup goroutine of a lazily cleaned cache
+// if it is not running yet.
+func (c *Cache[K, V]) startCleanupLocked() {
+	if c.cleanup != cleanupLazy || c.cleanupStarted {
+		return
+	}
+	c.cleanupStarted = true
+	select {
+	case <-c.stopCh:
+		// Closed: keep the goroutine from starting.
+	default:
+		go c.runCleanup()
+	}
+}
+
 func (c *Cache[K, V]) runCleanup() {
-	ticker := time.NewTicker(c.cleanupInterval)
+	ticker := c.source.NewTicker(c.cleanupInterval)
 	defer ticker.Stop()
 
 	for {
 		select {
-		case <-ticker.C:
+		case <-ticker.C():
 			c.removeExpiredEntries()
 		case <-c.stopCh:
 			return
@@ -236,42 +393,53 @@ func (c *Cache[K, V]) removeExpiredEntries() {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	c.removeExpiredLocked(c.now())
+	now := c.now()
+	c.removeExpiredLocked(now)
+	c.purgeTombstonesLocked(now)
+	c.recorder.SweepDuration(c.now().Sub(now))
 }
 
 func (c *Cache[K, V]) removeExpiredLocked(now time.Time) {
-	for elem := c.order.Back(); elem != nil; {
-		prev := elem.Prev()
-		ent := elem.Value.(*entry[K, V])
-		if c.isExpired(ent, now) {
-			c.removeElementLocked(elem)
+	for h := c.store.tail; h != nilHandle; {
+		sl := c.store.at(h)
+		prev := sl.prev
+		if c.isExpired(sl, now) {
+			c.expireLocked(h)
 		}
-		elem = prev
+		h = prev
 	}
 }
 
 func (c *Cache[K, V]) enforceCapacityLocked() {
-	for c.order.Len() > c.capacity {
-		tail := c.order.Back()
-		if tail == nil {
+	for c.store.len > c.capacity {
+		tail := c.store.tail
+		if tail == nilHandle {
 			return
 		}
-		c.removeElementLocked(tail)
+		c.removeLocked(tail)
+		c.recorder.Eviction()
 	}
 }
 
-func (c *Cache[K, V]) removeElementLocked(elem *list.Element) {
-	if elem == nil {
-		return
+// expireLocked removes the entry at h because its TTL has passed.
+func (c *Cache[K, V]) expireLocked(h handle) {
+	c.removeLocked(h)
+	c.recorder.Expiration()
+}
+
+func (c *Cache[K, V]) removeLocked(h handle) {
+	sl := c.store.at(h)
+	delete(c.entries, sl.key)
+	if c.prefixes != nil {
+		c.prefixes.Remove(c.keyString(sl.key))
 	}
-	ent := elem.Value.(*entry[K, V])
-	delete(c.entries, ent.key)
-	c.order.Remove(elem)
+	c.store.unlink(h)
+	c.store.release(h)
 }
 
-func (c *Cache[K, V]) isExpired(ent *entry[K, V], now time.Time) bool {
-	if ent.expiresAt.IsZero() {
+func (c *Cache[K, V]) isExpired(sl *slot[K, V], now time.Time) bool {
+	if sl.expiresAt == 0 {
 		return false
 	}
-	return !ent.expiresAt.After(now)
+	return sl.expiresAt <= now.UnixNano()
 }
@@ -4,6 +4,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/rselbach/agent-comparison/clock"
+	"github.com/rselbach/agent-comparison/metrics"
 	"github.com/stretchr/testify/require"
 )
 
@@ -137,12 +139,13 @@ func TestCacheExpiration(t *testing.T) {
 		tc := tc
 		t.Run(name, func(t *testing.T) {
 			r := require.New(t)
-			cache, err := New[string, int](1)
+			clk := clock.NewFake(time.Unix(0, 0))
+			cache, err := New[string, int](1, WithClockSource(clk))
 			r.NoError(err)
 			defer cache.Close()
 
 			r.NoError(cache.SetWithTTL("k", 99, tc.ttl))
-			time.Sleep(tc.delay)
+			clk.Advance(tc.delay)
 
 			val, ok := cache.Get("k")
 			r.Equal(tc.wantHit, ok)
@@ -178,13 +181,23 @@ func TestCacheAutomaticExpiration(t *testing.T) {
 		tc := tc
 		t.Run(name, func(t *testing.T) {
 			r := require.New(t)
-			cache, err := New[string, int](1, WithCleanupInterval(tc.cleanup))
+			clk := clock.NewFake(time.Unix(0, 0))
+			cache, err := New[string, int](1, WithCleanupInterval(tc.cleanup), WithClockSource(clk))
 			r.NoError(err)
 			defer cache.Close()
 
 			r.NoError(cache.SetWithTTL("key", 123, tc.ttl))
-			time.Sleep(tc.wait)
+			clk.BlockUntil(1)
+			clk.Advance(tc.wait)
 
+			if !tc.expectPresence {
+				// the sweep runs on the cleanup goroutine; Get would expire the entry itself
+				r.Eventually(func() bool {
+					cache.mu.Lock()
+					defer cache.mu.Unlock()
+					return len(cache.entries) == 0
+				}, time.Second, time.Millisecond)
+			}
 			_, ok := cache.Get("key")
 			r.Equal(tc.expectPresence, ok)
 		})
@@ -229,14 +242,15 @@ func TestCacheDelete(t *testing.T) {
 
 func TestCacheLenIgnoresExpired(t *testing.T) {
 	r := require.New(t)
-	cache, err := New[string, int](2, WithCleanupInterval(5*time.Millisecond))
+	clk := clock.NewFake(time.Unix(0, 0))
+	cache, err := New[string, int](2, WithCleanupInterval(5*time.Millisecond), WithClockSource(clk))
 	r.NoError(err)
 	defer cache.Close()
 
 	r.NoError(cache.SetWithTTL("soon", 1, 20*time.Millisecond))
 	r.NoError(cache.SetWithTTL("later", 2, 200*time.Millisecond))
 
-	time.Sleep(60 * time.Millisecond)
+	clk.Advance(60 * time.Millisecond)
 
 	r.Equal(1, cache.Len())
 }
@@ -250,3 +264,140 @@ func TestSetWithTTLValidation(t *testing.T) {
 	err = cache.SetWithTTL("a", 1, -time.Second)
 	r.ErrorIs(err, ErrNegativeTTL)
 }
+
+func TestCachePeek(t *testing.T) {
+	tests := map[string]struct {
+		run func(r *require.Assertions, c *Cache[string, int], advance func(time.Duration))
+	}{
+		"does not update recency": {
+			run: func(r *require.Assertions, c *Cache[string, int], advance func(time.Duration)) {
+				r.NoError(c.Set("a", 1))
+				r.NoError(c.Set("b", 2))
+
+				val, ok := c.Peek("a")
+				r.True(ok)
+				r.Equal(1, val)
+
+				r.NoError(c.Set("c", 3))
+				_, ok = c.Get("a")
+				r.False(ok)
+			},
+		},
+		"miss": {
+			run: func(r *require.Assertions, c *Cache[string, int], advance func(time.Duration)) {
+				_, ok := c.Peek("missing")
+				r.False(ok)
+			},
+		},
+		"honors expiry": {
+			run: func(r *require.Assertions, c *Cache[string, int], advance func(time.Duration)) {
+				r.NoError(c.SetWithTTL("a", 1, time.Second))
+				advance(2 * time.Second)
+				_, ok := c.Peek("a")
+				r.False(ok)
+				r.Equal(0, c.Len())
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			r := require.New(t)
+			now := time.Unix(0, 0)
+			cache, err := New[string, int](2, WithClock(func() time.Time { return now }))
+			r.NoError(err)
+			defer cache.Close()
+
+			tc.run(r, cache, func(d time.Duration) { now = now.Add(d) })
+		})
+	}
+}
+
+func TestCacheRecorder(t *testing.T) {
+	r := require.New(t)
+	clk := clock.NewFake(time.Unix(0, 0))
+	rec := &metrics.Counters{}
+	cache, err := New[string, int](2, WithCleanupInterval(time.Hour), WithClockSource(clk), WithRecorder(rec))
+	r.NoError(err)
+	defer cache.Close()
+	clk.BlockUntil(1)
+
+	r.NoError(cache.SetWithTTL("a", 1, time.Second))
+	r.NoError(cache.Set("b", 2))
+	cache.Get("a")
+	cache.Get("missing")
+	cache.Peek("b")
+	r.NoError(cache.Set("c", 3)) // evicts b
+
+	clk.Advance(2 * time.Second)
+	cache.Get("a")
+	r.NoError(cache.SetWithTTL("d", 4, time.Millisecond))
+	clk.Advance(time.Hour)
+
+	r.Eventually(func() bool { return rec.Snapshot().Sweeps == 1 }, time.Second, time.Millisecond)
+	r.Equal(metrics.Snapshot{Hits: 1, Misses: 2, Evictions: 1, Expirations: 2, Sweeps: 1}, rec.Snapshot())
+}
+
+func TestWithoutCleanup(t *testing.T) {
+	r := require.New(t)
+	clk := clock.NewFake(time.Unix(0, 0))
+	cache, err := New[string, int](2, WithCleanupInterval(time.Millisecond), WithClockSource(clk), WithoutCleanup(), WithLazyCleanup())
+	r.NoError(err)
+
+	r.NoError(cache.SetWithTTL("a", 1, time.Millisecond))
+	r.False(cache.cleanupStarted)
+	r.Zero(clk.Pending())
+
+	// expired entries are still removed on access
+	clk.Advance(time.Second)
+	_, ok := cache.Get("a")
+	r.False(ok)
+	cache.Close()
+}
+
+func TestWithLazyCleanup(t *testing.T) {
+	r := require.New(t)
+	clk := clock.NewFake(time.Unix(0, 0))
+	cache, err := New[string, int](2, WithCleanupInterval(time.Minute), WithClockSource(clk), WithLazyCleanup())
+	r.NoError(err)
+	defer cache.Close()
+
+	r.NoError(cache.Set("forever", 1))
+	r.False(cache.cleanupStarted)
+
+	r.NoError(cache.SetWithTTL("a", 1, time.Second))
+	r.True(cache.cleanupStarted)
+	clk.BlockUntil(1)
+
+	clk.Advance(time.Minute)
+	r.Eventually(func() bool {
+		cache.mu.Lock()
+		defer cache.mu.Unlock()
+		return len(cache.entries) == 1
+	}, time.Second, time.Millisecond)
+
+	// a second expiring write does not start another goroutine
+	r.NoError(cache.SetWithTTL("b", 1, time.Second))
+	r.Equal(1, clk.Pending())
+}
+
+func TestWithLazyCleanupAfterClose(t *testing.T) {
+	r := require.New(t)
+	clk := clock.NewFake(time.Unix(0, 0))
+	cache, err := New[string, int](2, WithClockSource(clk), WithLazyCleanup())
+	r.NoError(err)
+	cache.Close()
+
+	select {
+	case <-cache.stopCh:
+	default:
+		t.Fatal("expected Close to close stopCh")
+	}
+
+	// the first expiring write takes the closed path of startCleanupLocked,
+	// which marks cleanup as started without running the goroutine
+	r.NoError(cache.SetWithTTL("a", 1, time.Second))
+	r.True(cache.cleanupStarted)
+	r.Zero(clk.Pending())
+}
@@ -0,0 +1,36 @@
+package lru
+
+// SetIfAbsent stores value under key using the default TTL only if key is
+// absent or expired, and reports whether it did. The check and the write
+// happen under one lock, so of several concurrent calls for a key exactly one
+// succeeds. With WithTombstones it returns ErrRecentlyDeleted for keys deleted
+// within the window.
+func (c *Cache[K, V]) SetIfAbsent(key K, value V) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	if c.liveLocked(key, now) != nil {
+		return false, nil
+	}
+	if c.buriedLocked(key, now) {
+		return false, ErrRecentlyDeleted
+	}
+	c.setLocked(key, value, 0, now)
+	return true, nil
+}
+
+// Replace stores value under key using the default TTL only if key is
+// present and not expired, and reports whether it did. Like Set, it marks the
+// entry as recently used and restarts its TTL.
+func (c *Cache[K, V]) Replace(key K, value V) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	if c.liveLocked(key, now) == nil {
+		return false
+	}
+	c.setLocked(key, value, 0, now)
+	return true
+}
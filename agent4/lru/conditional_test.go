@@ -0,0 +1,87 @@
+package lru
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCacheSetIfAbsent(t *testing.T) {
+	r := require.New(t)
+	now := time.Unix(0, 0)
+	c, err := New[string, int](2, WithDefaultTTL(time.Minute), WithTombstones(time.Second), WithClock(func() time.Time { return now }))
+	r.NoError(err)
+	defer c.Close()
+
+	added, err := c.SetIfAbsent("a", 1)
+	r.NoError(err)
+	r.True(added)
+	added, err = c.SetIfAbsent("a", 2)
+	r.NoError(err)
+	r.False(added)
+	v, _ := c.Get("a")
+	r.Equal(1, v)
+
+	// an expired entry counts as absent
+	now = now.Add(2 * time.Minute)
+	added, err = c.SetIfAbsent("a", 3)
+	r.NoError(err)
+	r.True(added)
+	v, _ = c.Get("a")
+	r.Equal(3, v)
+
+	c.Delete("a")
+	added, err = c.SetIfAbsent("a", 4)
+	r.ErrorIs(err, ErrRecentlyDeleted)
+	r.False(added)
+}
+
+func TestCacheSetIfAbsentConcurrent(t *testing.T) {
+	r := require.New(t)
+	c, err := New[string, int](2)
+	r.NoError(err)
+	defer c.Close()
+
+	var added atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if ok, _ := c.SetIfAbsent("key", i); ok {
+				added.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	r.Equal(int32(1), added.Load())
+}
+
+func TestCacheReplace(t *testing.T) {
+	r := require.New(t)
+	now := time.Unix(0, 0)
+	c, err := New[string, int](2, WithDefaultTTL(time.Minute), WithClock(func() time.Time { return now }))
+	r.NoError(err)
+	defer c.Close()
+
+	r.False(c.Replace("a", 1))
+	_, ok := c.Peek("a")
+	r.False(ok)
+
+	r.NoError(c.Set("a", 1))
+	r.NoError(c.Set("b", 2))
+	now = now.Add(50 * time.Second)
+	r.True(c.Replace("a", 10))
+
+	// Replace restarts the TTL and marks a as recently used
+	now = now.Add(50 * time.Second)
+	v, ok := c.Peek("a")
+	r.True(ok)
+	r.Equal(10, v)
+	r.False(c.Replace("b", 20))
+	_, ok = c.Peek("b")
+	r.False(ok)
+}
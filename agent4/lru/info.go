@@ -0,0 +1,43 @@
+package lru
+
+import "time"
+
+// EntryInfo describes a cache entry at the time it was read.
+type EntryInfo struct {
+	// InsertedAt is when the key was added. Overwriting an existing key keeps
+	// the original time.
+	InsertedAt time.Time
+	// LastAccessedAt is when the entry was last read by Get or GetWithInfo,
+	// including the read that produced this EntryInfo.
+	LastAccessedAt time.Time
+	// RemainingTTL is the time left before the entry expires, or zero if it
+	// does not expire.
+	RemainingTTL time.Duration
+	// AccessCount is the number of reads by Get or GetWithInfo since the key
+	// was added, including the read that produced this EntryInfo.
+	AccessCount uint64
+}
+
+// GetWithInfo retrieves the value for key like Get and also returns metadata
+// about the entry. The zero EntryInfo is returned on a miss.
+func (c *Cache[K, V]) GetWithInfo(key K) (V, EntryInfo, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	sl := c.getLocked(key, now)
+	if sl == nil {
+		var zero V
+		return zero, EntryInfo{}, false
+	}
+
+	info := EntryInfo{
+		InsertedAt:     time.Unix(0, sl.insertedAt),
+		LastAccessedAt: time.Unix(0, sl.accessedAt),
+		AccessCount:    sl.accesses,
+	}
+	if sl.expiresAt != 0 {
+		info.RemainingTTL = time.Duration(sl.expiresAt - now.UnixNano())
+	}
+	return sl.value, info, true
+}
@@ -0,0 +1,79 @@
+package lru
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCacheGetWithInfo(t *testing.T) {
+	r := require.New(t)
+	start := time.Unix(1000, 0)
+	now := start
+	c, err := New[string, int](2, WithClock(func() time.Time { return now }))
+	r.NoError(err)
+	defer c.Close()
+
+	_, info, ok := c.GetWithInfo("missing")
+	r.False(ok)
+	r.Equal(EntryInfo{}, info)
+
+	r.NoError(c.SetWithTTL("a", 1, time.Minute))
+	r.NoError(c.Set("forever", 2))
+
+	now = now.Add(10 * time.Second)
+	_, ok = c.Get("a")
+	r.True(ok)
+	_, ok = c.Peek("a") // Peek does not count as an access
+	r.True(ok)
+
+	now = now.Add(5 * time.Second)
+	v, info, ok := c.GetWithInfo("a")
+	r.True(ok)
+	r.Equal(1, v)
+	r.Equal(EntryInfo{
+		InsertedAt:     start,
+		LastAccessedAt: now,
+		RemainingTTL:   45 * time.Second,
+		AccessCount:    2,
+	}, info)
+
+	// Overwriting keeps the insertion time and access history.
+	now = now.Add(time.Second)
+	r.NoError(c.SetWithTTL("a", 3, time.Minute))
+	_, info, ok = c.GetWithInfo("a")
+	r.True(ok)
+	r.Equal(start, info.InsertedAt)
+	r.Equal(uint64(3), info.AccessCount)
+	r.Equal(time.Minute, info.RemainingTTL)
+
+	_, info, ok = c.GetWithInfo("forever")
+	r.True(ok)
+	r.Zero(info.RemainingTTL)
+	r.Equal(uint64(1), info.AccessCount)
+
+	now = now.Add(2 * time.Minute)
+	_, _, ok = c.GetWithInfo("a")
+	r.False(ok)
+}
+
+func TestCacheGetWithInfoResetsOnReuse(t *testing.T) {
+	r := require.New(t)
+	now := time.Unix(1000, 0)
+	c, err := New[string, int](1, WithClock(func() time.Time { return now }))
+	r.NoError(err)
+	defer c.Close()
+
+	r.NoError(c.Set("a", 1))
+	_, ok := c.Get("a")
+	r.True(ok)
+
+	// b evicts a; its metadata starts fresh.
+	now = now.Add(time.Second)
+	r.NoError(c.Set("b", 2))
+	_, info, ok := c.GetWithInfo("b")
+	r.True(ok)
+	r.Equal(now, info.InsertedAt)
+	r.Equal(uint64(1), info.AccessCount)
+}
@@ -0,0 +1,183 @@
+package lru
+
+import (
+	"bytes"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// CachedResponse is a rendered HTTP response stored by Middleware.
+type CachedResponse struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// DefaultMiddlewareCapacity is how many responses Middleware keeps; the least
+// recently served are evicted first.
+const DefaultMiddlewareCapacity = 1024
+
+// Middleware serves GET and HEAD requests from its own cache when keyFn returns a
+// key and stores successful GET responses rendered by next under that key for ttl.
+// The cache holds DefaultMiddlewareCapacity responses; use MiddlewareWithCache to
+// choose its size or other options. A ttl of zero means responses do not expire.
+//
+// Responses are only stored when next answers with a 2xx status and the response
+// as sent neither sets a cookie nor is marked no-store or private, whether next
+// or an outer handler set those headers. Only the headers next sets or changes
+// are stored, so headers added by outer middleware before next runs, such as
+// request IDs, are not replayed to later clients. Headers and body are copied
+// both when storing and when replaying, so handlers and callers never share them.
+// The cache runs no background cleanup: expired responses are dropped when next
+// requested or evicted.
+func Middleware(next http.Handler, keyFn func(*http.Request) (string, bool), ttl time.Duration) http.Handler {
+	cfg := defaultConfig()
+	cfg.cleanup = cleanupOff
+	return MiddlewareWithCache(next, keyFn, newCache[string, CachedResponse](DefaultMiddlewareCapacity, cfg), ttl)
+}
+
+// MiddlewareWithCache is like Middleware but stores responses in cache, which
+// sets the capacity, clock and cleanup behaviour. A ttl of zero then applies
+// the cache's default TTL. The cache should not be shared with other handlers.
+func MiddlewareWithCache(next http.Handler, keyFn func(*http.Request) (string, bool), cache *Cache[string, CachedResponse], ttl time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key, ok := keyFn(r)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if cached, ok := cache.Get(key); ok {
+			cached.writeTo(w, r.Method != http.MethodHead)
+			return
+		}
+
+		if r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK, before: w.Header().Clone()}
+		next.ServeHTTP(rec, r)
+		rec.captureHeader()
+
+		if !rec.cacheable() {
+			return
+		}
+
+		_ = cache.SetWithTTL(key, CachedResponse{
+			Status: rec.status,
+			Header: rec.header,
+			Body:   bytes.Clone(rec.body.Bytes()),
+		}, ttl)
+	})
+}
+
+func (cr CachedResponse) writeTo(w http.ResponseWriter, withBody bool) {
+	header := w.Header()
+	for name, values := range cr.Header {
+		header[name] = append([]string(nil), values...)
+	}
+	w.WriteHeader(cr.Status)
+	if withBody {
+		_, _ = w.Write(cr.Body)
+	}
+}
+
+// responseRecorder passes writes through to the client while keeping a copy of
+// the status, body and the headers the handler set. It forwards Flush, and Unwrap
+// lets http.ResponseController reach the underlying writer's other optional
+// methods.
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+	// before is the header as it was when the handler was called, and header
+	// what the handler set on top of it, once the header has been sent.
+	before http.Header
+	header http.Header
+	// shareable reports whether the full header as sent allows storing the
+	// response for other clients.
+	shareable bool
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.wroteHeader {
+		return
+	}
+	r.wroteHeader = true
+	r.status = status
+	r.captureHeader()
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// captureHeader records the headers whose values differ from before and
+// whether the full header permits caching. Only the first call has an effect,
+// since later changes are not sent to the client.
+func (r *responseRecorder) captureHeader() {
+	if r.header != nil {
+		return
+	}
+	sent := r.ResponseWriter.Header()
+	r.shareable = shareable(sent)
+	r.header = make(http.Header)
+	for name, values := range sent {
+		if !slices.Equal(values, r.before[name]) {
+			r.header[name] = slices.Clone(values)
+		}
+	}
+}
+
+func (r *responseRecorder) Write(p []byte) (int, error) {
+	if !r.wroteHeader {
+		r.WriteHeader(http.StatusOK)
+	}
+	r.body.Write(p)
+	return r.ResponseWriter.Write(p)
+}
+
+// Flush sends buffered data to the client if the underlying writer supports it.
+func (r *responseRecorder) Flush() {
+	if !r.wroteHeader {
+		r.WriteHeader(http.StatusOK)
+	}
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying ResponseWriter.
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+func (r *responseRecorder) cacheable() bool {
+	return r.status >= 200 && r.status < 300 && r.shareable
+}
+
+// shareable reports whether a response with header may be replayed to other
+// clients: it must not set a cookie or be marked no-store or private.
+func shareable(header http.Header) bool {
+	if header.Get("Set-Cookie") != "" {
+		return false
+	}
+
+	for _, value := range header.Values("Cache-Control") {
+		for _, directive := range strings.Split(value, ",") {
+			switch strings.ToLower(strings.TrimSpace(directive)) {
+			case "no-store", "private":
+				return false
+			}
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,263 @@
+package lru
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMiddleware(t *testing.T) {
+	tests := map[string]struct {
+		handler    func(w http.ResponseWriter, r *http.Request)
+		method     string
+		path       string
+		wantCalls  int
+		wantStatus int
+		wantBody   string
+	}{
+		"caches successful get": {
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/plain")
+				_, _ = io.WriteString(w, "hello")
+			},
+			method:     http.MethodGet,
+			path:       "/page",
+			wantCalls:  1,
+			wantStatus: http.StatusOK,
+			wantBody:   "hello",
+		},
+		"skips requests without key": {
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = io.WriteString(w, "nokey")
+			},
+			method:     http.MethodGet,
+			path:       "/private",
+			wantCalls:  2,
+			wantStatus: http.StatusOK,
+			wantBody:   "nokey",
+		},
+		"skips error responses": {
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "boom", http.StatusInternalServerError)
+			},
+			method:     http.MethodGet,
+			path:       "/page",
+			wantCalls:  2,
+			wantStatus: http.StatusInternalServerError,
+			wantBody:   "boom\n",
+		},
+		"skips responses setting cookies": {
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc"})
+				_, _ = io.WriteString(w, "cookie")
+			},
+			method:     http.MethodGet,
+			path:       "/page",
+			wantCalls:  2,
+			wantStatus: http.StatusOK,
+			wantBody:   "cookie",
+		},
+		"skips no-store responses": {
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Cache-Control", "max-age=0, no-store")
+				_, _ = io.WriteString(w, "fresh")
+			},
+			method:     http.MethodGet,
+			path:       "/page",
+			wantCalls:  2,
+			wantStatus: http.StatusOK,
+			wantBody:   "fresh",
+		},
+		"passes through unsafe methods": {
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = io.WriteString(w, "posted")
+			},
+			method:     http.MethodPost,
+			path:       "/page",
+			wantCalls:  2,
+			wantStatus: http.StatusOK,
+			wantBody:   "posted",
+		},
+	}
+
+	keyFn := func(r *http.Request) (string, bool) {
+		if r.URL.Path == "/private" {
+			return "", false
+		}
+		return r.URL.Path, true
+	}
+
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			r := require.New(t)
+
+			calls := 0
+			next := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				calls++
+				tc.handler(w, req)
+			})
+			handler := Middleware(next, keyFn, time.Minute)
+
+			for i := 0; i < 2; i++ {
+				rec := httptest.NewRecorder()
+				handler.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.path, nil))
+				r.Equal(tc.wantStatus, rec.Code)
+				r.Equal(tc.wantBody, rec.Body.String())
+			}
+			r.Equal(tc.wantCalls, calls)
+		})
+	}
+}
+
+func TestMiddlewareReplaysHeadersAndHead(t *testing.T) {
+	r := require.New(t)
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = io.WriteString(w, `{"ok":true}`)
+	})
+	handler := Middleware(next, func(req *http.Request) (string, bool) {
+		return req.URL.Path, true
+	}, time.Minute)
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/x", nil))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/x", nil))
+	r.Equal(http.StatusCreated, rec.Code)
+	r.Equal("application/json", rec.Header().Get("Content-Type"))
+	r.Equal(`{"ok":true}`, rec.Body.String())
+
+	// Mutating a replayed header must not leak into the cached copy.
+	rec.Header().Set("Content-Type", "text/plain")
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodHead, "/x", nil))
+	r.Equal(http.StatusCreated, rec.Code)
+	r.Equal("application/json", rec.Header().Get("Content-Type"))
+	r.Empty(rec.Body.String())
+}
+
+func TestMiddlewareFlushes(t *testing.T) {
+	r := require.New(t)
+
+	var unwrapped http.ResponseWriter
+	next := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, _ = io.WriteString(w, "partial")
+		w.(http.Flusher).Flush()
+		unwrapped = w.(interface{ Unwrap() http.ResponseWriter }).Unwrap()
+		_, _ = io.WriteString(w, " rest")
+	})
+	handler := Middleware(next, func(req *http.Request) (string, bool) {
+		return req.URL.Path, true
+	}, time.Minute)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/stream", nil))
+	r.True(rec.Flushed)
+	r.Same(rec, unwrapped)
+	r.Equal("partial rest", rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/stream", nil))
+	r.Equal("partial rest", rec.Body.String())
+}
+
+func TestMiddlewareStoresOnlyHandlerHeaders(t *testing.T) {
+	r := require.New(t)
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("Vary", "Accept")
+		_, _ = io.WriteString(w, "hello")
+	})
+	handler := Middleware(next, func(req *http.Request) (string, bool) {
+		return req.URL.Path, true
+	}, time.Minute)
+	// outer stands in for middleware that sets per-request headers first.
+	outer := func(requestID string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Set("X-Request-Id", requestID)
+			w.Header().Set("Vary", "Origin")
+			handler.ServeHTTP(w, req)
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	outer("first").ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/x", nil))
+	r.Equal("first", rec.Header().Get("X-Request-Id"))
+
+	rec = httptest.NewRecorder()
+	outer("second").ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/x", nil))
+	r.Equal("hello", rec.Body.String())
+	r.Equal("second", rec.Header().Get("X-Request-Id"))
+	r.Equal("text/plain", rec.Header().Get("Content-Type"))
+	// next changed Vary, so its value is replayed.
+	r.Equal("Accept", rec.Header().Get("Vary"))
+}
+
+func TestMiddlewareWithCache(t *testing.T) {
+	r := require.New(t)
+
+	cache, err := New[string, CachedResponse](1, WithoutCleanup())
+	r.NoError(err)
+	calls := 0
+	next := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		calls++
+		_, _ = io.WriteString(w, req.URL.Path)
+	})
+	handler := MiddlewareWithCache(next, func(req *http.Request) (string, bool) {
+		return req.URL.Path, true
+	}, cache, time.Minute)
+
+	for _, path := range []string{"/a", "/a", "/b", "/a"} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		r.Equal(path, rec.Body.String())
+	}
+	// capacity 1: /b evicts /a, so the last request renders again
+	r.Equal(3, calls)
+	r.Equal(1, cache.Len())
+}
+
+func TestMiddlewareHonoursOuterHeaders(t *testing.T) {
+	tests := map[string]struct {
+		name, value string
+	}{
+		"private":    {"Cache-Control", "private"},
+		"no-store":   {"Cache-Control", "no-store"},
+		"set-cookie": {"Set-Cookie", "session=abc"},
+	}
+
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			r := require.New(t)
+
+			calls := 0
+			next := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				calls++
+				_, _ = io.WriteString(w, "per-user")
+			})
+			handler := Middleware(next, func(req *http.Request) (string, bool) {
+				return req.URL.Path, true
+			}, time.Minute)
+			// outer marks every response before next runs; next leaves it alone
+			outer := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				w.Header().Set(tc.name, tc.value)
+				handler.ServeHTTP(w, req)
+			})
+
+			for i := 0; i < 2; i++ {
+				outer.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/me", nil))
+			}
+			r.Equal(2, calls)
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package lru
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+)
+
+// ErrPrefixUnsupported indicates that WithPrefixIndex was used with a key type
+// whose underlying type is not string.
+var ErrPrefixUnsupported = errors.New("lru: prefix index requires string keys")
+
+// WithPrefixIndex maintains an auxiliary index over keys so DeletePrefix runs
+// in time proportional to the number of matching entries instead of scanning
+// the whole cache. It requires a key type whose underlying type is string.
+func WithPrefixIndex() Option {
+	return func(cfg *config) {
+		cfg.prefixIndex = true
+	}
+}
+
+// DeletePrefix removes every entry whose key starts with prefix and returns
+// the number of entries removed. Without WithPrefixIndex it falls back to a
+// full scan. For key types that are not string-like it removes nothing. With
+// WithTombstones each removed key is left a tombstone.
+func (c *Cache[K, V]) DeletePrefix(prefix K) int {
+	if c.keyString == nil {
+		return 0
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	p := c.keyString(prefix)
+
+	var matched []handle
+	if c.prefixes != nil {
+		matched = c.prefixes.Collect(p)
+	} else {
+		for h := c.store.head; h != nilHandle; {
+			sl := c.store.at(h)
+			if strings.HasPrefix(c.keyString(sl.key), p) {
+				matched = append(matched, h)
+			}
+			h = sl.next
+		}
+	}
+
+	now := c.now()
+	for _, h := range matched {
+		c.buryLocked(c.store.at(h).key, now)
+		c.removeLocked(h)
+	}
+	return len(matched)
+}
+
+// stringKeyFunc returns a conversion from K to string when K's underlying
+// type is string, and nil otherwise.
+func stringKeyFunc[K comparable]() func(K) string {
+	if reflect.TypeFor[K]().Kind() != reflect.String {
+		return nil
+	}
+	return func(key K) string {
+		if s, ok := any(key).(string); ok {
+			return s
+		}
+		return reflect.ValueOf(key).String()
+	}
+}
@@ -0,0 +1,78 @@
+package lru
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type entityID string
+
+func TestDeletePrefix(t *testing.T) {
+	tests := map[string]struct {
+		options []Option
+	}{
+		"scan":    {},
+		"indexed": {options: []Option{WithPrefixIndex()}},
+	}
+
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			r := require.New(t)
+
+			cache, err := New[string, int](10, tc.options...)
+			r.NoError(err)
+			defer cache.Close()
+
+			for i, key := range []string{"user:1", "user:1:posts", "user:12", "user:2", "org:1"} {
+				r.NoError(cache.Set(key, i))
+			}
+
+			r.Equal(3, cache.DeletePrefix("user:1"))
+			r.Equal(2, cache.Len())
+
+			_, ok := cache.Get("user:2")
+			r.True(ok)
+			_, ok = cache.Get("user:12")
+			r.False(ok)
+
+			r.Equal(0, cache.DeletePrefix("missing"))
+			r.Equal(2, cache.DeletePrefix(""))
+			r.Equal(0, cache.Len())
+		})
+	}
+}
+
+func TestPrefixIndexTracksRemovals(t *testing.T) {
+	r := require.New(t)
+
+	cache, err := New[entityID, int](2, WithPrefixIndex())
+	r.NoError(err)
+	defer cache.Close()
+
+	r.NoError(cache.Set("a:1", 1))
+	r.NoError(cache.Set("a:2", 2))
+	r.NoError(cache.Set("b:1", 3)) // evicts a:1
+	r.True(cache.Delete("a:2"))
+
+	r.Equal(0, cache.DeletePrefix("a"))
+	r.Empty(cache.prefixes.Collect("a"))
+
+	r.Equal(1, cache.DeletePrefix("b:"))
+	r.Empty(cache.prefixes.Collect(""))
+}
+
+func TestPrefixIndexRequiresStringKeys(t *testing.T) {
+	r := require.New(t)
+
+	_, err := New[int, int](2, WithPrefixIndex())
+	r.ErrorIs(err, ErrPrefixUnsupported)
+
+	cache, err := New[int, int](2)
+	r.NoError(err)
+	defer cache.Close()
+
+	r.NoError(cache.Set(10, 1))
+	r.Equal(0, cache.DeletePrefix(1))
+}
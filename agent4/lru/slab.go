@@ -0,0 +1,120 @@
+package lru
+
+// handle identifies a slot in a slabStore. Handles stay valid until the slot
+// is released, and a released slot may be handed out again.
+type handle int32
+
+// nilHandle marks the absence of a slot, e.g. the ends of the recency list.
+const nilHandle handle = -1
+
+// maxSlabSize bounds how many slots are allocated at once.
+const maxSlabSize = 4096
+
+// slot holds one cache entry. Expiry is kept as Unix nanoseconds rather than
+// a time.Time so that, for pointer-free K and V, slots contain no pointers at
+// all and the garbage collector does not need to scan the slabs.
+type slot[K comparable, V any] struct {
+	key       K
+	value     V
+	expiresAt int64 // 0 means the entry does not expire
+	// insertedAt and accessedAt are Unix nanoseconds; accessedAt is 0 until
+	// the first read.
+	insertedAt int64
+	accessedAt int64
+	accesses   uint64
+	prev       handle
+	next       handle
+}
+
+// slabStore keeps entries in fixed-size slabs addressed by integer handles and
+// threads them into a doubly linked recency list, most recent at head. Freed
+// slots are reused before new slabs are allocated, so a full cache performs
+// no further allocations for its entries. It is not safe for concurrent use.
+type slabStore[K comparable, V any] struct {
+	slabs    [][]slot[K, V]
+	slabSize int
+	next     int    // first never-used slot
+	free     handle // released slots, linked through next
+	head     handle
+	tail     handle
+	len      int
+}
+
+func newSlabStore[K comparable, V any](capacity int) *slabStore[K, V] {
+	size := capacity + 1 // an insert briefly holds one entry over capacity
+	if size > maxSlabSize {
+		size = maxSlabSize
+	}
+	return &slabStore[K, V]{
+		slabSize: size,
+		free:     nilHandle,
+		head:     nilHandle,
+		tail:     nilHandle,
+	}
+}
+
+func (s *slabStore[K, V]) at(h handle) *slot[K, V] {
+	return &s.slabs[int(h)/s.slabSize][int(h)%s.slabSize]
+}
+
+// alloc returns an unlinked slot for a new entry.
+func (s *slabStore[K, V]) alloc() handle {
+	if s.free != nilHandle {
+		h := s.free
+		s.free = s.at(h).next
+		return h
+	}
+
+	if s.next == len(s.slabs)*s.slabSize {
+		s.slabs = append(s.slabs, make([]slot[K, V], s.slabSize))
+	}
+	h := handle(s.next)
+	s.next++
+	return h
+}
+
+// release returns an unlinked slot to the free list, clearing it so the
+// previous key and value can be collected.
+func (s *slabStore[K, V]) release(h handle) {
+	sl := s.at(h)
+	*sl = slot[K, V]{prev: nilHandle, next: s.free}
+	s.free = h
+}
+
+func (s *slabStore[K, V]) pushFront(h handle) {
+	sl := s.at(h)
+	sl.prev = nilHandle
+	sl.next = s.head
+	if s.head != nilHandle {
+		s.at(s.head).prev = h
+	} else {
+		s.tail = h
+	}
+	s.head = h
+	s.len++
+}
+
+func (s *slabStore[K, V]) unlink(h handle) {
+	sl := s.at(h)
+	if sl.prev != nilHandle {
+		s.at(sl.prev).next = sl.next
+	} else {
+		s.head = sl.next
+	}
+	if sl.next != nilHandle {
+		s.at(sl.next).prev = sl.prev
+	} else {
+		s.tail = sl.prev
+	}
+	sl.prev = nilHandle
+	sl.next = nilHandle
+	s.len--
+}
+
+func (s *slabStore[K, V]) moveToFront(h handle) {
+	if s.head == h {
+		return
+	}
+	s.unlink(h)
+	s.pushFront(h)
+}
@@ -0,0 +1,59 @@
+package lru
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlabStoreReusesSlots(t *testing.T) {
+	r := require.New(t)
+
+	cache, err := New[int, *int](3)
+	r.NoError(err)
+	defer cache.Close()
+
+	for i := 0; i < 1000; i++ {
+		v := i
+		r.NoError(cache.Set(i, &v))
+	}
+
+	r.Equal(3, cache.Len())
+	r.Len(cache.store.slabs, 1)
+	r.Equal(4, cache.store.next, "slots beyond capacity+1 should never be handed out")
+
+	// Released slots must not retain their values.
+	for h := cache.store.free; h != nilHandle; h = cache.store.at(h).next {
+		r.Nil(cache.store.at(h).value)
+	}
+
+	for i := 997; i < 1000; i++ {
+		v, ok := cache.Get(i)
+		r.True(ok)
+		r.Equal(i, *v)
+	}
+}
+
+func TestSlabStoreSpansSlabs(t *testing.T) {
+	r := require.New(t)
+
+	capacity := 2*maxSlabSize + 10
+	cache, err := New[int, int](capacity)
+	r.NoError(err)
+	defer cache.Close()
+
+	for i := 0; i < capacity; i++ {
+		r.NoError(cache.Set(i, i))
+	}
+	r.Len(cache.store.slabs, 3)
+
+	for _, key := range []int{0, maxSlabSize, capacity - 1} {
+		v, ok := cache.Get(key)
+		r.True(ok)
+		r.Equal(key, v)
+	}
+
+	r.True(cache.Delete(maxSlabSize))
+	r.NoError(cache.Set(-1, -1))
+	r.Len(cache.store.slabs, 3)
+}
@@ -0,0 +1,88 @@
+package lru
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrRecentlyDeleted is returned by Set and SetWithTTL when the key was
+// deleted within the tombstone window configured by WithTombstones. The value
+// is not stored.
+var ErrRecentlyDeleted = errors.New("lru: key was recently deleted")
+
+// WithTombstones makes Delete and DeletePrefix leave a tombstone for each
+// deleted key that lasts for window. While a tombstone is live, writes to the
+// key fail with ErrRecentlyDeleted instead of re-creating the entry, so a slow
+// refresher that loaded data before an invalidation cannot repopulate the
+// cache with it. A non-positive window disables tombstones.
+func WithTombstones(window time.Duration) Option {
+	return func(cfg *config) {
+		cfg.tombstoneWindow = window
+	}
+}
+
+// tombstone records when a deletion stops blocking writes. Tombstones are
+// queued in deletion order, which is also expiry order because every
+// tombstone lives for the same window.
+type tombstone[K comparable] struct {
+	key       K
+	expiresAt int64
+}
+
+// ClearTombstone removes the tombstone for key, allowing writes to it again
+// before the window ends. It reports whether a live tombstone was removed.
+func (c *Cache[K, V]) ClearTombstone(key K) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.purgeTombstonesLocked(c.now())
+	if _, ok := c.tombstones[key]; !ok {
+		return false
+	}
+	delete(c.tombstones, key)
+	return true
+}
+
+func (c *Cache[K, V]) buryLocked(key K, now time.Time) {
+	if c.tombstoneWindow <= 0 {
+		return
+	}
+
+	c.purgeTombstonesLocked(now)
+	expiresAt := now.Add(c.tombstoneWindow).UnixNano()
+	c.tombstones[key] = expiresAt
+	c.graveyard = append(c.graveyard, tombstone[K]{key: key, expiresAt: expiresAt})
+}
+
+func (c *Cache[K, V]) buriedLocked(key K, now time.Time) bool {
+	if c.tombstoneWindow <= 0 {
+		return false
+	}
+
+	c.purgeTombstonesLocked(now)
+	_, ok := c.tombstones[key]
+	return ok
+}
+
+// purgeTombstonesLocked drops tombstones whose window has passed. A key that
+// was deleted again has a newer queue entry, so an older one only removes the
+// map entry when the expiry times match.
+func (c *Cache[K, V]) purgeTombstonesLocked(now time.Time) {
+	cutoff := now.UnixNano()
+	i := 0
+	for ; i < len(c.graveyard) && c.graveyard[i].expiresAt <= cutoff; i++ {
+		ts := c.graveyard[i]
+		if exp, ok := c.tombstones[ts.key]; ok && exp == ts.expiresAt {
+			delete(c.tombstones, ts.key)
+		}
+	}
+	if i == 0 {
+		return
+	}
+
+	clear(c.graveyard[:i])
+	c.graveyard = c.graveyard[i:]
+	if len(c.graveyard) == 0 {
+		c.graveyard = nil
+	}
+}
@@ -0,0 +1,118 @@
+package lru
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTombstones(t *testing.T) {
+	tests := map[string]struct {
+		run func(r *require.Assertions, c *Cache[string, int], advance func(time.Duration))
+	}{
+		"write within window is rejected": {
+			run: func(r *require.Assertions, c *Cache[string, int], advance func(time.Duration)) {
+				r.NoError(c.Set("a", 1))
+				r.True(c.Delete("a"))
+				advance(time.Second)
+				r.ErrorIs(c.Set("a", 2), ErrRecentlyDeleted)
+				_, ok := c.Get("a")
+				r.False(ok)
+			},
+		},
+		"write after window succeeds": {
+			run: func(r *require.Assertions, c *Cache[string, int], advance func(time.Duration)) {
+				r.False(c.Delete("a"))
+				advance(5 * time.Second)
+				r.NoError(c.Set("a", 2))
+				v, ok := c.Get("a")
+				r.True(ok)
+				r.Equal(2, v)
+			},
+		},
+		"delete again extends window": {
+			run: func(r *require.Assertions, c *Cache[string, int], advance func(time.Duration)) {
+				c.Delete("a")
+				advance(3 * time.Second)
+				c.Delete("a")
+				advance(3 * time.Second)
+				r.ErrorIs(c.SetWithTTL("a", 1, time.Minute), ErrRecentlyDeleted)
+				advance(2 * time.Second)
+				r.NoError(c.Set("a", 1))
+			},
+		},
+		"clear tombstone allows write": {
+			run: func(r *require.Assertions, c *Cache[string, int], advance func(time.Duration)) {
+				c.Delete("a")
+				r.True(c.ClearTombstone("a"))
+				r.False(c.ClearTombstone("a"))
+				r.NoError(c.Set("a", 1))
+			},
+		},
+		"delete prefix buries removed keys": {
+			run: func(r *require.Assertions, c *Cache[string, int], advance func(time.Duration)) {
+				r.NoError(c.Set("user:1", 1))
+				r.NoError(c.Set("user:2", 2))
+				r.Equal(2, c.DeletePrefix("user:"))
+				r.ErrorIs(c.Set("user:1", 1), ErrRecentlyDeleted)
+				r.NoError(c.Set("user:3", 3))
+			},
+		},
+		"other keys are unaffected": {
+			run: func(r *require.Assertions, c *Cache[string, int], advance func(time.Duration)) {
+				c.Delete("a")
+				r.NoError(c.Set("b", 1))
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			r := require.New(t)
+			now := time.Unix(0, 0)
+			c, err := New[string, int](4,
+				WithTombstones(5*time.Second),
+				WithClock(func() time.Time { return now }),
+			)
+			r.NoError(err)
+			t.Cleanup(c.Close)
+
+			tc.run(r, c, func(d time.Duration) { now = now.Add(d) })
+		})
+	}
+}
+
+func TestTombstonesDisabledByDefault(t *testing.T) {
+	r := require.New(t)
+	c, err := New[string, int](4)
+	r.NoError(err)
+	t.Cleanup(c.Close)
+
+	r.NoError(c.Set("a", 1))
+	r.True(c.Delete("a"))
+	r.NoError(c.Set("a", 2))
+	r.False(c.ClearTombstone("a"))
+}
+
+func TestTombstonesPurged(t *testing.T) {
+	r := require.New(t)
+	now := time.Unix(0, 0)
+	c, err := New[int, int](4,
+		WithTombstones(time.Second),
+		WithClock(func() time.Time { return now }),
+	)
+	r.NoError(err)
+	t.Cleanup(c.Close)
+
+	for i := 0; i < 100; i++ {
+		c.Delete(i)
+	}
+	r.Len(c.tombstones, 100)
+
+	now = now.Add(2 * time.Second)
+	c.removeExpiredEntries()
+	r.Empty(c.tombstones)
+	r.Empty(c.graveyard)
+}
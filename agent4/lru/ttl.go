@@ -0,0 +1,70 @@
+package lru
+
+import "time"
+
+// Touch restarts key's TTL from now using the default TTL, as if it had just
+// been written with Set, without changing its value or recency. Without a
+// default TTL the entry no longer expires. It reports whether key was present
+// and not expired.
+func (c *Cache[K, V]) Touch(key K) bool {
+	ok, _ := c.Expire(key, 0)
+	return ok
+}
+
+// Expire makes key expire ttl from now, replacing its current deadline,
+// without changing its value or recency. A ttl of zero uses the default TTL,
+// like SetWithTTL. It reports whether key was present and not expired, and
+// returns ErrNegativeTTL for a negative ttl.
+func (c *Cache[K, V]) Expire(key K, ttl time.Duration) (bool, error) {
+	if ttl < 0 {
+		return false, ErrNegativeTTL
+	}
+	if ttl == 0 {
+		ttl = c.defaultTTL
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	sl := c.liveLocked(key, now)
+	if sl == nil {
+		return false, nil
+	}
+	sl.expiresAt = 0
+	if ttl > 0 {
+		sl.expiresAt = now.Add(ttl).UnixNano()
+		c.startCleanupLocked()
+	}
+	return true, nil
+}
+
+// Persist removes key's expiry so it stays until evicted or deleted. It
+// reports whether key was present and not expired.
+func (c *Cache[K, V]) Persist(key K) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	sl := c.liveLocked(key, c.now())
+	if sl == nil {
+		return false
+	}
+	sl.expiresAt = 0
+	return true
+}
+
+// liveLocked returns the slot for key without marking it as recently used or
+// recording an access, or nil if key is absent or expired. Expired entries
+// are removed.
+func (c *Cache[K, V]) liveLocked(key K, now time.Time) *slot[K, V] {
+	h, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	sl := c.store.at(h)
+	if c.isExpired(sl, now) {
+		c.expireLocked(h)
+		return nil
+	}
+	return sl
+}
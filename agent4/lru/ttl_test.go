@@ -0,0 +1,90 @@
+package lru
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCacheTouch(t *testing.T) {
+	r := require.New(t)
+	now := time.Unix(0, 0)
+	c, err := New[string, int](2, WithDefaultTTL(time.Minute), WithClock(func() time.Time { return now }))
+	r.NoError(err)
+	defer c.Close()
+
+	r.NoError(c.Set("a", 1))
+	r.NoError(c.Set("b", 2))
+
+	now = now.Add(50 * time.Second)
+	r.True(c.Touch("a"))
+	r.False(c.Touch("missing"))
+
+	// Touch restarts the TTL but leaves a least recently used
+	now = now.Add(50 * time.Second)
+	v, ok := c.Peek("a")
+	r.True(ok)
+	r.Equal(1, v)
+	_, ok = c.Peek("b")
+	r.False(ok)
+
+	r.NoError(c.Set("b", 2))
+	r.NoError(c.Set("c", 3)) // evicts a
+	_, ok = c.Peek("a")
+	r.False(ok)
+
+	now = now.Add(2 * time.Minute)
+	r.False(c.Touch("b"), "an expired entry cannot be touched back to life")
+}
+
+func TestCacheExpireAndPersist(t *testing.T) {
+	r := require.New(t)
+	now := time.Unix(0, 0)
+	c, err := New[string, int](4, WithClock(func() time.Time { return now }))
+	r.NoError(err)
+	defer c.Close()
+
+	r.NoError(c.Set("a", 1))
+	r.NoError(c.SetWithTTL("b", 2, time.Second))
+
+	ok, err := c.Expire("a", time.Minute)
+	r.NoError(err)
+	r.True(ok)
+	_, err = c.Expire("a", -time.Second)
+	r.ErrorIs(err, ErrNegativeTTL)
+	ok, err = c.Expire("missing", time.Minute)
+	r.NoError(err)
+	r.False(ok)
+
+	r.True(c.Persist("b"))
+	r.False(c.Persist("missing"))
+
+	now = now.Add(30 * time.Second)
+	_, info, ok := c.GetWithInfo("a")
+	r.True(ok)
+	r.Equal(30*time.Second, info.RemainingTTL)
+
+	// Expire with zero uses the default TTL, which is none here
+	ok, err = c.Expire("a", 0)
+	r.NoError(err)
+	r.True(ok)
+
+	now = now.Add(time.Hour)
+	r.Equal(2, c.Len())
+}
+
+func TestCacheExpireStartsLazyCleanup(t *testing.T) {
+	r := require.New(t)
+	c, err := New[string, int](2, WithLazyCleanup())
+	r.NoError(err)
+	defer c.Close()
+
+	r.NoError(c.Set("a", 1))
+	r.True(c.Persist("a"))
+	r.False(c.cleanupStarted)
+	ok, err := c.Expire("a", time.Minute)
+	r.NoError(err)
+	r.True(ok)
+	r.True(c.cleanupStarted)
+}
@@ -0,0 +1,112 @@
+package agent5
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+)
+
+// ErrUnsupportedValue is returned by a Codec that cannot encode a value's type.
+var ErrUnsupportedValue = errors.New("agent5: codec does not support value type")
+
+// Codec transforms values as they are stored in and read from the cache.
+// Encode runs on Set and Decode on Get; implementations must be safe for
+// concurrent use.
+type Codec interface {
+	Encode(value interface{}) (interface{}, error)
+	Decode(stored interface{}) (interface{}, error)
+}
+
+// SetCodec installs codec for subsequent writes and all reads. A nil codec
+// stores values as given. Entries written under a previous codec are not
+// re-encoded, so changing codecs on a populated cache should be followed by
+// Clear.
+//
+// When Encode fails, Set stores nothing and returns the zero Handle, and
+// TrySet also returns the error; any previous value for the key is removed so
+// a stale plaintext is never served.
+// When Decode fails, Get reports the key as missing and removes it.
+func (c *Cache) SetCodec(codec Codec) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.codec = codec
+}
+
+// AESGCMCodec encrypts []byte and string values with AES-GCM so they are
+// never held in plaintext by the cache. Each value gets a fresh random nonce.
+// Other value types are rejected with ErrUnsupportedValue.
+type AESGCMCodec struct {
+	aead cipher.AEAD
+}
+
+// sealed is how AESGCMCodec stores a value: the nonce followed by the
+// ciphertext, plus whether the plaintext was a string.
+type sealed struct {
+	data   []byte
+	isText bool
+}
+
+// NewAESGCMCodec returns a codec using key, which must be 16, 24 or 32 bytes
+// to select AES-128, AES-192 or AES-256.
+func NewAESGCMCodec(key []byte) (*AESGCMCodec, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &AESGCMCodec{aead: aead}, nil
+}
+
+// Encode encrypts value.
+func (a *AESGCMCodec) Encode(value interface{}) (interface{}, error) {
+	var (
+		plaintext []byte
+		isText    bool
+	)
+	switch v := value.(type) {
+	case []byte:
+		plaintext = v
+	case string:
+		plaintext = []byte(v)
+		isText = true
+	default:
+		return nil, fmt.Errorf("%w: %T", ErrUnsupportedValue, value)
+	}
+
+	nonceSize := a.aead.NonceSize()
+	buf := make([]byte, nonceSize, nonceSize+len(plaintext)+a.aead.Overhead())
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+	if isText {
+		// The conversion above copied the string; wipe the copy once sealed.
+		defer clear(plaintext)
+	}
+	return sealed{data: a.aead.Seal(buf, buf, plaintext, nil), isText: isText}, nil
+}
+
+// Decode decrypts a value produced by Encode.
+func (a *AESGCMCodec) Decode(stored interface{}) (interface{}, error) {
+	s, ok := stored.(sealed)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T", ErrUnsupportedValue, stored)
+	}
+
+	nonceSize := a.aead.NonceSize()
+	if len(s.data) < nonceSize {
+		return nil, errors.New("agent5: sealed value too short")
+	}
+	plaintext, err := a.aead.Open(nil, s.data[:nonceSize], s.data[nonceSize:], nil)
+	if err != nil {
+		return nil, err
+	}
+	if s.isText {
+		return string(plaintext), nil
+	}
+	return plaintext, nil
+}
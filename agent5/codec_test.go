@@ -0,0 +1,122 @@
+package agent5
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestAESGCMCodec(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, 32)
+
+	tests := map[string]struct {
+		value   interface{}
+		wantErr error
+	}{
+		"string":      {value: "alice@example.com"},
+		"bytes":       {value: []byte("ssn:123-45-6789")},
+		"empty":       {value: ""},
+		"unsupported": {value: 42, wantErr: ErrUnsupportedValue},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			codec, err := NewAESGCMCodec(key)
+			if err != nil {
+				t.Fatalf("NewAESGCMCodec: %v", err)
+			}
+			c := New(4, 0)
+			c.SetCodec(codec)
+
+			h, err := c.TrySet("pii", tc.value)
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("want %v, got %v", tc.wantErr, err)
+				}
+				if h != (Handle{}) {
+					t.Fatalf("want zero handle, got %+v", h)
+				}
+				if _, ok := c.Get("pii"); ok {
+					t.Fatal("expected unsupported value not to be stored")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("TrySet: %v", err)
+			}
+
+			stored := c.items["pii"].Value.(*entry).value
+			s, ok := stored.(sealed)
+			if !ok {
+				t.Fatalf("want sealed value stored, got %T", stored)
+			}
+			var plaintext []byte
+			switch v := tc.value.(type) {
+			case string:
+				plaintext = []byte(v)
+			case []byte:
+				plaintext = v
+			}
+			if len(plaintext) > 0 && bytes.Contains(s.data, plaintext) {
+				t.Fatal("plaintext found in stored value")
+			}
+
+			got, ok := c.Get("pii")
+			if !ok {
+				t.Fatal("expected pii to exist")
+			}
+			if b, isBytes := tc.value.([]byte); isBytes {
+				if !bytes.Equal(got.([]byte), b) {
+					t.Fatalf("want %q, got %q", b, got)
+				}
+			} else if got != tc.value {
+				t.Fatalf("want %v, got %v", tc.value, got)
+			}
+		})
+	}
+}
+
+func TestAESGCMCodec_InvalidKey(t *testing.T) {
+	if _, err := NewAESGCMCodec([]byte("short")); err == nil {
+		t.Fatal("expected error for invalid key length")
+	}
+}
+
+func TestAESGCMCodec_UniqueNonces(t *testing.T) {
+	codec, err := NewAESGCMCodec(bytes.Repeat([]byte{1}, 16))
+	if err != nil {
+		t.Fatalf("NewAESGCMCodec: %v", err)
+	}
+	a, _ := codec.Encode("same")
+	b, _ := codec.Encode("same")
+	if bytes.Equal(a.(sealed).data, b.(sealed).data) {
+		t.Fatal("expected identical plaintexts to encrypt differently")
+	}
+}
+
+func TestCache_CodecFailures(t *testing.T) {
+	codec, err := NewAESGCMCodec(bytes.Repeat([]byte{2}, 16))
+	if err != nil {
+		t.Fatalf("NewAESGCMCodec: %v", err)
+	}
+	c := New(4, 0)
+	c.SetCodec(codec)
+
+	c.Set("k", "secret")
+	if h := c.Set("k", 42); h != (Handle{}) {
+		t.Fatalf("want zero handle from a failed encode, got %+v", h)
+	}
+	if _, ok := c.Get("k"); ok {
+		t.Fatal("expected failed encode to remove the previous value")
+	}
+
+	c.Set("tampered", "secret")
+	s := c.items["tampered"].Value.(*entry).value.(sealed)
+	s.data[len(s.data)-1] ^= 0xff
+	if _, ok := c.Get("tampered"); ok {
+		t.Fatal("expected tampered value to be reported missing")
+	}
+	if c.Len() != 0 {
+		t.Fatalf("want len 0, got %d", c.Len())
+	}
+}
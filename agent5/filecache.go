@@ -0,0 +1,131 @@
+package agent5
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FileInfo is the cached metadata for a file.
+type FileInfo struct {
+	Path    string
+	Size    int64
+	Mode    fs.FileMode
+	ModTime time.Time
+	// Hash is the hex-encoded SHA-256 of the file contents. It is empty
+	// until Hash has been called for the path.
+	Hash string
+}
+
+// FileCache caches file stat results and content hashes keyed by path.
+// Entries live until they are evicted, expire through the cache's TTL, or
+// are dropped with Invalidate, which is meant to be called from a file
+// watcher such as fsnotify.
+type FileCache struct {
+	cache *Cache
+
+	// mu orders stores against Invalidate. gen counts invalidations, so a
+	// lookup that saw an invalidation while it read the file stores nothing.
+	mu  sync.Mutex
+	gen uint64
+
+	// open is os.Open, replaceable in tests.
+	open func(name string) (*os.File, error)
+}
+
+// NewFileCache creates a FileCache backed by cache. The cache's TTL, if any,
+// bounds how long a missed invalidation can serve stale metadata.
+func NewFileCache(cache *Cache) *FileCache {
+	return &FileCache{cache: cache, open: os.Open}
+}
+
+// Stat returns the metadata for path, calling os.Stat on a miss.
+// Errors are not cached.
+func (f *FileCache) Stat(path string) (FileInfo, error) {
+	path = filepath.Clean(path)
+	if v, ok := f.cache.Get(path); ok {
+		return v.(FileInfo), nil
+	}
+
+	gen := f.generation()
+	st, err := os.Stat(path)
+	if err != nil {
+		return FileInfo{}, err
+	}
+	info := FileInfo{
+		Path:    path,
+		Size:    st.Size(),
+		Mode:    st.Mode(),
+		ModTime: st.ModTime(),
+	}
+	f.store(gen, info)
+	return info, nil
+}
+
+// Hash returns the hex-encoded SHA-256 of the contents of path, reading the
+// file only if no hash is cached for it.
+func (f *FileCache) Hash(path string) (string, error) {
+	gen := f.generation()
+	info, err := f.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if info.Hash != "" {
+		return info.Hash, nil
+	}
+
+	file, err := f.open(info.Path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", err
+	}
+	info.Hash = hex.EncodeToString(h.Sum(nil))
+
+	// Only store the hash if path still names the file that was read and it
+	// was not modified meanwhile. The open file keeps matching info after a
+	// rename replaces it, so path is stat'ed again.
+	opened, err := file.Stat()
+	if err != nil {
+		return info.Hash, nil
+	}
+	current, err := os.Stat(info.Path)
+	if err == nil && os.SameFile(opened, current) && current.Size() == info.Size && current.ModTime().Equal(info.ModTime) {
+		f.store(gen, info)
+	}
+	return info.Hash, nil
+}
+
+// Invalidate drops any cached metadata for path. Paths are cleaned, so the
+// names reported by file watchers can be passed directly. Lookups already
+// reading a file when Invalidate is called do not cache what they read.
+func (f *FileCache) Invalidate(path string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.gen++
+	f.cache.Delete(filepath.Clean(path))
+}
+
+func (f *FileCache) generation() uint64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.gen
+}
+
+// store caches info unless Invalidate has been called since gen was read.
+func (f *FileCache) store(gen uint64, info FileInfo) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.gen == gen {
+		f.cache.Set(info.Path, info)
+	}
+}
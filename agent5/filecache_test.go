@@ -0,0 +1,132 @@
+package agent5
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileCache_StatAndHash(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.js")
+	if err := os.WriteFile(path, []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	fc := NewFileCache(New(10, 0))
+
+	info, err := fc.Stat(path)
+	if err != nil {
+		t.Fatalf("stat: %v", err)
+	}
+	if info.Size != 5 {
+		t.Fatalf("want size 5, got %d", info.Size)
+	}
+
+	hash, err := fc.Hash(path)
+	if err != nil {
+		t.Fatalf("hash: %v", err)
+	}
+	const want = "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+	if hash != want {
+		t.Fatalf("want %s, got %s", want, hash)
+	}
+
+	// Changes on disk are not observed until the path is invalidated.
+	if err := os.WriteFile(path, []byte("hello, world"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := fc.Stat(path); info.Size != 5 {
+		t.Fatalf("want cached size 5, got %d", info.Size)
+	}
+	if h, _ := fc.Hash(path); h != want {
+		t.Fatalf("want cached hash, got %s", h)
+	}
+
+	fc.Invalidate(filepath.Join(dir, ".", "app.js"))
+
+	info, err = fc.Stat(path)
+	if err != nil {
+		t.Fatalf("stat: %v", err)
+	}
+	if info.Size != 12 || info.Hash != "" {
+		t.Fatalf("want fresh metadata without hash, got %+v", info)
+	}
+	if h, _ := fc.Hash(path); h == want {
+		t.Fatal("want new hash after invalidation")
+	}
+}
+
+func TestFileCache_ErrorsNotCached(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "missing.css")
+	fc := NewFileCache(New(10, 0))
+
+	if _, err := fc.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("want not-exist error, got %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte("body{}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fc.Stat(path); err != nil {
+		t.Fatalf("want file to be found once created, got %v", err)
+	}
+}
+
+func TestFileCache_HashReplacedWhileReading(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		invalidate bool
+	}{
+		{"invalidated", true},
+		{"event not yet delivered", false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "app.js")
+			if err := os.WriteFile(path, []byte("hello"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			st, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			fc := NewFileCache(New(10, 0))
+			fc.open = func(name string) (*os.File, error) {
+				file, err := os.Open(name)
+				if err != nil {
+					return nil, err
+				}
+				// Rename a same-sized file with the same mtime over path
+				// after it is opened, as an atomic deploy would.
+				tmp := filepath.Join(dir, "app.js.tmp")
+				if err := os.WriteFile(tmp, []byte("world"), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Chtimes(tmp, st.ModTime(), st.ModTime()); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Rename(tmp, name); err != nil {
+					t.Fatal(err)
+				}
+				if tc.invalidate {
+					fc.Invalidate(name)
+				}
+				return file, nil
+			}
+
+			const hello = "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+			if h, err := fc.Hash(path); err != nil || h != hello {
+				t.Fatalf("want hash of the opened file, got %s, %v", h, err)
+			}
+
+			// The stale hash was not cached, so the file is read again.
+			fc.open = os.Open
+			if h, err := fc.Hash(path); err != nil || h == hello {
+				t.Fatalf("want hash of the replacement, got %s, %v", h, err)
+			}
+		})
+	}
+}
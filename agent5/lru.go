@@ -4,12 +4,23 @@ import (
 	"container/list"
 	"sync"
 	"time"
+
+	"github.com/rselbach/agent-comparison/clock"
+	"github.com/rselbach/agent-comparison/metrics"
 )
 
 type entry struct {
 	key       interface{}
 	value     interface{}
 	expiresAt time.Time
+	id        uint64
+}
+
+// Handle identifies a single write made by Set.
+// The zero Handle refers to no entry.
+type Handle struct {
+	key interface{}
+	id  uint64
 }
 
 // Cache is an LRU cache with automatic expiration support.
@@ -19,8 +30,17 @@ type Cache struct {
 	items    map[interface{}]*list.Element
 	lru      *list.List
 	ttl      time.Duration
+	nextID   uint64
+	sweep    int
+	codec    Codec
+	clock    clock.Clock
+	metrics  metrics.Recorder
 }
 
+// DefaultWriteSweep is the number of least recently used entries each Set
+// inspects for expiration.
+const DefaultWriteSweep = 4
+
 // New creates a new LRU cache with the specified capacity and TTL.
 // If ttl is 0, items never expire automatically.
 func New(capacity int, ttl time.Duration) *Cache {
@@ -29,7 +49,39 @@ func New(capacity int, ttl time.Duration) *Cache {
 		items:    make(map[interface{}]*list.Element),
 		lru:      list.New(),
 		ttl:      ttl,
+		sweep:    DefaultWriteSweep,
+		clock:    clock.Real{},
+		metrics:  metrics.Nop{},
+	}
+}
+
+// SetClock sets the clock used to stamp and check expiration times. A nil
+// clock restores real time. Entries already stored keep their expiration.
+func (c *Cache) SetClock(clk clock.Clock) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.clock = clock.OrReal(clk)
+}
+
+// SetRecorder sets the recorder that receives the cache's hits, misses,
+// evictions, expirations and Purge durations. A nil recorder records nothing.
+func (c *Cache) SetRecorder(r metrics.Recorder) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.metrics = metrics.OrNop(r)
+}
+
+// SetWriteSweep sets how many entries from the least recently used end each
+// Set inspects, removing those that have expired. Since the cache has no
+// background janitor, this bounds how long expired entries linger in
+// write-heavy, read-light workloads. Zero disables it.
+func (c *Cache) SetWriteSweep(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if n < 0 {
+		n = 0
 	}
+	c.sweep = n
 }
 
 // Get retrieves a value from the cache.
@@ -40,36 +92,80 @@ func (c *Cache) Get(key interface{}) (interface{}, bool) {
 
 	elem, ok := c.items[key]
 	if !ok {
+		c.metrics.Miss()
 		return nil, false
 	}
 
 	e := elem.Value.(*entry)
 	if c.isExpired(e) {
-		c.removeElement(elem)
+		c.expire(elem)
+		c.metrics.Miss()
 		return nil, false
 	}
 
+	if c.codec != nil {
+		value, err := c.codec.Decode(e.value)
+		if err != nil {
+			c.removeElement(elem)
+			c.metrics.Miss()
+			return nil, false
+		}
+		c.lru.MoveToFront(elem)
+		c.metrics.Hit()
+		return value, true
+	}
+
 	c.lru.MoveToFront(elem)
+	c.metrics.Hit()
 	return e.value, true
 }
 
 // Set adds or updates a value in the cache.
-func (c *Cache) Set(key, value interface{}) {
+// The returned Handle refers to this particular write; see InvalidateHandle.
+// It is TrySet without the error: a value the codec cannot encode yields the
+// zero Handle.
+func (c *Cache) Set(key, value interface{}) Handle {
+	h, _ := c.TrySet(key, value)
+	return h
+}
+
+// TrySet is like Set but returns the codec's error if it cannot encode value.
+// In that case nothing is stored, any previous value for key is removed, and
+// the Handle is the zero Handle.
+func (c *Cache) TrySet(key, value interface{}) (Handle, error) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	c.sweepTail()
+
+	if c.codec != nil {
+		encoded, err := c.codec.Encode(value)
+		if err != nil {
+			if elem, ok := c.items[key]; ok {
+				c.removeElement(elem)
+			}
+			return Handle{}, err
+		}
+		value = encoded
+	}
+
+	c.nextID++
+	h := Handle{key: key, id: c.nextID}
+
 	if elem, ok := c.items[key]; ok {
 		c.lru.MoveToFront(elem)
 		e := elem.Value.(*entry)
 		e.value = value
 		e.expiresAt = c.getExpirationTime()
-		return
+		e.id = h.id
+		return h, nil
 	}
 
 	e := &entry{
 		key:       key,
 		value:     value,
 		expiresAt: c.getExpirationTime(),
+		id:        h.id,
 	}
 
 	elem := c.lru.PushFront(e)
@@ -78,6 +174,7 @@ func (c *Cache) Set(key, value interface{}) {
 	if c.lru.Len() > c.capacity {
 		c.evict()
 	}
+	return h, nil
 }
 
 // Delete removes a key from the cache.
@@ -90,6 +187,46 @@ func (c *Cache) Delete(key interface{}) {
 	}
 }
 
+// InvalidateHandle removes the entry written by the Set call that returned h.
+// It is a no-op, returning false, if that entry has since been evicted,
+// deleted, or replaced by a newer Set under the same key.
+func (c *Cache) InvalidateHandle(h Handle) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[h.key]
+	if !ok || h.id == 0 || elem.Value.(*entry).id != h.id {
+		return false
+	}
+	c.removeElement(elem)
+	return true
+}
+
+// Contains reports whether key is present and not expired. Unlike Get it does
+// not update recency, decode the value, or remove an expired entry.
+func (c *Cache) Contains(key interface{}) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	elem, ok := c.items[key]
+	return ok && !c.isExpired(elem.Value.(*entry))
+}
+
+// Keys returns the keys of all unexpired items, most recently used first.
+func (c *Cache) Keys() []interface{} {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	keys := make([]interface{}, 0, c.lru.Len())
+	for elem := c.lru.Front(); elem != nil; elem = elem.Next() {
+		e := elem.Value.(*entry)
+		if !c.isExpired(e) {
+			keys = append(keys, e.key)
+		}
+	}
+	return keys
+}
+
 // Len returns the current number of items in the cache.
 func (c *Cache) Len() int {
 	c.mu.RLock()
@@ -110,26 +247,51 @@ func (c *Cache) Purge() int {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	start := c.clock.Now()
 	count := 0
 	var next *list.Element
 	for elem := c.lru.Back(); elem != nil; elem = next {
 		next = elem.Prev()
 		e := elem.Value.(*entry)
 		if c.isExpired(e) {
-			c.removeElement(elem)
+			c.expire(elem)
 			count++
 		}
 	}
+	c.metrics.SweepDuration(c.clock.Now().Sub(start))
 	return count
 }
 
+// sweepTail removes expired entries among the c.sweep least recently used.
+func (c *Cache) sweepTail() {
+	if c.ttl == 0 {
+		return
+	}
+
+	elem := c.lru.Back()
+	for i := 0; i < c.sweep && elem != nil; i++ {
+		prev := elem.Prev()
+		if c.isExpired(elem.Value.(*entry)) {
+			c.expire(elem)
+		}
+		elem = prev
+	}
+}
+
 func (c *Cache) evict() {
 	elem := c.lru.Back()
 	if elem != nil {
 		c.removeElement(elem)
+		c.metrics.Eviction()
 	}
 }
 
+// expire removes elem because its entry has expired.
+func (c *Cache) expire(elem *list.Element) {
+	c.removeElement(elem)
+	c.metrics.Expiration()
+}
+
 func (c *Cache) removeElement(elem *list.Element) {
 	c.lru.Remove(elem)
 	e := elem.Value.(*entry)
@@ -140,12 +302,12 @@ func (c *Cache) isExpired(e *entry) bool {
 	if c.ttl == 0 {
 		return false
 	}
-	return time.Now().After(e.expiresAt)
+	return c.clock.Now().After(e.expiresAt)
 }
 
 func (c *Cache) getExpirationTime() time.Time {
 	if c.ttl == 0 {
 		return time.Time{}
 	}
-	return time.Now().Add(c.ttl)
+	return c.clock.Now().Add(c.ttl)
 }
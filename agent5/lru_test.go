@@ -3,6 +3,9 @@ package agent5
 import (
 	"testing"
 	"time"
+
+	"github.com/rselbach/agent-comparison/clock"
+	"github.com/rselbach/agent-comparison/metrics"
 )
 
 func TestCache_BasicOperations(t *testing.T) {
@@ -141,30 +144,30 @@ func TestCache_LRUOrdering(t *testing.T) {
 func TestCache_Expiration(t *testing.T) {
 	tests := map[string]struct {
 		ttl  time.Duration
-		ops  func(t *testing.T, c *Cache)
+		ops  func(t *testing.T, c *Cache, clk *clock.Fake)
 		want bool
 	}{
 		"item expires after TTL": {
 			ttl: 50 * time.Millisecond,
-			ops: func(t *testing.T, c *Cache) {
+			ops: func(t *testing.T, c *Cache, clk *clock.Fake) {
 				c.Set("key1", "value1")
-				time.Sleep(100 * time.Millisecond)
+				clk.Advance(100 * time.Millisecond)
 			},
 			want: false,
 		},
 		"item accessible before TTL": {
 			ttl: 200 * time.Millisecond,
-			ops: func(t *testing.T, c *Cache) {
+			ops: func(t *testing.T, c *Cache, clk *clock.Fake) {
 				c.Set("key1", "value1")
-				time.Sleep(50 * time.Millisecond)
+				clk.Advance(50 * time.Millisecond)
 			},
 			want: true,
 		},
 		"no expiration when TTL is 0": {
 			ttl: 0,
-			ops: func(t *testing.T, c *Cache) {
+			ops: func(t *testing.T, c *Cache, clk *clock.Fake) {
 				c.Set("key1", "value1")
-				time.Sleep(50 * time.Millisecond)
+				clk.Advance(50 * time.Millisecond)
 			},
 			want: true,
 		},
@@ -172,8 +175,10 @@ func TestCache_Expiration(t *testing.T) {
 
 	for name, tc := range tests {
 		t.Run(name, func(t *testing.T) {
+			clk := clock.NewFake(time.Unix(0, 0))
 			c := New(10, tc.ttl)
-			tc.ops(t, c)
+			c.SetClock(clk)
+			tc.ops(t, c, clk)
 			_, ok := c.Get("key1")
 			if ok != tc.want {
 				t.Fatalf("want %v, got %v", tc.want, ok)
@@ -183,13 +188,15 @@ func TestCache_Expiration(t *testing.T) {
 }
 
 func TestCache_Purge(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
 	c := New(10, 50*time.Millisecond)
+	c.SetClock(clk)
 
 	c.Set("key1", "value1")
 	c.Set("key2", "value2")
 	c.Set("key3", "value3")
 
-	time.Sleep(100 * time.Millisecond)
+	clk.Advance(100 * time.Millisecond)
 
 	count := c.Purge()
 	if count != 3 {
@@ -201,6 +208,70 @@ func TestCache_Purge(t *testing.T) {
 	}
 }
 
+func TestCache_Recorder(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	rec := &metrics.Counters{}
+	c := New(2, time.Second)
+	c.SetClock(clk)
+	c.SetRecorder(rec)
+
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.Get("a")
+	c.Get("missing")
+	c.Set("c", 3) // evicts b
+
+	clk.Advance(2 * time.Second)
+	c.Get("a")
+	c.Purge()
+
+	want := metrics.Snapshot{Hits: 1, Misses: 2, Evictions: 1, Expirations: 2, Sweeps: 1}
+	if got := rec.Snapshot(); got != want {
+		t.Errorf("recorded %+v, want %+v", got, want)
+	}
+}
+
+func TestCache_WriteSweep(t *testing.T) {
+	tests := map[string]struct {
+		sweep   int
+		wantLen int
+	}{
+		"default sweep removes expired tail": {
+			sweep:   DefaultWriteSweep,
+			wantLen: 1,
+		},
+		"sweep bounded by limit": {
+			sweep:   1,
+			wantLen: 3,
+		},
+		"disabled": {
+			sweep:   0,
+			wantLen: 4,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			clk := clock.NewFake(time.Unix(0, 0))
+			c := New(10, 50*time.Millisecond)
+			c.SetClock(clk)
+			c.SetWriteSweep(tc.sweep)
+
+			c.Set("key1", "value1")
+			c.Set("key2", "value2")
+			c.Set("key3", "value3")
+
+			clk.Advance(100 * time.Millisecond)
+
+			c.Set("key4", "value4")
+
+			if c.Len() != tc.wantLen {
+				t.Fatalf("want len %d, got %d", tc.wantLen, c.Len())
+			}
+		})
+	}
+}
+
 func TestCache_ConcurrentAccess(t *testing.T) {
 	c := New(100, 0)
 
@@ -224,3 +295,123 @@ func TestCache_ConcurrentAccess(t *testing.T) {
 		t.Fatalf("want len <= 100, got %d", c.Len())
 	}
 }
+
+func TestCache_InvalidateHandle(t *testing.T) {
+	tests := map[string]struct {
+		ops func(t *testing.T, c *Cache)
+	}{
+		"removes the entry it refers to": {
+			ops: func(t *testing.T, c *Cache) {
+				h := c.Set("key1", "value1")
+				if !c.InvalidateHandle(h) {
+					t.Fatal("expected handle to invalidate key1")
+				}
+				if _, ok := c.Get("key1"); ok {
+					t.Fatal("expected key1 to be removed")
+				}
+				if c.InvalidateHandle(h) {
+					t.Fatal("expected second invalidation to be a no-op")
+				}
+			},
+		},
+		"ignores replaced entries": {
+			ops: func(t *testing.T, c *Cache) {
+				old := c.Set("key1", "value1")
+				c.Set("key1", "value2")
+				if c.InvalidateHandle(old) {
+					t.Fatal("expected stale handle to be a no-op")
+				}
+				if val, ok := c.Get("key1"); !ok || val != "value2" {
+					t.Fatalf("want value2, got %v", val)
+				}
+			},
+		},
+		"ignores entries re-added after eviction": {
+			ops: func(t *testing.T, c *Cache) {
+				old := c.Set("key1", "value1")
+				c.Set("key2", "value2")
+				c.Set("key3", "value3") // evicts key1
+				c.Set("key1", "value4")
+				if c.InvalidateHandle(old) {
+					t.Fatal("expected handle to evicted entry to be a no-op")
+				}
+				if _, ok := c.Get("key1"); !ok {
+					t.Fatal("expected newer key1 to remain")
+				}
+			},
+		},
+		"zero handle": {
+			ops: func(t *testing.T, c *Cache) {
+				c.Set(nil, "value")
+				if c.InvalidateHandle(Handle{}) {
+					t.Fatal("expected zero handle to be a no-op")
+				}
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			c := New(2, 0)
+			tc.ops(t, c)
+		})
+	}
+}
+
+func TestCache_Contains(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	c := New(2, 50*time.Millisecond)
+	c.SetClock(clk)
+	c.Set("key1", "value1")
+	c.Set("key2", "value2")
+
+	if !c.Contains("key1") {
+		t.Fatal("want key1 present")
+	}
+	if c.Contains("missing") {
+		t.Fatal("want missing absent")
+	}
+
+	// Contains must not refresh key1, so adding key3 still evicts it.
+	c.Set("key3", "value3")
+	if c.Contains("key1") {
+		t.Fatal("want key1 evicted")
+	}
+
+	clk.Advance(100 * time.Millisecond)
+	if c.Contains("key2") {
+		t.Fatal("want expired key2 reported absent")
+	}
+	if c.Len() != 2 {
+		t.Fatalf("want expired entries left in place, got len %d", c.Len())
+	}
+}
+
+func TestCache_Keys(t *testing.T) {
+	c := New(10, 0)
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.Set("c", 3)
+	c.Get("a")
+
+	got := c.Keys()
+	want := []interface{}{"a", "c", "b"}
+	if len(got) != len(want) {
+		t.Fatalf("want %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("want %v, got %v", want, got)
+		}
+	}
+
+	clk := clock.NewFake(time.Unix(0, 0))
+	expiring := New(10, 50*time.Millisecond)
+	expiring.SetClock(clk)
+	expiring.Set("a", 1)
+	clk.Advance(100 * time.Millisecond)
+	expiring.Set("b", 2)
+	if got := expiring.Keys(); len(got) != 1 || got[0] != "b" {
+		t.Fatalf("want [b], got %v", got)
+	}
+}
@@ -0,0 +1,10 @@
+package lrucache
+
+import "github.com/rselbach/agent-comparison/clock"
+
+// Clock is the time source used for expiration and for the cleanup ticker.
+// It is the shared clock.Clock, so a clock.Fake can drive a Cache in tests.
+type Clock = clock.Clock
+
+// Ticker is the ticker a Clock returns for the cleanup goroutine.
+type Ticker = clock.Ticker
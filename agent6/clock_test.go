@@ -0,0 +1,102 @@
+package lrucache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rselbach/agent-comparison/clock"
+)
+
+func newFakeClock() *clock.Fake {
+	return clock.NewFake(time.Unix(0, 0))
+}
+
+// waitFor polls cond until it holds, failing the test after a second. Ticks
+// from a fake clock are delivered at once, but the sweep they trigger runs on
+// the cleanup goroutine.
+func waitFor(t *testing.T, msg string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal(msg)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestFakeClockExpiration(t *testing.T) {
+	clk := newFakeClock()
+	c := NewWithClock(10, time.Minute, clk)
+	defer c.Close()
+
+	c.Set("key1", "value1")
+
+	clk.Advance(59 * time.Second)
+	if _, ok := c.Get("key1"); !ok {
+		t.Error("key1 should exist before ttl elapses")
+	}
+
+	clk.Advance(2 * time.Minute)
+	if _, ok := c.Get("key1"); ok {
+		t.Error("key1 should have expired")
+	}
+}
+
+func TestFakeClockCleanup(t *testing.T) {
+	clk := newFakeClock()
+	c := NewWithClock(10, time.Minute, clk)
+	defer c.Close()
+
+	c.Set("key1", "value1")
+	c.Set("key2", "value2")
+
+	clk.BlockUntil(1)
+	clk.Advance(2 * time.Minute)
+
+	waitFor(t, "expected length 0 after cleanup", func() bool { return c.Len() == 0 })
+}
+
+func TestPeekAndTTL(t *testing.T) {
+	clk := newFakeClock()
+	c := NewWithClock(2, time.Minute, clk)
+	defer c.Close()
+
+	c.Set("key1", "value1")
+	c.Set("key2", "value2")
+
+	clk.Advance(20 * time.Second)
+	if val, ok := c.Peek("key1"); !ok || val != "value1" {
+		t.Errorf("expected value1, got %v", val)
+	}
+	if ttl, ok := c.TTL("key1"); !ok || ttl != 40*time.Second {
+		t.Errorf("expected 40s remaining, got %v, %v", ttl, ok)
+	}
+
+	// Neither Peek nor TTL promoted key1, so it is still the eviction victim.
+	c.Set("key3", "value3")
+	if _, ok := c.Peek("key1"); ok {
+		t.Error("key1 should have been evicted")
+	}
+	if _, ok := c.TTL("key1"); ok {
+		t.Error("TTL should report missing keys")
+	}
+
+	clk.Advance(time.Minute)
+	if _, ok := c.Peek("key2"); ok {
+		t.Error("key2 should have expired")
+	}
+	if _, ok := c.TTL("key2"); ok {
+		t.Error("TTL should report expired keys")
+	}
+}
+
+func TestTTLWithoutExpiry(t *testing.T) {
+	c := NewWithClock(2, 0, newFakeClock())
+	defer c.Close()
+
+	c.Set("key1", "value1")
+	if ttl, ok := c.TTL("key1"); !ok || ttl != 0 {
+		t.Errorf("expected 0, true for non-expiring entry, got %v, %v", ttl, ok)
+	}
+}
@@ -0,0 +1,173 @@
+package lrucache
+
+import (
+	"container/heap"
+	"sort"
+	"time"
+)
+
+// maxHeatKeys bounds how many keys a heat window tracks, so lookups of
+// arbitrary missing keys cannot grow it without limit.
+const maxHeatKeys = 1024
+
+// KeyHeat is one key's access count in a heat window. Once a window tracks
+// maxHeatKeys keys, a new key takes over the slot of the least accessed one
+// and inherits its count, so Count may overstate the key's accesses by up to
+// Err. Keys accessed more often than Err are never displaced.
+type KeyHeat struct {
+	Key   interface{}
+	Count uint64
+	Err   uint64
+}
+
+// heatCounter is one tracked key. seq orders keys first seen in a window so
+// ties in HotKeys are broken the same way every time.
+type heatCounter struct {
+	KeyHeat
+	seq   uint64
+	index int
+}
+
+// heatWindow counts accesses with the Space-Saving algorithm: at most
+// maxHeatKeys counters, kept in a min-heap by count so the least accessed one
+// can be replaced in O(log n).
+type heatWindow struct {
+	keys map[interface{}]*heatCounter
+	heap heatHeap
+	seq  uint64
+}
+
+func newHeatWindow() *heatWindow {
+	return &heatWindow{keys: make(map[interface{}]*heatCounter)}
+}
+
+func (w *heatWindow) record(key interface{}) {
+	if hc, ok := w.keys[key]; ok {
+		hc.Count++
+		heap.Fix(&w.heap, hc.index)
+		return
+	}
+
+	w.seq++
+	if len(w.heap) < maxHeatKeys {
+		hc := &heatCounter{KeyHeat: KeyHeat{Key: key, Count: 1}, seq: w.seq}
+		w.keys[key] = hc
+		heap.Push(&w.heap, hc)
+		return
+	}
+
+	hc := w.heap[0]
+	delete(w.keys, hc.Key)
+	hc.Key, hc.Err, hc.seq = key, hc.Count, w.seq
+	hc.Count++
+	w.keys[key] = hc
+	heap.Fix(&w.heap, 0)
+}
+
+type heatHeap []*heatCounter
+
+func (h heatHeap) Len() int           { return len(h) }
+func (h heatHeap) Less(i, j int) bool { return h[i].Count < h[j].Count }
+func (h heatHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *heatHeap) Push(x interface{}) {
+	hc := x.(*heatCounter)
+	hc.index = len(*h)
+	*h = append(*h, hc)
+}
+
+func (h *heatHeap) Pop() interface{} {
+	old := *h
+	hc := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return hc
+}
+
+// heatTracker counts key accesses in tumbling windows. The previous
+// completed window is kept so exports are not reset mid-window.
+type heatTracker struct {
+	window  time.Duration
+	start   time.Time
+	current *heatWindow
+	last    *heatWindow
+}
+
+func (h *heatTracker) roll(now time.Time) {
+	elapsed := now.Sub(h.start)
+	if elapsed < h.window {
+		return
+	}
+	if elapsed < 2*h.window {
+		h.last = h.current
+	} else {
+		h.last = newHeatWindow()
+	}
+	h.current = newHeatWindow()
+	h.start = h.start.Add(elapsed / h.window * h.window)
+}
+
+func (h *heatTracker) record(key interface{}, now time.Time) {
+	h.roll(now)
+	h.current.record(key)
+}
+
+// EnableHeatTracking starts counting Get and Set calls per key over
+// tumbling windows of the given length. Each window tracks at most 1024
+// keys; see KeyHeat for how counts are kept once it is full. A non-positive
+// window disables tracking and discards collected counts.
+func (c *Cache) EnableHeatTracking(window time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if window <= 0 {
+		c.heat = nil
+		return
+	}
+	c.heat = &heatTracker{
+		window:  window,
+		start:   c.clock.Now(),
+		current: newHeatWindow(),
+	}
+}
+
+// HotKeys returns up to k keys with the highest access counts, most
+// accessed first, with ties in the order the keys were first accessed in the
+// window. Counts come from the most recently completed window, or from the
+// window in progress if none has completed yet. A non-positive k returns
+// every tracked key.
+func (c *Cache) HotKeys(k int) []KeyHeat {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.heat == nil {
+		return nil
+	}
+	c.heat.roll(c.clock.Now())
+
+	w := c.heat.last
+	if w == nil {
+		w = c.heat.current
+	}
+
+	counters := append([]*heatCounter(nil), w.heap...)
+	sort.Slice(counters, func(i, j int) bool {
+		if counters[i].Count != counters[j].Count {
+			return counters[i].Count > counters[j].Count
+		}
+		return counters[i].seq < counters[j].seq
+	})
+
+	if k > 0 && k < len(counters) {
+		counters = counters[:k]
+	}
+	out := make([]KeyHeat, len(counters))
+	for i, hc := range counters {
+		out[i] = hc.KeyHeat
+	}
+	return out
+}
@@ -0,0 +1,102 @@
+package lrucache
+
+// valueIndex maps attributes extracted from cached values to the keys
+// holding those values.
+type valueIndex struct {
+	extract func(value interface{}) []string
+	attrs   map[string]map[interface{}]struct{}
+}
+
+// SetIndex registers extract to derive index attributes from each cached
+// value, e.g. the user-id owning a session. Existing entries are indexed
+// immediately. A nil extract removes the index.
+func (c *Cache) SetIndex(extract func(value interface{}) []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for elem := c.lru.Front(); elem != nil; elem = elem.Next() {
+		elem.Value.(*entry).attrs = nil
+	}
+
+	if extract == nil {
+		c.index = nil
+		return
+	}
+	c.index = &valueIndex{
+		extract: extract,
+		attrs:   make(map[string]map[interface{}]struct{}),
+	}
+	for elem := c.lru.Front(); elem != nil; elem = elem.Next() {
+		c.reindex(elem.Value.(*entry))
+	}
+}
+
+// KeysByIndex returns the keys whose values carry attr, in no particular
+// order.
+func (c *Cache) KeysByIndex(attr string) []interface{} {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.index == nil {
+		return nil
+	}
+	keys := make([]interface{}, 0, len(c.index.attrs[attr]))
+	for key := range c.index.attrs[attr] {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// DeleteByIndex removes every entry whose value carries attr and returns
+// how many were removed. It returns 0 when no index is registered.
+func (c *Cache) DeleteByIndex(attr string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.index == nil {
+		return 0
+	}
+
+	removed := 0
+	for key := range c.index.attrs[attr] {
+		if elem, exists := c.items[key]; exists {
+			c.removeElement(elem)
+			removed++
+		}
+	}
+	return removed
+}
+
+func (c *Cache) reindex(e *entry) {
+	if c.index == nil {
+		return
+	}
+	c.unindex(e)
+
+	for _, attr := range c.index.extract(e.value) {
+		keys, ok := c.index.attrs[attr]
+		if !ok {
+			keys = make(map[interface{}]struct{})
+			c.index.attrs[attr] = keys
+		}
+		if _, dup := keys[e.key]; dup {
+			continue
+		}
+		keys[e.key] = struct{}{}
+		e.attrs = append(e.attrs, attr)
+	}
+}
+
+func (c *Cache) unindex(e *entry) {
+	if c.index == nil {
+		return
+	}
+	for _, attr := range e.attrs {
+		keys := c.index.attrs[attr]
+		delete(keys, e.key)
+		if len(keys) == 0 {
+			delete(c.index.attrs, attr)
+		}
+	}
+	e.attrs = nil
+}
@@ -0,0 +1,79 @@
+package lrucache
+
+import "testing"
+
+type session struct {
+	userID string
+	roles  []string
+}
+
+func sessionAttrs(value interface{}) []string {
+	s, ok := value.(session)
+	if !ok {
+		return nil
+	}
+	attrs := []string{"user:" + s.userID}
+	for _, role := range s.roles {
+		attrs = append(attrs, "role:"+role)
+	}
+	return attrs
+}
+
+func TestDeleteByIndex(t *testing.T) {
+	c := New(10, 0)
+	defer c.Close()
+
+	c.Set("sess-existing", session{userID: "alice"})
+	c.SetIndex(sessionAttrs)
+
+	c.Set("sess-1", session{userID: "alice", roles: []string{"admin"}})
+	c.Set("sess-2", session{userID: "bob", roles: []string{"admin"}})
+	c.Set("sess-3", session{userID: "bob"})
+	c.Set("other", "not a session")
+
+	if keys := c.KeysByIndex("user:alice"); len(keys) != 2 {
+		t.Errorf("expected 2 sessions for alice, got %v", keys)
+	}
+
+	if removed := c.DeleteByIndex("user:alice"); removed != 2 {
+		t.Errorf("expected 2 removed, got %d", removed)
+	}
+	if _, ok := c.Get("sess-existing"); ok {
+		t.Error("expected entries present before SetIndex to be indexed")
+	}
+
+	// Updating a value moves it between index attributes.
+	c.Set("sess-2", session{userID: "bob"})
+	if removed := c.DeleteByIndex("role:admin"); removed != 0 {
+		t.Errorf("expected no admin sessions after update, got %d", removed)
+	}
+
+	if removed := c.DeleteByIndex("user:bob"); removed != 2 {
+		t.Errorf("expected 2 removed, got %d", removed)
+	}
+	if c.Len() != 1 {
+		t.Errorf("expected only the non-session entry to remain, got %d", c.Len())
+	}
+	if len(c.index.attrs) != 0 {
+		t.Errorf("expected empty index, got %v", c.index.attrs)
+	}
+}
+
+func TestIndexFollowsEviction(t *testing.T) {
+	c := New(2, 0)
+	defer c.Close()
+	c.SetIndex(sessionAttrs)
+
+	c.Set("sess-1", session{userID: "alice"})
+	c.Set("sess-2", session{userID: "alice"})
+	c.Set("sess-3", session{userID: "bob"})
+
+	if keys := c.KeysByIndex("user:alice"); len(keys) != 1 || keys[0] != "sess-2" {
+		t.Errorf("expected only sess-2 indexed for alice, got %v", keys)
+	}
+
+	c.SetIndex(nil)
+	if removed := c.DeleteByIndex("user:bob"); removed != 0 {
+		t.Errorf("expected no removals without an index, got %d", removed)
+	}
+}
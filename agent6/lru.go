@@ -4,12 +4,16 @@ import (
 	"container/list"
 	"sync"
 	"time"
+
+	"github.com/rselbach/agent-comparison/clock"
+	"github.com/rselbach/agent-comparison/metrics"
 )
 
 type entry struct {
 	key        interface{}
 	value      interface{}
 	expiration time.Time
+	attrs      []string
 }
 
 type Cache struct {
@@ -19,9 +23,22 @@ type Cache struct {
 	items    map[interface{}]*list.Element
 	lru      *list.List
 	stopCh   chan struct{}
+	heat     *heatTracker
+	index    *valueIndex
+	clock    Clock
+	metrics  metrics.Recorder
+
+	softFraction float64
 }
 
 func New(capacity int, ttl time.Duration) *Cache {
+	return NewWithClock(capacity, ttl, clock.Real{})
+}
+
+// NewWithClock is like New but reads time from clk and drives the
+// cleanup ticker from it, so expiration can be tested with a clock.Fake.
+func NewWithClock(capacity int, ttl time.Duration, clk Clock) *Cache {
+
 	if capacity <= 0 {
 		panic("capacity must be positive")
 	}
@@ -32,6 +49,8 @@ func New(capacity int, ttl time.Duration) *Cache {
 		items:    make(map[interface{}]*list.Element),
 		lru:      list.New(),
 		stopCh:   make(chan struct{}),
+		clock:    clock.OrReal(clk),
+		metrics:  metrics.Nop{},
 	}
 
 	if ttl > 0 {
@@ -41,13 +60,26 @@ func New(capacity int, ttl time.Duration) *Cache {
 	return c
 }
 
+// SetRecorder sets the recorder that receives the cache's hits, misses,
+// evictions, expirations and cleanup sweep durations. A nil recorder records
+// nothing.
+func (c *Cache) SetRecorder(r metrics.Recorder) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.metrics = metrics.OrNop(r)
+}
+
 func (c *Cache) Set(key, value interface{}) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	if c.heat != nil {
+		c.heat.record(key, c.clock.Now())
+	}
+
 	expiration := time.Time{}
 	if c.ttl > 0 {
-		expiration = time.Now().Add(c.ttl)
+		expiration = c.clock.Now().Add(c.ttl)
 	}
 
 	if elem, exists := c.items[key]; exists {
@@ -55,6 +87,7 @@ func (c *Cache) Set(key, value interface{}) {
 		e := elem.Value.(*entry)
 		e.value = value
 		e.expiration = expiration
+		c.reindex(e)
 		return
 	}
 
@@ -70,28 +103,73 @@ func (c *Cache) Set(key, value interface{}) {
 
 	elem := c.lru.PushFront(e)
 	c.items[key] = elem
+	c.reindex(e)
 }
 
 func (c *Cache) Get(key interface{}) (interface{}, bool) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	if c.heat != nil {
+		c.heat.record(key, c.clock.Now())
+	}
+
 	elem, exists := c.items[key]
 	if !exists {
+		c.metrics.Miss()
 		return nil, false
 	}
 
 	e := elem.Value.(*entry)
 
-	if !e.expiration.IsZero() && time.Now().After(e.expiration) {
+	if !e.expiration.IsZero() && c.clock.Now().After(e.expiration) {
 		c.removeElement(elem)
+		c.metrics.Expiration()
+		c.metrics.Miss()
 		return nil, false
 	}
 
 	c.lru.MoveToFront(elem)
+	c.metrics.Hit()
+	return e.value, true
+}
+
+func (c *Cache) Peek(key interface{}) (interface{}, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	elem, exists := c.items[key]
+	if !exists {
+		return nil, false
+	}
+
+	e := elem.Value.(*entry)
+	if !e.expiration.IsZero() && c.clock.Now().After(e.expiration) {
+		return nil, false
+	}
 	return e.value, true
 }
 
+func (c *Cache) TTL(key interface{}) (time.Duration, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	elem, exists := c.items[key]
+	if !exists {
+		return 0, false
+	}
+
+	e := elem.Value.(*entry)
+	if e.expiration.IsZero() {
+		return 0, true
+	}
+	remaining := e.expiration.Sub(c.clock.Now())
+	if remaining < 0 {
+		return 0, false
+	}
+	return remaining, true
+}
+
 func (c *Cache) Delete(key interface{}) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -112,6 +190,9 @@ func (c *Cache) Clear() {
 	defer c.mu.Unlock()
 	c.items = make(map[interface{}]*list.Element)
 	c.lru.Init()
+	if c.index != nil {
+		c.index.attrs = make(map[string]map[interface{}]struct{})
+	}
 }
 
 func (c *Cache) Close() {
@@ -122,6 +203,7 @@ func (c *Cache) evictOldest() {
 	elem := c.lru.Back()
 	if elem != nil {
 		c.removeElement(elem)
+		c.metrics.Eviction()
 	}
 }
 
@@ -129,15 +211,16 @@ func (c *Cache) removeElement(elem *list.Element) {
 	c.lru.Remove(elem)
 	e := elem.Value.(*entry)
 	delete(c.items, e.key)
+	c.unindex(e)
 }
 
 func (c *Cache) cleanupExpired() {
-	ticker := time.NewTicker(c.ttl / 2)
+	ticker := c.clock.NewTicker(c.ttl / 2)
 	defer ticker.Stop()
 
 	for {
 		select {
-		case <-ticker.C:
+		case <-ticker.C():
 			c.removeExpiredItems()
 		case <-c.stopCh:
 			return
@@ -149,15 +232,17 @@ func (c *Cache) removeExpiredItems() {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	now := time.Now()
+	now := c.clock.Now()
 	for elem := c.lru.Back(); elem != nil; {
 		e := elem.Value.(*entry)
 		if !e.expiration.IsZero() && now.After(e.expiration) {
 			next := elem.Prev()
 			c.removeElement(elem)
+			c.metrics.Expiration()
 			elem = next
 		} else {
 			elem = elem.Prev()
 		}
 	}
+	c.metrics.SweepDuration(c.clock.Now().Sub(now))
 }
@@ -3,6 +3,8 @@ package lrucache
 import (
 	"testing"
 	"time"
+
+	"github.com/rselbach/agent-comparison/metrics"
 )
 
 func TestNew(t *testing.T) {
@@ -77,7 +79,8 @@ func TestLRUOrdering(t *testing.T) {
 }
 
 func TestExpiration(t *testing.T) {
-	c := New(10, 100*time.Millisecond)
+	clk := newFakeClock()
+	c := NewWithClock(10, 100*time.Millisecond, clk)
 	defer c.Close()
 
 	c.Set("key1", "value1")
@@ -86,7 +89,7 @@ func TestExpiration(t *testing.T) {
 		t.Error("key1 should exist immediately after setting")
 	}
 
-	time.Sleep(150 * time.Millisecond)
+	clk.Advance(150 * time.Millisecond)
 
 	if _, ok := c.Get("key1"); ok {
 		t.Error("key1 should have expired")
@@ -94,7 +97,8 @@ func TestExpiration(t *testing.T) {
 }
 
 func TestAutoCleanup(t *testing.T) {
-	c := New(10, 100*time.Millisecond)
+	clk := newFakeClock()
+	c := NewWithClock(10, 100*time.Millisecond, clk)
 	defer c.Close()
 
 	c.Set("key1", "value1")
@@ -104,10 +108,33 @@ func TestAutoCleanup(t *testing.T) {
 		t.Errorf("expected length 2, got %d", c.Len())
 	}
 
-	time.Sleep(200 * time.Millisecond)
+	clk.BlockUntil(1)
+	clk.Advance(200 * time.Millisecond)
+	waitFor(t, "expected length 0 after expiration", func() bool { return c.Len() == 0 })
+}
 
-	if c.Len() != 0 {
-		t.Errorf("expected length 0 after expiration, got %d", c.Len())
+func TestRecorder(t *testing.T) {
+	clk := newFakeClock()
+	rec := &metrics.Counters{}
+	c := NewWithClock(2, time.Minute, clk)
+	defer c.Close()
+	c.SetRecorder(rec)
+
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.Get("a")
+	c.Get("missing")
+	c.Set("c", 3) // evicts b
+
+	clk.BlockUntil(1)
+	clk.Advance(2 * time.Minute)
+	waitFor(t, "expected the cleanup goroutine to record a sweep", func() bool { return rec.Snapshot().Sweeps != 0 })
+	c.Get("a")
+
+	got := rec.Snapshot()
+	want := metrics.Snapshot{Hits: 1, Misses: 2, Evictions: 1, Expirations: 2, Sweeps: got.Sweeps}
+	if got != want {
+		t.Errorf("recorded %+v, want %+v", got, want)
 	}
 }
 
@@ -176,3 +203,106 @@ func TestConcurrency(t *testing.T) {
 		<-done
 	}
 }
+
+func TestHotKeys(t *testing.T) {
+	c := New(10, 0)
+	defer c.Close()
+
+	if keys := c.HotKeys(5); keys != nil {
+		t.Errorf("expected nil before tracking is enabled, got %v", keys)
+	}
+
+	c.EnableHeatTracking(time.Hour)
+
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.Set("c", 3)
+	for i := 0; i < 3; i++ {
+		c.Get("b")
+	}
+	c.Get("c")
+	c.Get("missing")
+
+	keys := c.HotKeys(2)
+	if len(keys) != 2 {
+		t.Fatalf("expected 2 keys, got %d", len(keys))
+	}
+	if keys[0].Key != "b" || keys[0].Count != 4 {
+		t.Errorf("expected b with 4 accesses first, got %v", keys[0])
+	}
+	if keys[1].Key != "c" || keys[1].Count != 2 {
+		t.Errorf("expected c with 2 accesses second, got %v", keys[1])
+	}
+
+	if all := c.HotKeys(0); len(all) != 4 {
+		t.Errorf("expected 4 tracked keys, got %d", len(all))
+	}
+}
+
+func TestHotKeysWindowRollover(t *testing.T) {
+	clk := newFakeClock()
+	c := NewWithClock(10, 0, clk)
+	defer c.Close()
+
+	c.EnableHeatTracking(time.Minute)
+	c.Get("a")
+	c.Get("a")
+
+	clk.Advance(70 * time.Second)
+	c.Get("b")
+
+	keys := c.HotKeys(0)
+	if len(keys) != 1 || keys[0].Key != "a" || keys[0].Count != 2 {
+		t.Errorf("expected completed window with a=2, got %v", keys)
+	}
+
+	clk.Advance(2 * time.Minute)
+	if keys := c.HotKeys(0); len(keys) != 0 {
+		t.Errorf("expected an empty window after an idle one, got %v", keys)
+	}
+}
+
+func TestHotKeysTiesInFirstAccessOrder(t *testing.T) {
+	c := NewWithClock(10, 0, newFakeClock())
+	defer c.Close()
+
+	c.EnableHeatTracking(time.Hour)
+	for _, key := range []string{"d", "b", "a", "c", "b"} {
+		c.Get(key)
+	}
+
+	keys := c.HotKeys(0)
+	var got []interface{}
+	for _, kh := range keys {
+		got = append(got, kh.Key)
+	}
+	if len(got) != 4 || got[0] != "b" || got[1] != "d" || got[2] != "a" || got[3] != "c" {
+		t.Errorf("expected b, d, a, c, got %v", got)
+	}
+}
+
+func TestHotKeysBounded(t *testing.T) {
+	c := NewWithClock(10, 0, newFakeClock())
+	defer c.Close()
+
+	c.EnableHeatTracking(time.Hour)
+	for i := 0; i < 10; i++ {
+		c.Get("hot")
+	}
+	for i := 0; i < 2*maxHeatKeys; i++ {
+		c.Get(i)
+	}
+
+	keys := c.HotKeys(0)
+	if len(keys) != maxHeatKeys {
+		t.Fatalf("expected %d tracked keys, got %d", maxHeatKeys, len(keys))
+	}
+	if keys[0].Key != "hot" || keys[0].Count != 10 || keys[0].Err != 0 {
+		t.Errorf("expected hot to keep its exact count, got %v", keys[0])
+	}
+	for _, kh := range keys[1:] {
+		if kh.Count-kh.Err != 1 {
+			t.Fatalf("expected displaced keys to carry their inherited count as Err, got %v", kh)
+		}
+	}
+}
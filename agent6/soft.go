@@ -0,0 +1,37 @@
+package lrucache
+
+// SetSoftFraction marks fraction of the capacity as soft. Entries beyond the
+// hard share, i.e. the least recently used ones once the cache holds more
+// than capacity*(1-fraction) items, are kept only opportunistically:
+// ReleaseSoft drops them at once. Entries within the hard share follow normal
+// LRU eviction. The fraction is clamped to [0, 1]; 0 disables the mode.
+func (c *Cache) SetSoftFraction(fraction float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if fraction < 0 {
+		fraction = 0
+	}
+	if fraction > 1 {
+		fraction = 1
+	}
+	c.softFraction = fraction
+}
+
+// ReleaseSoft evicts least recently used entries until only the hard share
+// of the capacity is in use, and returns how many were removed. It is meant
+// to be registered as a memory-pressure callback, for example with a
+// container memory watcher; the cache refills its soft share as usual
+// afterwards.
+func (c *Cache) ReleaseSoft() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	hard := c.capacity - int(float64(c.capacity)*c.softFraction)
+	removed := 0
+	for c.lru.Len() > hard {
+		c.evictOldest()
+		removed++
+	}
+	return removed
+}
@@ -0,0 +1,72 @@
+package lrucache
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestReleaseSoft(t *testing.T) {
+	tests := []struct {
+		name        string
+		fraction    float64
+		fill        int
+		wantRemoved int
+	}{
+		{"disabled", 0, 10, 0},
+		{"full cache sheds soft share", 0.3, 10, 3},
+		{"partly used soft share", 0.5, 7, 2},
+		{"within hard share", 0.5, 4, 0},
+		{"all soft", 1, 10, 10},
+		{"fraction clamped", 2, 10, 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(10, 0)
+			defer c.Close()
+			c.SetSoftFraction(tt.fraction)
+
+			for i := 0; i < tt.fill; i++ {
+				c.Set(i, i)
+			}
+
+			if got := c.ReleaseSoft(); got != tt.wantRemoved {
+				t.Fatalf("ReleaseSoft() = %d, want %d", got, tt.wantRemoved)
+			}
+			if got := c.Len(); got != tt.fill-tt.wantRemoved {
+				t.Fatalf("Len() = %d, want %d", got, tt.fill-tt.wantRemoved)
+			}
+		})
+	}
+}
+
+func TestReleaseSoftDropsLeastRecentlyUsed(t *testing.T) {
+	c := New(4, 0)
+	defer c.Close()
+	c.SetSoftFraction(0.5)
+
+	for i := 0; i < 4; i++ {
+		c.Set(fmt.Sprint(i), i)
+	}
+	c.Get("0")
+
+	pressure := func() { c.ReleaseSoft() }
+	pressure()
+
+	for _, key := range []string{"1", "2"} {
+		if _, ok := c.Get(key); ok {
+			t.Errorf("expected %s to be released", key)
+		}
+	}
+	for _, key := range []string{"0", "3"} {
+		if _, ok := c.Get(key); !ok {
+			t.Errorf("expected %s to be retained", key)
+		}
+	}
+
+	c.Set("4", 4)
+	c.Set("5", 5)
+	if c.Len() != 4 {
+		t.Fatalf("expected cache to refill to capacity, got %d", c.Len())
+	}
+}
@@ -0,0 +1,209 @@
+// Package dnscache provides a caching wrapper around a DNS resolver.
+//
+// Resolver exposes the LookupHost and LookupIP methods of *net.Resolver and
+// stores their results in an lru.Cache. Positive answers are cached for the
+// record TTL when the underlying resolver reports one, and for a configured
+// default otherwise. "No such host" answers are cached for a shorter negative
+// TTL so misconfigured names do not hammer the upstream server.
+package dnscache
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/rselbach/agent-comparison/flight"
+
+	"github.com/rselbach/agent7/lru"
+)
+
+const (
+	// DefaultTTL is used for positive answers when the resolver does not report a TTL.
+	DefaultTTL = time.Minute
+	// DefaultNegativeTTL is used for "no such host" answers.
+	DefaultNegativeTTL = 10 * time.Second
+)
+
+// Lookuper is the subset of *net.Resolver wrapped by Resolver.
+type Lookuper interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+	LookupIP(ctx context.Context, network, host string) ([]net.IP, error)
+}
+
+// TTLLookuper is implemented by resolvers that know the TTL of the records they
+// return. A zero TTL means the answer must not be cached.
+type TTLLookuper interface {
+	LookupHostTTL(ctx context.Context, host string) ([]string, time.Duration, error)
+	LookupIPTTL(ctx context.Context, network, host string) ([]net.IP, time.Duration, error)
+}
+
+// Option configures a Resolver.
+type Option func(*Resolver)
+
+// WithTTL sets the TTL used for positive answers from resolvers that do not
+// implement TTLLookuper.
+func WithTTL(ttl time.Duration) Option {
+	return func(r *Resolver) {
+		r.ttl = ttl
+	}
+}
+
+// WithNegativeTTL sets how long "no such host" answers are cached.
+// A non-positive value disables negative caching.
+func WithNegativeTTL(ttl time.Duration) Option {
+	return func(r *Resolver) {
+		r.negativeTTL = ttl
+	}
+}
+
+// WithMaxTTL caps the TTL reported by a TTLLookuper. Zero means no cap.
+func WithMaxTTL(ttl time.Duration) Option {
+	return func(r *Resolver) {
+		r.maxTTL = ttl
+	}
+}
+
+// Resolver caches lookups made through an underlying resolver.
+// It is safe for concurrent use; concurrent misses for the same name share a
+// single upstream lookup.
+type Resolver struct {
+	upstream    Lookuper
+	cache       *lru.Cache
+	ttl         time.Duration
+	negativeTTL time.Duration
+	maxTTL      time.Duration
+
+	lookups flight.Group[string, result]
+}
+
+// result is what gets stored in the cache for each name.
+type result struct {
+	addrs []string
+	ips   []net.IP
+	err   error
+}
+
+// New wraps upstream, storing answers in cache. If upstream is nil,
+// net.DefaultResolver is used.
+func New(upstream Lookuper, cache *lru.Cache, opts ...Option) *Resolver {
+	if upstream == nil {
+		upstream = net.DefaultResolver
+	}
+
+	r := &Resolver{
+		upstream:    upstream,
+		cache:       cache,
+		ttl:         DefaultTTL,
+		negativeTTL: DefaultNegativeTTL,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// LookupHost looks up the given host, returning a slice of its addresses.
+func (r *Resolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	res := r.lookup(ctx, "host\x00"+strings.ToLower(host), func(ctx context.Context) (result, time.Duration) {
+		if tl, ok := r.upstream.(TTLLookuper); ok {
+			addrs, ttl, err := tl.LookupHostTTL(ctx, host)
+			return result{addrs: addrs, err: err}, r.capTTL(ttl)
+		}
+		addrs, err := r.upstream.LookupHost(ctx, host)
+		return result{addrs: addrs, err: err}, r.ttl
+	})
+	if res.err != nil {
+		return nil, res.err
+	}
+	return append([]string(nil), res.addrs...), nil
+}
+
+// LookupIP looks up host for the given network ("ip", "ip4" or "ip6").
+func (r *Resolver) LookupIP(ctx context.Context, network, host string) ([]net.IP, error) {
+	res := r.lookup(ctx, "ip\x00"+network+"\x00"+strings.ToLower(host), func(ctx context.Context) (result, time.Duration) {
+		if tl, ok := r.upstream.(TTLLookuper); ok {
+			ips, ttl, err := tl.LookupIPTTL(ctx, network, host)
+			return result{ips: ips, err: err}, r.capTTL(ttl)
+		}
+		ips, err := r.upstream.LookupIP(ctx, network, host)
+		return result{ips: ips, err: err}, r.ttl
+	})
+	if res.err != nil {
+		return nil, res.err
+	}
+	ips := make([]net.IP, len(res.ips))
+	for i, ip := range res.ips {
+		ips[i] = append(net.IP(nil), ip...)
+	}
+	return ips, nil
+}
+
+// Forget removes every cached answer for host.
+func (r *Resolver) Forget(host string) {
+	host = strings.ToLower(host)
+	r.cache.Delete("host\x00" + host)
+	for _, network := range []string{"ip", "ip4", "ip6"} {
+		r.cache.Delete("ip\x00" + network + "\x00" + host)
+	}
+}
+
+// lookup returns the cached result for key or runs fetch, caching its result.
+// The upstream lookup is shared by every caller waiting on key, so it runs
+// detached from the cancellation of the caller that started it; each caller
+// stops waiting when its own ctx is done.
+func (r *Resolver) lookup(ctx context.Context, key string, fetch func(ctx context.Context) (result, time.Duration)) result {
+	if v, ok := r.cache.Get(key); ok {
+		return v.(result)
+	}
+
+	done := make(chan result, 1)
+	detached := context.WithoutCancel(ctx)
+	go func() {
+		res, err := r.lookups.Do(key, func() (result, error) {
+			return r.fetch(detached, key, fetch), nil
+		})
+		if err != nil {
+			// the lookup panicked
+			res = result{err: err}
+		}
+		done <- res
+	}()
+
+	select {
+	case res := <-done:
+		return res
+	case <-ctx.Done():
+		return result{err: ctx.Err()}
+	}
+}
+
+// fetch runs the upstream lookup for key and caches its result.
+func (r *Resolver) fetch(ctx context.Context, key string, fetch func(ctx context.Context) (result, time.Duration)) result {
+	res, ttl := fetch(ctx)
+	if ttl, ok := r.cacheTTL(res, ttl); ok {
+		r.cache.Set(key, res, ttl)
+	}
+	return res
+}
+
+// cacheTTL reports whether res should be cached and for how long.
+func (r *Resolver) cacheTTL(res result, ttl time.Duration) (time.Duration, bool) {
+	if res.err == nil {
+		return ttl, ttl > 0
+	}
+	var dnsErr *net.DNSError
+	if errors.As(res.err, &dnsErr) && dnsErr.IsNotFound {
+		return r.negativeTTL, r.negativeTTL > 0
+	}
+	// timeouts and temporary failures are never cached
+	return 0, false
+}
+
+func (r *Resolver) capTTL(ttl time.Duration) time.Duration {
+	if r.maxTTL > 0 && ttl > r.maxTTL {
+		return r.maxTTL
+	}
+	return ttl
+}
@@ -0,0 +1,237 @@
+package dnscache_test
+
+import (
+	"context"
+	"net"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rselbach/agent-comparison/clock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rselbach/agent7/dnscache"
+	"github.com/rselbach/agent7/lru"
+)
+
+// fakeLookuper answers from a static table and counts upstream calls.
+type fakeLookuper struct {
+	calls atomic.Int32
+	hosts map[string][]string
+	// release, if set, holds every lookup until it is closed; a lookup whose
+	// ctx is done by then fails as net.Resolver would.
+	release chan struct{}
+}
+
+func (f *fakeLookuper) LookupHost(ctx context.Context, host string) ([]string, error) {
+	f.calls.Add(1)
+	if f.release != nil {
+		<-f.release
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+	}
+	addrs, ok := f.hosts[strings.ToLower(host)]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	return addrs, nil
+}
+
+func (f *fakeLookuper) LookupIP(ctx context.Context, network, host string) ([]net.IP, error) {
+	addrs, err := f.LookupHost(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	ips := make([]net.IP, len(addrs))
+	for i, a := range addrs {
+		ips[i] = net.ParseIP(a)
+	}
+	return ips, nil
+}
+
+// ttlLookuper reports a fixed record TTL.
+type ttlLookuper struct {
+	fakeLookuper
+	ttl time.Duration
+}
+
+func (t *ttlLookuper) LookupHostTTL(ctx context.Context, host string) ([]string, time.Duration, error) {
+	addrs, err := t.LookupHost(ctx, host)
+	return addrs, t.ttl, err
+}
+
+func (t *ttlLookuper) LookupIPTTL(ctx context.Context, network, host string) ([]net.IP, time.Duration, error) {
+	ips, err := t.LookupIP(ctx, network, host)
+	return ips, t.ttl, err
+}
+
+func newCache(t *testing.T) *lru.Cache {
+	c := lru.New(100, time.Minute)
+	t.Cleanup(c.Close)
+	return c
+}
+
+// newFakeCache returns a cache whose expiry follows the returned fake clock.
+func newFakeCache(t *testing.T) (*lru.Cache, *clock.Fake) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	c := lru.NewWithClock(100, time.Minute, clk)
+	t.Cleanup(c.Close)
+	return c, clk
+}
+
+// waitForLookup blocks until n callers are waiting on the LookupHost call for
+// host besides the one running it.
+func waitForLookup(res *dnscache.Resolver, host string, n int) {
+	for dnscache.WaitingHost(res, host) < n {
+		runtime.Gosched()
+	}
+}
+
+func TestResolver_CachesPositiveAnswers(t *testing.T) {
+	r := require.New(t)
+	up := &fakeLookuper{hosts: map[string][]string{"example.com": {"192.0.2.1"}}}
+	res := dnscache.New(up, newCache(t))
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		addrs, err := res.LookupHost(ctx, "Example.com")
+		r.NoError(err)
+		r.Equal([]string{"192.0.2.1"}, addrs)
+	}
+	r.Equal(int32(1), up.calls.Load())
+
+	ips, err := res.LookupIP(ctx, "ip4", "example.com")
+	r.NoError(err)
+	r.True(ips[0].Equal(net.ParseIP("192.0.2.1")))
+
+	// callers get their own copy
+	ips[0][0] = 0
+	ips, err = res.LookupIP(ctx, "ip4", "example.com")
+	r.NoError(err)
+	r.True(ips[0].Equal(net.ParseIP("192.0.2.1")))
+	r.Equal(int32(2), up.calls.Load())
+}
+
+func TestResolver_NegativeCaching(t *testing.T) {
+	r := require.New(t)
+	up := &fakeLookuper{}
+	cache, clk := newFakeCache(t)
+	res := dnscache.New(up, cache, dnscache.WithNegativeTTL(50*time.Millisecond))
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		_, err := res.LookupHost(ctx, "missing.test")
+		var dnsErr *net.DNSError
+		r.ErrorAs(err, &dnsErr)
+		r.True(dnsErr.IsNotFound)
+	}
+	r.Equal(int32(1), up.calls.Load())
+
+	clk.Advance(80 * time.Millisecond)
+	_, err := res.LookupHost(ctx, "missing.test")
+	r.Error(err)
+	r.Equal(int32(2), up.calls.Load())
+}
+
+func TestResolver_HonorsRecordTTL(t *testing.T) {
+	r := require.New(t)
+	up := &ttlLookuper{
+		fakeLookuper: fakeLookuper{hosts: map[string][]string{"example.com": {"192.0.2.1"}}},
+		ttl:          50 * time.Millisecond,
+	}
+	cache, clk := newFakeCache(t)
+	res := dnscache.New(up, cache, dnscache.WithTTL(time.Hour))
+	ctx := context.Background()
+
+	_, err := res.LookupHost(ctx, "example.com")
+	r.NoError(err)
+	_, err = res.LookupHost(ctx, "example.com")
+	r.NoError(err)
+	r.Equal(int32(1), up.calls.Load())
+
+	clk.Advance(80 * time.Millisecond)
+	_, err = res.LookupHost(ctx, "example.com")
+	r.NoError(err)
+	r.Equal(int32(2), up.calls.Load())
+
+	// a zero TTL means the answer must not be cached
+	up.ttl = 0
+	res.Forget("example.com")
+	_, _ = res.LookupHost(ctx, "example.com")
+	_, _ = res.LookupHost(ctx, "example.com")
+	r.Equal(int32(4), up.calls.Load())
+}
+
+func TestResolver_CollapsesConcurrentMisses(t *testing.T) {
+	r := require.New(t)
+	up := &fakeLookuper{
+		hosts:   map[string][]string{"example.com": {"192.0.2.1"}},
+		release: make(chan struct{}),
+	}
+	res := dnscache.New(up, newCache(t))
+
+	type answer struct {
+		addrs []string
+		err   error
+	}
+	answers := make(chan answer, 10)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			addrs, err := res.LookupHost(context.Background(), "example.com")
+			answers <- answer{addrs, err}
+		}()
+	}
+	waitForLookup(res, "example.com", 9)
+	close(up.release)
+	wg.Wait()
+	close(answers)
+	for a := range answers {
+		r.NoError(a.err)
+		r.Equal([]string{"192.0.2.1"}, a.addrs)
+	}
+	r.Equal(int32(1), up.calls.Load())
+}
+
+func TestResolver_LeaderCancelDoesNotFailWaiters(t *testing.T) {
+	r := require.New(t)
+	up := &fakeLookuper{
+		hosts:   map[string][]string{"example.com": {"192.0.2.1"}},
+		release: make(chan struct{}),
+	}
+	res := dnscache.New(up, newCache(t))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	leader := make(chan error, 1)
+	go func() {
+		_, err := res.LookupHost(ctx, "example.com")
+		leader <- err
+	}()
+	r.Eventually(func() bool { return up.calls.Load() == 1 }, time.Second, time.Millisecond)
+
+	type answer struct {
+		addrs []string
+		err   error
+	}
+	waiter := make(chan answer, 1)
+	go func() {
+		addrs, err := res.LookupHost(context.Background(), "example.com")
+		waiter <- answer{addrs, err}
+	}()
+	waitForLookup(res, "example.com", 1)
+
+	cancel()
+	r.ErrorIs(<-leader, context.Canceled)
+	close(up.release)
+
+	a := <-waiter
+	r.NoError(a.err)
+	r.Equal([]string{"192.0.2.1"}, a.addrs)
+	r.Equal(int32(1), up.calls.Load())
+}
@@ -0,0 +1,7 @@
+package dnscache
+
+// WaitingHost reports how many callers are waiting on the LookupHost call for
+// host, not counting the one running it.
+func WaitingHost(r *Resolver, host string) int {
+	return r.lookups.Waiting("host\x00" + host)
+}
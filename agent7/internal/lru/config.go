@@ -0,0 +1,66 @@
+package lru
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ConfigError describes one invalid constructor argument.
+type ConfigError struct {
+	// Field names the argument, e.g. "maxSize" or "namespaces[1].Name".
+	Field string
+	// Value is the rejected value.
+	Value interface{}
+	// Reason says what a valid value looks like.
+	Reason string
+}
+
+func (e *ConfigError) Error() string {
+	if s, ok := e.Value.(string); ok {
+		return fmt.Sprintf("lru: invalid %s %q: %s", e.Field, s, e.Reason)
+	}
+	return fmt.Sprintf("lru: invalid %s %v: %s", e.Field, e.Value, e.Reason)
+}
+
+// TryNew is like NewWithNamespaces but reports invalid arguments as an error
+// instead of panicking. Every problem is reported, joined with errors.Join;
+// use errors.As to inspect them as *ConfigError values.
+func TryNew(maxSize int, cleanupInterval time.Duration, namespaces ...Namespace) (*Cache, error) {
+	if err := validateConfig(maxSize, cleanupInterval, namespaces); err != nil {
+		return nil, err
+	}
+	return newCache(maxSize, cleanupInterval, nil, nil, namespaces), nil
+}
+
+func validateConfig(maxSize int, cleanupInterval time.Duration, namespaces []Namespace) error {
+	var errs []error
+	if maxSize <= 0 {
+		errs = append(errs, &ConfigError{Field: "maxSize", Value: maxSize, Reason: "must be greater than 0"})
+	}
+	if cleanupInterval < 0 {
+		errs = append(errs, &ConfigError{Field: "cleanupInterval", Value: cleanupInterval, Reason: "must not be negative"})
+	}
+
+	seen := make(map[string]bool, len(namespaces))
+	for i, cfg := range namespaces {
+		field := func(name string) string { return fmt.Sprintf("namespaces[%d].%s", i, name) }
+		switch {
+		case cfg.Name == "":
+			errs = append(errs, &ConfigError{Field: field("Name"), Value: cfg.Name, Reason: "must not be empty"})
+		case strings.Contains(cfg.Name, NamespaceSeparator):
+			errs = append(errs, &ConfigError{Field: field("Name"), Value: cfg.Name, Reason: fmt.Sprintf("must not contain %q", NamespaceSeparator)})
+		case seen[cfg.Name]:
+			errs = append(errs, &ConfigError{Field: field("Name"), Value: cfg.Name, Reason: "duplicates an earlier namespace"})
+		}
+		seen[cfg.Name] = true
+		if cfg.DefaultTTL < 0 {
+			errs = append(errs, &ConfigError{Field: field("DefaultTTL"), Value: cfg.DefaultTTL, Reason: "must not be negative"})
+		}
+		if cfg.MaxEntries < 0 {
+			errs = append(errs, &ConfigError{Field: field("MaxEntries"), Value: cfg.MaxEntries, Reason: "must not be negative"})
+		}
+	}
+	return errors.Join(errs...)
+}
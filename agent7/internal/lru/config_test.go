@@ -0,0 +1,84 @@
+package lru
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTryNew(t *testing.T) {
+	tests := map[string]struct {
+		maxSize         int
+		cleanupInterval time.Duration
+		namespaces      []Namespace
+		wantFields      []string
+	}{
+		"valid": {
+			maxSize:         10,
+			cleanupInterval: time.Second,
+			namespaces:      []Namespace{{Name: "session", DefaultTTL: time.Minute}},
+		},
+		"zero maxSize": {
+			maxSize:    0,
+			wantFields: []string{"maxSize"},
+		},
+		"negative cleanup interval": {
+			maxSize:         1,
+			cleanupInterval: -time.Second,
+			wantFields:      []string{"cleanupInterval"},
+		},
+		"every problem reported": {
+			maxSize:         -1,
+			cleanupInterval: -1,
+			namespaces: []Namespace{
+				{Name: "a"},
+				{Name: "a", MaxEntries: -1},
+				{Name: "b:c", DefaultTTL: -time.Second},
+				{},
+			},
+			wantFields: []string{
+				"maxSize",
+				"cleanupInterval",
+				"namespaces[1].Name",
+				"namespaces[1].MaxEntries",
+				"namespaces[2].Name",
+				"namespaces[2].DefaultTTL",
+				"namespaces[3].Name",
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := require.New(t)
+			cache, err := TryNew(tc.maxSize, tc.cleanupInterval, tc.namespaces...)
+			if len(tc.wantFields) == 0 {
+				r.NoError(err)
+				r.NotNil(cache)
+				cache.Close()
+				return
+			}
+
+			r.Nil(cache)
+			joined, ok := err.(interface{ Unwrap() []error })
+			r.True(ok, "expected joined errors, got %T", err)
+			var fields []string
+			for _, e := range joined.Unwrap() {
+				var cfgErr *ConfigError
+				r.True(errors.As(e, &cfgErr))
+				fields = append(fields, cfgErr.Field)
+			}
+			r.Equal(tc.wantFields, fields)
+		})
+	}
+}
+
+func TestConfigErrorMessage(t *testing.T) {
+	r := require.New(t)
+	_, err := TryNew(0, time.Second, Namespace{Name: "a:b"})
+	r.EqualError(err, "lru: invalid maxSize 0: must be greater than 0\n"+
+		`lru: invalid namespaces[0].Name "a:b": must not contain ":"`)
+	r.PanicsWithError(err.Error(), func() { NewWithNamespaces(0, time.Second, Namespace{Name: "a:b"}) })
+}
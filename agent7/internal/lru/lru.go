@@ -3,7 +3,11 @@ package lru
 import (
 	"container/list"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/rselbach/agent-comparison/clock"
+	"github.com/rselbach/agent-comparison/metrics"
 )
 
 // Cache is an LRU cache with automatic expiration support.
@@ -14,6 +18,25 @@ type Cache struct {
 	mu      sync.RWMutex
 	stopCh  chan struct{}
 	wg      sync.WaitGroup
+	clock   clock.Clock
+	metrics metrics.Recorder
+
+	namespaces map[string]*namespace
+
+	// version is the last version handed out. It is shared by all keys so a
+	// key that is removed and stored again never reuses an old version.
+	version uint64
+
+	// hits and misses are updated atomically so recording them never
+	// allocates or widens the critical section.
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+// Stats holds lookup counters since the cache was created.
+type Stats struct {
+	Hits   uint64
+	Misses uint64
 }
 
 // entry holds a cache value with its expiration time.
@@ -21,16 +44,51 @@ type entry struct {
 	key       string
 	value     interface{}
 	expiresAt time.Time
+	version   uint64
+
+	// ns is the configured namespace the key belongs to, if any, and
+	// nsElem its position in that namespace's recency list.
+	ns     *namespace
+	nsElem *list.Element
 }
 
 // New creates a new LRU cache with the specified maximum size and cleanup interval.
 // The cache will automatically remove expired entries.
 // If cleanupInterval is 0, a default of 1 minute is used.
 func New(maxSize int, cleanupInterval time.Duration) *Cache {
-	if maxSize <= 0 {
-		panic("lru: maxSize must be greater than 0")
+	return NewWithNamespaces(maxSize, cleanupInterval)
+}
+
+// NewWithNamespaces is like New but configures per-namespace defaults.
+// A key belongs to a namespace when it starts with the namespace name followed
+// by NamespaceSeparator, e.g. "session:abc" belongs to "session".
+// It panics on invalid arguments; use TryNew to get an error instead.
+func NewWithNamespaces(maxSize int, cleanupInterval time.Duration, namespaces ...Namespace) *Cache {
+	c, err := TryNew(maxSize, cleanupInterval, namespaces...)
+	if err != nil {
+		panic(err)
 	}
+	return c
+}
 
+// NewWithClock is like NewWithNamespaces but reads the time from clk and
+// drives the cleanup ticker from it, so expiration and cleanup can be tested
+// with a clock.Fake. A nil clk means real time.
+func NewWithClock(maxSize int, cleanupInterval time.Duration, clk clock.Clock, namespaces ...Namespace) *Cache {
+	return NewWithRecorder(maxSize, cleanupInterval, clk, nil, namespaces...)
+}
+
+// NewWithRecorder is like NewWithClock but also reports hits, misses,
+// evictions, expirations and cleanup durations to r. A nil r records nothing.
+func NewWithRecorder(maxSize int, cleanupInterval time.Duration, clk clock.Clock, r metrics.Recorder, namespaces ...Namespace) *Cache {
+	if err := validateConfig(maxSize, cleanupInterval, namespaces); err != nil {
+		panic(err)
+	}
+	return newCache(maxSize, cleanupInterval, clk, r, namespaces)
+}
+
+// newCache builds a cache from validated arguments.
+func newCache(maxSize int, cleanupInterval time.Duration, clk clock.Clock, r metrics.Recorder, namespaces []Namespace) *Cache {
 	if cleanupInterval == 0 {
 		cleanupInterval = time.Minute
 	}
@@ -40,7 +98,10 @@ func New(maxSize int, cleanupInterval time.Duration) *Cache {
 		items:   make(map[string]*list.Element),
 		list:    list.New(),
 		stopCh:  make(chan struct{}),
+		clock:   clock.OrReal(clk),
+		metrics: metrics.OrNop(r),
 	}
+	c.configureNamespaces(namespaces)
 
 	// start background cleanup goroutine
 	c.wg.Add(1)
@@ -52,50 +113,73 @@ func New(maxSize int, cleanupInterval time.Duration) *Cache {
 // Get retrieves a value from the cache.
 // Returns the value and true if found and not expired, or nil and false otherwise.
 func (c *Cache) Get(key string) (interface{}, bool) {
+	value, _, ok := c.get(key)
+	return value, ok
+}
+
+// get looks key up for Get and GetWithVersion, counting the hit or miss and
+// moving a live entry to the front.
+func (c *Cache) get(key string) (interface{}, uint64, bool) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	elem, exists := c.items[key]
 	if !exists {
-		return nil, false
+		c.misses.Add(1)
+		c.metrics.Miss()
+		return nil, 0, false
 	}
 
 	ent := elem.Value.(*entry)
 
 	// check if expired
-	if time.Now().After(ent.expiresAt) {
-		c.removeElement(elem)
-		return nil, false
+	if c.clock.Now().After(ent.expiresAt) {
+		c.expire(elem)
+		c.misses.Add(1)
+		c.metrics.Miss()
+		return nil, 0, false
 	}
 
 	// move to front (most recently used)
-	c.list.MoveToFront(elem)
+	c.moveToFront(elem)
+	c.hits.Add(1)
+	c.metrics.Hit()
 
-	return ent.value, true
+	return ent.value, ent.version, true
 }
 
 // Set adds or updates a value in the cache with the specified TTL (time to live).
 // If TTL is 0 or negative, the item never expires.
-func (c *Cache) Set(key string, value interface{}, ttl time.Duration) {
+// It returns the entry's new version.
+func (c *Cache) Set(key string, value interface{}, ttl time.Duration) uint64 {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	return c.set(key, value, ttl)
+}
+
+// set stores the entry under a new version and returns it.
+// must be called with lock held.
+func (c *Cache) set(key string, value interface{}, ttl time.Duration) uint64 {
 	var expiresAt time.Time
 	if ttl > 0 {
-		expiresAt = time.Now().Add(ttl)
+		expiresAt = c.clock.Now().Add(ttl)
 	} else {
 		// set to far future if no expiration
-		expiresAt = time.Now().Add(100 * 365 * 24 * time.Hour)
+		expiresAt = c.clock.Now().Add(100 * 365 * 24 * time.Hour)
 	}
 
+	c.version++
+
 	// check if key already exists
 	if elem, exists := c.items[key]; exists {
 		// update existing entry
 		ent := elem.Value.(*entry)
 		ent.value = value
 		ent.expiresAt = expiresAt
-		c.list.MoveToFront(elem)
-		return
+		ent.version = c.version
+		c.moveToFront(elem)
+		return c.version
 	}
 
 	// add new entry
@@ -103,14 +187,32 @@ func (c *Cache) Set(key string, value interface{}, ttl time.Duration) {
 		key:       key,
 		value:     value,
 		expiresAt: expiresAt,
+		version:   c.version,
 	}
 	elem := c.list.PushFront(ent)
 	c.items[key] = elem
 
+	// evict least recently used within the namespace if over its share
+	if ns := c.namespaceOf(key); ns != nil {
+		ent.ns = ns
+		ent.nsElem = ns.order.PushFront(elem)
+		if ns.maxEntries > 0 && ns.order.Len() > ns.maxEntries {
+			c.removeElement(ns.order.Back().Value.(*list.Element))
+			c.metrics.Eviction()
+		}
+	}
+
 	// evict least recently used if over capacity
 	if c.list.Len() > c.maxSize {
 		c.evict()
 	}
+
+	return c.version
+}
+
+// Stats returns the hit and miss counts recorded by Get.
+func (c *Cache) Stats() Stats {
+	return Stats{Hits: c.hits.Load(), Misses: c.misses.Load()}
 }
 
 // Delete removes a value from the cache.
@@ -130,6 +232,9 @@ func (c *Cache) Clear() {
 
 	c.list.Init()
 	c.items = make(map[string]*list.Element)
+	for _, ns := range c.namespaces {
+		ns.order.Init()
+	}
 }
 
 // Len returns the current number of items in the cache.
@@ -152,6 +257,18 @@ func (c *Cache) removeElement(elem *list.Element) {
 	ent := elem.Value.(*entry)
 	delete(c.items, ent.key)
 	c.list.Remove(elem)
+	if ent.ns != nil {
+		ent.ns.order.Remove(ent.nsElem)
+	}
+}
+
+// moveToFront marks elem as most recently used in the cache and its namespace.
+// must be called with lock held.
+func (c *Cache) moveToFront(elem *list.Element) {
+	c.list.MoveToFront(elem)
+	if ent := elem.Value.(*entry); ent.ns != nil {
+		ent.ns.order.MoveToFront(ent.nsElem)
+	}
 }
 
 // evict removes the least recently used item from the cache.
@@ -160,21 +277,29 @@ func (c *Cache) evict() {
 	elem := c.list.Back()
 	if elem != nil {
 		c.removeElement(elem)
+		c.metrics.Eviction()
 	}
 }
 
+// expire removes an expired element and records the expiration.
+// must be called with lock held.
+func (c *Cache) expire(elem *list.Element) {
+	c.removeElement(elem)
+	c.metrics.Expiration()
+}
+
 // cleanup periodically removes expired entries from the cache.
 func (c *Cache) cleanup(interval time.Duration) {
 	defer c.wg.Done()
 
-	ticker := time.NewTicker(interval)
+	ticker := c.clock.NewTicker(interval)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-c.stopCh:
 			return
-		case <-ticker.C:
+		case <-ticker.C():
 			c.removeExpired()
 		}
 	}
@@ -185,7 +310,7 @@ func (c *Cache) removeExpired() {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	now := time.Now()
+	now := c.clock.Now()
 	var toRemove []*list.Element
 
 	// collect expired elements
@@ -198,6 +323,8 @@ func (c *Cache) removeExpired() {
 
 	// remove expired elements
 	for _, elem := range toRemove {
-		c.removeElement(elem)
+		c.expire(elem)
 	}
+
+	c.metrics.SweepDuration(c.clock.Now().Sub(now))
 }
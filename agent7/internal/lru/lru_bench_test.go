@@ -0,0 +1,49 @@
+package lru
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func BenchmarkCache_GetHit(b *testing.B) {
+	const n = 1024
+	c := New(n, time.Hour)
+	defer c.Close()
+
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = "key:" + strconv.Itoa(i)
+		c.Set(keys[i], i, time.Hour)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, ok := c.Get(keys[i%n]); !ok {
+			b.Fatal("expected hit")
+		}
+	}
+	b.StopTimer()
+
+	if s := c.Stats(); s.Hits != uint64(b.N) || s.Misses != 0 {
+		b.Fatalf("unexpected stats %+v for %d lookups", s, b.N)
+	}
+}
+
+func TestCache_GetHitDoesNotAllocate(t *testing.T) {
+	c := New(4, time.Hour)
+	defer c.Close()
+
+	c.Set("user:1", 1, time.Hour)
+	c.Set("user:2", "two", 0)
+
+	allocs := testing.AllocsPerRun(1000, func() {
+		c.Get("user:1")
+		c.Get("user:2")
+		c.Get("missing")
+	})
+	if allocs != 0 {
+		t.Fatalf("expected Get to perform no allocations, got %v per run", allocs)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/rselbach/agent-comparison/clock"
+	"github.com/rselbach/agent-comparison/metrics"
 	"github.com/stretchr/testify/require"
 )
 
@@ -171,7 +173,8 @@ func TestCache_Clear(t *testing.T) {
 
 func TestCache_Expiration(t *testing.T) {
 	r := require.New(t)
-	cache := New(10, time.Minute)
+	clk := clock.NewFake(time.Unix(0, 0))
+	cache := NewWithClock(10, time.Minute, clk)
 	defer cache.Close()
 
 	// set item with short TTL
@@ -183,8 +186,8 @@ func TestCache_Expiration(t *testing.T) {
 	r.True(ok)
 	r.Equal("value1", val)
 
-	// wait for expiration
-	time.Sleep(150 * time.Millisecond)
+	// advance past expiration
+	clk.Advance(150 * time.Millisecond)
 
 	// expired item should return false
 	_, ok = cache.Get("key1")
@@ -198,9 +201,10 @@ func TestCache_Expiration(t *testing.T) {
 
 func TestCache_AutomaticCleanup(t *testing.T) {
 	r := require.New(t)
-	// use short cleanup interval for testing
-	cache := New(10, 100*time.Millisecond)
+	clk := clock.NewFake(time.Unix(0, 0))
+	cache := NewWithClock(10, 100*time.Millisecond, clk)
 	defer cache.Close()
+	clk.BlockUntil(1)
 
 	// add items with short TTL
 	cache.Set("key1", "value1", 50*time.Millisecond)
@@ -209,11 +213,9 @@ func TestCache_AutomaticCleanup(t *testing.T) {
 
 	r.Equal(3, cache.Len())
 
-	// wait for cleanup to run
-	time.Sleep(200 * time.Millisecond)
-
-	// expired items should be cleaned up
-	r.Equal(1, cache.Len())
+	// the tick wakes the cleanup goroutine, which removes the expired items
+	clk.Advance(100 * time.Millisecond)
+	r.Eventually(func() bool { return cache.Len() == 1 }, time.Second, time.Millisecond)
 
 	_, ok := cache.Get("key3")
 	r.True(ok)
@@ -304,3 +306,44 @@ func TestCache_Close(t *testing.T) {
 	r.True(ok)
 	r.Equal("value1", val)
 }
+
+func TestCache_Stats(t *testing.T) {
+	r := require.New(t)
+	clk := clock.NewFake(time.Unix(0, 0))
+	c := NewWithClock(2, time.Hour, clk)
+	defer c.Close()
+
+	c.Set("a", 1, 0)
+	c.Set("b", 2, time.Millisecond)
+	clk.Advance(5 * time.Millisecond)
+
+	c.Get("a")
+	c.Get("a")
+	c.Get("b") // expired
+	c.Get("missing")
+
+	r.Equal(Stats{Hits: 2, Misses: 2}, c.Stats())
+}
+
+func TestCache_Recorder(t *testing.T) {
+	r := require.New(t)
+	clk := clock.NewFake(time.Unix(0, 0))
+	rec := &metrics.Counters{}
+	c := NewWithRecorder(2, time.Hour, clk, rec)
+	defer c.Close()
+	clk.BlockUntil(1)
+
+	c.Set("a", 1, time.Second)
+	c.Set("b", 2, 0)
+	c.Get("a")
+	c.Get("missing")
+	c.Set("c", 3, 0) // evicts b
+
+	clk.Advance(2 * time.Second)
+	c.Get("a")
+	c.Set("d", 4, time.Millisecond)
+	clk.Advance(time.Hour)
+
+	r.Eventually(func() bool { return rec.Snapshot().Sweeps == 1 }, time.Second, time.Millisecond)
+	r.Equal(metrics.Snapshot{Hits: 1, Misses: 2, Evictions: 1, Expirations: 2, Sweeps: 1}, rec.Snapshot())
+}
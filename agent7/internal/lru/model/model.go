@@ -0,0 +1,229 @@
+// Package model provides a deliberately simple reference implementation of
+// the lru cache semantics for use in property-based tests.
+//
+// The model is a slice ordered from most to least recently used. Every
+// operation is a linear scan, there is no locking and no background cleanup,
+// so its behavior can be checked by reading it.
+package model
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// Cache is the reference LRU model. It is not safe for concurrent use.
+type Cache struct {
+	maxSize int
+	now     func() time.Time
+	entries []entry // front = most recently used
+	version uint64  // last version handed out by Set
+}
+
+type entry struct {
+	key       string
+	value     interface{}
+	expiresAt time.Time // zero means no expiration
+}
+
+// New creates a model with the given maximum size. If now is nil, time.Now is used.
+func New(maxSize int, now func() time.Time) *Cache {
+	if now == nil {
+		now = time.Now
+	}
+	return &Cache{maxSize: maxSize, now: now}
+}
+
+// Get returns the value for key and marks it most recently used.
+// Expired entries are removed and reported absent.
+func (m *Cache) Get(key string) (interface{}, bool) {
+	i := m.index(key)
+	if i < 0 {
+		return nil, false
+	}
+	e := m.entries[i]
+	if !e.expiresAt.IsZero() && m.now().After(e.expiresAt) {
+		m.remove(i)
+		return nil, false
+	}
+	m.remove(i)
+	m.entries = append([]entry{e}, m.entries...)
+	return e.value, true
+}
+
+// Set adds or updates key and marks it most recently used. If TTL is 0 or
+// negative, the item never expires. The least recently used entry is dropped
+// when the size limit is exceeded. Every call returns the next version.
+func (m *Cache) Set(key string, value interface{}, ttl time.Duration) uint64 {
+	m.version++
+	e := entry{key: key, value: value}
+	if ttl > 0 {
+		e.expiresAt = m.now().Add(ttl)
+	}
+	if i := m.index(key); i >= 0 {
+		m.remove(i)
+	}
+	m.entries = append([]entry{e}, m.entries...)
+	if len(m.entries) > m.maxSize {
+		m.entries = m.entries[:m.maxSize]
+	}
+	return m.version
+}
+
+// Delete removes key if present.
+func (m *Cache) Delete(key string) {
+	if i := m.index(key); i >= 0 {
+		m.remove(i)
+	}
+}
+
+// Clear removes all entries.
+func (m *Cache) Clear() {
+	m.entries = nil
+}
+
+// Len returns the number of stored entries, including expired entries that
+// have not been accessed since they expired.
+func (m *Cache) Len() int {
+	return len(m.entries)
+}
+
+// Keys returns the stored keys from most to least recently used.
+func (m *Cache) Keys() []string {
+	keys := make([]string, len(m.entries))
+	for i, e := range m.entries {
+		keys[i] = e.key
+	}
+	return keys
+}
+
+func (m *Cache) index(key string) int {
+	for i, e := range m.entries {
+		if e.key == key {
+			return i
+		}
+	}
+	return -1
+}
+
+func (m *Cache) remove(i int) {
+	m.entries = append(m.entries[:i], m.entries[i+1:]...)
+}
+
+// Target is the cache surface the model can be compared against.
+// *lru.Cache satisfies it.
+type Target interface {
+	Get(key string) (interface{}, bool)
+	Set(key string, value interface{}, ttl time.Duration) uint64
+	Delete(key string)
+	Clear()
+	Len() int
+}
+
+var _ Target = (*Cache)(nil)
+
+// OpKind identifies a cache operation.
+type OpKind int
+
+const (
+	OpGet OpKind = iota
+	OpSet
+	OpDelete
+	OpClear
+	OpLen
+)
+
+func (k OpKind) String() string {
+	switch k {
+	case OpGet:
+		return "Get"
+	case OpSet:
+		return "Set"
+	case OpDelete:
+		return "Delete"
+	case OpClear:
+		return "Clear"
+	case OpLen:
+		return "Len"
+	default:
+		return fmt.Sprintf("OpKind(%d)", int(k))
+	}
+}
+
+// Op is a single operation in a sequence. Key, Value and TTL are used only
+// by the kinds that take them.
+type Op struct {
+	Kind  OpKind
+	Key   string
+	Value interface{}
+	TTL   time.Duration
+}
+
+func (op Op) String() string {
+	switch op.Kind {
+	case OpGet, OpDelete:
+		return fmt.Sprintf("%s(%q)", op.Kind, op.Key)
+	case OpSet:
+		return fmt.Sprintf("%s(%q, %v, %s)", op.Kind, op.Key, op.Value, op.TTL)
+	default:
+		return op.Kind.String() + "()"
+	}
+}
+
+// Result is the observable outcome of applying an Op.
+type Result struct {
+	Value   interface{}
+	OK      bool
+	Len     int
+	Version uint64
+}
+
+// Apply performs op against c and returns its observable result.
+func Apply(c Target, op Op) Result {
+	switch op.Kind {
+	case OpGet:
+		v, ok := c.Get(op.Key)
+		return Result{Value: v, OK: ok}
+	case OpSet:
+		return Result{Version: c.Set(op.Key, op.Value, op.TTL)}
+	case OpDelete:
+		c.Delete(op.Key)
+	case OpClear:
+		c.Clear()
+	case OpLen:
+		return Result{Len: c.Len()}
+	}
+	return Result{}
+}
+
+// Compare applies ops in order to both got and want, returning an error that
+// describes the first operation whose results differ. After the sequence it
+// also checks that every key known to want reads back identically from got.
+func Compare(got Target, want *Cache, ops []Op) error {
+	for i, op := range ops {
+		g, w := Apply(got, op), Apply(want, op)
+		if !reflect.DeepEqual(g, w) {
+			return fmt.Errorf("op %d %s: got %+v, want %+v", i, op, g, w)
+		}
+	}
+	return Check(got, want)
+}
+
+// Check verifies that got holds the same entries as want, in the same
+// recency order. Keys are read from least to most recently used, which leaves
+// the recency order of both caches as it was.
+func Check(got Target, want *Cache) error {
+	if g, w := got.Len(), want.Len(); g != w {
+		return fmt.Errorf("Len: got %d, want %d", g, w)
+	}
+	keys := want.Keys()
+	for i := len(keys) - 1; i >= 0; i-- {
+		key := keys[i]
+		gv, gok := got.Get(key)
+		wv, wok := want.Get(key)
+		if gok != wok || !reflect.DeepEqual(gv, wv) {
+			return fmt.Errorf("Get(%q): got (%v, %t), want (%v, %t)", key, gv, gok, wv, wok)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package model_test
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/rselbach/agent7/internal/lru"
+	"github.com/rselbach/agent7/internal/lru/model"
+)
+
+func TestModel_LRUOrdering(t *testing.T) {
+	r := require.New(t)
+	m := model.New(2, nil)
+
+	m.Set("a", 1, 0)
+	m.Set("b", 2, 0)
+	m.Get("a")
+	m.Set("c", 3, 0)
+
+	r.Equal([]string{"c", "a"}, m.Keys())
+}
+
+func TestModel_Expiration(t *testing.T) {
+	r := require.New(t)
+	now := time.Unix(0, 0)
+	m := model.New(2, func() time.Time { return now })
+
+	m.Set("a", 1, time.Second)
+	now = now.Add(2 * time.Second)
+
+	r.Equal(1, m.Len())
+	_, ok := m.Get("a")
+	r.False(ok)
+	r.Equal(0, m.Len())
+}
+
+func TestCompare_DetectsDivergence(t *testing.T) {
+	r := require.New(t)
+	got := model.New(1, nil)
+	want := model.New(2, nil)
+
+	err := model.Compare(got, want, []model.Op{
+		{Kind: model.OpSet, Key: "a", Value: 1},
+		{Kind: model.OpSet, Key: "b", Value: 2},
+		{Kind: model.OpGet, Key: "a"},
+	})
+	r.Error(err)
+}
+
+func TestCompare_RandomSequences(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	kinds := []model.OpKind{model.OpGet, model.OpGet, model.OpSet, model.OpSet, model.OpDelete, model.OpLen, model.OpClear}
+
+	for seq := 0; seq < 200; seq++ {
+		maxSize := rng.Intn(5) + 1
+		ops := make([]model.Op, 50)
+		for i := range ops {
+			kind := kinds[rng.Intn(len(kinds))]
+			if kind == model.OpClear && rng.Intn(5) != 0 {
+				kind = model.OpGet
+			}
+			ops[i] = model.Op{
+				Kind:  kind,
+				Key:   fmt.Sprintf("k%d", rng.Intn(8)),
+				Value: rng.Intn(100),
+			}
+		}
+
+		t.Run(fmt.Sprint(seq), func(t *testing.T) {
+			cache := lru.New(maxSize, time.Hour)
+			defer cache.Close()
+			require.NoError(t, model.Compare(cache, model.New(maxSize, nil), ops))
+		})
+	}
+}
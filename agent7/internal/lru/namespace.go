@@ -0,0 +1,83 @@
+package lru
+
+import (
+	"container/list"
+	"strings"
+	"time"
+)
+
+// NamespaceSeparator separates a namespace name from the rest of a key.
+const NamespaceSeparator = ":"
+
+// Namespace configures defaults for keys under a common prefix.
+type Namespace struct {
+	// Name is the key prefix, without the trailing separator.
+	Name string
+	// DefaultTTL is the TTL SetDefault applies to keys in the namespace.
+	// 0 means entries never expire.
+	DefaultTTL time.Duration
+	// MaxEntries caps how many entries the namespace may hold. When it is
+	// exceeded the namespace's least recently used entry is evicted, so one
+	// namespace cannot push the others out. 0 means no cap beyond maxSize.
+	MaxEntries int
+}
+
+// namespace is the runtime state for a configured Namespace.
+type namespace struct {
+	defaultTTL time.Duration
+	maxEntries int
+	order      *list.List // of *list.Element in Cache.list, front = most recent
+}
+
+// SetDefault adds or updates a value using the default TTL of the namespace
+// the key belongs to. Keys outside a configured namespace never expire.
+// It returns the entry's new version.
+func (c *Cache) SetDefault(key string, value interface{}) uint64 {
+	var ttl time.Duration
+	if ns := c.namespaceOf(key); ns != nil {
+		ttl = ns.defaultTTL
+	}
+	return c.Set(key, value, ttl)
+}
+
+// NamespaceLen returns the number of items stored under the named namespace.
+// It returns 0 for namespaces that were not configured.
+func (c *Cache) NamespaceLen(name string) int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	ns, ok := c.namespaces[name]
+	if !ok {
+		return 0
+	}
+	return ns.order.Len()
+}
+
+// configureNamespaces installs namespaces, which validateConfig has checked.
+func (c *Cache) configureNamespaces(namespaces []Namespace) {
+	if len(namespaces) == 0 {
+		return
+	}
+
+	c.namespaces = make(map[string]*namespace, len(namespaces))
+	for _, cfg := range namespaces {
+		c.namespaces[cfg.Name] = &namespace{
+			defaultTTL: cfg.DefaultTTL,
+			maxEntries: cfg.MaxEntries,
+			order:      list.New(),
+		}
+	}
+}
+
+// namespaceOf returns the configured namespace key belongs to, or nil.
+// namespaces is immutable after construction, so no lock is needed.
+func (c *Cache) namespaceOf(key string) *namespace {
+	if c.namespaces == nil {
+		return nil
+	}
+	name, _, ok := strings.Cut(key, NamespaceSeparator)
+	if !ok {
+		return nil
+	}
+	return c.namespaces[name]
+}
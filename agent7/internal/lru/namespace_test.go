@@ -0,0 +1,76 @@
+package lru
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rselbach/agent-comparison/clock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetDefaultUsesNamespaceTTL(t *testing.T) {
+	r := require.New(t)
+	clk := clock.NewFake(time.Unix(0, 0))
+	c := NewWithClock(10, time.Hour, clk,
+		Namespace{Name: "session", DefaultTTL: 20 * time.Millisecond},
+		Namespace{Name: "user", DefaultTTL: time.Hour},
+	)
+	defer c.Close()
+
+	c.SetDefault("session:abc", 1)
+	c.SetDefault("user:1", 2)
+	c.SetDefault("other:1", 3)
+	c.SetDefault("plain", 4)
+
+	clk.Advance(40 * time.Millisecond)
+
+	_, ok := c.Get("session:abc")
+	r.False(ok, "session entry should have expired")
+	for _, key := range []string{"user:1", "other:1", "plain"} {
+		_, ok := c.Get(key)
+		r.True(ok, "expected %s to be present", key)
+	}
+}
+
+func TestNamespaceMaxEntries(t *testing.T) {
+	r := require.New(t)
+	c := NewWithNamespaces(10, time.Hour, Namespace{Name: "img", MaxEntries: 2})
+	defer c.Close()
+
+	c.Set("user:1", 1, 0)
+	c.Set("img:a", 1, 0)
+	c.Set("img:b", 2, 0)
+	_, _ = c.Get("img:a") // img:b is now least recently used in img
+	c.Set("img:c", 3, 0)
+
+	_, ok := c.Get("img:b")
+	r.False(ok)
+	_, ok = c.Get("img:a")
+	r.True(ok)
+	_, ok = c.Get("user:1")
+	r.True(ok, "other namespaces are not affected by the share")
+	r.Equal(2, c.NamespaceLen("img"))
+	r.Equal(3, c.Len())
+
+	c.Delete("img:a")
+	r.Equal(1, c.NamespaceLen("img"))
+	c.Clear()
+	r.Equal(0, c.NamespaceLen("img"))
+	r.Equal(0, c.NamespaceLen("unknown"))
+}
+
+func TestNewWithNamespacesValidation(t *testing.T) {
+	tests := map[string][]Namespace{
+		"empty name":     {{Name: ""}},
+		"separator":      {{Name: "a:b"}},
+		"duplicate":      {{Name: "a"}, {Name: "a"}},
+		"negative ttl":   {{Name: "a", DefaultTTL: -time.Second}},
+		"negative share": {{Name: "a", MaxEntries: -1}},
+	}
+
+	for name, namespaces := range tests {
+		t.Run(name, func(t *testing.T) {
+			require.Panics(t, func() { NewWithNamespaces(10, time.Hour, namespaces...) })
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package lru
+
+import "time"
+
+// GetWithVersion retrieves a value like Get together with its version.
+// Versions increase every time a key is written and are never reused, so a
+// caller can pass one to SetIfVersion to detect intervening writes.
+func (c *Cache) GetWithVersion(key string) (interface{}, uint64, bool) {
+	return c.get(key)
+}
+
+// SetIfVersion stores value like Set only if key is currently at version.
+// A version of 0 means the key must be absent or expired. It returns the new
+// version and true on success, or the current version (0 if absent) and false
+// if another write got there first.
+func (c *Cache) SetIfVersion(key string, value interface{}, version uint64, ttl time.Duration) (uint64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var current uint64
+	if elem, exists := c.items[key]; exists {
+		ent := elem.Value.(*entry)
+		if c.clock.Now().After(ent.expiresAt) {
+			c.expire(elem)
+		} else {
+			current = ent.version
+		}
+	}
+
+	if current != version {
+		return current, false
+	}
+
+	return c.set(key, value, ttl), true
+}
@@ -0,0 +1,130 @@
+package lru
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rselbach/agent-comparison/clock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache_Versions(t *testing.T) {
+	r := require.New(t)
+	cache := New(2, time.Second)
+	defer cache.Close()
+
+	v1 := cache.Set("a", 1, 0)
+	v2 := cache.Set("b", 2, 0)
+	v3 := cache.Set("a", 3, 0)
+	r.Greater(v2, v1)
+	r.Greater(v3, v2)
+
+	val, ver, ok := cache.GetWithVersion("a")
+	r.True(ok)
+	r.Equal(3, val)
+	r.Equal(v3, ver)
+
+	// a key stored again after removal never reuses an old version
+	cache.Delete("a")
+	v4 := cache.Set("a", 4, 0)
+	r.Greater(v4, v3)
+
+	_, ver, ok = cache.GetWithVersion("missing")
+	r.False(ok)
+	r.Zero(ver)
+}
+
+func TestCache_SetIfVersion(t *testing.T) {
+	tests := map[string]struct {
+		setup   func(c *Cache, clk *clock.Fake) uint64
+		version func(setupVersion uint64) uint64
+		wantOK  bool
+	}{
+		"matching version": {
+			setup:   func(c *Cache, _ *clock.Fake) uint64 { return c.Set("k", "old", 0) },
+			version: func(v uint64) uint64 { return v },
+			wantOK:  true,
+		},
+		"stale version": {
+			setup: func(c *Cache, _ *clock.Fake) uint64 {
+				v := c.Set("k", "old", 0)
+				c.Set("k", "newer", 0)
+				return v
+			},
+			version: func(v uint64) uint64 { return v },
+			wantOK:  false,
+		},
+		"zero creates missing key": {
+			setup:   func(c *Cache, _ *clock.Fake) uint64 { return 0 },
+			version: func(uint64) uint64 { return 0 },
+			wantOK:  true,
+		},
+		"zero fails for present key": {
+			setup:   func(c *Cache, _ *clock.Fake) uint64 { return c.Set("k", "old", 0) },
+			version: func(uint64) uint64 { return 0 },
+			wantOK:  false,
+		},
+		"zero succeeds for expired key": {
+			setup: func(c *Cache, clk *clock.Fake) uint64 {
+				c.Set("k", "old", time.Millisecond)
+				clk.Advance(5 * time.Millisecond)
+				return 0
+			},
+			version: func(uint64) uint64 { return 0 },
+			wantOK:  true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := require.New(t)
+			clk := clock.NewFake(time.Unix(0, 0))
+			cache := NewWithClock(4, time.Second, clk)
+			defer cache.Close()
+
+			setupVersion := tc.setup(cache, clk)
+			before, beforeVer, _ := cache.GetWithVersion("k")
+
+			ver, ok := cache.SetIfVersion("k", "written", tc.version(setupVersion), 0)
+			r.Equal(tc.wantOK, ok)
+
+			val, current, _ := cache.GetWithVersion("k")
+			r.Equal(current, ver)
+			if tc.wantOK {
+				r.Equal("written", val)
+				return
+			}
+			r.Equal(before, val)
+			r.Equal(beforeVer, current)
+		})
+	}
+}
+
+func TestCache_SetIfVersionConcurrent(t *testing.T) {
+	r := require.New(t)
+	cache := New(4, time.Second)
+	defer cache.Close()
+
+	cache.Set("counter", 0, 0)
+
+	const workers, increments = 8, 100
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := 0; n < increments; {
+				val, ver, _ := cache.GetWithVersion("counter")
+				if _, ok := cache.SetIfVersion("counter", val.(int)+1, ver, 0); ok {
+					n++
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	val, ok := cache.Get("counter")
+	r.True(ok)
+	r.Equal(workers*increments, val)
+}
@@ -0,0 +1,57 @@
+// Package lru makes agent7's cache, which lives in an internal package,
+// importable from other modules. Every identifier is an alias of, or forwards
+// to, the one in internal/lru; see there for the full documentation.
+package lru
+
+import (
+	"time"
+
+	"github.com/rselbach/agent-comparison/clock"
+	"github.com/rselbach/agent-comparison/metrics"
+	"github.com/rselbach/agent7/internal/lru"
+)
+
+// Cache is an LRU cache with automatic expiration support.
+type Cache = lru.Cache
+
+// Stats holds lookup counters since the cache was created.
+type Stats = lru.Stats
+
+// Namespace configures defaults for keys under a common prefix.
+type Namespace = lru.Namespace
+
+// ConfigError describes one invalid constructor argument.
+type ConfigError = lru.ConfigError
+
+// NamespaceSeparator separates a namespace name from the rest of a key.
+const NamespaceSeparator = lru.NamespaceSeparator
+
+// New creates a new LRU cache with the specified maximum size and cleanup
+// interval. If cleanupInterval is 0, a default of 1 minute is used.
+func New(maxSize int, cleanupInterval time.Duration) *Cache {
+	return lru.New(maxSize, cleanupInterval)
+}
+
+// NewWithNamespaces is like New but configures per-namespace defaults.
+// It panics on invalid arguments; use TryNew to get an error instead.
+func NewWithNamespaces(maxSize int, cleanupInterval time.Duration, namespaces ...Namespace) *Cache {
+	return lru.NewWithNamespaces(maxSize, cleanupInterval, namespaces...)
+}
+
+// TryNew is like NewWithNamespaces but reports invalid arguments as an error
+// instead of panicking.
+func TryNew(maxSize int, cleanupInterval time.Duration, namespaces ...Namespace) (*Cache, error) {
+	return lru.TryNew(maxSize, cleanupInterval, namespaces...)
+}
+
+// NewWithClock is like NewWithNamespaces but reads the time from clk and
+// drives the cleanup ticker from it. A nil clk means real time.
+func NewWithClock(maxSize int, cleanupInterval time.Duration, clk clock.Clock, namespaces ...Namespace) *Cache {
+	return lru.NewWithClock(maxSize, cleanupInterval, clk, namespaces...)
+}
+
+// NewWithRecorder is like NewWithClock but also reports hits, misses,
+// evictions, expirations and cleanup durations to r. A nil r records nothing.
+func NewWithRecorder(maxSize int, cleanupInterval time.Duration, clk clock.Clock, r metrics.Recorder, namespaces ...Namespace) *Cache {
+	return lru.NewWithRecorder(maxSize, cleanupInterval, clk, r, namespaces...)
+}
@@ -4,6 +4,9 @@ import (
 	"container/list"
 	"sync"
 	"time"
+
+	"github.com/rselbach/agent-comparison/clock"
+	"github.com/rselbach/agent-comparison/metrics"
 )
 
 type entry struct {
@@ -19,9 +22,19 @@ type LRU struct {
 	lruList  *list.List
 	ttl      time.Duration
 	stopCh   chan struct{}
+	clock    clock.Clock
+	metrics  metrics.Recorder
 }
 
 func NewLRU(capacity int, ttl time.Duration) *LRU {
+	return NewLRUWithClock(capacity, ttl, clock.Real{})
+}
+
+func NewLRUWithClock(capacity int, ttl time.Duration, clk clock.Clock) *LRU {
+	return NewLRUWithRecorder(capacity, ttl, clk, nil)
+}
+
+func NewLRUWithRecorder(capacity int, ttl time.Duration, clk clock.Clock, r metrics.Recorder) *LRU {
 	if capacity <= 0 {
 		panic("capacity must be positive")
 	}
@@ -32,6 +45,8 @@ func NewLRU(capacity int, ttl time.Duration) *LRU {
 		lruList:  list.New(),
 		ttl:      ttl,
 		stopCh:   make(chan struct{}),
+		clock:    clock.OrReal(clk),
+		metrics:  metrics.OrNop(r),
 	}
 
 	if ttl > 0 {
@@ -45,7 +60,7 @@ func (l *LRU) Set(key string, value any) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
-	now := time.Now()
+	now := l.clock.Now()
 	expiresAt := time.Time{}
 	if l.ttl > 0 {
 		expiresAt = now.Add(l.ttl)
@@ -78,17 +93,21 @@ func (l *LRU) Get(key string) (any, bool) {
 
 	elem, exists := l.items[key]
 	if !exists {
+		l.metrics.Miss()
 		return nil, false
 	}
 
 	e := elem.Value.(*entry)
 
-	if !e.expiresAt.IsZero() && time.Now().After(e.expiresAt) {
+	if l.isExpired(e, l.clock.Now()) {
 		l.removeElement(elem)
+		l.metrics.Expiration()
+		l.metrics.Miss()
 		return nil, false
 	}
 
 	l.lruList.MoveToFront(elem)
+	l.metrics.Hit()
 	return e.value, true
 }
 
@@ -101,17 +120,63 @@ func (l *LRU) Delete(key string) {
 	}
 }
 
+func (l *LRU) Contains(key string) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	elem, exists := l.items[key]
+	if !exists {
+		return false
+	}
+
+	return !l.isExpired(elem.Value.(*entry), l.clock.Now())
+}
+
+func (l *LRU) Keys() []string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	now := l.clock.Now()
+	keys := make([]string, 0, l.lruList.Len())
+	for elem := l.lruList.Front(); elem != nil; elem = elem.Next() {
+		e := elem.Value.(*entry)
+		if !l.isExpired(e, now) {
+			keys = append(keys, e.key)
+		}
+	}
+
+	return keys
+}
+
 func (l *LRU) Len() int {
 	l.mu.RLock()
 	defer l.mu.RUnlock()
 	return l.lruList.Len()
 }
 
-func (l *LRU) Clear() {
+func (l *LRU) Purge() int {
+	return l.removeExpiredEntries()
+}
+
+func (l *LRU) Clear(onClear ...func(key string, value any)) {
 	l.mu.Lock()
-	defer l.mu.Unlock()
+	var removed []*entry
+	if len(onClear) > 0 {
+		removed = make([]*entry, 0, l.lruList.Len())
+		for elem := l.lruList.Front(); elem != nil; elem = elem.Next() {
+			removed = append(removed, elem.Value.(*entry))
+		}
+	}
 	l.items = make(map[string]*list.Element)
 	l.lruList.Init()
+	l.mu.Unlock()
+
+	// run callbacks outside the lock so they may use the cache
+	for _, e := range removed {
+		for _, fn := range onClear {
+			fn(e.key, e.value)
+		}
+	}
 }
 
 func (l *LRU) Close() {
@@ -122,6 +187,7 @@ func (l *LRU) evictOldest() {
 	elem := l.lruList.Back()
 	if elem != nil {
 		l.removeElement(elem)
+		l.metrics.Eviction()
 	}
 }
 
@@ -132,12 +198,12 @@ func (l *LRU) removeElement(elem *list.Element) {
 }
 
 func (l *LRU) cleanupExpired() {
-	ticker := time.NewTicker(l.ttl / 2)
+	ticker := l.clock.NewTicker(l.ttl / 2)
 	defer ticker.Stop()
 
 	for {
 		select {
-		case <-ticker.C:
+		case <-ticker.C():
 			l.removeExpiredEntries()
 		case <-l.stopCh:
 			return
@@ -145,21 +211,29 @@ func (l *LRU) cleanupExpired() {
 	}
 }
 
-func (l *LRU) removeExpiredEntries() {
+func (l *LRU) removeExpiredEntries() int {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
-	now := time.Now()
+	now := l.clock.Now()
 	var toRemove []*list.Element
 
 	for elem := l.lruList.Back(); elem != nil; elem = elem.Prev() {
 		e := elem.Value.(*entry)
-		if !e.expiresAt.IsZero() && now.After(e.expiresAt) {
+		if l.isExpired(e, now) {
 			toRemove = append(toRemove, elem)
 		}
 	}
 
 	for _, elem := range toRemove {
 		l.removeElement(elem)
+		l.metrics.Expiration()
 	}
+
+	l.metrics.SweepDuration(l.clock.Now().Sub(now))
+	return len(toRemove)
+}
+
+func (l *LRU) isExpired(e *entry, now time.Time) bool {
+	return !e.expiresAt.IsZero() && now.After(e.expiresAt)
 }
@@ -4,6 +4,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/rselbach/agent-comparison/clock"
+	"github.com/rselbach/agent-comparison/metrics"
 	"github.com/stretchr/testify/require"
 )
 
@@ -103,7 +105,8 @@ func TestLRU_LRUOrder(t *testing.T) {
 
 func TestLRU_Expiration(t *testing.T) {
 	r := require.New(t)
-	lru := NewLRU(3, 100*time.Millisecond)
+	clk := clock.NewFake(time.Unix(0, 0))
+	lru := NewLRUWithClock(3, 100*time.Millisecond, clk)
 	defer lru.Close()
 
 	lru.Set("key1", "value1")
@@ -111,7 +114,7 @@ func TestLRU_Expiration(t *testing.T) {
 	_, ok := lru.Get("key1")
 	r.True(ok)
 
-	time.Sleep(150 * time.Millisecond)
+	clk.Advance(150 * time.Millisecond)
 
 	_, ok = lru.Get("key1")
 	r.False(ok)
@@ -119,8 +122,10 @@ func TestLRU_Expiration(t *testing.T) {
 
 func TestLRU_CleanupExpired(t *testing.T) {
 	r := require.New(t)
-	lru := NewLRU(5, 100*time.Millisecond)
+	clk := clock.NewFake(time.Unix(0, 0))
+	lru := NewLRUWithClock(5, 100*time.Millisecond, clk)
 	defer lru.Close()
+	clk.BlockUntil(1)
 
 	lru.Set("key1", "value1")
 	lru.Set("key2", "value2")
@@ -128,13 +133,35 @@ func TestLRU_CleanupExpired(t *testing.T) {
 
 	r.Equal(3, lru.Len())
 
-	time.Sleep(150 * time.Millisecond)
+	clk.Advance(150 * time.Millisecond)
 
 	r.Eventually(func() bool {
 		return lru.Len() == 0
 	}, 200*time.Millisecond, 10*time.Millisecond)
 }
 
+func TestLRU_Recorder(t *testing.T) {
+	r := require.New(t)
+	clk := clock.NewFake(time.Unix(0, 0))
+	rec := &metrics.Counters{}
+	lru := NewLRUWithRecorder(2, time.Minute, clk, rec)
+	defer lru.Close()
+
+	lru.Set("a", 1)
+	lru.Set("b", 2)
+	lru.Get("a")
+	lru.Get("missing")
+	lru.Set("c", 3) // evicts b
+
+	clk.BlockUntil(1)
+	clk.Advance(2 * time.Minute)
+	r.Eventually(func() bool { return rec.Snapshot().Sweeps > 0 }, time.Second, time.Millisecond)
+	lru.Get("a")
+
+	got := rec.Snapshot()
+	r.Equal(metrics.Snapshot{Hits: 1, Misses: 2, Evictions: 1, Expirations: 2, Sweeps: got.Sweeps}, got)
+}
+
 func TestLRU_Delete(t *testing.T) {
 	r := require.New(t)
 	lru := NewLRU(3, 0)
@@ -178,3 +205,82 @@ func TestLRU_PanicOnInvalidCapacity(t *testing.T) {
 		NewLRU(0, 0)
 	})
 }
+
+func TestLRU_KeysAndContains(t *testing.T) {
+	r := require.New(t)
+	lru := NewLRU(3, 0)
+	defer lru.Close()
+
+	r.Empty(lru.Keys())
+	r.False(lru.Contains("key1"))
+
+	lru.Set("key1", "value1")
+	lru.Set("key2", "value2")
+	lru.Set("key3", "value3")
+	lru.Get("key1")
+
+	r.Equal([]string{"key1", "key3", "key2"}, lru.Keys())
+	r.True(lru.Contains("key2"))
+	r.False(lru.Contains("missing"))
+
+	// neither Keys nor Contains promoted key2
+	lru.Set("key4", "value4")
+	r.False(lru.Contains("key2"))
+	r.Equal([]string{"key4", "key1", "key3"}, lru.Keys())
+}
+
+func TestLRU_KeysAndContainsSkipExpired(t *testing.T) {
+	r := require.New(t)
+	clk := clock.NewFake(time.Unix(0, 0))
+	lru := NewLRUWithClock(3, 20*time.Millisecond, clk)
+	defer lru.Close()
+
+	lru.Set("key1", "value1")
+	clk.Advance(30 * time.Millisecond)
+	lru.Set("key2", "value2")
+
+	r.False(lru.Contains("key1"))
+	r.Equal([]string{"key2"}, lru.Keys())
+}
+
+func TestLRU_Purge(t *testing.T) {
+	r := require.New(t)
+	clk := clock.NewFake(time.Unix(0, 0))
+	lru := NewLRUWithClock(5, 20*time.Millisecond, clk)
+	// stop the background cleanup so only Purge removes entries
+	lru.Close()
+
+	r.Equal(0, lru.Purge())
+
+	lru.Set("key1", "value1")
+	lru.Set("key2", "value2")
+	clk.Advance(30 * time.Millisecond)
+	lru.Set("key3", "value3")
+
+	r.Equal(3, lru.Len())
+	r.Equal(2, lru.Purge())
+	r.Equal(1, lru.Len())
+	r.Equal(0, lru.Purge())
+	r.True(lru.Contains("key3"))
+}
+
+func TestLRU_ClearWithCallback(t *testing.T) {
+	r := require.New(t)
+	lru := NewLRU(3, 0)
+	defer lru.Close()
+
+	lru.Set("key1", "value1")
+	lru.Set("key2", "value2")
+	lru.Set("key3", "value3")
+	lru.Get("key1")
+
+	var released []string
+	lru.Clear(func(key string, value any) {
+		// the callback may safely use the cache again
+		r.False(lru.Contains(key))
+		released = append(released, key+"="+value.(string))
+	})
+
+	r.Equal([]string{"key1=value1", "key3=value3", "key2=value2"}, released)
+	r.Equal(0, lru.Len())
+}
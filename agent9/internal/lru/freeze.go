@@ -0,0 +1,31 @@
+package lru
+
+import "errors"
+
+// ErrFrozen is returned by mutating operations while the cache is frozen.
+var ErrFrozen = errors.New("lru: cache is frozen")
+
+// Freeze switches the cache into read-only mode. Set and Delete fail with
+// ErrFrozen, lookups leave recency and expired entries untouched, and the
+// janitor skips its scans, so the contents stay exactly as they were until
+// Thaw is called. Freezing a frozen cache is a no-op.
+func (c *Cache[K, V]) Freeze() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.frozen = true
+}
+
+// Thaw leaves read-only mode. Entries that expired while frozen are reclaimed
+// by the next lookup or scan as usual.
+func (c *Cache[K, V]) Thaw() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.frozen = false
+}
+
+// Frozen reports whether the cache is in read-only mode.
+func (c *Cache[K, V]) Frozen() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.frozen
+}
@@ -0,0 +1,79 @@
+package lru
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rselbach/agent-comparison/clock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFreezeRejectsMutations(t *testing.T) {
+	r := require.New(t)
+	c := New[string, int](2, WithoutJanitor[string, int]())
+	defer c.Close()
+	r.NoError(c.Set("a", 1, 0))
+	r.NoError(c.Set("b", 2, 0))
+
+	c.Freeze()
+	r.True(c.Frozen())
+	r.ErrorIs(c.Set("c", 3, 0), ErrFrozen)
+	ok, err := c.Delete("a")
+	r.ErrorIs(err, ErrFrozen)
+	r.False(ok)
+	r.Equal(uint64(0), c.BumpEpoch())
+
+	// Reads do not touch recency, so a stays the eviction candidate.
+	v, ok := c.Get("a")
+	r.True(ok)
+	r.Equal(1, v)
+
+	c.Thaw()
+	r.False(c.Frozen())
+	r.NoError(c.Set("c", 3, 0))
+	_, ok = c.Peek("a")
+	r.False(ok)
+	_, ok = c.Peek("b")
+	r.True(ok)
+}
+
+func TestFreezeKeepsExpiredEntries(t *testing.T) {
+	r := require.New(t)
+	clk := clock.NewFake(time.Unix(0, 0))
+	c := New[string, int](4, WithClock[string, int](clk), WithoutJanitor[string, int]())
+	defer c.Close()
+	r.NoError(c.Set("short", 1, time.Millisecond))
+	r.NoError(c.Set("keep", 2, 0))
+
+	c.Freeze()
+	clk.Advance(5 * time.Millisecond)
+	_, ok := c.Get("short")
+	r.False(ok)
+	_, ok = c.Peek("short")
+	r.False(ok)
+	r.Equal(0, c.RunExpireScan())
+	r.Equal(2, c.Len())
+
+	c.Thaw()
+	r.Equal(1, c.RunExpireScan())
+	r.Equal(1, c.Len())
+}
+
+func TestFreezeReadBuffer(t *testing.T) {
+	r := require.New(t)
+	c := New[string, int](2, WithReadBuffer[string, int](), WithoutJanitor[string, int]())
+	defer c.Close()
+	r.NoError(c.Set("a", 1, 0))
+	r.NoError(c.Set("b", 2, 0))
+
+	c.Freeze()
+	for i := 0; i < 1000; i++ {
+		_, ok := c.Get("a")
+		r.True(ok)
+	}
+	c.Thaw()
+
+	r.NoError(c.Set("c", 3, 0))
+	_, ok := c.Peek("a")
+	r.False(ok)
+}
@@ -2,8 +2,12 @@ package lru
 
 import (
 	"container/list"
+	"runtime"
 	"sync"
 	"time"
+
+	"github.com/rselbach/agent-comparison/clock"
+	"github.com/rselbach/agent-comparison/metrics"
 )
 
 // Cache is an LRU cache with per-entry ttl expiration and background janitor.
@@ -15,6 +19,11 @@ type Cache[K comparable, V any] struct {
 	items   map[K]*list.Element
 	list    *list.List // front = most recent
 	janitor *janitor
+	epoch   uint64
+	reads   *readBuffer
+	frozen  bool
+	clock   clock.Clock
+	metrics metrics.Recorder
 }
 
 type entry[K comparable, V any] struct {
@@ -22,6 +31,7 @@ type entry[K comparable, V any] struct {
 	value     V
 	expiresAt time.Time
 	ttl       time.Duration
+	epoch     uint64
 }
 
 // Option configures cache creation.
@@ -34,76 +44,183 @@ func WithCapacity[K comparable, V any](c int) Option[K, V] {
 	}
 }
 
-// WithJanitorInterval sets the interval for background expiration scan.
+// WithJanitorInterval fixes the interval for background expiration scan,
+// disabling adaptation. An interval <= 0 disables the janitor.
 func WithJanitorInterval[K comparable, V any](d time.Duration) Option[K, V] {
+	return WithJanitorBounds[K, V](d, d)
+}
+
+// WithJanitorBounds sets the range the janitor's interval adapts within. The
+// janitor starts at min, halves its interval when a scan finds at least a
+// quarter of entries expired, and doubles it up to max when a scan finds none.
+// The default is 1s to 30s. A min <= 0 disables the janitor, as
+// WithoutJanitor does, and a max below min is raised to min.
+func WithJanitorBounds[K comparable, V any](min, max time.Duration) Option[K, V] {
 	return func(cache *Cache[K, V]) {
 		if cache.janitor != nil {
-			cache.janitor.interval = d
+			cache.janitor.min = min
+			cache.janitor.max = max
 		}
 	}
 }
 
+// WithoutJanitor disables the background expiration scan. Expired entries are
+// still hidden by Get/Peek; call RunExpireScan to reclaim them explicitly.
+func WithoutJanitor[K comparable, V any]() Option[K, V] {
+	return func(cache *Cache[K, V]) {
+		cache.janitor = nil
+	}
+}
+
+// WithReadBuffer enables the experimental read-optimized mode. Get takes only
+// the read lock and records hits in striped buffers that are applied to the
+// recency list in batches, so concurrent readers do not serialize on the
+// write lock. Eviction order becomes approximate, and expired or stale entries
+// found by Get are reported absent but left for the janitor or RunExpireScan.
+func WithReadBuffer[K comparable, V any]() Option[K, V] {
+	return func(cache *Cache[K, V]) {
+		cache.reads = &readBuffer{}
+	}
+}
+
+// WithClock sets the clock used for expiry and for the janitor's timer, so a
+// clock.Fake can drive both in tests. A nil clock means real time.
+func WithClock[K comparable, V any](clk clock.Clock) Option[K, V] {
+	return func(cache *Cache[K, V]) {
+		cache.clock = clock.OrReal(clk)
+	}
+}
+
+// WithRecorder sets the recorder that receives hits, misses, evictions,
+// expirations and expire scan durations. Entries dropped because they predate
+// BumpEpoch are not expirations. A nil recorder records nothing.
+func WithRecorder[K comparable, V any](r metrics.Recorder) Option[K, V] {
+	return func(cache *Cache[K, V]) {
+		cache.metrics = metrics.OrNop(r)
+	}
+}
+
 // New constructs a cache with given capacity and options. Capacity must be > 0.
 func New[K comparable, V any](capacity int, opts ...Option[K, V]) *Cache[K, V] {
 	if capacity <= 0 {
 		panic("capacity must be > 0")
 	}
 	c := &Cache[K, V]{
-		cap:   capacity,
-		items: make(map[K]*list.Element, capacity),
-		list:  list.New(),
+		cap:     capacity,
+		items:   make(map[K]*list.Element, capacity),
+		list:    list.New(),
+		clock:   clock.Real{},
+		metrics: metrics.Nop{},
 	}
-	c.janitor = &janitor{interval: time.Second * 30, stop: make(chan struct{})}
+	c.janitor = &janitor{min: time.Second, max: time.Second * 30, stop: make(chan struct{})}
 	for _, o := range opts {
 		o(c)
 	}
+	if j := c.janitor; j != nil {
+		if j.min <= 0 {
+			c.janitor = nil
+		} else if j.max < j.min {
+			j.max = j.min
+		}
+	}
 	c.startJanitor()
 	return c
 }
 
 // Set inserts or updates a value with ttl. ttl <= 0 means no expiration.
-func (c *Cache[K, V]) Set(key K, value V, ttl time.Duration) {
+// It returns ErrFrozen while the cache is frozen.
+func (c *Cache[K, V]) Set(key K, value V, ttl time.Duration) error {
 	var exp time.Time
 	if ttl > 0 {
-		exp = time.Now().Add(ttl)
+		exp = c.clock.Now().Add(ttl)
 	}
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	if c.frozen {
+		return ErrFrozen
+	}
 	if el, ok := c.items[key]; ok {
 		ent := el.Value.(*entry[K, V])
 		ent.value = value
 		ent.ttl = ttl
 		ent.expiresAt = exp
+		ent.epoch = c.epoch
 		c.list.MoveToFront(el)
-		return
+		return nil
 	}
 	if c.list.Len() >= c.cap {
+		if c.reads != nil {
+			c.reads.drain(c.list)
+		}
 		c.removeOldestLocked()
 	}
-	el := c.list.PushFront(&entry[K, V]{key: key, value: value, ttl: ttl, expiresAt: exp})
+	el := c.list.PushFront(&entry[K, V]{key: key, value: value, ttl: ttl, expiresAt: exp, epoch: c.epoch})
 	c.items[key] = el
+	return nil
 }
 
-// Get returns value and a bool indicating presence. Expired items are evicted and reported absent.
+// Get returns value and a bool indicating presence. Expired items and items
+// written before the last BumpEpoch are evicted and reported absent. While the
+// cache is frozen, Get neither evicts nor updates recency.
 func (c *Cache[K, V]) Get(key K) (V, bool) {
+	if c.reads != nil {
+		return c.getBuffered(key)
+	}
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	el, ok := c.items[key]
 	if !ok {
+		c.metrics.Miss()
 		var zero V
 		return zero, false
 	}
 	ent := el.Value.(*entry[K, V])
-	if ent.ttl > 0 && time.Now().After(ent.expiresAt) {
-		c.removeElementLocked(el)
+	if now := c.clock.Now(); c.staleLocked(ent, now) {
+		if !c.frozen {
+			c.removeStaleLocked(el, now)
+		}
+		c.metrics.Miss()
 		var zero V
 		return zero, false
 	}
-	c.list.MoveToFront(el)
+	if !c.frozen {
+		c.list.MoveToFront(el)
+	}
+	c.metrics.Hit()
 	return ent.value, true
 }
 
-// Peek returns value without updating recency. Expired items are evicted.
+// getBuffered is Get for the read-optimized mode.
+func (c *Cache[K, V]) getBuffered(key K) (V, bool) {
+	c.mu.RLock()
+	el, ok := c.items[key]
+	var (
+		value V
+		full  bool
+	)
+	if ok {
+		ent := el.Value.(*entry[K, V])
+		if ok = !c.staleLocked(ent, c.clock.Now()); ok {
+			value = ent.value
+			full = !c.frozen && c.reads.record(el)
+		}
+	}
+	c.mu.RUnlock()
+	if ok {
+		c.metrics.Hit()
+	} else {
+		c.metrics.Miss()
+	}
+
+	if full && c.mu.TryLock() {
+		c.reads.drain(c.list)
+		c.mu.Unlock()
+	}
+	return value, ok
+}
+
+// Peek returns value without updating recency. Expired items are evicted
+// unless the cache is frozen.
 func (c *Cache[K, V]) Peek(key K) (V, bool) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -113,27 +230,33 @@ func (c *Cache[K, V]) Peek(key K) (V, bool) {
 		return zero, false
 	}
 	ent := el.Value.(*entry[K, V])
-	if ent.ttl > 0 && time.Now().After(ent.expiresAt) {
-		c.removeElementLocked(el)
+	if now := c.clock.Now(); c.staleLocked(ent, now) {
+		if !c.frozen {
+			c.removeStaleLocked(el, now)
+		}
 		var zero V
 		return zero, false
 	}
 	return ent.value, true
 }
 
-// Delete removes a key if present.
-func (c *Cache[K, V]) Delete(key K) bool {
+// Delete removes a key if present and reports whether it was. It returns
+// ErrFrozen while the cache is frozen.
+func (c *Cache[K, V]) Delete(key K) (bool, error) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	if c.frozen {
+		return false, ErrFrozen
+	}
 	el, ok := c.items[key]
 	if !ok {
-		return false
+		return false, nil
 	}
 	c.removeElementLocked(el)
-	return true
+	return true, nil
 }
 
-// Len returns current number of items.
+// Len returns current number of items, including expired or stale ones not yet reclaimed.
 func (c *Cache[K, V]) Len() int {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
@@ -159,12 +282,53 @@ func (c *Cache[K, V]) Close() {
 	}
 }
 
+// BumpEpoch invalidates every entry currently in the cache in O(1) and returns
+// the new epoch. Invalidated entries are reported absent immediately and are
+// reclaimed lazily by lookups, eviction, and RunExpireScan, which works in
+// bounded batches, so the lock is never held for a full clear. While the cache
+// is frozen it returns the current epoch unchanged.
+func (c *Cache[K, V]) BumpEpoch() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.frozen {
+		return c.epoch
+	}
+	c.epoch++
+	return c.epoch
+}
+
+// Epoch returns the current epoch.
+func (c *Cache[K, V]) Epoch() uint64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.epoch
+}
+
+// staleLocked reports whether ent has expired or predates the current epoch.
+func (c *Cache[K, V]) staleLocked(ent *entry[K, V], now time.Time) bool {
+	if ent.epoch != c.epoch {
+		return true
+	}
+	return ent.ttl > 0 && now.After(ent.expiresAt)
+}
+
 func (c *Cache[K, V]) removeOldestLocked() {
 	el := c.list.Back()
 	if el == nil {
 		return
 	}
 	c.removeElementLocked(el)
+	c.metrics.Eviction()
+}
+
+// removeStaleLocked removes a stale entry, recording an expiration if its ttl
+// has passed rather than only its epoch.
+func (c *Cache[K, V]) removeStaleLocked(el *list.Element, now time.Time) {
+	ent := el.Value.(*entry[K, V])
+	c.removeElementLocked(el)
+	if ent.ttl > 0 && now.After(ent.expiresAt) {
+		c.metrics.Expiration()
+	}
 }
 
 func (c *Cache[K, V]) removeElementLocked(el *list.Element) {
@@ -173,23 +337,42 @@ func (c *Cache[K, V]) removeElementLocked(el *list.Element) {
 	c.list.Remove(el)
 }
 
+// denseExpiryRatio is the inverse of the fraction of scanned entries that must
+// have expired for the janitor to shorten its interval.
+const denseExpiryRatio = 4
+
 type janitor struct {
-	interval time.Duration
+	min, max time.Duration
 	stop     chan struct{}
 }
 
+// next returns the interval to wait after a scan that removed removed of
+// scanned entries, given the interval that preceded it.
+func (j *janitor) next(interval time.Duration, removed, scanned int) time.Duration {
+	switch {
+	case removed > 0 && removed*denseExpiryRatio >= scanned:
+		interval /= 2
+	case removed == 0:
+		interval *= 2
+	}
+	return min(max(interval, j.min), j.max)
+}
+
 func (c *Cache[K, V]) startJanitor() {
 	j := c.janitor
 	if j == nil {
 		return
 	}
 	go func() {
-		ticker := time.NewTicker(j.interval)
-		defer ticker.Stop()
+		interval := j.min
+		timer := c.clock.NewTimer(interval)
+		defer timer.Stop()
 		for {
 			select {
-			case <-ticker.C:
-				c.expireScan()
+			case <-timer.C():
+				removed, scanned := c.expireScan()
+				interval = j.next(interval, removed, scanned)
+				timer.Reset(interval)
 			case <-j.stop:
 				return
 			}
@@ -197,17 +380,55 @@ func (c *Cache[K, V]) startJanitor() {
 	}()
 }
 
-// expireScan removes expired entries. holds lock briefly per check.
-func (c *Cache[K, V]) expireScan() {
-	now := time.Now()
+// RunExpireScan removes expired and stale entries synchronously and returns how many were removed.
+// It is what the janitor runs on each tick; callers using WithoutJanitor invoke it directly.
+// The lock is released after every expireScanBatch entries, so a scan after BumpEpoch does not
+// block other callers for the length of the cache. Entries moved or removed by those callers in
+// the meantime can cut the scan short; whatever it missed is left for the next scan.
+// While the cache is frozen it removes nothing, which pauses the janitor.
+func (c *Cache[K, V]) RunExpireScan() int {
+	removed, _ := c.expireScan()
+	return removed
+}
+
+// expireScanBatch is how many entries expireScan examines per hold of the lock.
+const expireScanBatch = 1024
+
+// expireScan is RunExpireScan, also reporting how many entries were examined.
+func (c *Cache[K, V]) expireScan() (removed, scanned int) {
+	start := c.clock.Now()
 	c.mu.Lock()
-	for el := c.list.Back(); el != nil; {
-		prev := el.Prev()
-		ent := el.Value.(*entry[K, V])
-		if ent.ttl > 0 && now.After(ent.expiresAt) {
-			c.removeElementLocked(el)
+	defer c.mu.Unlock()
+	if c.frozen {
+		return 0, 0
+	}
+	el := c.list.Back()
+	for el != nil {
+		now := c.clock.Now()
+		for n := 0; el != nil && n < expireScanBatch; n++ {
+			prev := el.Prev()
+			ent := el.Value.(*entry[K, V])
+			if c.staleLocked(ent, now) {
+				c.removeStaleLocked(el, now)
+				removed++
+			}
+			scanned++
+			el = prev
 		}
-		el = prev
+		if el == nil {
+			break
+		}
+
+		// let other callers in, then resume at the same entry if it is still there
+		key := el.Value.(*entry[K, V]).key
+		c.mu.Unlock()
+		runtime.Gosched()
+		c.mu.Lock()
+		if c.frozen {
+			break
+		}
+		el = c.items[key]
 	}
-	c.mu.Unlock()
+	c.metrics.SweepDuration(c.clock.Now().Sub(start))
+	return removed, scanned
 }
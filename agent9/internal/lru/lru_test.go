@@ -4,6 +4,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/rselbach/agent-comparison/clock"
+	"github.com/rselbach/agent-comparison/metrics"
 	"github.com/stretchr/testify/require"
 )
 
@@ -43,7 +45,8 @@ func TestCacheRecency(t *testing.T) {
 
 func TestExpiration(t *testing.T) {
 	r := require.New(t)
-	c := New[string, int](3, WithJanitorInterval[string, int](10*time.Millisecond))
+	clk := clock.NewFake(time.Unix(0, 0))
+	c := New[string, int](3, WithJanitorInterval[string, int](10*time.Millisecond), WithClock[string, int](clk))
 	c.Set("short", 1, 20*time.Millisecond)
 	c.Set("long", 2, 200*time.Millisecond)
 	c.Set("none", 3, 0)
@@ -54,7 +57,7 @@ func TestExpiration(t *testing.T) {
 	r.True(ok)
 	_, ok = c.Get("none")
 	r.True(ok)
-	time.Sleep(60 * time.Millisecond) // allow short to expire and janitor run
+	clk.Advance(60 * time.Millisecond) // short expires; long and none do not
 	_, ok = c.Get("short")
 	r.False(ok)
 	_, ok = c.Get("long")
@@ -66,9 +69,10 @@ func TestExpiration(t *testing.T) {
 
 func TestManualExpireOnGet(t *testing.T) {
 	r := require.New(t)
-	c := New[string, int](2)
+	clk := clock.NewFake(time.Unix(0, 0))
+	c := New[string, int](2, WithClock[string, int](clk))
 	c.Set("x", 9, 10*time.Millisecond)
-	time.Sleep(20 * time.Millisecond)
+	clk.Advance(20 * time.Millisecond)
 	_, ok := c.Get("x")
 	r.False(ok)
 	c.Close()
@@ -76,11 +80,12 @@ func TestManualExpireOnGet(t *testing.T) {
 
 func TestUpdateResetsTTL(t *testing.T) {
 	r := require.New(t)
-	c := New[string, int](1)
+	clk := clock.NewFake(time.Unix(0, 0))
+	c := New[string, int](1, WithClock[string, int](clk))
 	c.Set("a", 1, 10*time.Millisecond)
-	time.Sleep(5 * time.Millisecond)
+	clk.Advance(5 * time.Millisecond)
 	c.Set("a", 2, 20*time.Millisecond)
-	time.Sleep(12 * time.Millisecond)
+	clk.Advance(12 * time.Millisecond)
 	v, ok := c.Get("a")
 	r.True(ok)
 	r.Equal(2, v)
@@ -91,9 +96,156 @@ func TestDelete(t *testing.T) {
 	r := require.New(t)
 	c := New[string, int](1)
 	c.Set("a", 1, 0)
-	r.True(c.Delete("a"))
-	r.False(c.Delete("a"))
+	ok, err := c.Delete("a")
+	r.NoError(err)
+	r.True(ok)
+	ok, err = c.Delete("a")
+	r.NoError(err)
+	r.False(ok)
+	_, ok = c.Get("a")
+	r.False(ok)
+	c.Close()
+}
+
+func TestJanitorNextInterval(t *testing.T) {
+	j := &janitor{min: time.Second, max: 8 * time.Second}
+	tests := []struct {
+		name             string
+		interval         time.Duration
+		removed, scanned int
+		want             time.Duration
+	}{
+		{"idle backs off", 2 * time.Second, 0, 100, 4 * time.Second},
+		{"empty backs off", 2 * time.Second, 0, 0, 4 * time.Second},
+		{"backoff capped at max", 8 * time.Second, 0, 100, 8 * time.Second},
+		{"dense shortens", 4 * time.Second, 25, 100, 2 * time.Second},
+		{"shortening capped at min", time.Second, 100, 100, time.Second},
+		{"sparse holds", 4 * time.Second, 10, 100, 4 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, j.next(tt.interval, tt.removed, tt.scanned))
+		})
+	}
+}
+
+func TestAdaptiveJanitorSweeps(t *testing.T) {
+	r := require.New(t)
+	c := New[string, int](8, WithJanitorBounds[string, int](5*time.Millisecond, time.Second))
+	defer c.Close()
+	for i := 0; i < 4; i++ {
+		c.Set(string(rune('a'+i)), i, 20*time.Millisecond)
+	}
+	c.Set("none", 0, 0)
+	r.Eventually(func() bool { return c.Len() == 1 }, time.Second, 5*time.Millisecond)
+}
+
+func TestJanitorBoundsValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []Option[string, int]
+		wantMin time.Duration
+		wantMax time.Duration
+		off     bool
+	}{
+		{"zero interval", []Option[string, int]{WithJanitorInterval[string, int](0)}, 0, 0, true},
+		{"negative min", []Option[string, int]{WithJanitorBounds[string, int](-time.Second, time.Second)}, 0, 0, true},
+		{"max below min", []Option[string, int]{WithJanitorBounds[string, int](time.Second, time.Millisecond)}, time.Second, time.Second, false},
+		{"without janitor first", []Option[string, int]{WithoutJanitor[string, int](), WithJanitorBounds[string, int](0, -1)}, 0, 0, true},
+		{"without janitor last", []Option[string, int]{WithJanitorBounds[string, int](0, -1), WithoutJanitor[string, int]()}, 0, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+			c := New[string, int](2, tt.opts...)
+			defer c.Close()
+			if tt.off {
+				r.Nil(c.janitor)
+				return
+			}
+			r.NotNil(c.janitor)
+			r.Equal(tt.wantMin, c.janitor.min)
+			r.Equal(tt.wantMax, c.janitor.max)
+		})
+	}
+}
+
+func TestRunExpireScanWithoutJanitor(t *testing.T) {
+	r := require.New(t)
+	clk := clock.NewFake(time.Unix(0, 0))
+	c := New[string, int](3, WithoutJanitor[string, int](), WithClock[string, int](clk))
+	c.Set("short", 1, 10*time.Millisecond)
+	c.Set("none", 2, 0)
+	clk.Advance(20 * time.Millisecond)
+	r.Equal(2, c.Len()) // nothing swept without a janitor
+	r.Equal(1, c.RunExpireScan())
+	r.Equal(1, c.Len())
+	r.Equal(0, c.RunExpireScan())
+	c.Close()
+}
+
+func TestBumpEpoch(t *testing.T) {
+	r := require.New(t)
+	c := New[string, int](4, WithoutJanitor[string, int]())
+	c.Set("a", 1, 0)
+	c.Set("b", 2, 0)
+	r.Equal(uint64(1), c.BumpEpoch())
+	r.Equal(uint64(1), c.Epoch())
 	_, ok := c.Get("a")
 	r.False(ok)
+	c.Set("c", 3, 0)
+	v, ok := c.Peek("c")
+	r.True(ok)
+	r.Equal(3, v)
+	// b is stale but still held until reclaimed
+	r.Equal(2, c.Len())
+	r.Equal(1, c.RunExpireScan())
+	r.Equal(1, c.Len())
+	// rewriting a key after a bump makes it live again
+	c.Set("b", 4, 0)
+	v, ok = c.Get("b")
+	r.True(ok)
+	r.Equal(4, v)
 	c.Close()
 }
+
+func TestExpireScanBatches(t *testing.T) {
+	r := require.New(t)
+	n := 3*expireScanBatch + 1
+	c := New[int, int](n, WithoutJanitor[int, int]())
+	defer c.Close()
+	for i := 0; i < n; i++ {
+		c.Set(i, i, 0)
+	}
+	c.BumpEpoch()
+	c.Set(n, n, 0) // evicts the oldest stale entry
+
+	removed, scanned := c.expireScan()
+	r.Equal(n-1, removed)
+	r.Equal(n, scanned)
+	r.Equal(1, c.Len())
+}
+
+func TestRecorder(t *testing.T) {
+	r := require.New(t)
+	clk := clock.NewFake(time.Unix(0, 0))
+	rec := &metrics.Counters{}
+	c := New[string, int](2, WithoutJanitor[string, int](), WithClock[string, int](clk), WithRecorder[string, int](rec))
+	defer c.Close()
+
+	c.Set("a", 1, time.Second)
+	c.Set("b", 2, 0)
+	c.Get("a")
+	c.Get("missing")
+	c.Peek("b")
+	c.Set("c", 3, 0) // evicts b
+
+	clk.Advance(2 * time.Second)
+	c.Get("a")
+	c.Set("d", 4, time.Millisecond)
+	clk.Advance(time.Second)
+	c.BumpEpoch() // c is stale but has not expired
+	r.Equal(2, c.RunExpireScan())
+
+	r.Equal(metrics.Snapshot{Hits: 1, Misses: 2, Evictions: 1, Expirations: 2, Sweeps: 1}, rec.Snapshot())
+}
@@ -0,0 +1,56 @@
+package lru
+
+import (
+	"container/list"
+	"math/rand/v2"
+	"sync/atomic"
+)
+
+const (
+	readStripes    = 16
+	readStripeSize = 64
+)
+
+// readBuffer records Get hits without taking the write lock. Hits are spread
+// across stripes to avoid contending on a single counter and are applied to
+// the recency list in batches. Recording is lossy: when a stripe is full and
+// the cache is busy, further hits on it are dropped until it is drained.
+type readBuffer struct {
+	stripes [readStripes]readStripe
+}
+
+type readStripe struct {
+	n     atomic.Int32
+	elems [readStripeSize]atomic.Pointer[list.Element]
+	_     [64]byte // keep neighbouring stripes off the same cache line
+}
+
+// record stores el in a random stripe and reports whether that stripe is full
+// and should be drained.
+func (b *readBuffer) record(el *list.Element) bool {
+	s := &b.stripes[rand.Uint32N(readStripes)]
+	i := s.n.Add(1) - 1
+	if i < readStripeSize {
+		s.elems[i].Store(el)
+	}
+	return i >= readStripeSize-1
+}
+
+// drain moves every recorded element to the front of l. Elements removed from
+// the cache since they were recorded are skipped by MoveToFront itself.
+// The caller must hold the cache write lock.
+func (b *readBuffer) drain(l *list.List) {
+	for i := range b.stripes {
+		s := &b.stripes[i]
+		n := int(s.n.Load())
+		if n > readStripeSize {
+			n = readStripeSize
+		}
+		for j := 0; j < n; j++ {
+			if el := s.elems[j].Swap(nil); el != nil {
+				l.MoveToFront(el)
+			}
+		}
+		s.n.Store(0)
+	}
+}
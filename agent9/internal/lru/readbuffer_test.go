@@ -0,0 +1,95 @@
+package lru
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rselbach/agent-comparison/clock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadBufferRecency(t *testing.T) {
+	r := require.New(t)
+	c := New[string, int](2, WithReadBuffer[string, int](), WithoutJanitor[string, int]())
+	defer c.Close()
+	c.Set("a", 1, 0)
+	c.Set("b", 2, 0)
+	v, ok := c.Get("a")
+	r.True(ok)
+	r.Equal(1, v)
+	// the buffered hit on a is applied before evicting, so b goes
+	c.Set("c", 3, 0)
+	_, ok = c.Get("b")
+	r.False(ok)
+	_, ok = c.Get("a")
+	r.True(ok)
+}
+
+func TestReadBufferHidesStaleEntries(t *testing.T) {
+	r := require.New(t)
+	clk := clock.NewFake(time.Unix(0, 0))
+	c := New[string, int](4, WithClock[string, int](clk), WithReadBuffer[string, int](), WithoutJanitor[string, int]())
+	defer c.Close()
+	c.Set("short", 1, time.Millisecond)
+	c.Set("keep", 2, 0)
+	clk.Advance(5 * time.Millisecond)
+	_, ok := c.Get("short")
+	r.False(ok)
+	r.Equal(2, c.Len()) // left for the scan
+	r.Equal(1, c.RunExpireScan())
+	c.BumpEpoch()
+	_, ok = c.Get("keep")
+	r.False(ok)
+}
+
+func TestReadBufferConcurrent(t *testing.T) {
+	c := New[int, int](64, WithReadBuffer[int, int](), WithoutJanitor[int, int]())
+	defer c.Close()
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 2000; i++ {
+				k := (g*31 + i) % 128
+				if i%4 == 0 {
+					c.Set(k, i, 0)
+					continue
+				}
+				c.Get(k)
+			}
+		}(g)
+	}
+	wg.Wait()
+	require.LessOrEqual(t, c.Len(), 64)
+}
+
+func BenchmarkGetParallel(b *testing.B) {
+	modes := map[string][]Option[string, int]{
+		"strict":   nil,
+		"buffered": {WithReadBuffer[string, int]()},
+	}
+	for name, opts := range modes {
+		b.Run(name, func(b *testing.B) {
+			const n = 1024
+			c := New[string, int](n, append(opts, WithoutJanitor[string, int]())...)
+			defer c.Close()
+			keys := make([]string, n)
+			for i := range keys {
+				keys[i] = strconv.Itoa(i)
+				c.Set(keys[i], i, 0)
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				i := 0
+				for pb.Next() {
+					c.Get(keys[i%n])
+					i++
+				}
+			})
+		})
+	}
+}
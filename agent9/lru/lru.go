@@ -0,0 +1,66 @@
+// Package lru makes agent9's cache, which lives in an internal package,
+// importable from other modules. Every identifier is an alias of, or forwards
+// to, the one in internal/lru; see there for the full documentation.
+package lru
+
+import (
+	"time"
+
+	"agent9/internal/lru"
+
+	"github.com/rselbach/agent-comparison/clock"
+	"github.com/rselbach/agent-comparison/metrics"
+)
+
+// Cache is an LRU cache with per-entry ttl expiration and background janitor.
+type Cache[K comparable, V any] = lru.Cache[K, V]
+
+// Option configures cache creation.
+type Option[K comparable, V any] = lru.Option[K, V]
+
+// ErrFrozen is returned by mutating operations while the cache is frozen.
+var ErrFrozen = lru.ErrFrozen
+
+// New constructs a cache with given capacity and options. Capacity must be > 0.
+func New[K comparable, V any](capacity int, opts ...Option[K, V]) *Cache[K, V] {
+	return lru.New[K, V](capacity, opts...)
+}
+
+// WithCapacity overrides default capacity.
+func WithCapacity[K comparable, V any](c int) Option[K, V] {
+	return lru.WithCapacity[K, V](c)
+}
+
+// WithJanitorInterval fixes the interval for background expiration scan,
+// disabling adaptation. An interval <= 0 disables the janitor.
+func WithJanitorInterval[K comparable, V any](d time.Duration) Option[K, V] {
+	return lru.WithJanitorInterval[K, V](d)
+}
+
+// WithJanitorBounds sets the range the janitor's interval adapts within.
+// A min <= 0 disables the janitor and a max below min is raised to min.
+func WithJanitorBounds[K comparable, V any](min, max time.Duration) Option[K, V] {
+	return lru.WithJanitorBounds[K, V](min, max)
+}
+
+// WithoutJanitor disables the background expiration scan.
+func WithoutJanitor[K comparable, V any]() Option[K, V] {
+	return lru.WithoutJanitor[K, V]()
+}
+
+// WithReadBuffer enables the experimental read-optimized mode.
+func WithReadBuffer[K comparable, V any]() Option[K, V] {
+	return lru.WithReadBuffer[K, V]()
+}
+
+// WithClock sets the clock used for expiry and for the janitor's timer. A nil
+// clock means real time.
+func WithClock[K comparable, V any](clk clock.Clock) Option[K, V] {
+	return lru.WithClock[K, V](clk)
+}
+
+// WithRecorder sets the recorder that receives hits, misses, evictions,
+// expirations and expire scan durations. A nil recorder records nothing.
+func WithRecorder[K comparable, V any](r metrics.Recorder) Option[K, V] {
+	return lru.WithRecorder[K, V](r)
+}
@@ -0,0 +1,233 @@
+// Package sessionstore implements idle-timeout sessions on top of lru.Cache.
+//
+// Every successful Get or Touch slides a session's expiry forward by the
+// idle timeout, optionally capped by an absolute lifetime measured from
+// creation. Sessions are also dropped when the cache evicts them for capacity.
+package sessionstore
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"sync"
+	"time"
+
+	"agent9/lru"
+
+	"github.com/rselbach/agent-comparison/clock"
+)
+
+var (
+	// ErrNotFound is returned by Refresh when the session does not exist or has expired.
+	ErrNotFound = errors.New("sessionstore: session not found")
+	// ErrIDGeneration is returned when a session ID cannot be generated.
+	ErrIDGeneration = errors.New("sessionstore: failed to generate session id")
+)
+
+// Session is a snapshot of a stored session.
+type Session[V any] struct {
+	ID        string
+	Data      V
+	CreatedAt time.Time
+	LastSeen  time.Time
+	ExpiresAt time.Time
+}
+
+// Store is the session-store interface implemented by CacheStore.
+type Store[V any] interface {
+	// Create starts a new session holding data.
+	Create(data V) (Session[V], error)
+	// Get returns the session and marks it as active, sliding its expiry.
+	Get(id string) (Session[V], bool)
+	// Touch slides the session's expiry without reading it.
+	Touch(id string) bool
+	// Refresh moves the session to a new ID, revoking the old one.
+	Refresh(id string) (Session[V], error)
+	// Revoke ends the session.
+	Revoke(id string) bool
+}
+
+// Option configures a CacheStore.
+type Option func(*config)
+
+type config struct {
+	maxLifetime time.Duration
+	clock       clock.Clock
+}
+
+// WithMaxLifetime caps how long a session may live regardless of activity.
+// Zero (the default) means sessions live as long as they stay active.
+func WithMaxLifetime(d time.Duration) Option {
+	return func(c *config) {
+		c.maxLifetime = d
+	}
+}
+
+// WithClock sets the clock used for session timestamps and expiry checks. A
+// nil clk means real time. Pass the same clock to the cache with lru.WithClock
+// so both agree on when a session expires.
+func WithClock(clk clock.Clock) Option {
+	return func(c *config) {
+		c.clock = clk
+	}
+}
+
+// CacheStore is a Store backed by an lru.Cache. Safe for concurrent use.
+type CacheStore[V any] struct {
+	mu          sync.Mutex
+	cache       *lru.Cache[string, *Session[V]]
+	idle        time.Duration
+	maxLifetime time.Duration
+	clock       clock.Clock
+}
+
+var _ Store[struct{}] = (*CacheStore[struct{}])(nil)
+
+// New returns a CacheStore keeping sessions in cache with the given idle timeout.
+// idle must be > 0.
+func New[V any](cache *lru.Cache[string, *Session[V]], idle time.Duration, opts ...Option) *CacheStore[V] {
+	if idle <= 0 {
+		panic("sessionstore: idle timeout must be > 0")
+	}
+	var cfg config
+	for _, o := range opts {
+		o(&cfg)
+	}
+	return &CacheStore[V]{
+		cache:       cache,
+		idle:        idle,
+		maxLifetime: cfg.maxLifetime,
+		clock:       clock.OrReal(cfg.clock),
+	}
+}
+
+// Create starts a new session holding data and returns it. It fails with
+// lru.ErrFrozen while the underlying cache is frozen.
+func (s *CacheStore[V]) Create(data V) (Session[V], error) {
+	id, err := newID()
+	if err != nil {
+		return Session[V]{}, err
+	}
+	now := s.clock.Now()
+	sess := &Session[V]{ID: id, Data: data, CreatedAt: now}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.storeLocked(sess, now); err != nil {
+		return Session[V]{}, err
+	}
+	return *sess, nil
+}
+
+// Get returns the session for id and slides its expiry. While the underlying
+// cache is frozen the session is returned without sliding.
+func (s *CacheStore[V]) Get(id string) (Session[V], bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess, _ := s.touchLocked(id)
+	if sess == nil {
+		return Session[V]{}, false
+	}
+	return *sess, true
+}
+
+// Touch slides the expiry of the session for id. It reports false when the
+// session does not exist, has expired, has reached its maximum lifetime or
+// cannot be updated because the underlying cache is frozen.
+func (s *CacheStore[V]) Touch(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := s.touchLocked(id)
+	return err == nil
+}
+
+// Refresh moves the session for id to a freshly generated ID, keeping its data
+// and creation time, and revokes id. Use it after privilege changes such as
+// login to prevent session fixation.
+func (s *CacheStore[V]) Refresh(id string) (Session[V], error) {
+	newSessID, err := newID()
+	if err != nil {
+		return Session[V]{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old, ok := s.liveLocked(id, s.clock.Now())
+	if !ok {
+		return Session[V]{}, ErrNotFound
+	}
+	if _, err := s.cache.Delete(id); err != nil {
+		return Session[V]{}, err
+	}
+
+	sess := *old
+	sess.ID = newSessID
+	if err := s.storeLocked(&sess, s.clock.Now()); err != nil {
+		return Session[V]{}, err
+	}
+	return sess, nil
+}
+
+// Revoke ends the session for id, reporting whether it existed. It reports
+// false without revoking while the underlying cache is frozen.
+func (s *CacheStore[V]) Revoke(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ok, _ := s.cache.Delete(id)
+	return ok
+}
+
+// touchLocked slides the session for id and returns the updated copy. When the
+// session is live but cannot be stored, it returns the previous copy together
+// with the error.
+func (s *CacheStore[V]) touchLocked(id string) (*Session[V], error) {
+	now := s.clock.Now()
+	sess, ok := s.liveLocked(id, now)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	updated := *sess
+	if err := s.storeLocked(&updated, now); err != nil {
+		return sess, err
+	}
+	return &updated, nil
+}
+
+// liveLocked returns the stored session if present and within its lifetime.
+func (s *CacheStore[V]) liveLocked(id string, now time.Time) (*Session[V], bool) {
+	sess, ok := s.cache.Get(id)
+	if !ok {
+		return nil, false
+	}
+	if !now.Before(sess.ExpiresAt) {
+		s.cache.Delete(id)
+		return nil, false
+	}
+	return sess, true
+}
+
+// storeLocked records activity at now and writes sess with its remaining TTL.
+// Stored values are never mutated afterwards, so snapshots stay consistent.
+func (s *CacheStore[V]) storeLocked(sess *Session[V], now time.Time) error {
+	sess.LastSeen = now
+	sess.ExpiresAt = now.Add(s.idle)
+	if s.maxLifetime > 0 {
+		if limit := sess.CreatedAt.Add(s.maxLifetime); limit.Before(sess.ExpiresAt) {
+			sess.ExpiresAt = limit
+		}
+	}
+	ttl := sess.ExpiresAt.Sub(now)
+	if ttl <= 0 {
+		_, err := s.cache.Delete(sess.ID)
+		return err
+	}
+	return s.cache.Set(sess.ID, sess, ttl)
+}
+
+func newID() (string, error) {
+	var b [32]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", errors.Join(ErrIDGeneration, err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b[:]), nil
+}
@@ -0,0 +1,134 @@
+package sessionstore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rselbach/agent-comparison/clock"
+	"github.com/stretchr/testify/require"
+
+	"agent9/lru"
+)
+
+func newStore(t *testing.T, idle time.Duration, opts ...Option) (*CacheStore[string], *clock.Fake) {
+	s, clk, _ := newStoreWithCache(t, idle, opts...)
+	return s, clk
+}
+
+func newStoreWithCache(t *testing.T, idle time.Duration, opts ...Option) (*CacheStore[string], *clock.Fake, *lru.Cache[string, *Session[string]]) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	cache := lru.New[string, *Session[string]](16,
+		lru.WithoutJanitor[string, *Session[string]](),
+		lru.WithClock[string, *Session[string]](clk),
+	)
+	t.Cleanup(cache.Close)
+	return New(cache, idle, append(opts, WithClock(clk))...), clk, cache
+}
+
+func TestCreateGet(t *testing.T) {
+	r := require.New(t)
+	s, _ := newStore(t, time.Minute)
+	sess, err := s.Create("alice")
+	r.NoError(err)
+	r.NotEmpty(sess.ID)
+	got, ok := s.Get(sess.ID)
+	r.True(ok)
+	r.Equal("alice", got.Data)
+	_, ok = s.Get("missing")
+	r.False(ok)
+}
+
+func TestSlidingIdleTimeout(t *testing.T) {
+	r := require.New(t)
+	s, clk := newStore(t, time.Minute)
+	sess, err := s.Create("alice")
+	r.NoError(err)
+
+	// activity within the idle window keeps the session alive indefinitely
+	for i := 0; i < 5; i++ {
+		clk.Advance(50 * time.Second)
+		r.True(s.Touch(sess.ID))
+	}
+	clk.Advance(50 * time.Second)
+	got, ok := s.Get(sess.ID)
+	r.True(ok)
+	r.Equal(clk.Now().Add(time.Minute), got.ExpiresAt)
+
+	// a full idle window without activity ends it
+	clk.Advance(time.Minute)
+	_, ok = s.Get(sess.ID)
+	r.False(ok)
+	r.False(s.Touch(sess.ID))
+}
+
+func TestMaxLifetime(t *testing.T) {
+	r := require.New(t)
+	s, clk := newStore(t, time.Minute, WithMaxLifetime(90*time.Second))
+	sess, err := s.Create("alice")
+	r.NoError(err)
+
+	clk.Advance(50 * time.Second)
+	got, ok := s.Get(sess.ID)
+	r.True(ok)
+	r.Equal(sess.CreatedAt.Add(90*time.Second), got.ExpiresAt)
+
+	clk.Advance(40 * time.Second)
+	r.False(s.Touch(sess.ID))
+}
+
+func TestRefreshRotatesID(t *testing.T) {
+	r := require.New(t)
+	s, clk := newStore(t, time.Minute)
+	sess, err := s.Create("alice")
+	r.NoError(err)
+
+	clk.Advance(10 * time.Second)
+	fresh, err := s.Refresh(sess.ID)
+	r.NoError(err)
+	r.NotEqual(sess.ID, fresh.ID)
+	r.Equal("alice", fresh.Data)
+	r.Equal(sess.CreatedAt, fresh.CreatedAt)
+
+	_, ok := s.Get(sess.ID)
+	r.False(ok)
+	_, ok = s.Get(fresh.ID)
+	r.True(ok)
+
+	_, err = s.Refresh(sess.ID)
+	r.ErrorIs(err, ErrNotFound)
+}
+
+func TestRevoke(t *testing.T) {
+	r := require.New(t)
+	s, _ := newStore(t, time.Minute)
+	sess, err := s.Create("alice")
+	r.NoError(err)
+	r.True(s.Revoke(sess.ID))
+	r.False(s.Revoke(sess.ID))
+	_, ok := s.Get(sess.ID)
+	r.False(ok)
+}
+
+func TestFrozenCache(t *testing.T) {
+	r := require.New(t)
+	s, clk, cache := newStoreWithCache(t, time.Minute)
+	sess, err := s.Create("alice")
+	r.NoError(err)
+
+	cache.Freeze()
+	clk.Advance(30 * time.Second)
+
+	got, ok := s.Get(sess.ID)
+	r.True(ok)
+	r.Equal(sess.LastSeen, got.LastSeen)
+	r.False(s.Touch(sess.ID))
+	r.False(s.Revoke(sess.ID))
+	_, err = s.Create("bob")
+	r.ErrorIs(err, lru.ErrFrozen)
+	_, err = s.Refresh(sess.ID)
+	r.ErrorIs(err, lru.ErrFrozen)
+
+	cache.Thaw()
+	r.True(s.Touch(sess.ID))
+	r.True(s.Revoke(sess.ID))
+}
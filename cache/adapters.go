@@ -0,0 +1,405 @@
+package cache
+
+import (
+	"time"
+
+	agent10 "agent10/lru"
+	agent11 "agent11/lru"
+	agent9 "agent9/lru"
+	agent2 "lru"
+
+	agent3 "github.com/gemini/lrucache"
+	agent4 "github.com/opencode/lru/lru"
+	agent12 "github.com/rselbach/agent12/lru"
+	"github.com/rselbach/agent13"
+	"github.com/rselbach/agent14"
+	agent15 "github.com/rselbach/agent15/lru"
+	"github.com/rselbach/agent5"
+	agent7 "github.com/rselbach/agent7/lru"
+	"github.com/rselbach/agent8"
+	agent1 "github.com/rselbach/cc/lrucache"
+	agent6 "github.com/rselbach/lrucache"
+)
+
+var (
+	_ Cache[string, any] = (*Agent1[any])(nil)
+	_ Cache[string, any] = (*Agent2[string, any])(nil)
+	_ Cache[string, any] = (*Agent3[string, any])(nil)
+	_ Cache[string, any] = (*Agent4[string, any])(nil)
+	_ Cache[string, any] = (*Agent5[string, any])(nil)
+	_ Cache[string, any] = (*Agent6[string, any])(nil)
+	_ Cache[string, any] = (*Agent7[any])(nil)
+	_ Cache[string, any] = (*Agent8[any])(nil)
+	_ Cache[string, any] = (*Agent9[string, any])(nil)
+	_ Cache[string, any] = (*Agent10[string, any])(nil)
+	_ Cache[string, any] = (*Agent11[string, any])(nil)
+	_ Cache[string, any] = (*Agent12[any])(nil)
+	_ Cache[string, any] = (*Agent13[any])(nil)
+	_ Cache[string, any] = (*Agent14[any])(nil)
+	_ Cache[string, any] = (*Agent15[any])(nil)
+)
+
+// Agent1 adapts agent1's LRUCache. agent1 takes a TTL on every write and
+// treats a non-positive one as already expired, so SetWithTTL(key, value, 0)
+// stores an entry that is never returned. Close also empties the cache.
+type Agent1[V any] struct {
+	c   *agent1.LRUCache
+	ttl time.Duration
+}
+
+// NewAgent1 wraps c, using defaultTTL for Set.
+func NewAgent1[V any](c *agent1.LRUCache, defaultTTL time.Duration) *Agent1[V] {
+	return &Agent1[V]{c: c, ttl: defaultTTL}
+}
+
+func (a *Agent1[V]) Set(key string, value V) { a.c.Set(key, value, orForever(a.ttl)) }
+func (a *Agent1[V]) SetWithTTL(key string, value V, ttl time.Duration) {
+	a.c.Set(key, value, ttl)
+}
+func (a *Agent1[V]) Get(key string) (V, bool)  { return value[V](a.c.Get(key)) }
+func (a *Agent1[V]) Peek(key string) (V, bool) { return value[V](a.c.Peek(key)) }
+func (a *Agent1[V]) Delete(key string) bool    { return a.c.Delete(key) }
+func (a *Agent1[V]) Len() int                  { return a.c.Len() }
+func (a *Agent1[V]) Close()                    { a.c.Close() }
+
+// Agent2 adapts agent2's Cache. agent2 has no Peek, so Peek promotes the
+// entry like Get.
+type Agent2[K comparable, V any] struct {
+	c *agent2.Cache[K, V]
+}
+
+// NewAgent2 wraps c.
+func NewAgent2[K comparable, V any](c *agent2.Cache[K, V]) *Agent2[K, V] {
+	return &Agent2[K, V]{c: c}
+}
+
+func (a *Agent2[K, V]) Set(key K, value V) { a.c.Set(key, value) }
+func (a *Agent2[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+	a.c.SetWithTTL(key, value, ttl)
+}
+func (a *Agent2[K, V]) Get(key K) (V, bool)  { return a.c.Get(key) }
+func (a *Agent2[K, V]) Peek(key K) (V, bool) { return a.c.Get(key) }
+func (a *Agent2[K, V]) Delete(key K) bool    { return a.c.Delete(key) }
+func (a *Agent2[K, V]) Len() int             { return a.c.Len() }
+func (a *Agent2[K, V]) Close()               { a.c.Close() }
+
+// Agent3 adapts agent3's Cache. Like agent1 it treats a non-positive TTL as
+// already expired. It has no Peek, so Peek promotes the entry like Get, and
+// Delete looks the key up before removing it to report whether it was there,
+// so two concurrent Deletes of one key can both report true.
+type Agent3[K comparable, V any] struct {
+	c   *agent3.Cache
+	ttl time.Duration
+}
+
+// NewAgent3 wraps c, using defaultTTL for Set.
+func NewAgent3[K comparable, V any](c *agent3.Cache, defaultTTL time.Duration) *Agent3[K, V] {
+	return &Agent3[K, V]{c: c, ttl: defaultTTL}
+}
+
+func (a *Agent3[K, V]) Set(key K, value V) { a.c.Add(key, value, orForever(a.ttl)) }
+func (a *Agent3[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+	a.c.Add(key, value, ttl)
+}
+func (a *Agent3[K, V]) Get(key K) (V, bool)  { return value[V](a.c.Get(key)) }
+func (a *Agent3[K, V]) Peek(key K) (V, bool) { return value[V](a.c.Get(key)) }
+func (a *Agent3[K, V]) Len() int             { return a.c.Len() }
+func (a *Agent3[K, V]) Close()               { a.c.Close() }
+
+func (a *Agent3[K, V]) Delete(key K) bool {
+	_, ok := a.c.Get(key)
+	a.c.Remove(key)
+	return ok
+}
+
+// Agent4 adapts agent4's Cache. Writes agent4 rejects, such as a negative
+// TTL or a key inside its tombstone window, are dropped.
+type Agent4[K comparable, V any] struct {
+	c *agent4.Cache[K, V]
+}
+
+// NewAgent4 wraps c.
+func NewAgent4[K comparable, V any](c *agent4.Cache[K, V]) *Agent4[K, V] {
+	return &Agent4[K, V]{c: c}
+}
+
+func (a *Agent4[K, V]) Set(key K, value V) { _ = a.c.Set(key, value) }
+func (a *Agent4[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+	_ = a.c.SetWithTTL(key, value, ttl)
+}
+func (a *Agent4[K, V]) Get(key K) (V, bool)  { return a.c.Get(key) }
+func (a *Agent4[K, V]) Peek(key K) (V, bool) { return a.c.Peek(key) }
+func (a *Agent4[K, V]) Delete(key K) bool    { return a.c.Delete(key) }
+func (a *Agent4[K, V]) Len() int             { return a.c.Len() }
+func (a *Agent4[K, V]) Close()               { a.c.Close() }
+
+// Agent5 adapts agent5's Cache. agent5 has a single cache-wide TTL, so
+// SetWithTTL ignores ttl. It has no Peek, so Peek promotes the entry like
+// Get, and no background goroutine, so Close does nothing. Its Delete reports
+// nothing, so the adapter checks Contains first; two concurrent Deletes of
+// one key can both report true.
+type Agent5[K comparable, V any] struct {
+	c *agent5.Cache
+}
+
+// NewAgent5 wraps c.
+func NewAgent5[K comparable, V any](c *agent5.Cache) *Agent5[K, V] {
+	return &Agent5[K, V]{c: c}
+}
+
+func (a *Agent5[K, V]) Set(key K, value V)                         { a.c.Set(key, value) }
+func (a *Agent5[K, V]) SetWithTTL(key K, value V, _ time.Duration) { a.c.Set(key, value) }
+func (a *Agent5[K, V]) Get(key K) (V, bool)                        { return value[V](a.c.Get(key)) }
+func (a *Agent5[K, V]) Peek(key K) (V, bool)                       { return value[V](a.c.Get(key)) }
+func (a *Agent5[K, V]) Len() int                                   { return a.c.Len() }
+func (a *Agent5[K, V]) Close()                                     {}
+
+func (a *Agent5[K, V]) Delete(key K) bool {
+	ok := a.c.Contains(key)
+	a.c.Delete(key)
+	return ok
+}
+
+// Agent6 adapts agent6's Cache. agent6 has a single cache-wide TTL, so
+// SetWithTTL ignores ttl. Its Delete reports nothing, so the adapter Peeks
+// first; two concurrent Deletes of one key can both report true.
+type Agent6[K comparable, V any] struct {
+	c *agent6.Cache
+}
+
+// NewAgent6 wraps c.
+func NewAgent6[K comparable, V any](c *agent6.Cache) *Agent6[K, V] {
+	return &Agent6[K, V]{c: c}
+}
+
+func (a *Agent6[K, V]) Set(key K, value V)                         { a.c.Set(key, value) }
+func (a *Agent6[K, V]) SetWithTTL(key K, value V, _ time.Duration) { a.c.Set(key, value) }
+func (a *Agent6[K, V]) Get(key K) (V, bool)                        { return value[V](a.c.Get(key)) }
+func (a *Agent6[K, V]) Peek(key K) (V, bool)                       { return value[V](a.c.Peek(key)) }
+func (a *Agent6[K, V]) Len() int                                   { return a.c.Len() }
+func (a *Agent6[K, V]) Close()                                     { a.c.Close() }
+
+func (a *Agent6[K, V]) Delete(key K) bool {
+	_, ok := a.c.Peek(key)
+	a.c.Delete(key)
+	return ok
+}
+
+// Agent7 adapts agent7's Cache. agent7 has no Peek, so Peek promotes the
+// entry like Get. Its Delete reports nothing, so the adapter Gets first; two
+// concurrent Deletes of one key can both report true. Close closes a channel,
+// so it must not be called twice.
+type Agent7[V any] struct {
+	c   *agent7.Cache
+	ttl time.Duration
+}
+
+// NewAgent7 wraps c, using defaultTTL for Set.
+func NewAgent7[V any](c *agent7.Cache, defaultTTL time.Duration) *Agent7[V] {
+	return &Agent7[V]{c: c, ttl: defaultTTL}
+}
+
+func (a *Agent7[V]) Set(key string, value V) { a.c.Set(key, value, a.ttl) }
+func (a *Agent7[V]) SetWithTTL(key string, value V, ttl time.Duration) {
+	a.c.Set(key, value, ttl)
+}
+func (a *Agent7[V]) Get(key string) (V, bool)  { return value[V](a.c.Get(key)) }
+func (a *Agent7[V]) Peek(key string) (V, bool) { return value[V](a.c.Get(key)) }
+func (a *Agent7[V]) Len() int                  { return a.c.Len() }
+func (a *Agent7[V]) Close()                    { a.c.Close() }
+
+func (a *Agent7[V]) Delete(key string) bool {
+	_, ok := a.c.Get(key)
+	a.c.Delete(key)
+	return ok
+}
+
+// Agent8 adapts agent8's LRU. agent8 has a single cache-wide TTL, so
+// SetWithTTL ignores ttl. It has no Peek, so Peek promotes the entry like
+// Get. Its Delete reports nothing, so the adapter checks Contains first; two
+// concurrent Deletes of one key can both report true.
+type Agent8[V any] struct {
+	c *agent8.LRU
+}
+
+// NewAgent8 wraps c.
+func NewAgent8[V any](c *agent8.LRU) *Agent8[V] {
+	return &Agent8[V]{c: c}
+}
+
+func (a *Agent8[V]) Set(key string, value V)                         { a.c.Set(key, value) }
+func (a *Agent8[V]) SetWithTTL(key string, value V, _ time.Duration) { a.c.Set(key, value) }
+func (a *Agent8[V]) Get(key string) (V, bool)                        { return value[V](a.c.Get(key)) }
+func (a *Agent8[V]) Peek(key string) (V, bool)                       { return value[V](a.c.Get(key)) }
+func (a *Agent8[V]) Len() int                                        { return a.c.Len() }
+func (a *Agent8[V]) Close()                                          { a.c.Close() }
+
+func (a *Agent8[V]) Delete(key string) bool {
+	ok := a.c.Contains(key)
+	a.c.Delete(key)
+	return ok
+}
+
+// Agent9 adapts agent9's Cache. Writes agent9 rejects while frozen are
+// dropped.
+type Agent9[K comparable, V any] struct {
+	c   *agent9.Cache[K, V]
+	ttl time.Duration
+}
+
+// NewAgent9 wraps c, using defaultTTL for Set.
+func NewAgent9[K comparable, V any](c *agent9.Cache[K, V], defaultTTL time.Duration) *Agent9[K, V] {
+	return &Agent9[K, V]{c: c, ttl: defaultTTL}
+}
+
+func (a *Agent9[K, V]) Set(key K, value V) { _ = a.c.Set(key, value, a.ttl) }
+func (a *Agent9[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+	_ = a.c.Set(key, value, ttl)
+}
+func (a *Agent9[K, V]) Get(key K) (V, bool)  { return a.c.Get(key) }
+func (a *Agent9[K, V]) Peek(key K) (V, bool) { return a.c.Peek(key) }
+func (a *Agent9[K, V]) Len() int             { return a.c.Len() }
+func (a *Agent9[K, V]) Close()               { a.c.Close() }
+
+func (a *Agent9[K, V]) Delete(key K) bool {
+	ok, _ := a.c.Delete(key)
+	return ok
+}
+
+// Agent10 adapts agent10's Cache. agent10 has no Peek, so Peek promotes the
+// entry like Get.
+type Agent10[K comparable, V any] struct {
+	c   *agent10.Cache[K, V]
+	ttl time.Duration
+}
+
+// NewAgent10 wraps c, using defaultTTL for Set.
+func NewAgent10[K comparable, V any](c *agent10.Cache[K, V], defaultTTL time.Duration) *Agent10[K, V] {
+	return &Agent10[K, V]{c: c, ttl: defaultTTL}
+}
+
+func (a *Agent10[K, V]) Set(key K, value V) { a.c.Set(key, value, a.ttl) }
+func (a *Agent10[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+	a.c.Set(key, value, ttl)
+}
+func (a *Agent10[K, V]) Get(key K) (V, bool)  { return a.c.Get(key) }
+func (a *Agent10[K, V]) Peek(key K) (V, bool) { return a.c.Get(key) }
+func (a *Agent10[K, V]) Delete(key K) bool    { return a.c.Delete(key) }
+func (a *Agent10[K, V]) Len() int             { return a.c.Len() }
+func (a *Agent10[K, V]) Close()               { a.c.Close() }
+
+// Agent11 adapts agent11's Cache.
+type Agent11[K comparable, V any] struct {
+	c *agent11.Cache[K, V]
+}
+
+// NewAgent11 wraps c.
+func NewAgent11[K comparable, V any](c *agent11.Cache[K, V]) *Agent11[K, V] {
+	return &Agent11[K, V]{c: c}
+}
+
+func (a *Agent11[K, V]) Set(key K, value V) { a.c.Set(key, value) }
+func (a *Agent11[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+	a.c.SetWithTTL(key, value, ttl)
+}
+func (a *Agent11[K, V]) Get(key K) (V, bool)  { return a.c.Get(key) }
+func (a *Agent11[K, V]) Peek(key K) (V, bool) { return a.c.Peek(key) }
+func (a *Agent11[K, V]) Delete(key K) bool    { return a.c.Delete(key) }
+func (a *Agent11[K, V]) Len() int             { return a.c.Len() }
+func (a *Agent11[K, V]) Close()               { a.c.Close() }
+
+// Agent12 adapts agent12's Cache. Its Delete reports nothing, so the adapter
+// Peeks first; two concurrent Deletes of one key can both report true.
+type Agent12[V any] struct {
+	c   *agent12.Cache
+	ttl time.Duration
+}
+
+// NewAgent12 wraps c, using defaultTTL for Set.
+func NewAgent12[V any](c *agent12.Cache, defaultTTL time.Duration) *Agent12[V] {
+	return &Agent12[V]{c: c, ttl: defaultTTL}
+}
+
+func (a *Agent12[V]) Set(key string, value V) { a.c.Set(key, value, a.ttl) }
+func (a *Agent12[V]) SetWithTTL(key string, value V, ttl time.Duration) {
+	a.c.Set(key, value, ttl)
+}
+func (a *Agent12[V]) Get(key string) (V, bool)  { return value[V](a.c.Get(key)) }
+func (a *Agent12[V]) Peek(key string) (V, bool) { return value[V](a.c.Peek(key)) }
+func (a *Agent12[V]) Len() int                  { return a.c.Len() }
+func (a *Agent12[V]) Close()                    { a.c.Close() }
+
+func (a *Agent12[V]) Delete(key string) bool {
+	_, ok := a.c.Peek(key)
+	a.c.Delete(key)
+	return ok
+}
+
+// Agent13 adapts agent13's Cache.
+type Agent13[V any] struct {
+	c   *agent13.Cache
+	ttl time.Duration
+}
+
+// NewAgent13 wraps c, using defaultTTL for Set.
+func NewAgent13[V any](c *agent13.Cache, defaultTTL time.Duration) *Agent13[V] {
+	return &Agent13[V]{c: c, ttl: defaultTTL}
+}
+
+func (a *Agent13[V]) Set(key string, value V) { a.c.Set(key, value, a.ttl) }
+func (a *Agent13[V]) SetWithTTL(key string, value V, ttl time.Duration) {
+	a.c.Set(key, value, ttl)
+}
+func (a *Agent13[V]) Get(key string) (V, bool)  { return value[V](a.c.Get(key)) }
+func (a *Agent13[V]) Peek(key string) (V, bool) { return value[V](a.c.Peek(key)) }
+func (a *Agent13[V]) Delete(key string) bool    { return a.c.Delete(key) }
+func (a *Agent13[V]) Len() int                  { return a.c.Len() }
+func (a *Agent13[V]) Close()                    { a.c.Close() }
+
+// Agent14 adapts agent14's Cache. agent14 has no Peek, so Peek promotes the
+// entry like Get.
+type Agent14[V any] struct {
+	c *agent14.Cache
+}
+
+// NewAgent14 wraps c.
+func NewAgent14[V any](c *agent14.Cache) *Agent14[V] {
+	return &Agent14[V]{c: c}
+}
+
+func (a *Agent14[V]) Set(key string, value V) { a.c.Set(key, value) }
+func (a *Agent14[V]) SetWithTTL(key string, value V, ttl time.Duration) {
+	a.c.SetWithTTL(key, value, ttl)
+}
+func (a *Agent14[V]) Peek(key string) (V, bool) { return a.Get(key) }
+func (a *Agent14[V]) Delete(key string) bool    { return a.c.Delete(key) }
+func (a *Agent14[V]) Len() int                  { return a.c.Len() }
+func (a *Agent14[V]) Close()                    { a.c.Close() }
+
+func (a *Agent14[V]) Get(key string) (V, bool) {
+	v, err := a.c.Get(key)
+	return value[V](v, err == nil)
+}
+
+// Agent15 adapts agent15's LRU. agent15 takes a TTL on every Put and treats
+// zero as expiring at once, so SetWithTTL(key, value, 0) stores an entry that
+// is gone as soon as the clock moves. It has no Peek, so Peek promotes the
+// entry like Get.
+type Agent15[V any] struct {
+	c *agent15.LRU
+}
+
+// NewAgent15 wraps c.
+func NewAgent15[V any](c *agent15.LRU) *Agent15[V] {
+	return &Agent15[V]{c: c}
+}
+
+func (a *Agent15[V]) Set(key string, value V) { a.c.Set(key, value) }
+func (a *Agent15[V]) SetWithTTL(key string, value V, ttl time.Duration) {
+	a.c.Put(key, value, ttl)
+}
+func (a *Agent15[V]) Get(key string) (V, bool)  { return value[V](a.c.Get(key)) }
+func (a *Agent15[V]) Peek(key string) (V, bool) { return value[V](a.c.Get(key)) }
+func (a *Agent15[V]) Delete(key string) bool    { return a.c.Delete(key) }
+func (a *Agent15[V]) Len() int                  { return a.c.Len() }
+func (a *Agent15[V]) Close()                    { a.c.Close() }
@@ -0,0 +1,365 @@
+// Package bench measures agent caches under configurable workloads.
+//
+// A Workload describes how keys are chosen (uniform, Zipf or a sequential
+// scan), the share of writes, the TTLs writes carry and how many goroutines
+// drive the cache. Run replays the same seeded operations against each
+// implementation and reports throughput, latency percentiles and hit ratio.
+// Operations are generated before timing starts so the random number
+// generator does not count against the cache. RunHTTP replays the same
+// workloads as requests through an httpcache.Transport backed by each
+// implementation.
+package bench
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"slices"
+	"strconv"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rselbach/agent-comparison/cache"
+)
+
+// Distribution selects how keys are drawn.
+type Distribution string
+
+const (
+	// Uniform draws every key with equal probability.
+	Uniform Distribution = "uniform"
+	// Zipf draws key i with probability proportional to 1/(i+1)^Skew.
+	Zipf Distribution = "zipf"
+	// Scan walks the key space in order, wrapping around. Each goroutine
+	// starts at a different offset.
+	Scan Distribution = "scan"
+)
+
+// Workload describes the operations driven against a cache.
+type Workload struct {
+	Name string `json:"name"`
+	// Capacity is the cache capacity.
+	Capacity int `json:"capacity"`
+	// Keys is the number of distinct keys.
+	Keys int          `json:"keys"`
+	Dist Distribution `json:"dist"`
+	// Skew is the Zipf exponent and must be greater than 1 for Zipf.
+	Skew float64 `json:"skew,omitempty"`
+	// WriteRatio is the fraction of operations that are writes.
+	WriteRatio float64 `json:"write_ratio"`
+	// FillOnMiss writes the key after a read misses, as a read-through
+	// cache would. The write is part of the timed operation.
+	FillOnMiss bool `json:"fill_on_miss"`
+	// MinTTL and MaxTTL bound the TTL of each write, drawn uniformly.
+	// Zero for both writes with Set, so the cache's default applies.
+	MinTTL time.Duration `json:"min_ttl,omitempty"`
+	MaxTTL time.Duration `json:"max_ttl,omitempty"`
+	// ValueSize is the length of the stored values in bytes.
+	ValueSize int `json:"value_size"`
+	// Warmup operations run on a single goroutine before timing starts.
+	Warmup int `json:"warmup"`
+	// Ops is the number of timed operations, split across Goroutines.
+	Ops        int   `json:"ops"`
+	Goroutines int   `json:"goroutines"`
+	Seed       int64 `json:"seed"`
+}
+
+// Result is the outcome of one workload against one implementation.
+type Result struct {
+	Impl     string `json:"impl"`
+	Workload string `json:"workload"`
+	Ops      int    `json:"ops"`
+	// Elapsed is the wall time of the timed phase.
+	Elapsed time.Duration `json:"elapsed_ns"`
+	// Throughput is operations per second.
+	Throughput float64       `json:"ops_per_sec"`
+	P50        time.Duration `json:"p50_ns"`
+	P99        time.Duration `json:"p99_ns"`
+	Max        time.Duration `json:"max_ns"`
+	Reads      int           `json:"reads"`
+	Hits       int           `json:"hits"`
+	HitRatio   float64       `json:"hit_ratio"`
+}
+
+// DefaultWorkloads returns a small set of representative workloads.
+func DefaultWorkloads() []Workload {
+	base := Workload{
+		Capacity:   1000,
+		Keys:       10000,
+		Dist:       Zipf,
+		Skew:       1.1,
+		WriteRatio: 0.1,
+		FillOnMiss: true,
+		ValueSize:  64,
+		Warmup:     10000,
+		Ops:        200000,
+		Goroutines: 4,
+		Seed:       1,
+	}
+
+	uniform := base
+	uniform.Name, uniform.Dist, uniform.Skew = "uniform-read-heavy", Uniform, 0
+
+	zipfRead := base
+	zipfRead.Name = "zipf-read-heavy"
+
+	zipfWrite := base
+	zipfWrite.Name, zipfWrite.WriteRatio = "zipf-write-heavy", 0.5
+
+	scan := base
+	scan.Name, scan.Dist, scan.Skew, scan.Keys = "scan", Scan, 0, 2*base.Capacity
+
+	ttl := base
+	ttl.Name, ttl.MinTTL, ttl.MaxTTL = "zipf-short-ttls", time.Millisecond, 50*time.Millisecond
+
+	return []Workload{uniform, zipfRead, zipfWrite, scan, ttl}
+}
+
+func (w Workload) validate() error {
+	var errs []error
+	if w.Capacity <= 0 {
+		errs = append(errs, errors.New("capacity must be positive"))
+	}
+	if w.Keys <= 0 {
+		errs = append(errs, errors.New("keys must be positive"))
+	}
+	switch w.Dist {
+	case Uniform, Scan:
+	case Zipf:
+		if w.Skew <= 1 {
+			errs = append(errs, errors.New("zipf skew must be greater than 1"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("unknown distribution %q", w.Dist))
+	}
+	if w.WriteRatio < 0 || w.WriteRatio > 1 {
+		errs = append(errs, errors.New("write ratio must be between 0 and 1"))
+	}
+	if w.MinTTL < 0 || w.MaxTTL < w.MinTTL {
+		errs = append(errs, errors.New("TTL bounds must satisfy 0 <= min <= max"))
+	}
+	if w.ValueSize < 0 || w.Warmup < 0 {
+		errs = append(errs, errors.New("value size and warmup must not be negative"))
+	}
+	if w.Ops <= 0 || w.Goroutines <= 0 {
+		errs = append(errs, errors.New("ops and goroutines must be positive"))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("workload %q: %w", w.Name, err)
+	}
+	return nil
+}
+
+// op is one pre-generated operation.
+type op struct {
+	key   int
+	write bool
+	ttl   time.Duration
+}
+
+// generate returns n operations for stream, which selects the goroutine so
+// each one gets a different but reproducible sequence.
+func (w Workload) generate(stream, n int) []op {
+	r := rand.New(rand.NewSource(w.Seed + int64(stream)*7919))
+	var zipf *rand.Zipf
+	if w.Dist == Zipf {
+		zipf = rand.NewZipf(r, w.Skew, 1, uint64(w.Keys-1))
+	}
+	next := (stream * w.Keys / w.Goroutines) % w.Keys
+
+	ops := make([]op, n)
+	for i := range ops {
+		o := &ops[i]
+		switch w.Dist {
+		case Uniform:
+			o.key = r.Intn(w.Keys)
+		case Zipf:
+			o.key = int(zipf.Uint64())
+		case Scan:
+			o.key = next
+			next = (next + 1) % w.Keys
+		}
+		o.write = r.Float64() < w.WriteRatio
+		if o.write && w.MaxTTL > 0 {
+			o.ttl = w.MinTTL
+			if span := w.MaxTTL - w.MinTTL; span > 0 {
+				o.ttl += time.Duration(r.Int63n(int64(span) + 1))
+			}
+		}
+	}
+	return ops
+}
+
+// Run drives every workload against every implementation.
+func Run(workloads []Workload, impls []cache.Implementation[[]byte]) ([]Result, error) {
+	return runAll(workloads, impls, direct)
+}
+
+// runAll validates workloads and runs each against every implementation,
+// applying operations with the target built by newTarget.
+func runAll(workloads []Workload, impls []cache.Implementation[[]byte], newTarget func(cache.Cache[string, []byte], Workload) target) ([]Result, error) {
+	for _, w := range workloads {
+		if err := w.validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	var results []Result
+	for _, w := range workloads {
+		for _, impl := range impls {
+			results = append(results, runOne(w, impl, newTarget))
+		}
+	}
+	return results, nil
+}
+
+// target applies one operation and reports whether it was a read that hit.
+type target func(o op) bool
+
+// direct returns a target that calls the cache itself.
+func direct(c cache.Cache[string, []byte], w Workload) target {
+	keys := make([]string, w.Keys)
+	for i := range keys {
+		keys[i] = "key:" + strconv.Itoa(i)
+	}
+	value := make([]byte, w.ValueSize)
+
+	return func(o op) bool {
+		key := keys[o.key]
+		if o.write {
+			set(c, key, value, o.ttl)
+			return false
+		}
+		if _, ok := c.Get(key); ok {
+			return true
+		}
+		if w.FillOnMiss {
+			set(c, key, value, o.ttl)
+		}
+		return false
+	}
+}
+
+// worker is one goroutine's share of the timed phase.
+type worker struct {
+	ops       []op
+	latencies []time.Duration
+	reads     int
+	hits      int
+}
+
+func runOne(w Workload, impl cache.Implementation[[]byte], newTarget func(cache.Cache[string, []byte], Workload) target) Result {
+	c := impl.New(w.Capacity, 0)
+	defer c.Close()
+	apply := newTarget(c, w)
+
+	var warm worker
+	warm.ops = w.generate(w.Goroutines, w.Warmup)
+	warm.run(apply, false)
+
+	workers := make([]*worker, w.Goroutines)
+	for i := range workers {
+		n := w.Ops / w.Goroutines
+		if i < w.Ops%w.Goroutines {
+			n++
+		}
+		workers[i] = &worker{ops: w.generate(i, n), latencies: make([]time.Duration, 0, n)}
+	}
+
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for _, wk := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			wk.run(apply, true)
+		}()
+	}
+	began := time.Now()
+	close(start)
+	wg.Wait()
+	elapsed := time.Since(began)
+
+	res := Result{Impl: impl.Name, Workload: w.Name, Ops: w.Ops, Elapsed: elapsed}
+	var all []time.Duration
+	for _, wk := range workers {
+		all = append(all, wk.latencies...)
+		res.Reads += wk.reads
+		res.Hits += wk.hits
+	}
+	slices.Sort(all)
+	res.P50 = percentile(all, 0.50)
+	res.P99 = percentile(all, 0.99)
+	res.Max = all[len(all)-1]
+	if elapsed > 0 {
+		res.Throughput = float64(w.Ops) / elapsed.Seconds()
+	}
+	if res.Reads > 0 {
+		res.HitRatio = float64(res.Hits) / float64(res.Reads)
+	}
+	return res
+}
+
+// run applies the worker's operations, recording latencies if timed.
+func (wk *worker) run(apply target, timed bool) {
+	for _, o := range wk.ops {
+		var began time.Time
+		if timed {
+			began = time.Now()
+		}
+		if !o.write {
+			wk.reads++
+		}
+		if apply(o) {
+			wk.hits++
+		}
+		if timed {
+			wk.latencies = append(wk.latencies, time.Since(began))
+		}
+	}
+	if !timed {
+		wk.reads, wk.hits = 0, 0
+	}
+}
+
+func set(c cache.Cache[string, []byte], key string, value []byte, ttl time.Duration) {
+	if ttl > 0 {
+		c.SetWithTTL(key, value, ttl)
+	} else {
+		c.Set(key, value)
+	}
+}
+
+// percentile returns the p-th quantile of sorted.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(p * float64(len(sorted)))
+	if i >= len(sorted) {
+		i = len(sorted) - 1
+	}
+	return sorted[i]
+}
+
+// WriteJSON writes results as indented JSON.
+func WriteJSON(w io.Writer, results []Result) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(results)
+}
+
+// WriteTable writes results as an aligned text table.
+func WriteTable(w io.Writer, results []Result) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "workload\timpl\tops/s\tp50\tp99\tmax\thit ratio\t")
+	for _, r := range results {
+		fmt.Fprintf(tw, "%s\t%s\t%.0f\t%v\t%v\t%v\t%.3f\t\n",
+			r.Workload, r.Impl, r.Throughput, r.P50, r.P99, r.Max, r.HitRatio)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,138 @@
+package bench
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/rselbach/agent-comparison/cache"
+)
+
+func smallWorkloads() []Workload {
+	ws := DefaultWorkloads()
+	for i := range ws {
+		ws[i].Capacity, ws[i].Keys, ws[i].Warmup, ws[i].Ops = 50, 500, 200, 2000
+		if ws[i].Dist == Scan {
+			ws[i].Keys = 100
+		}
+	}
+	return ws
+}
+
+func TestRun(t *testing.T) {
+	impls := cache.Implementations[[]byte]()
+	ws := smallWorkloads()
+	results, err := Run(ws, impls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != len(ws)*len(impls) {
+		t.Fatalf("got %d results, want %d", len(results), len(ws)*len(impls))
+	}
+	for _, r := range results {
+		if r.Ops != 2000 || r.Throughput <= 0 || r.P99 < r.P50 || r.Max < r.P99 {
+			t.Errorf("implausible result %+v", r)
+		}
+		if r.Hits > r.Reads || r.HitRatio < 0 || r.HitRatio > 1 {
+			t.Errorf("implausible hit counts %+v", r)
+		}
+	}
+}
+
+func TestScanDefeatsLRU(t *testing.T) {
+	w := smallWorkloads()[3]
+	w.WriteRatio, w.Goroutines = 0, 1
+	results, err := Run([]Workload{w}, cache.Implementations[[]byte]()[:1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a cyclic scan over twice the capacity always misses in an LRU
+	if results[0].Hits != 0 {
+		t.Fatalf("expected a scan larger than the cache to miss every time, got %+v", results[0])
+	}
+}
+
+func TestZipfHitsMoreThanUniform(t *testing.T) {
+	ws := smallWorkloads()
+	results, err := Run(ws[:2], cache.Implementations[[]byte]()[:1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[1].HitRatio <= results[0].HitRatio {
+		t.Fatalf("zipf hit ratio %v not above uniform %v", results[1].HitRatio, results[0].HitRatio)
+	}
+}
+
+func TestGenerateIsReproducible(t *testing.T) {
+	w := DefaultWorkloads()[4]
+	a, b := w.generate(1, 1000), w.generate(1, 1000)
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("op %d differs: %+v vs %+v", i, a[i], b[i])
+		}
+		if a[i].write && (a[i].ttl < w.MinTTL || a[i].ttl > w.MaxTTL) {
+			t.Fatalf("ttl %v out of range", a[i].ttl)
+		}
+	}
+}
+
+func TestInvalidWorkload(t *testing.T) {
+	for name, mutate := range map[string]func(*Workload){
+		"capacity":    func(w *Workload) { w.Capacity = 0 },
+		"keys":        func(w *Workload) { w.Keys = 0 },
+		"skew":        func(w *Workload) { w.Skew = 1 },
+		"dist":        func(w *Workload) { w.Dist = "gamma" },
+		"write ratio": func(w *Workload) { w.WriteRatio = 2 },
+		"ttl bounds":  func(w *Workload) { w.MinTTL, w.MaxTTL = 2, 1 },
+		"goroutines":  func(w *Workload) { w.Goroutines = 0 },
+	} {
+		w := DefaultWorkloads()[1]
+		mutate(&w)
+		if _, err := Run([]Workload{w}, nil); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestReports(t *testing.T) {
+	results, err := Run(smallWorkloads()[:1], cache.Implementations[[]byte]()[:2])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteJSON(&buf, results); err != nil {
+		t.Fatal(err)
+	}
+	var decoded []Result
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != 2 || decoded[1] != results[1] {
+		t.Fatalf("round trip lost data: %+v", decoded)
+	}
+
+	buf.Reset()
+	if err := WriteTable(&buf, results); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(buf.String()), "\n"); len(lines) != 3 {
+		t.Fatalf("expected header and 2 rows, got %q", buf.String())
+	}
+}
+
+// BenchmarkWorkloads runs the default workloads against every implementation,
+// b.N operations at a time.
+func BenchmarkWorkloads(b *testing.B) {
+	for _, w := range DefaultWorkloads() {
+		for _, impl := range cache.Implementations[[]byte]() {
+			b.Run(w.Name+"/"+impl.Name, func(b *testing.B) {
+				w.Ops = max(b.N, w.Goroutines)
+				r := runOne(w, impl, direct)
+				b.ReportMetric(r.HitRatio, "hit-ratio")
+				b.ReportMetric(float64(r.P99.Nanoseconds()), "p99-ns")
+			})
+		}
+	}
+}
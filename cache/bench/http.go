@@ -0,0 +1,78 @@
+package bench
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/rselbach/agent-comparison/cache"
+	"github.com/rselbach/agent-comparison/httpcache"
+)
+
+// originURL is the base of the URLs requested by RunHTTP. Requests never
+// leave the process.
+const originURL = "http://origin.invalid/"
+
+// RunHTTP drives every workload against every implementation through an
+// httpcache.Transport, so each operation also pays for request handling and
+// response serialisation. Reads are GET requests and writes are POST requests,
+// which invalidate the cached response for their URL. The in-process origin
+// marks every response fresh for an hour, so MinTTL, MaxTTL and FillOnMiss
+// have no effect: a miss always fills the cache with the origin's response.
+func RunHTTP(workloads []Workload, impls []cache.Implementation[[]byte]) ([]Result, error) {
+	return runAll(workloads, impls, overHTTP)
+}
+
+// overHTTP returns a target that requests keys through a Transport backed by c.
+func overHTTP(c cache.Cache[string, []byte], w Workload) target {
+	t := &httpcache.Transport{Cache: c, Transport: origin{body: make([]byte, w.ValueSize)}}
+	urls := make([]string, w.Keys)
+	for i := range urls {
+		urls[i] = originURL + "key:" + strconv.Itoa(i)
+	}
+
+	return func(o op) bool {
+		method := http.MethodGet
+		if o.write {
+			method = http.MethodPost
+		}
+		req, err := http.NewRequest(method, urls[o.key], nil)
+		if err != nil {
+			return false
+		}
+		resp, err := t.RoundTrip(req)
+		if err != nil {
+			return false
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		return !o.write && resp.Header.Get(httpcache.XFromCache) == "1"
+	}
+}
+
+// origin answers GET requests with body, cacheable for an hour, and any other
+// request with 204 No Content.
+type origin struct {
+	body []byte
+}
+
+func (o origin) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp := &http.Response{
+		StatusCode: http.StatusNoContent,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     make(http.Header),
+		Body:       http.NoBody,
+		Request:    req,
+	}
+	if req.Method == http.MethodGet {
+		resp.StatusCode = http.StatusOK
+		resp.Header.Set("Cache-Control", "max-age=3600")
+		resp.Body = io.NopCloser(bytes.NewReader(o.body))
+		resp.ContentLength = int64(len(o.body))
+	}
+	resp.Status = strconv.Itoa(resp.StatusCode) + " " + http.StatusText(resp.StatusCode)
+	return resp, nil
+}
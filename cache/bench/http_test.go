@@ -0,0 +1,83 @@
+package bench
+
+import (
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/rselbach/agent-comparison/cache"
+	"github.com/rselbach/agent-comparison/httpcache"
+)
+
+func TestTransportWithEveryImplementation(t *testing.T) {
+	for _, impl := range cache.Implementations[[]byte]() {
+		t.Run(impl.Name, func(t *testing.T) {
+			c := impl.New(8, 0)
+			defer c.Close()
+			client := (&httpcache.Transport{Cache: c, Transport: origin{body: []byte("hello")}}).Client()
+
+			do := func(method string) *http.Response {
+				t.Helper()
+				req, err := http.NewRequest(method, originURL+"a", nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				resp, err := client.Do(req)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer resp.Body.Close()
+				if body, _ := io.ReadAll(resp.Body); method == http.MethodGet && string(body) != "hello" {
+					t.Fatalf("body = %q, want hello", body)
+				}
+				return resp
+			}
+
+			if resp := do(http.MethodGet); resp.Header.Get(httpcache.XFromCache) != "" {
+				t.Fatal("first GET served from cache")
+			}
+			if resp := do(http.MethodGet); resp.Header.Get(httpcache.XFromCache) != "1" {
+				t.Fatal("second GET not served from cache")
+			}
+			do(http.MethodPost)
+			if resp := do(http.MethodGet); resp.Header.Get(httpcache.XFromCache) != "" {
+				t.Fatal("GET after POST served from cache")
+			}
+		})
+	}
+}
+
+func TestRunHTTP(t *testing.T) {
+	impls := cache.Implementations[[]byte]()
+	ws := smallWorkloads()[1:2]
+	results, err := RunHTTP(ws, impls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != len(impls) {
+		t.Fatalf("got %d results, want %d", len(results), len(impls))
+	}
+	for _, r := range results {
+		if r.Ops != 2000 || r.Throughput <= 0 || r.P99 < r.P50 || r.Max < r.P99 {
+			t.Errorf("implausible result %+v", r)
+		}
+		if r.Hits == 0 || r.Hits > r.Reads {
+			t.Errorf("implausible hit counts %+v", r)
+		}
+	}
+}
+
+// BenchmarkHTTP runs the default workloads through an httpcache.Transport
+// backed by every implementation, b.N requests at a time.
+func BenchmarkHTTP(b *testing.B) {
+	for _, w := range DefaultWorkloads() {
+		for _, impl := range cache.Implementations[[]byte]() {
+			b.Run(w.Name+"/"+impl.Name, func(b *testing.B) {
+				w.Ops = max(b.N, w.Goroutines)
+				r := runOne(w, impl, overHTTP)
+				b.ReportMetric(r.HitRatio, "hit-ratio")
+				b.ReportMetric(float64(r.P99.Nanoseconds()), "p99-ns")
+			})
+		}
+	}
+}
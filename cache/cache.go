@@ -0,0 +1,57 @@
+// Package cache declares the interface shared by the agent implementations and
+// adapters that put each agent's cache behind it, so applications
+// and the comparison tooling can swap one implementation for another. agent7,
+// agent9, agent10 and agent12 keep their caches in internal packages; each
+// also has a public lru package that re-exports it, which is what is adapted
+// here.
+//
+// The adapters are deliberately thin: they translate method names and
+// signatures but keep each agent's own semantics, such as what a zero TTL
+// means, whether Close also empties the cache, or whether TTLs are per entry
+// at all. Each adapter documents where its agent departs from the interface.
+package cache
+
+import "time"
+
+// Cache is the common surface of the agent caches.
+type Cache[K comparable, V any] interface {
+	// Set stores value under key with the cache's default TTL. A zero
+	// default means the entry does not expire.
+	Set(key K, value V)
+	// SetWithTTL stores value under key, expiring it after ttl. How a
+	// non-positive ttl is treated varies between implementations.
+	SetWithTTL(key K, value V, ttl time.Duration)
+	// Get returns the value for key and marks it most recently used.
+	Get(key K) (V, bool)
+	// Peek returns the value for key without changing its recency.
+	Peek(key K) (V, bool)
+	// Delete removes key and reports whether it was present.
+	Delete(key K) bool
+	// Len returns the number of entries.
+	Len() int
+	// Close releases background resources such as cleanup goroutines.
+	Close()
+}
+
+// forever stands in for "no expiry" on implementations whose writes always
+// take a TTL and treat zero as already expired.
+const forever = 100 * 365 * 24 * time.Hour
+
+// orForever returns ttl, or forever if ttl is zero.
+func orForever(ttl time.Duration) time.Duration {
+	if ttl == 0 {
+		return forever
+	}
+	return ttl
+}
+
+// value converts a stored interface{} back to V. A stored nil becomes V's
+// zero value.
+func value[V any](v interface{}, ok bool) (V, bool) {
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	val, _ := v.(V)
+	return val, true
+}
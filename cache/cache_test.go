@@ -0,0 +1,104 @@
+package cache
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rselbach/agent-comparison/clock"
+)
+
+func TestImplementations(t *testing.T) {
+	for _, impl := range Implementations[string]() {
+		t.Run(impl.Name, func(t *testing.T) {
+			c := impl.New(2, 0)
+			defer c.Close()
+
+			c.Set("a", "1")
+			c.Set("b", "2")
+			if v, ok := c.Get("a"); !ok || v != "1" {
+				t.Fatalf("Get(a) = %q, %v", v, ok)
+			}
+			if v, ok := c.Peek("b"); !ok || v != "2" {
+				t.Fatalf("Peek(b) = %q, %v", v, ok)
+			}
+			c.SetWithTTL("c", "3", time.Hour)
+			if n := c.Len(); n != 2 {
+				t.Fatalf("Len() = %d, want 2", n)
+			}
+			if v, ok := c.Get("c"); !ok || v != "3" {
+				t.Fatalf("Get(c) = %q, %v", v, ok)
+			}
+			if !c.Delete("c") {
+				t.Fatal("Delete(c) = false")
+			}
+			if c.Delete("c") {
+				t.Fatal("second Delete(c) = true")
+			}
+			if _, ok := c.Get("c"); ok {
+				t.Fatal("c still present after Delete")
+			}
+		})
+	}
+}
+
+func TestImplementationsDefaultTTL(t *testing.T) {
+	for _, impl := range Implementations[int]() {
+		t.Run(impl.Name, func(t *testing.T) {
+			clk := clock.NewFake(time.Unix(0, 0))
+			c := impl.NewWithClock(4, 20*time.Millisecond, clk)
+			defer c.Close()
+
+			c.Set("a", 1)
+			if _, ok := c.Get("a"); !ok {
+				t.Fatal("a missing before its TTL")
+			}
+			clk.Advance(40 * time.Millisecond)
+			if _, ok := c.Get("a"); ok {
+				t.Fatal("a still present after its TTL")
+			}
+		})
+	}
+}
+
+func TestImplementationsConcurrent(t *testing.T) {
+	for _, impl := range Implementations[int]() {
+		t.Run(impl.Name, func(t *testing.T) {
+			c := impl.New(64, 0)
+			defer c.Close()
+
+			var wg sync.WaitGroup
+			for g := 0; g < 4; g++ {
+				wg.Add(1)
+				go func(g int) {
+					defer wg.Done()
+					for i := 0; i < 500; i++ {
+						key := strconv.Itoa(g*500 + i)
+						c.Set(key, i)
+						c.Get(key)
+						c.Peek(key)
+						if i%3 == 0 {
+							c.Delete(key)
+						}
+					}
+				}(g)
+			}
+			wg.Wait()
+
+			if n := c.Len(); n > 64 {
+				t.Fatalf("Len() = %d exceeds capacity", n)
+			}
+		})
+	}
+}
+
+func TestNilValue(t *testing.T) {
+	c := Implementations[any]()[0].New(2, 0)
+	defer c.Close()
+
+	c.Set("a", nil)
+	if v, ok := c.Get("a"); !ok || v != nil {
+		t.Fatalf("Get(a) = %v, %v", v, ok)
+	}
+}
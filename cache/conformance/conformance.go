@@ -0,0 +1,362 @@
+// Package conformance checks implementations of the shared cache interface
+// against the behaviour an application swapping one for another would
+// expect.
+//
+// Each Check exercises one property on a fresh cache and returns an error
+// describing how the implementation departs from it. Not every agent is
+// expected to pass every check: the suite exists to make the semantic drift
+// between them, such as a zero TTL meaning "never expire" in one and "expire
+// immediately" in another, explicit and runnable.
+//
+// TTL checks build their cache on a clock.Fake and advance it past the TTL
+// instead of sleeping, so they do not depend on how fast the host runs. They
+// advance it by seconds, well short of the minute background sweeps usually
+// run at, so an implementation that only notices expiry in such a sweep fails
+// them.
+package conformance
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rselbach/agent-comparison/cache"
+	"github.com/rselbach/agent-comparison/clock"
+)
+
+// Factory returns an empty cache holding at most capacity entries, applying
+// defaultTTL in Set and reading time from clk. Zero defaultTTL means no
+// expiry. Implementation.NewWithClock is a Factory.
+type Factory func(capacity int, defaultTTL time.Duration, clk clock.Clock) cache.Cache[string, string]
+
+// Check is one behavioural property.
+type Check struct {
+	Name string
+	// Doc states the expected behaviour.
+	Doc string
+	Run func(f Factory) error
+}
+
+// Result is the outcome of one Check. Err is nil if the check passed.
+type Result struct {
+	Check string
+	Err   error
+}
+
+// shortTTL is the TTL used by expiry checks, and settle how far they advance
+// the clock for it to pass.
+const (
+	shortTTL = time.Second
+	settle   = 3 * shortTTL
+)
+
+// newClock returns the fake clock a check builds its cache on.
+func newClock() *clock.Fake {
+	return clock.NewFake(time.Unix(0, 0))
+}
+
+// Checks is the suite, in the order it runs.
+var Checks = []Check{
+	{"lru-eviction", "inserting past capacity evicts the least recently used entry", checkLRUEviction},
+	{"get-promotes", "Get marks an entry most recently used", checkGetPromotes},
+	{"update-resets-recency", "overwriting a key marks it most recently used", checkUpdateResetsRecency},
+	{"peek-does-not-promote", "Peek leaves recency unchanged", checkPeekDoesNotPromote},
+	{"update-replaces-value", "overwriting a key replaces its value without adding an entry", checkUpdateReplacesValue},
+	{"delete", "Delete removes a key and reports whether it was present", checkDelete},
+	{"len-bounded", "Len never exceeds capacity", checkLenBounded},
+	{"ttl-expiry", "an entry written with SetWithTTL is gone once its TTL passes", checkTTLExpiry},
+	{"per-entry-ttl", "entries written with different TTLs expire independently", checkPerEntryTTL},
+	{"default-ttl", "Set applies the default TTL", checkDefaultTTL},
+	{"zero-ttl-never-expires", "SetWithTTL with a zero TTL stores an entry that does not expire", checkZeroTTL},
+	{"close-keeps-entries", "Close releases background resources but leaves entries readable", checkCloseKeepsEntries},
+	{"close-idempotent", "calling Close twice is harmless", checkCloseIdempotent},
+	{"concurrent-safety", "concurrent use does not panic or exceed capacity", checkConcurrent},
+}
+
+// RunChecks runs every check against f and returns their results in order.
+// A check that panics fails with the panic as its error.
+func RunChecks(f Factory) []Result {
+	results := make([]Result, len(Checks))
+	for i, c := range Checks {
+		results[i] = Result{Check: c.Name, Err: runCheck(c, f)}
+	}
+	return results
+}
+
+// Run runs every check against f as a subtest of t, failing those that do
+// not pass. Checks named in skip are not run.
+func Run(t *testing.T, f Factory, skip ...string) {
+	t.Helper()
+	skipped := make(map[string]bool, len(skip))
+	for _, name := range skip {
+		skipped[name] = true
+	}
+	for _, c := range Checks {
+		t.Run(c.Name, func(t *testing.T) {
+			if skipped[c.Name] {
+				t.Skip("skipped: known deviation")
+			}
+			if err := runCheck(c, f); err != nil {
+				t.Errorf("%s: %v", c.Doc, err)
+			}
+		})
+	}
+}
+
+func runCheck(c Check, f Factory) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return c.Run(f)
+}
+
+// fill sets each key to its own name.
+func fill(c cache.Cache[string, string], keys ...string) {
+	for _, k := range keys {
+		c.Set(k, k)
+	}
+}
+
+// expect checks which of keys are present, using Peek so the check itself
+// does not disturb recency.
+func expect(c cache.Cache[string, string], present bool, keys ...string) error {
+	for _, k := range keys {
+		if _, ok := c.Peek(k); ok != present {
+			if present {
+				return fmt.Errorf("%q missing", k)
+			}
+			return fmt.Errorf("%q still present", k)
+		}
+	}
+	return nil
+}
+
+func checkLRUEviction(f Factory) error {
+	c := f(3, 0, newClock())
+	defer c.Close()
+
+	fill(c, "a", "b", "c", "d")
+	if err := expect(c, false, "a"); err != nil {
+		return err
+	}
+	return expect(c, true, "b", "c", "d")
+}
+
+func checkGetPromotes(f Factory) error {
+	c := f(3, 0, newClock())
+	defer c.Close()
+
+	fill(c, "a", "b", "c")
+	c.Get("a")
+	fill(c, "d")
+	if err := expect(c, false, "b"); err != nil {
+		return err
+	}
+	return expect(c, true, "a", "c", "d")
+}
+
+func checkUpdateResetsRecency(f Factory) error {
+	c := f(3, 0, newClock())
+	defer c.Close()
+
+	fill(c, "a", "b", "c")
+	c.Set("a", "a2")
+	fill(c, "d")
+	if err := expect(c, false, "b"); err != nil {
+		return err
+	}
+	return expect(c, true, "a", "c", "d")
+}
+
+func checkPeekDoesNotPromote(f Factory) error {
+	c := f(3, 0, newClock())
+	defer c.Close()
+
+	fill(c, "a", "b", "c")
+	if v, ok := c.Peek("a"); !ok || v != "a" {
+		return fmt.Errorf("Peek(a) = %q, %v", v, ok)
+	}
+	fill(c, "d")
+	if _, ok := c.Get("a"); ok {
+		return fmt.Errorf("%q survived eviction after Peek", "a")
+	}
+	return nil
+}
+
+func checkUpdateReplacesValue(f Factory) error {
+	c := f(3, 0, newClock())
+	defer c.Close()
+
+	c.Set("a", "1")
+	c.Set("a", "2")
+	if v, ok := c.Get("a"); !ok || v != "2" {
+		return fmt.Errorf("Get(a) = %q, %v after overwrite", v, ok)
+	}
+	if n := c.Len(); n != 1 {
+		return fmt.Errorf("Len() = %d after overwrite, want 1", n)
+	}
+	return nil
+}
+
+func checkDelete(f Factory) error {
+	c := f(3, 0, newClock())
+	defer c.Close()
+
+	fill(c, "a", "b")
+	if !c.Delete("a") {
+		return fmt.Errorf("Delete of present key returned false")
+	}
+	if c.Delete("a") {
+		return fmt.Errorf("Delete of absent key returned true")
+	}
+	if _, ok := c.Get("a"); ok {
+		return fmt.Errorf("%q still present after Delete", "a")
+	}
+	if n := c.Len(); n != 1 {
+		return fmt.Errorf("Len() = %d after Delete, want 1", n)
+	}
+	return nil
+}
+
+func checkLenBounded(f Factory) error {
+	const capacity = 8
+	c := f(capacity, 0, newClock())
+	defer c.Close()
+
+	for i := 0; i < 10*capacity; i++ {
+		c.Set(strconv.Itoa(i), "v")
+		if n := c.Len(); n > capacity {
+			return fmt.Errorf("Len() = %d after %d inserts, capacity %d", n, i+1, capacity)
+		}
+	}
+	if n := c.Len(); n != capacity {
+		return fmt.Errorf("Len() = %d when full, want %d", n, capacity)
+	}
+	return nil
+}
+
+func checkTTLExpiry(f Factory) error {
+	clk := newClock()
+	c := f(3, 0, clk)
+	defer c.Close()
+
+	c.SetWithTTL("a", "a", shortTTL)
+	if _, ok := c.Get("a"); !ok {
+		return fmt.Errorf("%q missing before its TTL", "a")
+	}
+	clk.Advance(settle)
+	if _, ok := c.Get("a"); ok {
+		return fmt.Errorf("%q still present %v after a %v TTL", "a", settle, shortTTL)
+	}
+	return nil
+}
+
+func checkPerEntryTTL(f Factory) error {
+	clk := newClock()
+	c := f(3, 0, clk)
+	defer c.Close()
+
+	c.SetWithTTL("short", "short", shortTTL)
+	c.SetWithTTL("long", "long", time.Hour)
+	clk.Advance(settle)
+	if _, ok := c.Get("short"); ok {
+		return fmt.Errorf("entry with a %v TTL still present after %v", shortTTL, settle)
+	}
+	if _, ok := c.Get("long"); !ok {
+		return fmt.Errorf("entry with a one hour TTL missing after %v", settle)
+	}
+	return nil
+}
+
+func checkDefaultTTL(f Factory) error {
+	clk := newClock()
+	c := f(3, shortTTL, clk)
+	defer c.Close()
+
+	c.Set("a", "a")
+	if _, ok := c.Get("a"); !ok {
+		return fmt.Errorf("%q missing before the default TTL", "a")
+	}
+	clk.Advance(settle)
+	if _, ok := c.Get("a"); ok {
+		return fmt.Errorf("%q still present %v after a %v default TTL", "a", settle, shortTTL)
+	}
+	return nil
+}
+
+func checkZeroTTL(f Factory) error {
+	clk := newClock()
+	c := f(3, 0, clk)
+	defer c.Close()
+
+	c.SetWithTTL("a", "a", 0)
+	clk.Advance(shortTTL)
+	if _, ok := c.Get("a"); !ok {
+		return fmt.Errorf("entry written with a zero TTL is gone")
+	}
+	return nil
+}
+
+func checkCloseKeepsEntries(f Factory) error {
+	c := f(3, 0, newClock())
+	fill(c, "a")
+	c.Close()
+	if _, ok := c.Get("a"); !ok {
+		return fmt.Errorf("%q missing after Close", "a")
+	}
+	return nil
+}
+
+func checkCloseIdempotent(f Factory) error {
+	c := f(3, 0, newClock())
+	c.Close()
+	c.Close()
+	return nil
+}
+
+func checkConcurrent(f Factory) error {
+	const capacity = 64
+	c := f(capacity, 0, newClock())
+	defer c.Close()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			defer func() {
+				if r := recover(); r != nil {
+					errs <- fmt.Errorf("goroutine %d panicked: %v", g, r)
+				}
+			}()
+			for i := 0; i < 1000; i++ {
+				key := strconv.Itoa((g*1000 + i) % (4 * capacity))
+				switch i % 4 {
+				case 0:
+					c.Set(key, key)
+				case 1:
+					if v, ok := c.Get(key); ok && v != key {
+						errs <- fmt.Errorf("Get(%q) = %q", key, v)
+						return
+					}
+				case 2:
+					c.Peek(key)
+				case 3:
+					c.Delete(key)
+				}
+				if n := c.Len(); n > capacity {
+					errs <- fmt.Errorf("Len() = %d exceeds capacity %d", n, capacity)
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	close(errs)
+	return <-errs
+}
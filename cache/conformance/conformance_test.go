@@ -0,0 +1,83 @@
+package conformance
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rselbach/agent-comparison/cache"
+	"github.com/rselbach/agent-comparison/clock"
+)
+
+// deviations lists the checks each agent is known to fail. TestConformance
+// skips them and TestDeviations makes sure they still fail, so the table is
+// kept accurate as agents change.
+var deviations = map[string][]string{
+	// zero TTL expires immediately; Close clears the cache and closes a channel
+	"agent1": {"zero-ttl-never-expires", "close-keeps-entries", "close-idempotent"},
+	// no Peek
+	"agent2": {"peek-does-not-promote"},
+	// no Peek; zero TTL expires immediately
+	"agent3": {"peek-does-not-promote", "zero-ttl-never-expires"},
+	// no Peek; one cache-wide TTL
+	"agent5": {"peek-does-not-promote", "ttl-expiry", "per-entry-ttl"},
+	// one cache-wide TTL; Close closes a channel
+	"agent6": {"ttl-expiry", "per-entry-ttl", "close-idempotent"},
+	// no Peek; Close closes a channel
+	"agent7": {"peek-does-not-promote", "close-idempotent"},
+	// no Peek; one cache-wide TTL; Close closes a channel
+	"agent8": {"peek-does-not-promote", "ttl-expiry", "per-entry-ttl", "close-idempotent"},
+	// no Peek
+	"agent10": {"peek-does-not-promote"},
+	// Close closes a channel
+	"agent13": {"close-idempotent"},
+	// no Peek; Close closes a channel
+	"agent14": {"peek-does-not-promote", "close-idempotent"},
+	// no Peek; zero TTL expires immediately
+	"agent15": {"peek-does-not-promote", "zero-ttl-never-expires"},
+}
+
+func factory(impl cache.Implementation[string]) Factory {
+	return impl.NewWithClock
+}
+
+func TestConformance(t *testing.T) {
+	for _, impl := range cache.Implementations[string]() {
+		t.Run(impl.Name, func(t *testing.T) {
+			Run(t, factory(impl), deviations[impl.Name]...)
+		})
+	}
+}
+
+func TestDeviations(t *testing.T) {
+	checks := make(map[string]Check, len(Checks))
+	for _, c := range Checks {
+		checks[c.Name] = c
+	}
+
+	for _, impl := range cache.Implementations[string]() {
+		for _, name := range deviations[impl.Name] {
+			c, ok := checks[name]
+			if !ok {
+				t.Errorf("%s: unknown check %q", impl.Name, name)
+				continue
+			}
+			if err := runCheck(c, factory(impl)); err == nil {
+				t.Errorf("%s now passes %q; remove it from deviations", impl.Name, name)
+			}
+		}
+	}
+}
+
+func TestRunChecksRecoversPanics(t *testing.T) {
+	results := RunChecks(func(int, time.Duration, clock.Clock) cache.Cache[string, string] {
+		panic("boom")
+	})
+	if len(results) != len(Checks) {
+		t.Fatalf("got %d results, want %d", len(results), len(Checks))
+	}
+	for _, r := range results {
+		if r.Err == nil {
+			t.Fatalf("%s passed against a panicking factory", r.Check)
+		}
+	}
+}
@@ -0,0 +1,415 @@
+// Package lincheck runs the same concurrent schedule of cache operations
+// against an implementation of the shared cache interface, records when each
+// operation started and finished and what it returned, and checks that the
+// results are linearizable: that some order of the operations, consistent
+// with which finished before which started, produces exactly those results
+// on a single-threaded reference LRU.
+//
+// The conformance suite's concurrency check only catches panics and a Len
+// over capacity. A cache that, say, implements Delete as a lookup followed by
+// a separate removal passes it but lets two concurrent Deletes of one key
+// both report success, which this checker reports.
+//
+// Operations only overlap when the clients run in parallel, so races are
+// far likelier to show up with several CPUs. On one, the schedules still
+// interleave and the results are still checked against the model.
+//
+// The search is exponential in the worst case, so schedules are kept short:
+// a handful of clients with a few dozen operations between them. Histories
+// are limited to 64 operations.
+package lincheck
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/rselbach/agent-comparison/cache"
+)
+
+// Factory returns an empty cache holding at most capacity entries whose
+// entries never expire.
+type Factory func(capacity int) cache.Cache[string, int]
+
+// Kind is the kind of an Op.
+type Kind uint8
+
+const (
+	Set Kind = iota
+	Get
+	Peek
+	Delete
+	Len
+)
+
+var kindNames = [...]string{"Set", "Get", "Peek", "Delete", "Len"}
+
+func (k Kind) String() string {
+	if int(k) < len(kindNames) {
+		return kindNames[k]
+	}
+	return "Kind(" + strconv.Itoa(int(k)) + ")"
+}
+
+// Op is one cache operation.
+type Op struct {
+	Kind Kind
+	Key  string
+	// Value is what Set writes. Generate makes every write's value unique so
+	// a read identifies the write it saw.
+	Value int
+}
+
+func (o Op) String() string {
+	switch o.Kind {
+	case Set:
+		return fmt.Sprintf("Set(%s, %d)", o.Key, o.Value)
+	case Len:
+		return "Len()"
+	default:
+		return fmt.Sprintf("%v(%s)", o.Kind, o.Key)
+	}
+}
+
+// Result is what an operation returned: Value and OK for Get and Peek, OK
+// for Delete and N for Len. Set has no result.
+type Result struct {
+	Value int
+	OK    bool
+	N     int
+}
+
+// Operation is one operation in a History.
+type Operation struct {
+	Client int
+	Op     Op
+	Result Result
+	// Call and Return order the operation against every other one in its
+	// history: it started after every operation whose Return is below Call.
+	Call, Return int64
+}
+
+func (o Operation) String() string {
+	var res string
+	switch o.Op.Kind {
+	case Set:
+		res = "-"
+	case Get, Peek:
+		if o.Result.OK {
+			res = strconv.Itoa(o.Result.Value)
+		} else {
+			res = "miss"
+		}
+	case Delete:
+		res = strconv.FormatBool(o.Result.OK)
+	case Len:
+		res = strconv.Itoa(o.Result.N)
+	}
+	return fmt.Sprintf("client %d [%d,%d] %v = %s", o.Client, o.Call, o.Return, o.Op, res)
+}
+
+// History is a recorded concurrent run.
+type History []Operation
+
+func (h History) String() string {
+	sorted := append(History(nil), h...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Call < sorted[j].Call })
+	var b strings.Builder
+	for _, o := range sorted {
+		b.WriteString(o.String())
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// Config describes the cache and the schedules Generate produces.
+type Config struct {
+	Capacity int
+	// Clients is the number of goroutines, each running its own schedule.
+	Clients int
+	// Ops is the number of operations each client runs. Clients*Ops must not
+	// exceed 64.
+	Ops int
+	// Keys is how many distinct keys ops draw from. Zero means the capacity
+	// plus two, so entries keep being evicted.
+	Keys int
+}
+
+func (cfg Config) keys() int {
+	if cfg.Keys > 0 {
+		return cfg.Keys
+	}
+	return cfg.Capacity + 2
+}
+
+// maxOps is the longest history Check accepts.
+const maxOps = 64
+
+// Generate returns a schedule per client drawn from r. Writes carry distinct
+// positive values.
+func Generate(r *rand.Rand, cfg Config) [][]Op {
+	schedules := make([][]Op, cfg.Clients)
+	value := 0
+	for c := range schedules {
+		ops := make([]Op, cfg.Ops)
+		for i := range ops {
+			op := Op{Key: "k" + strconv.Itoa(r.IntN(cfg.keys()))}
+			switch p := r.IntN(100); {
+			case p < 40:
+				value++
+				op.Kind, op.Value = Set, value
+			case p < 70:
+				op.Kind = Get
+			case p < 80:
+				op.Kind = Peek
+			case p < 95:
+				op.Kind = Delete
+			default:
+				op.Kind, op.Key = Len, ""
+			}
+			ops[i] = op
+		}
+		schedules[c] = ops
+	}
+	return schedules
+}
+
+// Record runs each schedule on its own goroutine against c and returns the
+// history. Operations are so short that a goroutine woken by a channel would
+// usually finish its whole schedule before the next one started, so the
+// goroutines spin until all of them are running and yield between
+// operations to keep the schedules interleaved.
+func Record(c cache.Cache[string, int], schedules [][]Op) History {
+	var (
+		clock, ready atomic.Int64
+		wg           sync.WaitGroup
+	)
+	histories := make([]History, len(schedules))
+	for client, ops := range schedules {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ready.Add(1)
+			for ready.Load() < int64(len(schedules)) {
+				runtime.Gosched()
+			}
+			h := make(History, len(ops))
+			for i, op := range ops {
+				h[i] = Operation{Client: client, Op: op, Call: clock.Add(1)}
+				h[i].Result = apply(c, op)
+				h[i].Return = clock.Add(1)
+				runtime.Gosched()
+			}
+			histories[client] = h
+		}()
+	}
+	wg.Wait()
+
+	var h History
+	for _, ch := range histories {
+		h = append(h, ch...)
+	}
+	return h
+}
+
+func apply(c cache.Cache[string, int], op Op) Result {
+	var r Result
+	switch op.Kind {
+	case Set:
+		c.Set(op.Key, op.Value)
+	case Get:
+		r.Value, r.OK = c.Get(op.Key)
+	case Peek:
+		r.Value, r.OK = c.Peek(op.Key)
+	case Delete:
+		r.OK = c.Delete(op.Key)
+	case Len:
+		r.N = c.Len()
+	}
+	return r
+}
+
+// Model is the sequential LRU a history is checked against.
+type Model struct {
+	Capacity int
+	// PeekPromotes makes Peek mark the entry most recently used, like Get,
+	// for implementations that have no separate Peek.
+	PeekPromotes bool
+	// UncheckedDelete accepts any result from Delete while still applying
+	// its removal, for adapters that report presence from a separate lookup
+	// and so can have two concurrent Deletes of one key both report true.
+	UncheckedDelete bool
+}
+
+// state is the model's contents, most recently used first.
+type state []entry
+
+type entry struct {
+	key   string
+	value int
+}
+
+func (s state) find(key string) int {
+	for i, e := range s {
+		if e.key == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// promote returns a copy of s with the entry at i moved to the front.
+func (s state) promote(i int) state {
+	next := make(state, 0, len(s))
+	next = append(next, s[i])
+	next = append(next, s[:i]...)
+	return append(next, s[i+1:]...)
+}
+
+// step applies op to s, returning the next state and what op returns.
+func (m Model) step(s state, op Op) (state, Result) {
+	i := s.find(op.Key)
+	switch op.Kind {
+	case Set:
+		if i >= 0 {
+			s = s.promote(i)
+			s[0].value = op.Value
+			return s, Result{}
+		}
+		next := make(state, 0, len(s)+1)
+		next = append(next, entry{op.Key, op.Value})
+		next = append(next, s...)
+		if len(next) > m.Capacity {
+			next = next[:m.Capacity]
+		}
+		return next, Result{}
+	case Get, Peek:
+		if i < 0 {
+			return s, Result{}
+		}
+		v := s[i].value
+		if op.Kind == Get || m.PeekPromotes {
+			s = s.promote(i)
+		}
+		return s, Result{Value: v, OK: true}
+	case Delete:
+		if i < 0 {
+			return s, Result{}
+		}
+		next := make(state, 0, len(s)-1)
+		next = append(next, s[:i]...)
+		return append(next, s[i+1:]...), Result{OK: true}
+	case Len:
+		return s, Result{N: len(s)}
+	}
+	return s, Result{}
+}
+
+func (s state) String() string {
+	var b strings.Builder
+	for _, e := range s {
+		b.WriteString(e.key)
+		b.WriteByte('=')
+		b.WriteString(strconv.Itoa(e.value))
+		b.WriteByte(' ')
+	}
+	return b.String()
+}
+
+// ErrTooLong is returned by Check for a history of more than 64 operations.
+var ErrTooLong = errors.New("lincheck: history longer than 64 operations")
+
+// Check reports whether h is linearizable with respect to m, starting from
+// an empty cache.
+func Check(h History, m Model) (bool, error) {
+	if len(h) > maxOps {
+		return false, ErrTooLong
+	}
+	c := checker{h: h, m: m, seen: make(map[memoKey]bool)}
+	return c.search(0, nil), nil
+}
+
+type memoKey struct {
+	done  uint64
+	state string
+}
+
+type checker struct {
+	h    History
+	m    Model
+	seen map[memoKey]bool
+}
+
+// search tries every operation that may come next after the set done has
+// been linearized, reaching s. It is a depth-first version of Wing and
+// Gong's algorithm, pruning (done, s) pairs already explored.
+func (c *checker) search(done uint64, s state) bool {
+	if done == 1<<len(c.h)-1 {
+		return true
+	}
+	key := memoKey{done, s.String()}
+	if c.seen[key] {
+		return false
+	}
+	c.seen[key] = true
+
+	// Anything still pending that returned before another started must be
+	// linearized first, so only operations called before the earliest
+	// pending return can go next.
+	minReturn := int64(-1)
+	for i, o := range c.h {
+		if done&(1<<i) == 0 && (minReturn < 0 || o.Return < minReturn) {
+			minReturn = o.Return
+		}
+	}
+	for i, o := range c.h {
+		if done&(1<<i) != 0 || o.Call > minReturn {
+			continue
+		}
+		next, res := c.m.step(s, o.Op)
+		if o.Op.Kind == Delete && c.m.UncheckedDelete {
+			res = o.Result
+		}
+		if res == o.Result && c.search(done|1<<i, next) {
+			return true
+		}
+	}
+	return false
+}
+
+// Violation reports a history with no linearization.
+type Violation struct {
+	History History
+}
+
+func (v *Violation) Error() string {
+	return "not linearizable:\n" + v.History.String()
+}
+
+// Run generates rounds schedules from r, records each against a fresh cache
+// from f and checks it against m. It returns the first history that is not
+// linearizable as a *Violation, or nil.
+func Run(f Factory, cfg Config, m Model, r *rand.Rand, rounds int) error {
+	if cfg.Clients*cfg.Ops > maxOps {
+		return ErrTooLong
+	}
+	for i := 0; i < rounds; i++ {
+		c := f(cfg.Capacity)
+		h := Record(c, Generate(r, cfg))
+		c.Close()
+		ok, err := Check(h, m)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return &Violation{History: h}
+		}
+	}
+	return nil
+}
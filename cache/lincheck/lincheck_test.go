@@ -0,0 +1,212 @@
+package lincheck
+
+import (
+	"errors"
+	"math/rand/v2"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rselbach/agent-comparison/cache"
+)
+
+// peekPromotes lists the agents without a separate Peek, matching the
+// peek-does-not-promote deviations in the conformance suite.
+var peekPromotes = map[string]bool{
+	"agent2": true, "agent3": true, "agent5": true, "agent7": true, "agent8": true,
+	"agent10": true, "agent14": true, "agent15": true,
+}
+
+// uncheckedDelete lists the agents whose adapters build Delete's result from
+// a separate lookup because the agent's own Delete reports nothing.
+var uncheckedDelete = map[string]bool{
+	"agent3": true, "agent5": true, "agent6": true, "agent7": true, "agent8": true, "agent12": true,
+}
+
+var configs = []Config{
+	{Capacity: 1, Clients: 2, Ops: 8},
+	{Capacity: 2, Clients: 3, Ops: 6},
+	{Capacity: 3, Clients: 4, Ops: 5},
+}
+
+func TestImplementations(t *testing.T) {
+	rounds := 300
+	if testing.Short() {
+		rounds = 30
+	}
+	for _, impl := range cache.Implementations[int]() {
+		t.Run(impl.Name, func(t *testing.T) {
+			f := func(capacity int) cache.Cache[string, int] { return impl.New(capacity, 0) }
+			for i, cfg := range configs {
+				m := Model{
+					Capacity:        cfg.Capacity,
+					PeekPromotes:    peekPromotes[impl.Name],
+					UncheckedDelete: uncheckedDelete[impl.Name],
+				}
+				r := rand.New(rand.NewPCG(uint64(i), 1))
+				if err := Run(f, cfg, m, r, rounds); err != nil {
+					t.Fatalf("%+v: %v", cfg, err)
+				}
+			}
+		})
+	}
+}
+
+// op builds an Operation for hand-written histories.
+func op(client int, call, ret int64, o Op, r Result) Operation {
+	return Operation{Client: client, Op: o, Result: r, Call: call, Return: ret}
+}
+
+func TestCheck(t *testing.T) {
+	set := func(k string, v int) Op { return Op{Kind: Set, Key: k, Value: v} }
+	get := Op{Kind: Get, Key: "a"}
+	hit := func(v int) Result { return Result{Value: v, OK: true} }
+
+	type checkCase struct {
+		m    Model
+		h    History
+		want bool
+	}
+	tests := map[string]checkCase{
+		"concurrent read sees write": {
+			m: Model{Capacity: 2},
+			h: History{
+				op(0, 1, 4, set("a", 1), Result{}),
+				op(1, 2, 3, get, hit(1)),
+			},
+			want: true,
+		},
+		"concurrent read misses write": {
+			m: Model{Capacity: 2},
+			h: History{
+				op(0, 1, 4, set("a", 1), Result{}),
+				op(1, 2, 3, get, Result{}),
+			},
+			want: true,
+		},
+		"later read misses write": {
+			m: Model{Capacity: 2},
+			h: History{
+				op(0, 1, 2, set("a", 1), Result{}),
+				op(1, 3, 4, get, Result{}),
+			},
+			want: false,
+		},
+		"read sees overwritten value": {
+			m: Model{Capacity: 2},
+			h: History{
+				op(0, 1, 2, set("a", 1), Result{}),
+				op(0, 3, 4, set("a", 2), Result{}),
+				op(1, 5, 6, get, hit(1)),
+			},
+			want: false,
+		},
+		"both deletes succeed": {
+			m: Model{Capacity: 2},
+			h: History{
+				op(0, 1, 2, set("a", 1), Result{}),
+				op(0, 3, 6, Op{Kind: Delete, Key: "a"}, Result{OK: true}),
+				op(1, 4, 5, Op{Kind: Delete, Key: "a"}, Result{OK: true}),
+			},
+			want: false,
+		},
+		"evicted entry returned": {
+			m: Model{Capacity: 1},
+			h: History{
+				op(0, 1, 2, set("a", 1), Result{}),
+				op(0, 3, 4, set("b", 2), Result{}),
+				op(1, 5, 6, get, hit(1)),
+			},
+			want: false,
+		},
+		"len over capacity": {
+			m: Model{Capacity: 1},
+			h: History{
+				op(0, 1, 2, set("a", 1), Result{}),
+				op(0, 3, 4, set("b", 2), Result{}),
+				op(1, 5, 6, Op{Kind: Len}, Result{N: 2}),
+			},
+			want: false,
+		},
+	}
+
+	// Peek then insert past capacity: a is evicted unless Peek promoted it.
+	peek := History{
+		op(0, 1, 2, set("a", 1), Result{}),
+		op(0, 3, 4, set("b", 2), Result{}),
+		op(0, 5, 6, Op{Kind: Peek, Key: "a"}, hit(1)),
+		op(0, 7, 8, set("c", 3), Result{}),
+		op(0, 9, 10, get, hit(1)),
+	}
+	tests["peek does not promote"] = checkCase{Model{Capacity: 2}, peek, false}
+	tests["peek promotes"] = checkCase{Model{Capacity: 2, PeekPromotes: true}, peek, true}
+
+	deletes := tests["both deletes succeed"]
+	deletes.m.UncheckedDelete, deletes.want = true, true
+	tests["both deletes succeed, unchecked"] = deletes
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := Check(tc.h, tc.m)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tc.want {
+				t.Fatalf("Check() = %v, want %v for\n%v", got, tc.want, tc.h)
+			}
+		})
+	}
+}
+
+func TestCheckTooLong(t *testing.T) {
+	h := make(History, maxOps+1)
+	if _, err := Check(h, Model{Capacity: 1}); !errors.Is(err, ErrTooLong) {
+		t.Fatalf("Check() error = %v, want ErrTooLong", err)
+	}
+}
+
+// unbounded is a cache that never evicts.
+type unbounded struct {
+	mu sync.Mutex
+	m  map[string]int
+}
+
+func (u *unbounded) Set(key string, value int) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.m[key] = value
+}
+func (u *unbounded) SetWithTTL(key string, value int, _ time.Duration) { u.Set(key, value) }
+func (u *unbounded) Get(key string) (int, bool) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	v, ok := u.m[key]
+	return v, ok
+}
+func (u *unbounded) Peek(key string) (int, bool) { return u.Get(key) }
+func (u *unbounded) Delete(key string) bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	_, ok := u.m[key]
+	delete(u.m, key)
+	return ok
+}
+func (u *unbounded) Len() int {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return len(u.m)
+}
+func (u *unbounded) Close() {}
+
+func TestRunReportsViolation(t *testing.T) {
+	f := func(int) cache.Cache[string, int] { return &unbounded{m: map[string]int{}} }
+	cfg := Config{Capacity: 1, Clients: 2, Ops: 8}
+	err := Run(f, cfg, Model{Capacity: 1}, rand.New(rand.NewPCG(1, 1)), 100)
+	var v *Violation
+	if !errors.As(err, &v) {
+		t.Fatalf("Run() = %v, want a violation", err)
+	}
+	if len(v.History) != cfg.Clients*cfg.Ops {
+		t.Fatalf("violation has %d operations, want %d", len(v.History), cfg.Clients*cfg.Ops)
+	}
+}
@@ -0,0 +1,244 @@
+// Package memprof measures what agent caches cost in memory.
+//
+// A Shape describes the entries: how many, how long their keys are and what
+// their values are. For each shape and implementation Run fills a fresh cache
+// to capacity and reports the heap bytes and objects it added per entry, the
+// allocations a Get hit and an evicting Set make, and how long a garbage
+// collection takes while the cache is live. Keys and values are allocated
+// before the baseline is taken, so the per-entry figures are the cache's own
+// overhead: list nodes, map buckets and, for agents that store interface{}
+// values, the boxing of each value.
+//
+// The figures come from runtime.MemStats and forced collections, so Run must
+// not share the process with other work it would then count.
+package memprof
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rselbach/agent-comparison/cache"
+)
+
+// ValueKind selects the type of the stored values.
+type ValueKind string
+
+const (
+	// Bytes stores a distinct []byte of ValueSize bytes per entry.
+	Bytes ValueKind = "bytes"
+	// Int stores an int per entry, which agents with interface{} values
+	// have to box.
+	Int ValueKind = "int"
+)
+
+// Shape describes the entries a cache is filled with.
+type Shape struct {
+	Name string `json:"name"`
+	// Entries is the cache capacity and the number of entries stored.
+	Entries int `json:"entries"`
+	// KeySize is the length of each key. It must fit the decimal index of
+	// every key used, which is below twice Entries.
+	KeySize   int       `json:"key_size"`
+	Value     ValueKind `json:"value"`
+	ValueSize int       `json:"value_size,omitempty"`
+}
+
+// Result is the footprint of one shape in one implementation.
+type Result struct {
+	Impl  string `json:"impl"`
+	Shape string `json:"shape"`
+	// BytesPerEntry is the live heap the filled cache added, per entry.
+	BytesPerEntry float64 `json:"bytes_per_entry"`
+	// ObjectsPerEntry is the number of live heap objects it added, per
+	// entry.
+	ObjectsPerEntry float64 `json:"objects_per_entry"`
+	// GetAllocs and SetAllocs are heap allocations per Get hit and per Set
+	// of a new key into a full cache.
+	GetAllocs float64 `json:"get_allocs"`
+	SetAllocs float64 `json:"set_allocs"`
+	// GCTime is the mean wall time of a forced collection with the filled
+	// cache live, and GCPause the mean stop-the-world pause within it.
+	GCTime  time.Duration `json:"gc_time_ns"`
+	GCPause time.Duration `json:"gc_pause_ns"`
+}
+
+// DefaultShapes returns a small set of representative shapes. They are kept
+// to tens of thousands of entries because agent11 scans every entry for
+// expiry on each Set, making a fill quadratic.
+func DefaultShapes() []Shape {
+	return []Shape{
+		{Name: "int", Entries: 20000, KeySize: 16, Value: Int},
+		{Name: "bytes-64", Entries: 20000, KeySize: 16, Value: Bytes, ValueSize: 64},
+		{Name: "bytes-1k", Entries: 10000, KeySize: 16, Value: Bytes, ValueSize: 1024},
+		{Name: "long-keys", Entries: 20000, KeySize: 128, Value: Int},
+	}
+}
+
+func (s Shape) validate() error {
+	var errs []error
+	if s.Entries <= 0 {
+		errs = append(errs, errors.New("entries must be positive"))
+	} else if s.KeySize < len(strconv.Itoa(2*s.Entries-1)) {
+		errs = append(errs, fmt.Errorf("key size must be at least %d for %d entries", len(strconv.Itoa(2*s.Entries-1)), s.Entries))
+	}
+	switch s.Value {
+	case Int:
+	case Bytes:
+		if s.ValueSize < 0 {
+			errs = append(errs, errors.New("value size must not be negative"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("unknown value kind %q", s.Value))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("shape %q: %w", s.Name, err)
+	}
+	return nil
+}
+
+// Run measures every shape in every implementation, in that order. If names
+// is not empty, only the implementations it names are measured.
+func Run(shapes []Shape, names ...string) ([]Result, error) {
+	for _, s := range shapes {
+		if err := s.validate(); err != nil {
+			return nil, err
+		}
+	}
+	want := make(map[string]bool, len(names))
+	for _, n := range names {
+		want[n] = true
+	}
+	for _, impl := range cache.Implementations[int]() {
+		delete(want, impl.Name)
+	}
+	for n := range want {
+		return nil, fmt.Errorf("unknown implementation %q", n)
+	}
+
+	var results []Result
+	for _, s := range shapes {
+		switch s.Value {
+		case Int:
+			results = append(results, runShape(s, names, func(i int) int { return i })...)
+		case Bytes:
+			results = append(results, runShape(s, names, func(int) []byte { return make([]byte, s.ValueSize) })...)
+		}
+	}
+	return results, nil
+}
+
+func runShape[V any](s Shape, names []string, value func(i int) V) []Result {
+	var results []Result
+	for _, impl := range cache.Implementations[V]() {
+		if len(names) > 0 && !contains(names, impl.Name) {
+			continue
+		}
+		results = append(results, measure(s, impl, value))
+	}
+	return results
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// gcRuns is how many forced collections GCTime and GCPause average over.
+const gcRuns = 5
+
+func measure[V any](s Shape, impl cache.Implementation[V], value func(i int) V) Result {
+	// The first Entries keys fill the cache and the rest are used to measure
+	// evicting Sets.
+	keys := make([]string, 2*s.Entries)
+	values := make([]V, 2*s.Entries)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("%0*d", s.KeySize, i)
+		values[i] = value(i)
+	}
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	c := impl.New(s.Entries, 0)
+	defer c.Close()
+	for i := 0; i < s.Entries; i++ {
+		c.Set(keys[i], values[i])
+	}
+
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+	res := Result{
+		Impl:            impl.Name,
+		Shape:           s.Name,
+		BytesPerEntry:   float64(int64(after.HeapAlloc)-int64(before.HeapAlloc)) / float64(s.Entries),
+		ObjectsPerEntry: float64(int64(after.HeapObjects)-int64(before.HeapObjects)) / float64(s.Entries),
+	}
+	res.GCTime, res.GCPause = gcCost()
+
+	res.GetAllocs = allocsPerOp(s.Entries, func(i int) { c.Get(keys[i]) })
+	res.SetAllocs = allocsPerOp(s.Entries, func(i int) { c.Set(keys[s.Entries+i], values[s.Entries+i]) })
+
+	runtime.KeepAlive(keys)
+	runtime.KeepAlive(values)
+	return res
+}
+
+// gcCost forces gcRuns collections and returns their mean wall time and mean
+// stop-the-world pause.
+func gcCost() (time.Duration, time.Duration) {
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	began := time.Now()
+	for i := 0; i < gcRuns; i++ {
+		runtime.GC()
+	}
+	elapsed := time.Since(began)
+	runtime.ReadMemStats(&after)
+
+	var pause time.Duration
+	if n := after.NumGC - before.NumGC; n > 0 {
+		pause = time.Duration((after.PauseTotalNs - before.PauseTotalNs) / uint64(n))
+	}
+	return elapsed / gcRuns, pause
+}
+
+// allocsPerOp runs op for 0..n-1 and returns the mean number of heap
+// allocations per call.
+func allocsPerOp(n int, op func(i int)) float64 {
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	for i := 0; i < n; i++ {
+		op(i)
+	}
+	runtime.ReadMemStats(&after)
+	return float64(after.Mallocs-before.Mallocs) / float64(n)
+}
+
+// WriteJSON writes results as indented JSON.
+func WriteJSON(w io.Writer, results []Result) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(results)
+}
+
+// WriteTable writes results as an aligned text table.
+func WriteTable(w io.Writer, results []Result) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "shape\timpl\tbytes/entry\tobjects/entry\tallocs/get\tallocs/set\tgc time\tgc pause\t")
+	for _, r := range results {
+		fmt.Fprintf(tw, "%s\t%s\t%.1f\t%.2f\t%.2f\t%.2f\t%v\t%v\t\n",
+			r.Shape, r.Impl, r.BytesPerEntry, r.ObjectsPerEntry, r.GetAllocs, r.SetAllocs, r.GCTime, r.GCPause)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,97 @@
+package memprof
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func smallShapes() []Shape {
+	shapes := DefaultShapes()
+	for i := range shapes {
+		shapes[i].Entries = 1000
+	}
+	return shapes
+}
+
+func TestRun(t *testing.T) {
+	shapes := smallShapes()
+	results, err := Run(shapes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) == 0 || len(results)%len(shapes) != 0 {
+		t.Fatalf("got %d results for %d shapes", len(results), len(shapes))
+	}
+	for _, r := range results {
+		if r.BytesPerEntry <= 0 || r.ObjectsPerEntry < 0 || r.GetAllocs < 0 || r.SetAllocs < 0 || r.GCTime <= 0 {
+			t.Errorf("implausible result %+v", r)
+		}
+	}
+}
+
+func TestBoxingCostsObjects(t *testing.T) {
+	// agent1 keeps interface{} values in container/list elements; agent2 has
+	// generic intrusive nodes.
+	results, err := Run(smallShapes()[:1], "agent1", "agent2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want 2", len(results))
+	}
+	boxed, generic := results[0], results[1]
+	if boxed.ObjectsPerEntry < generic.ObjectsPerEntry+1 {
+		t.Fatalf("expected boxing and list elements to add objects: agent1 %.2f, agent2 %.2f per entry",
+			boxed.ObjectsPerEntry, generic.ObjectsPerEntry)
+	}
+}
+
+func TestInvalidShape(t *testing.T) {
+	for name, mutate := range map[string]func(*Shape){
+		"entries":    func(s *Shape) { s.Entries = 0 },
+		"key size":   func(s *Shape) { s.KeySize = 3 },
+		"value kind": func(s *Shape) { s.Value = "float" },
+		"value size": func(s *Shape) { s.Value, s.ValueSize = Bytes, -1 },
+	} {
+		s := smallShapes()[0]
+		mutate(&s)
+		if _, err := Run([]Shape{s}); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestUnknownImplementation(t *testing.T) {
+	if _, err := Run(smallShapes()[:1], "agent99"); err == nil {
+		t.Fatal("expected error for an unknown implementation")
+	}
+}
+
+func TestReports(t *testing.T) {
+	results, err := Run(smallShapes()[:1], "agent2", "agent4")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteJSON(&buf, results); err != nil {
+		t.Fatal(err)
+	}
+	var decoded []Result
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != 2 || decoded[1] != results[1] {
+		t.Fatalf("round trip lost data: %+v", decoded)
+	}
+
+	buf.Reset()
+	if err := WriteTable(&buf, results); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(buf.String()), "\n"); len(lines) != 3 {
+		t.Fatalf("expected header and 2 rows, got:\n%s", buf.String())
+	}
+}
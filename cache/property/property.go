@@ -0,0 +1,311 @@
+// Package property runs random sequences of cache operations against an
+// implementation of the shared cache interface and checks invariants that
+// must hold whatever the sequence:
+//
+//   - Len never exceeds the capacity;
+//   - a hit returns the value most recently written for the key;
+//   - a deleted key is not returned, and deleting it again reports false;
+//   - a key whose TTL has passed is not returned;
+//   - at the end, every key that hits is counted by Len, and when no entry
+//     carries a TTL, Len counts exactly the keys that hit.
+//
+// A cache whose map and recency list drift apart typically breaks the last
+// two first: an entry unlinked from the list but left in the map keeps
+// answering lookups after it should have been evicted, and one left on the
+// list but dropped from the map is still counted.
+//
+// Time is a clock.Fake, so Advance ops move expiry forward without sleeping.
+package property
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"time"
+
+	"github.com/rselbach/agent-comparison/cache"
+	"github.com/rselbach/agent-comparison/clock"
+)
+
+// Factory returns an empty cache holding at most capacity entries, applying
+// defaultTTL in Set and reading the time from clk. Zero defaultTTL means no
+// expiry.
+type Factory func(capacity int, defaultTTL time.Duration, clk clock.Clock) cache.Cache[string, int]
+
+// Kind is the kind of an Op.
+type Kind uint8
+
+const (
+	Set Kind = iota
+	SetWithTTL
+	Get
+	Peek
+	Delete
+	Advance
+	numKinds
+)
+
+var kindNames = [...]string{"Set", "SetWithTTL", "Get", "Peek", "Delete", "Advance"}
+
+func (k Kind) String() string {
+	if k < numKinds {
+		return kindNames[k]
+	}
+	return "Kind(" + strconv.Itoa(int(k)) + ")"
+}
+
+// Op is one cache operation.
+type Op struct {
+	Kind Kind
+	// Key indexes the key the op touches. Advance ignores it.
+	Key int
+	// D is the TTL for SetWithTTL and how far to move the clock for
+	// Advance. Other kinds ignore it.
+	D time.Duration
+}
+
+func (o Op) String() string {
+	switch o.Kind {
+	case SetWithTTL:
+		return fmt.Sprintf("SetWithTTL(%s, %v)", key(o.Key), o.D)
+	case Advance:
+		return fmt.Sprintf("Advance(%v)", o.D)
+	default:
+		return fmt.Sprintf("%v(%s)", o.Kind, key(o.Key))
+	}
+}
+
+// Config describes the cache a sequence runs against.
+type Config struct {
+	Capacity   int
+	DefaultTTL time.Duration
+	// Keys is how many distinct keys ops draw from. Zero means twice the
+	// capacity, so roughly half of all lookups miss and writes keep evicting.
+	Keys int
+	// CacheWideTTL marks implementations that apply one TTL to every entry
+	// and ignore the one passed to SetWithTTL. SetWithTTL ops then run as
+	// Set.
+	CacheWideTTL bool
+}
+
+func (cfg Config) keys() int {
+	if cfg.Keys > 0 {
+		return cfg.Keys
+	}
+	return 2 * cfg.Capacity
+}
+
+// maxD bounds the TTLs and clock advances ops carry, so entries expire
+// within a few dozen ops.
+const maxD = 50 * time.Millisecond
+
+// weights is how often Generate picks each Kind, out of their sum.
+var weights = [numKinds]int{Set: 30, SetWithTTL: 15, Get: 25, Peek: 10, Delete: 10, Advance: 10}
+
+// Generate returns n random ops for cfg drawn from r.
+func Generate(r *rand.Rand, cfg Config, n int) []Op {
+	total := 0
+	for _, w := range weights {
+		total += w
+	}
+	ops := make([]Op, n)
+	for i := range ops {
+		pick := r.IntN(total)
+		kind := Kind(0)
+		for pick >= weights[kind] {
+			pick -= weights[kind]
+			kind++
+		}
+		ops[i] = Op{
+			Kind: kind,
+			Key:  r.IntN(cfg.keys()),
+			D:    time.Duration(1+r.IntN(int(maxD/time.Millisecond))) * time.Millisecond,
+		}
+	}
+	return ops
+}
+
+// Decode turns fuzzer input into ops for cfg, two bytes per op: the first
+// picks the Kind and the second the key and duration. A trailing odd byte is
+// ignored.
+func Decode(data []byte, cfg Config) []Op {
+	ops := make([]Op, 0, len(data)/2)
+	for i := 0; i+1 < len(data); i += 2 {
+		b := int(data[i+1])
+		ops = append(ops, Op{
+			Kind: Kind(data[i] % byte(numKinds)),
+			Key:  b % cfg.keys(),
+			D:    time.Duration(1+b%int(maxD/time.Millisecond)) * time.Millisecond,
+		})
+	}
+	return ops
+}
+
+// Violation reports an invariant that did not hold.
+type Violation struct {
+	Invariant string
+	// Step is the index of the op after which the invariant failed, or
+	// len(ops) for the checks made once the sequence has run.
+	Step   int
+	Op     Op
+	Detail string
+}
+
+func (v *Violation) Error() string {
+	return fmt.Sprintf("%s after step %d (%v): %s", v.Invariant, v.Step, v.Op, v.Detail)
+}
+
+// Invariant names, as reported in Violation.
+const (
+	NoPanic       = "no-panic"
+	LenBounded    = "len-bounded"
+	LatestValue   = "latest-value"
+	DeletedAbsent = "deleted-absent"
+	ExpiredAbsent = "expired-absent"
+	LenConsistent = "len-consistent"
+)
+
+// epoch is where the fake clock starts.
+var epoch = time.Unix(1_000_000, 0)
+
+// Run builds a cache with f and cfg, applies ops to it and returns the first
+// invariant that fails, as a *Violation, or nil if they all hold. A panic
+// in the cache is reported as a NoPanic violation.
+func Run(f Factory, cfg Config, ops []Op) (err error) {
+	r := &runner{cfg: cfg, clk: clock.NewFake(epoch), model: make(map[int]*entry)}
+	defer func() {
+		if p := recover(); p != nil {
+			err = r.violation(NoPanic, fmt.Sprint(p))
+		}
+	}()
+
+	r.c = f(cfg.Capacity, cfg.DefaultTTL, r.clk)
+	defer r.c.Close()
+
+	for i, op := range ops {
+		r.step, r.op = i, op
+		if err := r.apply(op); err != nil {
+			return err
+		}
+		if n := r.c.Len(); n > cfg.Capacity {
+			return r.violation(LenBounded, fmt.Sprintf("Len() = %d, capacity %d", n, cfg.Capacity))
+		}
+	}
+	r.step, r.op = len(ops), Op{}
+	return r.finish()
+}
+
+// entry is what the model knows about a key.
+type entry struct {
+	// value is the value last written, which is the index of the writing
+	// op plus one so it is never int's zero value.
+	value int
+	// deadline is when the last write expires, zero if it does not.
+	deadline time.Time
+	// live is set by a write and cleared by Delete. A live entry may still
+	// have been evicted or have expired.
+	live bool
+}
+
+type runner struct {
+	cfg   Config
+	clk   *clock.Fake
+	c     cache.Cache[string, int]
+	model map[int]*entry
+	step  int
+	op    Op
+}
+
+func (r *runner) violation(invariant, detail string) *Violation {
+	return &Violation{Invariant: invariant, Step: r.step, Op: r.op, Detail: detail}
+}
+
+func key(i int) string { return "k" + strconv.Itoa(i) }
+
+func (r *runner) apply(op Op) error {
+	k := key(op.Key)
+	e := r.model[op.Key]
+	kind := op.Kind
+	if kind == SetWithTTL && r.cfg.CacheWideTTL {
+		kind = Set
+	}
+
+	switch kind {
+	case Set, SetWithTTL:
+		ttl := r.cfg.DefaultTTL
+		v := r.step + 1
+		if kind == Set {
+			r.c.Set(k, v)
+		} else {
+			ttl = op.D
+			r.c.SetWithTTL(k, v, ttl)
+		}
+		e = &entry{value: v, live: true}
+		if ttl > 0 {
+			e.deadline = r.clk.Now().Add(ttl)
+		}
+		r.model[op.Key] = e
+	case Get, Peek:
+		var v int
+		var ok bool
+		if kind == Get {
+			v, ok = r.c.Get(k)
+		} else {
+			v, ok = r.c.Peek(k)
+		}
+		if ok {
+			return r.checkHit(k, e, v)
+		}
+	case Delete:
+		if r.c.Delete(k) && (e == nil || !e.live) {
+			return r.violation(DeletedAbsent, fmt.Sprintf("Delete(%s) = true for a key that is not stored", k))
+		}
+		if e != nil {
+			e.live = false
+		}
+	case Advance:
+		r.clk.Advance(op.D)
+	}
+	return nil
+}
+
+// checkHit checks a lookup of k that returned v.
+func (r *runner) checkHit(k string, e *entry, v int) error {
+	switch {
+	case e == nil:
+		return r.violation(DeletedAbsent, fmt.Sprintf("%s returned %d but was never written", k, v))
+	case !e.live:
+		return r.violation(DeletedAbsent, fmt.Sprintf("%s returned %d after Delete", k, v))
+	case v != e.value:
+		return r.violation(LatestValue, fmt.Sprintf("%s returned %d, last written %d", k, v, e.value))
+	case !e.deadline.IsZero() && r.clk.Now().After(e.deadline):
+		return r.violation(ExpiredAbsent, fmt.Sprintf("%s returned %v after its deadline", k, r.clk.Now().Sub(e.deadline)))
+	}
+	return nil
+}
+
+// finish runs the checks made once every op has been applied. It looks each
+// key up, so it must come last.
+func (r *runner) finish() error {
+	n := r.c.Len()
+	hits, timed := 0, false
+	for i, e := range r.model {
+		if e.live && !e.deadline.IsZero() {
+			timed = true
+		}
+		v, ok := r.c.Peek(key(i))
+		if !ok {
+			continue
+		}
+		if err := r.checkHit(key(i), e, v); err != nil {
+			return err
+		}
+		hits++
+	}
+	// Expired entries may or may not be counted until something removes
+	// them, so only entries without a TTL pin Len down exactly.
+	if hits > n || (!timed && hits != n) {
+		return r.violation(LenConsistent, fmt.Sprintf("Len() = %d but %d keys hit", n, hits))
+	}
+	return nil
+}
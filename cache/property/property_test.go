@@ -0,0 +1,201 @@
+package property
+
+import (
+	"errors"
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"github.com/rselbach/agent-comparison/cache"
+	"github.com/rselbach/agent-comparison/clock"
+)
+
+// cacheWideTTL lists the agents that ignore the TTL passed to SetWithTTL,
+// matching the ttl-expiry deviations in the conformance suite.
+var cacheWideTTL = map[string]bool{"agent5": true, "agent6": true, "agent8": true}
+
+// configs are the shapes each agent is exercised in: a cache small enough
+// that nearly every write evicts, one with a default TTL, and a larger one.
+var configs = []Config{
+	{Capacity: 1},
+	{Capacity: 3, DefaultTTL: 20 * time.Millisecond},
+	{Capacity: 16, Keys: 24},
+}
+
+func factory(impl cache.Implementation[int]) Factory {
+	return func(capacity int, defaultTTL time.Duration, clk clock.Clock) cache.Cache[string, int] {
+		return impl.NewWithClock(capacity, defaultTTL, clk)
+	}
+}
+
+func forImpl(cfg Config, name string) Config {
+	cfg.CacheWideTTL = cacheWideTTL[name]
+	return cfg
+}
+
+func TestImplementations(t *testing.T) {
+	seeds := 50
+	if testing.Short() {
+		seeds = 5
+	}
+	for _, impl := range cache.Implementations[int]() {
+		t.Run(impl.Name, func(t *testing.T) {
+			for _, base := range configs {
+				cfg := forImpl(base, impl.Name)
+				for seed := 0; seed < seeds; seed++ {
+					r := rand.New(rand.NewPCG(uint64(seed), uint64(cfg.Capacity)))
+					ops := Generate(r, cfg, 300)
+					if err := Run(factory(impl), cfg, ops); err != nil {
+						t.Fatalf("%+v seed %d: %v", cfg, seed, err)
+					}
+				}
+			}
+		})
+	}
+}
+
+func FuzzImplementations(f *testing.F) {
+	f.Add([]byte{0, 1, 2, 1, 4, 1, 2, 1})
+	f.Add([]byte{1, 10, 5, 20, 2, 10, 0, 3, 0, 4, 3, 3})
+	f.Add([]byte{0, 0, 0, 1, 0, 2, 0, 3, 2, 0, 4, 1, 0, 4, 3, 1})
+
+	impls := cache.Implementations[int]()
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for _, impl := range impls {
+			for _, base := range configs {
+				cfg := forImpl(base, impl.Name)
+				if err := Run(factory(impl), cfg, Decode(data, cfg)); err != nil {
+					t.Errorf("%s %+v: %v", impl.Name, cfg, err)
+				}
+			}
+		}
+	})
+}
+
+// broken is a map-backed cache with deliberate faults, to make sure each
+// invariant is actually checked.
+type broken struct {
+	clk      clock.Clock
+	m        map[string]int
+	deadline map[string]time.Time
+	// noEvict skips eviction; keepDeleted makes Delete a no-op that still
+	// reports success; noExpiry ignores TTLs; miscount adds to Len; stale
+	// makes Set keep the first value written.
+	noEvict, keepDeleted, noExpiry, miscount, stale bool
+	capacity                                        int
+}
+
+func (b *broken) Set(key string, value int) { b.SetWithTTL(key, value, 0) }
+func (b *broken) SetWithTTL(key string, value int, ttl time.Duration) {
+	if _, ok := b.m[key]; ok && b.stale {
+		return
+	}
+	if _, ok := b.m[key]; !ok && len(b.m) >= b.capacity && !b.noEvict {
+		for k := range b.m {
+			delete(b.m, k)
+			break
+		}
+	}
+	b.m[key] = value
+	delete(b.deadline, key)
+	if ttl > 0 {
+		b.deadline[key] = b.clk.Now().Add(ttl)
+	}
+}
+func (b *broken) Get(key string) (int, bool) { return b.Peek(key) }
+func (b *broken) Peek(key string) (int, bool) {
+	if d, ok := b.deadline[key]; ok && !b.noExpiry && b.clk.Now().After(d) {
+		return 0, false
+	}
+	v, ok := b.m[key]
+	return v, ok
+}
+func (b *broken) Delete(key string) bool {
+	_, ok := b.m[key]
+	if !b.keepDeleted {
+		delete(b.m, key)
+	}
+	return ok
+}
+func (b *broken) Len() int {
+	if b.miscount {
+		return len(b.m) + 1
+	}
+	return len(b.m)
+}
+func (b *broken) Close() {}
+
+func TestRunDetectsViolations(t *testing.T) {
+	set := func(k int) Op { return Op{Kind: Set, Key: k} }
+	tests := map[string]struct {
+		fault     func(*broken)
+		ops       []Op
+		invariant string
+	}{
+		"len-bounded": {
+			fault:     func(b *broken) { b.noEvict = true },
+			ops:       []Op{set(0), set(1), set(2)},
+			invariant: LenBounded,
+		},
+		"latest-value": {
+			fault:     func(b *broken) { b.stale = true },
+			ops:       []Op{set(0), set(0), {Kind: Get, Key: 0}},
+			invariant: LatestValue,
+		},
+		"deleted-absent": {
+			fault:     func(b *broken) { b.keepDeleted = true },
+			ops:       []Op{set(0), {Kind: Delete, Key: 0}, {Kind: Peek, Key: 0}},
+			invariant: DeletedAbsent,
+		},
+		"expired-absent": {
+			fault:     func(b *broken) { b.noExpiry = true },
+			ops:       []Op{{Kind: SetWithTTL, Key: 0, D: time.Millisecond}, {Kind: Advance, D: 2 * time.Millisecond}, {Kind: Get, Key: 0}},
+			invariant: ExpiredAbsent,
+		},
+		"len-consistent": {
+			fault:     func(b *broken) { b.miscount = true },
+			ops:       []Op{set(0)},
+			invariant: LenConsistent,
+		},
+		"no-panic": {
+			fault:     func(b *broken) { b.m = nil },
+			ops:       []Op{set(0)},
+			invariant: NoPanic,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			f := func(capacity int, _ time.Duration, clk clock.Clock) cache.Cache[string, int] {
+				b := &broken{clk: clk, m: map[string]int{}, deadline: map[string]time.Time{}, capacity: capacity}
+				tc.fault(b)
+				return b
+			}
+			err := Run(f, Config{Capacity: 2}, tc.ops)
+			var v *Violation
+			if !errors.As(err, &v) {
+				t.Fatalf("Run() = %v, want a violation", err)
+			}
+			if v.Invariant != tc.invariant {
+				t.Fatalf("got %v, want %s", v, tc.invariant)
+			}
+		})
+	}
+}
+
+func TestDecode(t *testing.T) {
+	cfg := Config{Capacity: 2}
+	ops := Decode([]byte{1, 7, byte(Advance), 60, 9}, cfg)
+	want := []Op{
+		{Kind: SetWithTTL, Key: 3, D: 8 * time.Millisecond},
+		{Kind: Advance, Key: 0, D: 11 * time.Millisecond},
+	}
+	if len(ops) != len(want) {
+		t.Fatalf("got %d ops, want %d", len(ops), len(want))
+	}
+	for i := range want {
+		if ops[i] != want[i] {
+			t.Errorf("op %d = %v, want %v", i, ops[i], want[i])
+		}
+	}
+}
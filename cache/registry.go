@@ -0,0 +1,127 @@
+package cache
+
+import (
+	"time"
+
+	agent10 "agent10/lru"
+	agent11 "agent11/lru"
+	agent9 "agent9/lru"
+	agent2 "lru"
+
+	agent3 "github.com/gemini/lrucache"
+	agent4 "github.com/opencode/lru/lru"
+	agent12 "github.com/rselbach/agent12/lru"
+	"github.com/rselbach/agent13"
+	"github.com/rselbach/agent14"
+	agent15 "github.com/rselbach/agent15/lru"
+	"github.com/rselbach/agent5"
+	agent7 "github.com/rselbach/agent7/lru"
+	"github.com/rselbach/agent8"
+	agent1 "github.com/rselbach/cc/lrucache"
+	agent6 "github.com/rselbach/lrucache"
+
+	"github.com/rselbach/agent-comparison/clock"
+	"github.com/rselbach/agent-comparison/metrics"
+)
+
+// Implementation names an agent cache and how to build one.
+type Implementation[V any] struct {
+	Name string
+	// New returns an empty cache holding at most capacity entries, which
+	// must be positive, with defaultTTL applied by Set. Zero defaultTTL
+	// means no expiry. Background cleanup, where an agent has it and can
+	// stop it on Close, runs once a minute.
+	New func(capacity int, defaultTTL time.Duration) Cache[string, V]
+	// NewWithClock is like New but reads the time from clk and drives
+	// background cleanup from its tickers, so tests can control expiry with
+	// a clock.Fake.
+	NewWithClock func(capacity int, defaultTTL time.Duration, clk clock.Clock) Cache[string, V]
+	// NewWithRecorder is like NewWithClock but also reports the cache's
+	// hits, misses, evictions and expirations to rec.
+	NewWithRecorder func(capacity int, defaultTTL time.Duration, clk clock.Clock, rec metrics.Recorder) Cache[string, V]
+}
+
+// implementation builds an Implementation whose New uses the real clock and
+// whose New and NewWithClock record nothing.
+func implementation[V any](name string, newWithRecorder func(int, time.Duration, clock.Clock, metrics.Recorder) Cache[string, V]) Implementation[V] {
+	return Implementation[V]{
+		Name: name,
+		New: func(capacity int, ttl time.Duration) Cache[string, V] {
+			return newWithRecorder(capacity, ttl, clock.Real{}, metrics.Nop{})
+		},
+		NewWithClock: func(capacity int, ttl time.Duration, clk clock.Clock) Cache[string, V] {
+			return newWithRecorder(capacity, ttl, clk, metrics.Nop{})
+		},
+		NewWithRecorder: newWithRecorder,
+	}
+}
+
+// Implementations returns every agent cache, in agent order, for
+// string keys and values of type V.
+func Implementations[V any]() []Implementation[V] {
+	return []Implementation[V]{
+		implementation("agent1", func(capacity int, ttl time.Duration, clk clock.Clock, rec metrics.Recorder) Cache[string, V] {
+			return NewAgent1[V](agent1.New(capacity, agent1.WithClock(clk), agent1.WithRecorder(rec)), ttl)
+		}),
+		implementation("agent2", func(capacity int, ttl time.Duration, clk clock.Clock, rec metrics.Recorder) Cache[string, V] {
+			return NewAgent2(must(agent2.New[string, V](capacity, agent2.WithDefaultTTL(ttl), agent2.WithClock(clk), agent2.WithRecorder(rec))))
+		}),
+		implementation("agent3", func(capacity int, ttl time.Duration, clk clock.Clock, rec metrics.Recorder) Cache[string, V] {
+			return NewAgent3[string, V](agent3.New(capacity, agent3.WithJanitor(time.Minute), agent3.WithClock(clk), agent3.WithRecorder(rec)), ttl)
+		}),
+		implementation("agent4", func(capacity int, ttl time.Duration, clk clock.Clock, rec metrics.Recorder) Cache[string, V] {
+			return NewAgent4(must(agent4.New[string, V](capacity, agent4.WithDefaultTTL(ttl), agent4.WithClockSource(clk), agent4.WithRecorder(rec))))
+		}),
+		implementation("agent5", func(capacity int, ttl time.Duration, clk clock.Clock, rec metrics.Recorder) Cache[string, V] {
+			c := agent5.New(capacity, ttl)
+			c.SetClock(clk)
+			c.SetRecorder(rec)
+			return NewAgent5[string, V](c)
+		}),
+		implementation("agent6", func(capacity int, ttl time.Duration, clk clock.Clock, rec metrics.Recorder) Cache[string, V] {
+			c := agent6.NewWithClock(capacity, ttl, clk)
+			c.SetRecorder(rec)
+			return NewAgent6[string, V](c)
+		}),
+		implementation("agent7", func(capacity int, ttl time.Duration, clk clock.Clock, rec metrics.Recorder) Cache[string, V] {
+			return NewAgent7[V](agent7.NewWithRecorder(capacity, time.Minute, clk, rec), ttl)
+		}),
+		implementation("agent8", func(capacity int, ttl time.Duration, clk clock.Clock, rec metrics.Recorder) Cache[string, V] {
+			return NewAgent8[V](agent8.NewLRUWithRecorder(capacity, ttl, clk, rec))
+		}),
+		implementation("agent9", func(capacity int, ttl time.Duration, clk clock.Clock, rec metrics.Recorder) Cache[string, V] {
+			return NewAgent9(agent9.New[string, V](capacity, agent9.WithJanitorInterval[string, V](time.Minute), agent9.WithClock[string, V](clk), agent9.WithRecorder[string, V](rec)), ttl)
+		}),
+		implementation("agent10", func(capacity int, ttl time.Duration, clk clock.Clock, rec metrics.Recorder) Cache[string, V] {
+			return NewAgent10(agent10.New[string, V](capacity, agent10.WithScheduler(clk), agent10.WithRecorder(rec)), ttl)
+		}),
+		implementation("agent11", func(capacity int, ttl time.Duration, clk clock.Clock, rec metrics.Recorder) Cache[string, V] {
+			return NewAgent11(agent11.New[string, V](capacity, agent11.WithTTL(ttl), agent11.WithClockSource(clk), agent11.WithRecorder(rec)))
+		}),
+		implementation("agent12", func(capacity int, ttl time.Duration, clk clock.Clock, rec metrics.Recorder) Cache[string, V] {
+			c := agent12.NewWithClock(capacity, time.Minute, clk)
+			c.SetRecorder(rec)
+			return NewAgent12[V](c, ttl)
+		}),
+		implementation("agent13", func(capacity int, ttl time.Duration, clk clock.Clock, rec metrics.Recorder) Cache[string, V] {
+			c := agent13.NewWithClock(capacity, time.Minute, clk)
+			c.SetRecorder(rec)
+			return NewAgent13[V](c, ttl)
+		}),
+		implementation("agent14", func(capacity int, ttl time.Duration, clk clock.Clock, rec metrics.Recorder) Cache[string, V] {
+			return NewAgent14[V](agent14.New(agent14.Config{Capacity: capacity, CleanupInterval: time.Minute, DefaultTTL: ttl, Clock: clk, Recorder: rec}))
+		}),
+		implementation("agent15", func(capacity int, ttl time.Duration, clk clock.Clock, rec metrics.Recorder) Cache[string, V] {
+			return NewAgent15[V](must(agent15.NewWithOptions(capacity, agent15.WithCleanupInterval(time.Minute), agent15.WithDefaultTTL(ttl), agent15.WithClock(clk), agent15.WithRecorder(rec))))
+		}),
+	}
+}
+
+// must panics if err is not nil. The constructors it wraps only fail for a
+// non-positive capacity, which New rules out.
+func must[T any](v T, err error) T {
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
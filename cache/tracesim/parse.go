@@ -0,0 +1,144 @@
+package tracesim
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Format identifies a trace file format.
+type Format string
+
+const (
+	// ARC is the format of the ARC paper's traces (*.lis): each line is
+	// "start count ignored request", standing for count consecutive block
+	// requests starting at block start.
+	ARC Format = "arc"
+	// LIRS is the format of the LIRS paper's traces (*.trc): one block
+	// number per line.
+	LIRS Format = "lirs"
+	// CSV has one request per record; the key is the first field and any
+	// other fields are ignored.
+	CSV Format = "csv"
+)
+
+// FormatFromName guesses a trace's format from its file extension.
+func FormatFromName(name string) (Format, error) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".lis", ".arc":
+		return ARC, nil
+	case ".trc", ".lirs":
+		return LIRS, nil
+	case ".csv", ".txt":
+		return CSV, nil
+	}
+	return "", fmt.Errorf("tracesim: cannot tell the format of %q", name)
+}
+
+// Load reads the trace at path, guessing its format from the extension.
+func Load(path string) ([]string, error) {
+	format, err := FormatFromName(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Read(f, format)
+}
+
+// Read returns the keys requested by the trace in r, in order. Blank lines
+// and lines starting with '#' are skipped in every format.
+func Read(r io.Reader, format Format) ([]string, error) {
+	switch format {
+	case ARC:
+		return readLines(r, parseARC)
+	case LIRS:
+		return readLines(r, parseLIRS)
+	case CSV:
+		return readCSV(r)
+	}
+	return nil, fmt.Errorf("tracesim: unknown format %q", format)
+}
+
+func readLines(r io.Reader, parse func(fields []string, keys []string) ([]string, error)) ([]string, error) {
+	var keys []string
+	sc := bufio.NewScanner(r)
+	for line := 1; sc.Scan(); line++ {
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		var err error
+		if keys, err = parse(fields, keys); err != nil {
+			return nil, fmt.Errorf("tracesim: line %d: %w", line, err)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// maxARCRun bounds the block count of a single ARC line so a corrupt trace
+// cannot exhaust memory.
+const maxARCRun = 1 << 20
+
+func parseARC(fields []string, keys []string) ([]string, error) {
+	if len(fields) < 2 {
+		return nil, errors.New("want at least start and count fields")
+	}
+	start, err := strconv.ParseUint(fields[0], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("start: %w", err)
+	}
+	count, err := strconv.ParseUint(fields[1], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("count: %w", err)
+	}
+	if count > maxARCRun {
+		return nil, fmt.Errorf("count %d exceeds %d", count, maxARCRun)
+	}
+	for i := uint64(0); i < count; i++ {
+		keys = append(keys, strconv.FormatUint(start+i, 10))
+	}
+	return keys, nil
+}
+
+func parseLIRS(fields []string, keys []string) ([]string, error) {
+	// some LIRS traces mark the end with a line holding "*"
+	if fields[0] == "*" {
+		return keys, nil
+	}
+	if _, err := strconv.ParseInt(fields[0], 10, 64); err != nil {
+		return nil, fmt.Errorf("block: %w", err)
+	}
+	return append(keys, fields[0]), nil
+}
+
+func readCSV(r io.Reader) ([]string, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.Comment = '#'
+	var keys []string
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			return keys, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("tracesim: %w", err)
+		}
+		if key := strings.TrimSpace(rec[0]); key != "" {
+			keys = append(keys, key)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package tracesim
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestRead(t *testing.T) {
+	for _, tt := range []struct {
+		format Format
+		input  string
+		want   []string
+	}{
+		{ARC, "10 3 0 1\n# comment\n\n5 1 0 2\n", []string{"10", "11", "12", "5"}},
+		{LIRS, "7\n8\n\n7\n*\n", []string{"7", "8", "7"}},
+		{CSV, "user:1,2024-01-01\nuser:2\n# skipped\n\"a,b\",x\n", []string{"user:1", "user:2", "a,b"}},
+	} {
+		got, err := Read(strings.NewReader(tt.input), tt.format)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.format, err)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	for _, tt := range []struct {
+		format Format
+		input  string
+	}{
+		{ARC, "10\n"},
+		{ARC, "x 1 0 0\n"},
+		{ARC, "1 99999999 0 0\n"},
+		{LIRS, "block\n"},
+		{CSV, "\"unterminated\n"},
+		{"xyz", ""},
+	} {
+		if _, err := Read(strings.NewReader(tt.input), tt.format); err == nil {
+			t.Errorf("%s %q: expected error", tt.format, tt.input)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "p1.lis")
+	if err := os.WriteFile(path, []byte("1 2 0 0\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	keys, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(keys, []string{"1", "2"}) {
+		t.Fatalf("got %q", keys)
+	}
+
+	if _, err := Load(filepath.Join(t.TempDir(), "trace.bin")); err == nil {
+		t.Fatal("expected an unknown extension to fail")
+	}
+}
@@ -0,0 +1,146 @@
+// Package tracesim replays recorded access traces against cache
+// implementations to measure how well they choose what to keep.
+//
+// Where sim generates synthetic workloads, tracesim takes real key streams:
+// the ARC and LIRS research trace formats, or a CSV with one key per record.
+// Each request is a read; a miss is followed by a write, as a read-through
+// cache would do. Replay reports hit ratio, how many entries were evicted to
+// make room, and how often an implementation served an entry past the TTL it
+// was written with.
+package tracesim
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rselbach/agent-comparison/cache"
+	"github.com/rselbach/agent-comparison/clock"
+	"github.com/rselbach/agent-comparison/metrics"
+)
+
+// Config controls a replay.
+type Config struct {
+	// Capacities to replay the trace at, one run per implementation each.
+	Capacities []int `json:"capacities"`
+	// TTL is the default TTL each cache is created with and so applies to
+	// every write. Zero means entries do not expire.
+	TTL time.Duration `json:"ttl,omitempty"`
+	// Interval is how far the virtual clock each cache runs on advances
+	// between requests, so that TTLs come into play. Zero keeps time still.
+	Interval time.Duration `json:"interval,omitempty"`
+}
+
+// Result is the outcome of replaying a trace against one implementation at
+// one capacity.
+type Result struct {
+	Impl     string  `json:"impl"`
+	Capacity int     `json:"capacity"`
+	Requests int     `json:"requests"`
+	Hits     int     `json:"hits"`
+	HitRatio float64 `json:"hit_ratio"`
+	// Evictions counts entries the implementation evicted to make room, as
+	// reported to its metrics.Recorder; entries reclaimed after expiring are
+	// not included. It is -1 if the implementation cannot report evictions.
+	Evictions int `json:"evictions"`
+	// ExpiredServes counts hits on entries older than the TTL they were
+	// written with.
+	ExpiredServes int `json:"expired_serves"`
+}
+
+// Replay runs keys against every implementation at every capacity. Values
+// stored are the entries' deadlines, which is how expired serves are spotted.
+func Replay(keys []string, cfg Config, impls []cache.Implementation[int64]) ([]Result, error) {
+	if len(cfg.Capacities) == 0 {
+		return nil, errors.New("tracesim: no capacities")
+	}
+	for _, c := range cfg.Capacities {
+		if c <= 0 {
+			return nil, fmt.Errorf("tracesim: capacity %d must be positive", c)
+		}
+	}
+	if cfg.TTL < 0 || cfg.Interval < 0 {
+		return nil, errors.New("tracesim: TTL and interval must not be negative")
+	}
+
+	var results []Result
+	for _, impl := range impls {
+		for _, capacity := range cfg.Capacities {
+			results = append(results, replayOne(keys, cfg, impl, capacity))
+		}
+	}
+	return results, nil
+}
+
+// replayOne replays keys against one cache on a fake clock advanced by
+// cfg.Interval per request. The cache's background sweeps are disabled with
+// clock.Synchronous so results do not depend on goroutine scheduling.
+func replayOne(keys []string, cfg Config, impl cache.Implementation[int64], capacity int) Result {
+	fake := clock.NewFake(time.Unix(0, 0))
+	clk := clock.Synchronous(fake)
+	var counters *metrics.Counters
+	var c cache.Cache[string, int64]
+	if impl.NewWithRecorder != nil {
+		counters = &metrics.Counters{}
+		c = impl.NewWithRecorder(capacity, cfg.TTL, clk, counters)
+	} else {
+		c = impl.NewWithClock(capacity, cfg.TTL, clk)
+	}
+	defer c.Close()
+
+	res := Result{Impl: impl.Name, Capacity: capacity, Requests: len(keys)}
+	for i, key := range keys {
+		if i > 0 && cfg.Interval > 0 {
+			fake.Advance(cfg.Interval)
+		}
+
+		if deadline, ok := c.Get(key); ok {
+			res.Hits++
+			if deadline != 0 && fake.Now().UnixNano() > deadline {
+				res.ExpiredServes++
+			}
+			continue
+		}
+
+		var deadline int64
+		if cfg.TTL > 0 {
+			deadline = fake.Now().Add(cfg.TTL).UnixNano()
+		}
+		c.Set(key, deadline)
+	}
+	if counters != nil {
+		res.Evictions = int(counters.Snapshot().Evictions)
+	} else {
+		res.Evictions = -1
+	}
+	if res.Requests > 0 {
+		res.HitRatio = float64(res.Hits) / float64(res.Requests)
+	}
+	return res
+}
+
+// WriteJSON writes results as indented JSON.
+func WriteJSON(w io.Writer, results []Result) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(results)
+}
+
+// WriteTable writes results as an aligned text table.
+func WriteTable(w io.Writer, results []Result) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "impl\tcapacity\trequests\thit ratio\tevictions\texpired serves\t")
+	for _, r := range results {
+		evictions := "-"
+		if r.Evictions >= 0 {
+			evictions = strconv.Itoa(r.Evictions)
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.4f\t%s\t%d\t\n",
+			r.Impl, r.Capacity, r.Requests, r.HitRatio, evictions, r.ExpiredServes)
+	}
+	return tw.Flush()
+}
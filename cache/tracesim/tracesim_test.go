@@ -0,0 +1,138 @@
+package tracesim
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/rselbach/agent-comparison/cache"
+	"github.com/rselbach/agent-comparison/clock"
+)
+
+// loop requests n distinct keys in order, rounds times.
+func loop(n, rounds int) []string {
+	var keys []string
+	for r := 0; r < rounds; r++ {
+		for i := 0; i < n; i++ {
+			keys = append(keys, strconv.Itoa(i))
+		}
+	}
+	return keys
+}
+
+func TestReplay(t *testing.T) {
+	impls := cache.Implementations[int64]()
+	results, err := Replay(loop(10, 5), Config{Capacities: []int{5, 10}}, impls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2*len(impls) {
+		t.Fatalf("got %d results, want %d", len(results), 2*len(impls))
+	}
+	for i := 0; i < len(results); i += 2 {
+		small, big := results[i], results[i+1]
+		// a loop larger than the cache always misses under LRU; one that fits
+		// misses only on the first round
+		if small.Hits != 0 || small.Evictions != 45 {
+			t.Errorf("%s at capacity 5: %+v", small.Impl, small)
+		}
+		if big.Hits != 40 || big.Evictions != 0 || big.HitRatio != 0.8 {
+			t.Errorf("%s at capacity 10: %+v", big.Impl, big)
+		}
+	}
+}
+
+// staleCache ignores TTLs entirely.
+type staleCache struct{ m map[string]int64 }
+
+func (c *staleCache) Set(key string, v int64)                         { c.m[key] = v }
+func (c *staleCache) SetWithTTL(key string, v int64, _ time.Duration) { c.m[key] = v }
+func (c *staleCache) Get(key string) (int64, bool)                    { v, ok := c.m[key]; return v, ok }
+func (c *staleCache) Peek(key string) (int64, bool)                   { return c.Get(key) }
+func (c *staleCache) Delete(key string) bool                          { _, ok := c.m[key]; delete(c.m, key); return ok }
+func (c *staleCache) Len() int                                        { return len(c.m) }
+func (c *staleCache) Close()                                          {}
+
+func TestReplayExpiredServes(t *testing.T) {
+	stale := cache.Implementation[int64]{Name: "stale", NewWithClock: func(int, time.Duration, clock.Clock) cache.Cache[string, int64] {
+		return &staleCache{m: make(map[string]int64)}
+	}}
+	impls := []cache.Implementation[int64]{cache.Implementations[int64]()[1], stale}
+
+	cfg := Config{Capacities: []int{4}, TTL: 20 * time.Second, Interval: 15 * time.Second}
+	results, err := Replay([]string{"a", "b", "a", "a"}, cfg, impls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a is written at 0s and expired by the 30s request
+	if r := results[0]; r.ExpiredServes != 0 || r.Hits != 1 || r.Evictions != 0 {
+		t.Errorf("agent2: %+v", r)
+	}
+	// stale has no recorder, so its evictions are unknown
+	if r := results[1]; r.ExpiredServes != 2 || r.Hits != 2 || r.Evictions != -1 {
+		t.Errorf("stale: %+v", r)
+	}
+}
+
+func TestReplayRepeats(t *testing.T) {
+	var keys []string
+	for i := 0; i < 2000; i++ {
+		keys = append(keys, strconv.Itoa(i*i%97))
+	}
+	// Virtual time spans many janitor intervals without sleeping.
+	cfg := Config{Capacities: []int{16, 64}, TTL: 40 * time.Second, Interval: time.Second}
+	first, err := Replay(keys, cfg, cache.Implementations[int64]())
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := Replay(keys, cfg, cache.Implementations[int64]())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range first {
+		if first[i] != second[i] {
+			t.Errorf("replays differ: %+v and %+v", first[i], second[i])
+		}
+	}
+}
+
+func TestReplayInvalidConfig(t *testing.T) {
+	for _, cfg := range []Config{
+		{},
+		{Capacities: []int{0}},
+		{Capacities: []int{1}, TTL: -1},
+	} {
+		if _, err := Replay(nil, cfg, nil); err == nil {
+			t.Errorf("%+v: expected error", cfg)
+		}
+	}
+}
+
+func TestReports(t *testing.T) {
+	results, err := Replay(loop(3, 2), Config{Capacities: []int{3}}, cache.Implementations[int64]()[:2])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteJSON(&buf, results); err != nil {
+		t.Fatal(err)
+	}
+	var decoded []Result
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != 2 || decoded[0] != results[0] {
+		t.Fatalf("round trip lost data: %+v", decoded)
+	}
+
+	buf.Reset()
+	if err := WriteTable(&buf, results); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("agent2")) {
+		t.Fatalf("table missing rows: %q", buf.String())
+	}
+}
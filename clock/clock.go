@@ -0,0 +1,78 @@
+// Package clock is the time source shared by the cache implementations.
+//
+// Caches take a Clock instead of calling the time package directly, so tests
+// can hand them a Fake and move time forward explicitly: expiry is checked
+// against Fake.Now and janitors wake when Fake.Advance passes their ticker,
+// without sleeping.
+package clock
+
+import "time"
+
+// Clock supplies the current time and the timers built on it.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+	// NewTimer returns a timer that fires once after d.
+	NewTimer(d time.Duration) Timer
+	// NewTicker returns a ticker that fires every d. It panics if d is not
+	// positive.
+	NewTicker(d time.Duration) Ticker
+	// AfterFunc calls f after d and returns a timer that can cancel the call.
+	// The returned timer's channel is nil.
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Timer is the interface of *time.Timer.
+type Timer interface {
+	// C returns the channel on which the time is delivered.
+	C() <-chan time.Time
+	// Stop prevents the timer from firing and reports whether it was active.
+	Stop() bool
+	// Reset makes the timer fire after d and reports whether it was active.
+	Reset(d time.Duration) bool
+}
+
+// Ticker is the interface of *time.Ticker.
+type Ticker interface {
+	// C returns the channel on which ticks are delivered.
+	C() <-chan time.Time
+	// Stop turns off the ticker.
+	Stop()
+	// Reset stops the ticker and restarts it with period d.
+	Reset(d time.Duration)
+}
+
+// Real is the Clock backed by the time package.
+type Real struct{}
+
+func (Real) Now() time.Time { return time.Now() }
+
+func (Real) NewTimer(d time.Duration) Timer { return realTimer{time.NewTimer(d)} }
+
+func (Real) NewTicker(d time.Duration) Ticker { return realTicker{time.NewTicker(d)} }
+
+func (Real) AfterFunc(d time.Duration, f func()) Timer { return realTimer{time.AfterFunc(d, f)} }
+
+type realTimer struct{ t *time.Timer }
+
+func (r realTimer) C() <-chan time.Time { return r.t.C }
+
+func (r realTimer) Stop() bool { return r.t.Stop() }
+
+func (r realTimer) Reset(d time.Duration) bool { return r.t.Reset(d) }
+
+type realTicker struct{ t *time.Ticker }
+
+func (r realTicker) C() <-chan time.Time { return r.t.C }
+
+func (r realTicker) Stop() { r.t.Stop() }
+
+func (r realTicker) Reset(d time.Duration) { r.t.Reset(d) }
+
+// OrReal returns c, or Real if c is nil.
+func OrReal(c Clock) Clock {
+	if c == nil {
+		return Real{}
+	}
+	return c
+}
@@ -0,0 +1,229 @@
+package clock
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Fake is a Clock that only moves when told to. It is safe for concurrent
+// use.
+//
+// Timer and ticker channels have a buffer of one and, like the time package's,
+// drop ticks nobody has received. Functions passed to AfterFunc run on the
+// goroutine calling Advance, before it returns.
+type Fake struct {
+	mu      sync.Mutex
+	cond    sync.Cond
+	now     time.Time
+	waiters []*waiter
+}
+
+// waiter is a pending timer, ticker or AfterFunc.
+type waiter struct {
+	clock  *Fake
+	at     time.Time
+	period time.Duration // non-zero for tickers
+	ch     chan time.Time
+	fn     func()
+}
+
+// NewFake returns a Fake reading now.
+func NewFake(now time.Time) *Fake {
+	f := &Fake{now: now}
+	f.cond.L = &f.mu
+	return f
+}
+
+// Now returns the fake's current time.
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// NewTimer returns a timer that fires once the fake has advanced by d.
+func (f *Fake) NewTimer(d time.Duration) Timer {
+	return fakeTimer{f.add(&waiter{at: f.Now().Add(d), ch: make(chan time.Time, 1)})}
+}
+
+// NewTicker returns a ticker that fires each time the fake advances past
+// another multiple of d.
+func (f *Fake) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("clock: non-positive interval for NewTicker")
+	}
+	return fakeTicker{f.add(&waiter{at: f.Now().Add(d), period: d, ch: make(chan time.Time, 1)})}
+}
+
+// AfterFunc arranges for f to be called by the Advance that moves the fake
+// past d from now.
+func (f *Fake) AfterFunc(d time.Duration, fn func()) Timer {
+	return fakeTimer{f.add(&waiter{at: f.Now().Add(d), fn: fn})}
+}
+
+// Advance moves the fake forward by d, firing in order every timer, ticker
+// and AfterFunc that falls due on the way.
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	end := f.now.Add(d)
+	for {
+		w := f.next(end)
+		if w == nil {
+			break
+		}
+		f.now = w.at
+		if w.period > 0 {
+			w.at = w.at.Add(w.period)
+		} else {
+			f.remove(w)
+		}
+		if w.fn != nil {
+			f.mu.Unlock()
+			w.fn()
+			f.mu.Lock()
+			continue
+		}
+		select {
+		case w.ch <- f.now:
+		default:
+		}
+	}
+	if end.After(f.now) {
+		f.now = end
+	}
+	f.mu.Unlock()
+}
+
+// BlockUntil waits until at least n timers, tickers and AfterFuncs are
+// pending. Tests use it to be sure a goroutine has created its ticker before
+// they advance the clock.
+func (f *Fake) BlockUntil(n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for len(f.waiters) < n {
+		f.cond.Wait()
+	}
+}
+
+// Pending reports how many timers, tickers and AfterFuncs are waiting to
+// fire. Fired timers and stopped ones do not count.
+func (f *Fake) Pending() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.waiters)
+}
+
+func (f *Fake) add(w *waiter) *waiter {
+	w.clock = f
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.waiters = append(f.waiters, w)
+	f.cond.Broadcast()
+	return w
+}
+
+// next returns the earliest waiter due by end, or nil.
+func (f *Fake) next(end time.Time) *waiter {
+	var first *waiter
+	for _, w := range f.waiters {
+		if !w.at.After(end) && (first == nil || w.at.Before(first.at)) {
+			first = w
+		}
+	}
+	return first
+}
+
+// remove drops w and reports whether it was pending.
+func (f *Fake) remove(w *waiter) bool {
+	for i, p := range f.waiters {
+		if p == w {
+			f.waiters = append(f.waiters[:i], f.waiters[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (w *waiter) stop() bool {
+	w.clock.mu.Lock()
+	defer w.clock.mu.Unlock()
+	return w.clock.remove(w)
+}
+
+func (w *waiter) reset(d time.Duration) bool {
+	f := w.clock
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	active := f.remove(w)
+	w.at = f.now.Add(d)
+	if w.period > 0 {
+		w.period = d
+	}
+	f.waiters = append(f.waiters, w)
+	f.cond.Broadcast()
+	return active
+}
+
+type fakeTimer struct{ w *waiter }
+
+func (t fakeTimer) C() <-chan time.Time { return t.w.ch }
+
+func (t fakeTimer) Stop() bool { return t.w.stop() }
+
+func (t fakeTimer) Reset(d time.Duration) bool { return t.w.reset(d) }
+
+type fakeTicker struct{ w *waiter }
+
+func (t fakeTicker) C() <-chan time.Time { return t.w.ch }
+
+func (t fakeTicker) Stop() { t.w.stop() }
+
+func (t fakeTicker) Reset(d time.Duration) {
+	if d <= 0 {
+		panic("clock: non-positive interval for Ticker.Reset")
+	}
+	t.w.reset(d)
+}
+
+// Synchronous returns a Clock reading f whose tickers and channel timers
+// never fire. AfterFunc callbacks still run inside f.Advance. A cache built on
+// it therefore does no work in background goroutines: expiry happens in its
+// own calls or on the goroutine advancing f, so a run driven by a single
+// goroutine repeats exactly.
+func Synchronous(f *Fake) Clock {
+	return synchronous{f}
+}
+
+type synchronous struct{ *Fake }
+
+func (synchronous) NewTimer(time.Duration) Timer { return &idleTimer{} }
+
+func (synchronous) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("clock: non-positive interval for NewTicker")
+	}
+	return idleTicker{}
+}
+
+// idleTimer is a timer whose channel never delivers.
+type idleTimer struct{ stopped atomic.Bool }
+
+func (*idleTimer) C() <-chan time.Time { return nil }
+
+func (t *idleTimer) Stop() bool { return !t.stopped.Swap(true) }
+
+func (t *idleTimer) Reset(time.Duration) bool { return !t.stopped.Swap(false) }
+
+// idleTicker is a ticker whose channel never delivers.
+type idleTicker struct{}
+
+func (idleTicker) C() <-chan time.Time { return nil }
+
+func (idleTicker) Stop() {}
+
+func (idleTicker) Reset(d time.Duration) {
+	if d <= 0 {
+		panic("clock: non-positive interval for Ticker.Reset")
+	}
+}
@@ -0,0 +1,208 @@
+package clock
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+var epoch = time.Unix(1000, 0)
+
+func TestFakeNowAndAdvance(t *testing.T) {
+	f := NewFake(epoch)
+	if got := f.Now(); !got.Equal(epoch) {
+		t.Fatalf("Now = %v, want %v", got, epoch)
+	}
+	f.Advance(time.Minute)
+	if got := f.Now(); !got.Equal(epoch.Add(time.Minute)) {
+		t.Fatalf("Now = %v after advancing a minute", got)
+	}
+}
+
+func TestFakeTimer(t *testing.T) {
+	f := NewFake(epoch)
+	timer := f.NewTimer(time.Second)
+
+	f.Advance(999 * time.Millisecond)
+	select {
+	case <-timer.C():
+		t.Fatal("timer fired early")
+	default:
+	}
+
+	f.Advance(time.Millisecond)
+	select {
+	case at := <-timer.C():
+		if !at.Equal(epoch.Add(time.Second)) {
+			t.Fatalf("fired at %v", at)
+		}
+	default:
+		t.Fatal("timer did not fire")
+	}
+	if timer.Stop() {
+		t.Fatal("Stop reported a fired timer as active")
+	}
+
+	if timer.Reset(time.Second) {
+		t.Fatal("Reset reported a fired timer as active")
+	}
+	if !timer.Stop() {
+		t.Fatal("Stop reported a reset timer as inactive")
+	}
+	f.Advance(time.Hour)
+	select {
+	case <-timer.C():
+		t.Fatal("stopped timer fired")
+	default:
+	}
+}
+
+func TestFakeTicker(t *testing.T) {
+	f := NewFake(epoch)
+	ticker := f.NewTicker(10 * time.Second)
+
+	f.Advance(25 * time.Second)
+	// the second tick is dropped because the first was never received
+	if at := <-ticker.C(); !at.Equal(epoch.Add(10 * time.Second)) {
+		t.Fatalf("first tick at %v", at)
+	}
+	select {
+	case <-ticker.C():
+		t.Fatal("unreceived tick was buffered")
+	default:
+	}
+
+	f.Advance(5 * time.Second)
+	if at := <-ticker.C(); !at.Equal(epoch.Add(30 * time.Second)) {
+		t.Fatalf("third tick at %v", at)
+	}
+
+	ticker.Reset(time.Minute)
+	f.Advance(59 * time.Second)
+	select {
+	case <-ticker.C():
+		t.Fatal("reset ticker fired early")
+	default:
+	}
+	f.Advance(time.Second)
+	<-ticker.C()
+
+	ticker.Stop()
+	f.Advance(time.Hour)
+	select {
+	case <-ticker.C():
+		t.Fatal("stopped ticker fired")
+	default:
+	}
+}
+
+func TestFakeAfterFunc(t *testing.T) {
+	f := NewFake(epoch)
+	var order []string
+	f.AfterFunc(2*time.Second, func() { order = append(order, "b") })
+	f.AfterFunc(time.Second, func() {
+		order = append(order, "a")
+		// the callback may use the clock, and sees the time it was due
+		if !f.Now().Equal(epoch.Add(time.Second)) {
+			t.Errorf("callback saw %v", f.Now())
+		}
+	})
+	cancelled := f.AfterFunc(time.Second, func() { order = append(order, "cancelled") })
+	if !cancelled.Stop() {
+		t.Fatal("Stop reported a pending AfterFunc as inactive")
+	}
+	if cancelled.C() != nil {
+		t.Fatal("AfterFunc timer has a channel")
+	}
+
+	if n := f.Pending(); n != 2 {
+		t.Fatalf("Pending = %d, want 2", n)
+	}
+
+	f.Advance(5 * time.Second)
+	if n := f.Pending(); n != 0 {
+		t.Fatalf("Pending = %d after firing everything", n)
+	}
+	if len(order) != 2 || order[0] != "a" || order[1] != "b" {
+		t.Fatalf("callbacks ran as %v", order)
+	}
+}
+
+func TestFakeBlockUntil(t *testing.T) {
+	f := NewFake(epoch)
+	ticks := make(chan time.Time)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := f.NewTicker(time.Second)
+		defer ticker.Stop()
+		ticks <- <-ticker.C()
+	}()
+
+	f.BlockUntil(1)
+	f.Advance(time.Second)
+	if at := <-ticks; !at.Equal(epoch.Add(time.Second)) {
+		t.Fatalf("tick at %v", at)
+	}
+	wg.Wait()
+}
+
+func TestNonPositiveTickerPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected a panic")
+		}
+	}()
+	NewFake(epoch).NewTicker(0)
+}
+
+func TestOrReal(t *testing.T) {
+	if _, ok := OrReal(nil).(Real); !ok {
+		t.Fatal("OrReal(nil) is not Real")
+	}
+	f := NewFake(epoch)
+	if OrReal(f) != Clock(f) {
+		t.Fatal("OrReal replaced a non-nil clock")
+	}
+}
+
+func TestReal(t *testing.T) {
+	var c Clock = Real{}
+	timer := c.NewTimer(time.Millisecond)
+	<-timer.C()
+	ticker := c.NewTicker(time.Millisecond)
+	<-ticker.C()
+	ticker.Stop()
+	done := make(chan struct{})
+	c.AfterFunc(time.Millisecond, func() { close(done) })
+	<-done
+}
+
+func TestSynchronous(t *testing.T) {
+	f := NewFake(epoch)
+	clk := Synchronous(f)
+	timer := clk.NewTimer(time.Second)
+	ticker := clk.NewTicker(time.Second)
+	fired := false
+	clk.AfterFunc(time.Second, func() { fired = true })
+
+	f.Advance(time.Minute)
+	if !fired {
+		t.Fatal("expected AfterFunc to run inside Advance")
+	}
+	if got := clk.Now(); !got.Equal(epoch.Add(time.Minute)) {
+		t.Fatalf("Now = %v, want the fake's time", got)
+	}
+	select {
+	case <-timer.C():
+		t.Fatal("timer fired")
+	case <-ticker.C():
+		t.Fatal("ticker fired")
+	default:
+	}
+	if !timer.Stop() || timer.Stop() {
+		t.Fatal("expected only the first Stop to report an active timer")
+	}
+	ticker.Stop()
+}
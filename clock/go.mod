@@ -0,0 +1,3 @@
+module github.com/rselbach/agent-comparison/clock
+
+go 1.21
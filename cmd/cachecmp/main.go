@@ -0,0 +1,261 @@
+// Command cachecmp runs the comparison suites against the agent
+// caches and writes one machine-readable report.
+//
+// Usage:
+//
+//	cachecmp [-impls agent1,agent2] [-suites conformance,bench,memory] [-format json|csv] [-o report.json] [-quick]
+//
+// conformance runs every check in cache/conformance and records whether it
+// passed; bench runs the default cache/bench workloads; memory runs the
+// default cache/memprof shapes. -quick shrinks the workloads and shapes so a
+// full report takes seconds, for smoke tests. The JSON report nests each
+// suite's results; the CSV report has one row per measurement with the
+// columns suite, impl, subject, metric and value.
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rselbach/agent-comparison/cache"
+	"github.com/rselbach/agent-comparison/cache/bench"
+	"github.com/rselbach/agent-comparison/cache/conformance"
+	"github.com/rselbach/agent-comparison/cache/memprof"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "cachecmp:", err)
+		os.Exit(1)
+	}
+}
+
+var suites = []string{"conformance", "bench", "memory"}
+
+// Report is everything one run measured.
+type Report struct {
+	Env         Env                 `json:"env"`
+	Impls       []string            `json:"impls"`
+	Quick       bool                `json:"quick"`
+	Conformance []ConformanceResult `json:"conformance,omitempty"`
+	Bench       []bench.Result      `json:"bench,omitempty"`
+	Memory      []memprof.Result    `json:"memory,omitempty"`
+}
+
+// Env records where the report was produced, since timings and memory
+// figures depend on it.
+type Env struct {
+	GoVersion string `json:"go_version"`
+	GOOS      string `json:"goos"`
+	GOARCH    string `json:"goarch"`
+	CPUs      int    `json:"cpus"`
+}
+
+// ConformanceResult is the outcome of one conformance check against one
+// implementation. Error is empty if the check passed.
+type ConformanceResult struct {
+	Impl  string `json:"impl"`
+	Check string `json:"check"`
+	Pass  bool   `json:"pass"`
+	Error string `json:"error,omitempty"`
+}
+
+func run(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("cachecmp", flag.ContinueOnError)
+	implList := fs.String("impls", "", "comma-separated implementations to compare; empty means all")
+	suiteList := fs.String("suites", strings.Join(suites, ","), "comma-separated suites to run")
+	format := fs.String("format", "json", "report format: json or csv")
+	output := fs.String("o", "", "write the report to this file instead of stdout")
+	quick := fs.Bool("quick", false, "use small workloads and shapes")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	if *format != "json" && *format != "csv" {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+
+	impls, err := selectImpls(*implList)
+	if err != nil {
+		return err
+	}
+	selected := make(map[string]bool)
+	for _, s := range split(*suiteList) {
+		if !slices.Contains(suites, s) {
+			return fmt.Errorf("unknown suite %q (try: %s)", s, strings.Join(suites, ", "))
+		}
+		selected[s] = true
+	}
+
+	rep := Report{
+		Env:   Env{GoVersion: runtime.Version(), GOOS: runtime.GOOS, GOARCH: runtime.GOARCH, CPUs: runtime.NumCPU()},
+		Impls: impls,
+		Quick: *quick,
+	}
+	if selected["conformance"] {
+		rep.Conformance = runConformance(impls)
+	}
+	if selected["bench"] {
+		if rep.Bench, err = runBench(impls, *quick); err != nil {
+			return err
+		}
+	}
+	if selected["memory"] {
+		if rep.Memory, err = runMemory(impls, *quick); err != nil {
+			return err
+		}
+	}
+
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		if err := write(f, rep, *format); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	}
+	return write(out, rep, *format)
+}
+
+// split splits a comma-separated flag value, dropping empty items.
+func split(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// selectImpls resolves the -impls flag to implementation names in agent
+// order.
+func selectImpls(list string) ([]string, error) {
+	var all []string
+	for _, impl := range cache.Implementations[string]() {
+		all = append(all, impl.Name)
+	}
+	want := split(list)
+	if len(want) == 0 {
+		return all, nil
+	}
+	for _, name := range want {
+		if !slices.Contains(all, name) {
+			return nil, fmt.Errorf("unknown implementation %q (try: %s)", name, strings.Join(all, ", "))
+		}
+	}
+	var names []string
+	for _, name := range all {
+		if slices.Contains(want, name) {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// filter returns the implementations in all named in names.
+func filter[V any](all []cache.Implementation[V], names []string) []cache.Implementation[V] {
+	var impls []cache.Implementation[V]
+	for _, impl := range all {
+		if slices.Contains(names, impl.Name) {
+			impls = append(impls, impl)
+		}
+	}
+	return impls
+}
+
+func runConformance(names []string) []ConformanceResult {
+	var results []ConformanceResult
+	for _, impl := range filter(cache.Implementations[string](), names) {
+		for _, r := range conformance.RunChecks(impl.NewWithClock) {
+			res := ConformanceResult{Impl: impl.Name, Check: r.Check, Pass: r.Err == nil}
+			if r.Err != nil {
+				res.Error = r.Err.Error()
+			}
+			results = append(results, res)
+		}
+	}
+	return results
+}
+
+func runBench(names []string, quick bool) ([]bench.Result, error) {
+	workloads := bench.DefaultWorkloads()
+	if quick {
+		for i := range workloads {
+			w := &workloads[i]
+			w.Capacity, w.Keys, w.Warmup, w.Ops = 50, 500, 200, 2000
+			if w.Dist == bench.Scan {
+				w.Keys = 2 * w.Capacity
+			}
+		}
+	}
+	return bench.Run(workloads, filter(cache.Implementations[[]byte](), names))
+}
+
+func runMemory(names []string, quick bool) ([]memprof.Result, error) {
+	shapes := memprof.DefaultShapes()
+	if quick {
+		for i := range shapes {
+			shapes[i].Entries = 1000
+		}
+	}
+	return memprof.Run(shapes, names...)
+}
+
+func write(w io.Writer, rep Report, format string) error {
+	if format == "csv" {
+		return writeCSV(w, rep)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(rep)
+}
+
+// writeCSV writes rep as one row per measurement.
+func writeCSV(w io.Writer, rep Report) error {
+	cw := csv.NewWriter(w)
+	row := func(suite, impl, subject, metric, value string) {
+		cw.Write([]string{suite, impl, subject, metric, value})
+	}
+	f := func(v float64) string { return strconv.FormatFloat(v, 'g', -1, 64) }
+	d := func(v time.Duration) string { return strconv.FormatInt(int64(v), 10) }
+
+	row("suite", "impl", "subject", "metric", "value")
+	for _, r := range rep.Conformance {
+		row("conformance", r.Impl, r.Check, "pass", strconv.FormatBool(r.Pass))
+		if r.Error != "" {
+			row("conformance", r.Impl, r.Check, "error", r.Error)
+		}
+	}
+	for _, r := range rep.Bench {
+		row("bench", r.Impl, r.Workload, "ops_per_sec", f(r.Throughput))
+		row("bench", r.Impl, r.Workload, "p50_ns", d(r.P50))
+		row("bench", r.Impl, r.Workload, "p99_ns", d(r.P99))
+		row("bench", r.Impl, r.Workload, "max_ns", d(r.Max))
+		row("bench", r.Impl, r.Workload, "hit_ratio", f(r.HitRatio))
+	}
+	for _, r := range rep.Memory {
+		row("memory", r.Impl, r.Shape, "bytes_per_entry", f(r.BytesPerEntry))
+		row("memory", r.Impl, r.Shape, "objects_per_entry", f(r.ObjectsPerEntry))
+		row("memory", r.Impl, r.Shape, "get_allocs", f(r.GetAllocs))
+		row("memory", r.Impl, r.Shape, "set_allocs", f(r.SetAllocs))
+		row("memory", r.Impl, r.Shape, "gc_time_ns", d(r.GCTime))
+		row("memory", r.Impl, r.Shape, "gc_pause_ns", d(r.GCPause))
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestJSONReport(t *testing.T) {
+	var out bytes.Buffer
+	if err := run([]string{"-quick", "-impls", "agent4,agent2"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	var rep Report
+	if err := json.Unmarshal(out.Bytes(), &rep); err != nil {
+		t.Fatal(err)
+	}
+	if len(rep.Impls) != 2 || rep.Impls[0] != "agent2" || rep.Impls[1] != "agent4" {
+		t.Fatalf("impls = %v, want agent2 and agent4 in agent order", rep.Impls)
+	}
+	if !rep.Quick || rep.Env.GoVersion == "" {
+		t.Fatalf("missing run metadata: %+v", rep)
+	}
+	if len(rep.Conformance) == 0 || len(rep.Bench) == 0 || len(rep.Memory) == 0 {
+		t.Fatalf("expected every suite to report, got %d conformance, %d bench, %d memory results",
+			len(rep.Conformance), len(rep.Bench), len(rep.Memory))
+	}
+	for _, r := range rep.Conformance {
+		if r.Pass != (r.Error == "") {
+			t.Errorf("inconsistent conformance result %+v", r)
+		}
+		// agent2 has no Peek of its own, which the suite is expected to flag
+		if r.Impl == "agent2" && r.Check == "peek-does-not-promote" && r.Pass {
+			t.Errorf("expected agent2 to fail %s", r.Check)
+		}
+	}
+	for _, r := range rep.Bench {
+		if r.Impl != "agent2" && r.Impl != "agent4" {
+			t.Errorf("unselected implementation in bench results: %s", r.Impl)
+		}
+	}
+}
+
+func TestCSVReport(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.csv")
+	if err := run([]string{"-quick", "-impls", "agent13", "-suites", "conformance,memory", "-format", "csv", "-o", path}, nil); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) < 2 || rows[0][0] != "suite" {
+		t.Fatalf("expected a header and rows, got %v", rows)
+	}
+	suites := map[string]int{}
+	for _, row := range rows[1:] {
+		if row[1] != "agent13" {
+			t.Errorf("unexpected impl in row %v", row)
+		}
+		suites[row[0]]++
+	}
+	if suites["conformance"] == 0 || suites["memory"] == 0 || suites["bench"] != 0 {
+		t.Fatalf("rows per suite = %v, want conformance and memory only", suites)
+	}
+}
+
+func TestBadFlags(t *testing.T) {
+	for name, args := range map[string][]string{
+		"impl":     {"-impls", "agent99"},
+		"suite":    {"-suites", "speed"},
+		"format":   {"-format", "xml"},
+		"argument": {"extra"},
+	} {
+		if err := run(args, &bytes.Buffer{}); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
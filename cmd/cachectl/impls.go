@@ -0,0 +1,41 @@
+package main
+
+import (
+	"time"
+
+	"github.com/rselbach/agent-comparison/cache"
+)
+
+// store is the view of a cache that cachectl drives. Keys and values are
+// strings; the implementations come from the shared cache registry.
+type store = cache.Cache[string, string]
+
+// implNames returns the registered implementation names in agent order.
+func implNames() []string {
+	impls := cache.Implementations[string]()
+	names := make([]string, len(impls))
+	for i, impl := range impls {
+		names[i] = impl.Name
+	}
+	return names
+}
+
+// lookup returns the registered implementation called name.
+func lookup(name string) (cache.Implementation[string], bool) {
+	for _, impl := range cache.Implementations[string]() {
+		if impl.Name == name {
+			return impl, true
+		}
+	}
+	return cache.Implementation[string]{}, false
+}
+
+// set stores value under key, expiring it after ttl, or after the store's
+// default TTL if ttl is zero.
+func set(s store, key, value string, ttl time.Duration) {
+	if ttl == 0 {
+		s.Set(key, value)
+		return
+	}
+	s.SetWithTTL(key, value, ttl)
+}
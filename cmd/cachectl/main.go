@@ -0,0 +1,200 @@
+// Command cachectl serves any agent cache over HTTP and talks to it.
+//
+// Usage:
+//
+//	cachectl list
+//	cachectl serve [-impl agent1] [-addr :7070] [-capacity 1024] [-ttl 0]
+//	cachectl get   [-addr localhost:7070] key
+//	cachectl set   [-addr localhost:7070] [-ttl 30s] key value
+//	cachectl del   [-addr localhost:7070] key
+//	cachectl stats [-addr localhost:7070]
+//	cachectl bench [-impl agent1] [-capacity 1024] [-keys 4096] [-n 1000000] [-writes 0.1] [-ttl 0]
+//
+// serve runs the chosen implementation behind the HTTP admin API described on
+// server; get, set, del and stats are clients of that API. bench drives an
+// implementation in-process and reports throughput and hit ratio.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultAddr = "localhost:7070"
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "cachectl:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New("usage: cachectl list|serve|get|set|del|stats|bench [flags]")
+	}
+	cmd, args := args[0], args[1:]
+	switch cmd {
+	case "list":
+		for _, name := range implNames() {
+			fmt.Fprintln(out, name)
+		}
+		return nil
+	case "serve":
+		return serve(args, out)
+	case "get", "set", "del", "stats":
+		return client(cmd, args, out)
+	case "bench":
+		return bench(args, out)
+	default:
+		return fmt.Errorf("unknown command %q", cmd)
+	}
+}
+
+// newStore builds the named implementation. A zero ttl means no expiry.
+func newStore(impl string, capacity int, ttl time.Duration) (store, error) {
+	i, ok := lookup(impl)
+	if !ok {
+		return nil, fmt.Errorf("unknown implementation %q (try: %s)", impl, strings.Join(implNames(), ", "))
+	}
+	if capacity <= 0 {
+		return nil, errors.New("capacity must be positive")
+	}
+	return i.New(capacity, ttl), nil
+}
+
+func serve(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	impl := fs.String("impl", "agent1", "implementation to serve")
+	addr := fs.String("addr", ":7070", "listen address")
+	capacity := fs.Int("capacity", 1024, "maximum number of entries")
+	ttl := fs.Duration("ttl", 0, "default TTL; 0 means no expiry")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	s, err := newStore(*impl, *capacity, *ttl)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "serving %s on %s\n", *impl, *addr)
+	return http.ListenAndServe(*addr, newServer(*impl, *capacity, s).handler())
+}
+
+func client(cmd string, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet(cmd, flag.ContinueOnError)
+	addr := fs.String("addr", defaultAddr, "server address")
+	var ttl *time.Duration
+	if cmd == "set" {
+		ttl = fs.Duration("ttl", 0, "TTL for the entry; 0 uses the server default")
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	want := map[string]int{"get": 1, "set": 2, "del": 1, "stats": 0}[cmd]
+	if fs.NArg() != want {
+		return fmt.Errorf("%s takes %d argument(s), got %d", cmd, want, fs.NArg())
+	}
+
+	base := "http://" + *addr
+	var req *http.Request
+	var err error
+	switch cmd {
+	case "get":
+		req, err = http.NewRequest(http.MethodGet, base+"/keys/"+url.PathEscape(fs.Arg(0)), nil)
+	case "set":
+		u := base + "/keys/" + url.PathEscape(fs.Arg(0))
+		if *ttl != 0 {
+			u += "?ttl=" + url.QueryEscape(ttl.String())
+		}
+		req, err = http.NewRequest(http.MethodPut, u, strings.NewReader(fs.Arg(1)))
+	case "del":
+		req, err = http.NewRequest(http.MethodDelete, base+"/keys/"+url.PathEscape(fs.Arg(0)), nil)
+	case "stats":
+		req, err = http.NewRequest(http.MethodGet, base+"/stats", nil)
+	}
+	if err != nil {
+		return err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return errors.New("not found")
+	case resp.StatusCode >= 300:
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	if len(body) > 0 {
+		fmt.Fprintln(out, strings.TrimSuffix(string(body), "\n"))
+	}
+	return nil
+}
+
+func bench(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("bench", flag.ContinueOnError)
+	impl := fs.String("impl", "agent1", "implementation to benchmark")
+	capacity := fs.Int("capacity", 1024, "maximum number of entries")
+	keys := fs.Int("keys", 4096, "number of distinct keys")
+	n := fs.Int("n", 1000000, "number of operations")
+	writes := fs.Float64("writes", 0.1, "fraction of operations that are writes")
+	ttl := fs.Duration("ttl", 0, "default TTL; 0 means no expiry")
+	seed := fs.Int64("seed", 1, "random seed")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *keys <= 0 || *n <= 0 {
+		return errors.New("keys and n must be positive")
+	}
+
+	s, err := newStore(*impl, *capacity, *ttl)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	names := make([]string, *keys)
+	for i := range names {
+		names[i] = "k" + strconv.Itoa(i)
+	}
+
+	r := rand.New(rand.NewSource(*seed))
+	var reads, hits int
+	start := time.Now()
+	for i := 0; i < *n; i++ {
+		key := names[r.Intn(len(names))]
+		if r.Float64() < *writes {
+			s.Set(key, key)
+			continue
+		}
+		reads++
+		if _, ok := s.Get(key); ok {
+			hits++
+		}
+	}
+	elapsed := time.Since(start)
+
+	ratio := 0.0
+	if reads > 0 {
+		ratio = float64(hits) / float64(reads)
+	}
+	fmt.Fprintf(out, "%s: %d ops in %v (%.0f ns/op), hit ratio %.3f, len %d\n",
+		*impl, *n, elapsed.Round(time.Millisecond), float64(elapsed.Nanoseconds())/float64(*n), ratio, s.Len())
+	return nil
+}
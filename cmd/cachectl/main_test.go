@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestImplementations(t *testing.T) {
+	for _, name := range implNames() {
+		t.Run(name, func(t *testing.T) {
+			s, err := newStore(name, 2, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer s.Close()
+			set(s, "a", "1", 0)
+			set(s, "b", "2", time.Hour)
+			if v, ok := s.Get("a"); !ok || v != "1" {
+				t.Fatalf("Get(a) = %q, %v", v, ok)
+			}
+			set(s, "c", "3", 0)
+			if _, ok := s.Get("b"); ok {
+				t.Fatal("expected b to be evicted")
+			}
+			if n := s.Len(); n != 2 {
+				t.Fatalf("Len() = %d, want 2", n)
+			}
+			if !s.Delete("a") {
+				t.Fatal("Delete(a) = false")
+			}
+			if s.Delete("a") {
+				t.Fatal("second Delete(a) = true")
+			}
+		})
+	}
+}
+
+func TestUnknownImplementation(t *testing.T) {
+	if _, err := newStore("agent99", 8, 0); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestClientAgainstServer(t *testing.T) {
+	s, err := newStore("agent13", 8, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(newServer("agent13", 8, s).handler())
+	defer ts.Close()
+	addr := strings.TrimPrefix(ts.URL, "http://")
+
+	var out bytes.Buffer
+	do := func(args ...string) error {
+		out.Reset()
+		return run(append(args[:1:1], append([]string{"-addr", addr}, args[1:]...)...), &out)
+	}
+
+	if err := do("set", "-ttl", "1m", "greeting", "hello world"); err != nil {
+		t.Fatal(err)
+	}
+	if err := do("get", "greeting"); err != nil || out.String() != "hello world\n" {
+		t.Fatalf("get = %q, %v", out.String(), err)
+	}
+	if err := do("get", "missing"); err == nil {
+		t.Fatal("expected not found")
+	}
+	if err := do("del", "greeting"); err != nil {
+		t.Fatal(err)
+	}
+	if err := do("del", "greeting"); err == nil {
+		t.Fatal("expected not found on second delete")
+	}
+
+	if err := do("stats"); err != nil {
+		t.Fatal(err)
+	}
+	var st Stats
+	if err := json.Unmarshal(out.Bytes(), &st); err != nil {
+		t.Fatal(err)
+	}
+	want := Stats{Impl: "agent13", Capacity: 8, Hits: 1, Misses: 1, Sets: 1, Deletes: 1}
+	if st != want {
+		t.Fatalf("stats = %+v, want %+v", st, want)
+	}
+}
+
+func TestServerRejectsBadTTL(t *testing.T) {
+	s, err := newStore("agent2", 8, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := newServer("agent2", 8, s).handler()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/keys/a?ttl=soon", strings.NewReader("v")))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", rec.Code)
+	}
+}
+
+func TestBench(t *testing.T) {
+	var out bytes.Buffer
+	if err := run([]string{"bench", "-impl", "agent4", "-n", "1000", "-keys", "100"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out.String(), "agent4: 1000 ops") {
+		t.Fatalf("unexpected output %q", out.String())
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// Stats is the body of GET /stats.
+type Stats struct {
+	Impl     string `json:"impl"`
+	Capacity int    `json:"capacity"`
+	Len      int    `json:"len"`
+	Hits     uint64 `json:"hits"`
+	Misses   uint64 `json:"misses"`
+	Sets     uint64 `json:"sets"`
+	Deletes  uint64 `json:"deletes"`
+}
+
+// maxValueSize bounds request bodies accepted by PUT /keys/{key}.
+const maxValueSize = 1 << 20
+
+// server exposes a store over HTTP:
+//
+//	GET    /keys/{key}          value, or 404
+//	PUT    /keys/{key}?ttl=30s  store the request body; ttl is optional
+//	DELETE /keys/{key}          204, or 404 if absent
+//	GET    /stats               Stats as JSON
+type server struct {
+	impl     string
+	capacity int
+	store    store
+
+	hits, misses, sets, deletes atomic.Uint64
+}
+
+func newServer(impl string, capacity int, s store) *server {
+	return &server{impl: impl, capacity: capacity, store: s}
+}
+
+func (s *server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /keys/{key}", s.get)
+	mux.HandleFunc("PUT /keys/{key}", s.set)
+	mux.HandleFunc("DELETE /keys/{key}", s.del)
+	mux.HandleFunc("GET /stats", s.stats)
+	return mux
+}
+
+func (s *server) get(w http.ResponseWriter, r *http.Request) {
+	value, ok := s.store.Get(r.PathValue("key"))
+	if !ok {
+		s.misses.Add(1)
+		http.NotFound(w, r)
+		return
+	}
+	s.hits.Add(1)
+	io.WriteString(w, value)
+}
+
+func (s *server) set(w http.ResponseWriter, r *http.Request) {
+	var ttl time.Duration
+	if v := r.URL.Query().Get("ttl"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			http.Error(w, "invalid ttl", http.StatusBadRequest)
+			return
+		}
+		ttl = d
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxValueSize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	set(s.store, r.PathValue("key"), string(body), ttl)
+	s.sets.Add(1)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *server) del(w http.ResponseWriter, r *http.Request) {
+	if !s.store.Delete(r.PathValue("key")) {
+		http.NotFound(w, r)
+		return
+	}
+	s.deletes.Add(1)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *server) stats(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Stats{
+		Impl:     s.impl,
+		Capacity: s.capacity,
+		Len:      s.store.Len(),
+		Hits:     s.hits.Load(),
+		Misses:   s.misses.Load(),
+		Sets:     s.sets.Load(),
+		Deletes:  s.deletes.Load(),
+	})
+}
@@ -0,0 +1,77 @@
+// Package flight collapses concurrent calls for the same key into one, so a
+// cache miss under load runs its loader once instead of once per caller.
+package flight
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrPanicked is returned to callers waiting on a call whose function
+// panicked. The caller that ran the function sees the panic itself.
+var ErrPanicked = errors.New("flight: call panicked")
+
+// Group runs at most one call per key at a time. The zero value is ready to
+// use; a Group must not be copied after first use.
+type Group[K comparable, V any] struct {
+	mu    sync.Mutex
+	calls map[K]*call[V]
+}
+
+// call is a function call in progress. value and err are set before done is
+// closed.
+type call[V any] struct {
+	done    chan struct{}
+	waiters int
+	value   V
+	err     error
+}
+
+// Do calls fn and returns its result. If a call for key is already in flight,
+// Do waits for it and returns its result instead of calling fn. The key is
+// forgotten once the call returns, so later calls run fn again; callers that
+// want the result kept should store it before fn returns.
+func (g *Group[K, V]) Do(key K, fn func() (V, error)) (V, error) {
+	g.mu.Lock()
+	if c, ok := g.calls[key]; ok {
+		c.waiters++
+		g.mu.Unlock()
+		<-c.done
+		return c.value, c.err
+	}
+	if g.calls == nil {
+		g.calls = make(map[K]*call[V])
+	}
+	c := &call[V]{done: make(chan struct{})}
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	completed := false
+	defer func() {
+		if !completed {
+			var zero V
+			c.value, c.err = zero, ErrPanicked
+		}
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		close(c.done)
+	}()
+
+	c.value, c.err = fn()
+	completed = true
+	return c.value, c.err
+}
+
+// Waiting reports how many callers are waiting on the call for key, not
+// counting the one running it. It is meant for tests that need to know a
+// caller has joined a call before letting it finish.
+func (g *Group[K, V]) Waiting(key K) int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if c, ok := g.calls[key]; ok {
+		return c.waiters
+	}
+	return 0
+}
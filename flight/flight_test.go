@@ -0,0 +1,102 @@
+package flight
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// waitFor blocks until n callers are waiting on key.
+func waitFor[K comparable, V any](g *Group[K, V], key K, n int) {
+	for g.Waiting(key) < n {
+		runtime.Gosched()
+	}
+}
+
+func TestDoSharesResult(t *testing.T) {
+	var g Group[string, int]
+	var calls atomic.Int32
+	release := make(chan struct{})
+
+	results := make(chan int, 5)
+	errs := make(chan error, 5)
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := g.Do("a", func() (int, error) {
+				calls.Add(1)
+				<-release
+				return 42, nil
+			})
+			results <- v
+			errs <- err
+		}()
+	}
+	waitFor(&g, "a", 4)
+	close(release)
+	wg.Wait()
+	close(results)
+	close(errs)
+
+	for v := range results {
+		if v != 42 {
+			t.Fatalf("expected 42, got %d", v)
+		}
+	}
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("expected one call, got %d", n)
+	}
+}
+
+func TestDoForgetsKey(t *testing.T) {
+	var g Group[string, int]
+	boom := errors.New("boom")
+
+	if _, err := g.Do("a", func() (int, error) { return 0, boom }); !errors.Is(err, boom) {
+		t.Fatalf("expected boom, got %v", err)
+	}
+	v, err := g.Do("a", func() (int, error) { return 1, nil })
+	if err != nil || v != 1 {
+		t.Fatalf("expected a later call to run again, got %d, %v", v, err)
+	}
+}
+
+func TestDoPanic(t *testing.T) {
+	var g Group[string, int]
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	panicked := make(chan any, 1)
+	go func() {
+		defer func() { panicked <- recover() }()
+		g.Do("a", func() (int, error) {
+			close(entered)
+			<-release
+			panic("boom")
+		})
+	}()
+	<-entered
+
+	waited := make(chan error, 1)
+	go func() {
+		_, err := g.Do("a", func() (int, error) { return 1, nil })
+		waited <- err
+	}()
+	waitFor(&g, "a", 1)
+	close(release)
+
+	if p := <-panicked; p != "boom" {
+		t.Fatalf("expected the panic to reach the caller running the call, got %v", p)
+	}
+	if err := <-waited; !errors.Is(err, ErrPanicked) {
+		t.Fatalf("expected ErrPanicked, got %v", err)
+	}
+}
@@ -0,0 +1,3 @@
+module github.com/rselbach/agent-comparison/flight
+
+go 1.21
@@ -0,0 +1,359 @@
+// Package httpcache implements a private HTTP caching transport in the spirit
+// of RFC 7234, backed by any cache that can store byte slices with a TTL.
+//
+// It is intentionally "lite": only GET responses are stored, freshness comes
+// from Cache-Control max-age or Expires (no heuristic freshness), stale
+// responses carrying an ETag or Last-Modified validator are revalidated with
+// a conditional request, and Vary is honoured by comparing the nominated
+// request headers. Successful unsafe requests invalidate the cached entry for
+// their URL.
+package httpcache
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/gob"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// XFromCache is set to "1" on responses served from the cache, including
+// responses refreshed by a 304 Not Modified revalidation.
+const XFromCache = "X-From-Cache"
+
+// Cache is the storage used by Transport. It is the subset of the shared
+// cache interface needed here, instantiated with string keys and []byte
+// values, so any agent implementation can back it through its adapter; the
+// cache/bench package runs Transport over each of them.
+type Cache interface {
+	Get(key string) ([]byte, bool)
+	SetWithTTL(key string, value []byte, ttl time.Duration)
+	Delete(key string) bool
+}
+
+// Transport is an http.RoundTripper that serves fresh responses from Cache
+// and stores cacheable responses fetched through Transport.
+type Transport struct {
+	// Cache stores serialised responses. It must not be nil.
+	Cache Cache
+	// Transport performs the actual requests. If nil, http.DefaultTransport is used.
+	Transport http.RoundTripper
+	// StaleTTL is how long a response that has validators is kept after it
+	// becomes stale so it can be revalidated instead of refetched. Zero
+	// discards responses as soon as they become stale.
+	StaleTTL time.Duration
+	// Now returns the current time. If nil, time.Now is used.
+	Now func() time.Time
+}
+
+// NewTransport returns a Transport backed by c using http.DefaultTransport.
+func NewTransport(c Cache) *Transport {
+	return &Transport{Cache: c}
+}
+
+// Client returns an *http.Client that uses t as its transport.
+func (t *Transport) Client() *http.Client {
+	return &http.Client{Transport: t}
+}
+
+// stored is the value written to the cache for each response.
+type stored struct {
+	Response  []byte
+	StoredAt  time.Time
+	InitAge   time.Duration
+	Lifetime  time.Duration
+	VaryValue map[string]string
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	key := cacheKey(req)
+
+	if req.Method != http.MethodGet {
+		resp, err := t.transport().RoundTrip(req)
+		if err == nil && !isSafeMethod(req.Method) && resp.StatusCode < 400 {
+			t.Cache.Delete(key)
+		}
+		return resp, err
+	}
+
+	reqCC := parseCacheControl(req.Header)
+	if _, ok := reqCC["no-store"]; ok {
+		return t.transport().RoundTrip(req)
+	}
+
+	var cached *stored
+	if _, noCache := reqCC["no-cache"]; !noCache {
+		cached = t.load(key, req)
+	}
+
+	if cached != nil {
+		age := t.age(cached)
+		if age < cached.Lifetime {
+			resp, err := cached.response(req)
+			if err == nil {
+				resp.Header.Set("Age", strconv.FormatInt(int64(age/time.Second), 10))
+				resp.Header.Set(XFromCache, "1")
+				return resp, nil
+			}
+		}
+		if resp, ok, err := t.revalidate(req, key, cached); ok || err != nil {
+			return resp, err
+		}
+	}
+
+	resp, err := t.transport().RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	return t.store(req, key, resp)
+}
+
+func (t *Transport) transport() http.RoundTripper {
+	if t.Transport != nil {
+		return t.Transport
+	}
+	return http.DefaultTransport
+}
+
+func (t *Transport) now() time.Time {
+	if t.Now != nil {
+		return t.Now()
+	}
+	return time.Now()
+}
+
+func (t *Transport) age(s *stored) time.Duration {
+	return s.InitAge + t.now().Sub(s.StoredAt)
+}
+
+// load returns the stored response for key if it exists, decodes and
+// matches req's Vary headers.
+func (t *Transport) load(key string, req *http.Request) *stored {
+	raw, ok := t.Cache.Get(key)
+	if !ok {
+		return nil
+	}
+	var s stored
+	if err := gob.NewDecoder(bytes.NewReader(raw)).Decode(&s); err != nil {
+		t.Cache.Delete(key)
+		return nil
+	}
+	for name, value := range s.VaryValue {
+		if req.Header.Get(name) != value {
+			return nil
+		}
+	}
+	return &s
+}
+
+// revalidate issues a conditional request for a stale entry. It reports
+// ok=false when the entry has no validators and a plain fetch is needed.
+func (t *Transport) revalidate(req *http.Request, key string, s *stored) (*http.Response, bool, error) {
+	cachedResp, err := s.response(req)
+	if err != nil {
+		return nil, false, nil
+	}
+	etag := cachedResp.Header.Get("ETag")
+	lastModified := cachedResp.Header.Get("Last-Modified")
+	if etag == "" && lastModified == "" {
+		cachedResp.Body.Close()
+		return nil, false, nil
+	}
+
+	cond := req.Clone(req.Context())
+	if etag != "" {
+		cond.Header.Set("If-None-Match", etag)
+	}
+	if lastModified != "" {
+		cond.Header.Set("If-Modified-Since", lastModified)
+	}
+
+	resp, err := t.transport().RoundTrip(cond)
+	if err != nil {
+		cachedResp.Body.Close()
+		return nil, true, err
+	}
+	if resp.StatusCode != http.StatusNotModified {
+		cachedResp.Body.Close()
+		resp, err = t.store(req, key, resp)
+		return resp, true, err
+	}
+
+	// 304: merge the new headers into the stored response and serve it.
+	resp.Body.Close()
+	for name, values := range resp.Header {
+		switch name {
+		case "Content-Length", "Transfer-Encoding":
+			continue
+		}
+		cachedResp.Header[name] = values
+	}
+	cachedResp.Request = req
+	out, err := t.store(req, key, cachedResp)
+	if err != nil {
+		return nil, true, err
+	}
+	out.Header.Set(XFromCache, "1")
+	return out, true, nil
+}
+
+// store saves resp if it is cacheable and returns a response whose body can
+// still be read by the caller.
+func (t *Transport) store(req *http.Request, key string, resp *http.Response) (*http.Response, error) {
+	if !isCacheableStatus(resp.StatusCode) {
+		return resp, nil
+	}
+	respCC := parseCacheControl(resp.Header)
+	if _, ok := respCC["no-store"]; ok {
+		t.Cache.Delete(key)
+		return resp, nil
+	}
+	lifetime, ok := t.freshness(resp.Header, respCC)
+	if !ok {
+		return resp, nil
+	}
+
+	ttl := lifetime
+	if resp.Header.Get("ETag") != "" || resp.Header.Get("Last-Modified") != "" {
+		ttl += t.StaleTTL
+	}
+	initAge := headerSeconds(resp.Header.Get("Age"))
+	ttl -= initAge
+	if ttl <= 0 {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	resp.ContentLength = int64(len(body))
+	resp.TransferEncoding = nil
+
+	dump, err := httputil.DumpResponse(resp, true)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	s := stored{
+		Response: dump,
+		StoredAt: t.now(),
+		InitAge:  initAge,
+		Lifetime: lifetime,
+	}
+	for _, name := range headerList(resp.Header, "Vary") {
+		if name == "*" {
+			return resp, nil
+		}
+		if s.VaryValue == nil {
+			s.VaryValue = make(map[string]string)
+		}
+		s.VaryValue[http.CanonicalHeaderKey(name)] = req.Header.Get(name)
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&s); err != nil {
+		return nil, err
+	}
+	t.Cache.SetWithTTL(key, buf.Bytes(), ttl)
+	return resp, nil
+}
+
+// freshness returns the response's freshness lifetime from max-age or
+// Expires. It reports false when the response carries no explicit lifetime.
+func (t *Transport) freshness(h http.Header, cc map[string]string) (time.Duration, bool) {
+	if _, ok := cc["no-cache"]; ok {
+		return 0, true
+	}
+	if v, ok := cc["max-age"]; ok {
+		if secs, err := strconv.ParseInt(v, 10, 64); err == nil && secs >= 0 {
+			return time.Duration(secs) * time.Second, true
+		}
+		return 0, true
+	}
+	if v := h.Get("Expires"); v != "" {
+		expires, err := http.ParseTime(v)
+		if err != nil {
+			return 0, true
+		}
+		date := t.now()
+		if d, err := http.ParseTime(h.Get("Date")); err == nil {
+			date = d
+		}
+		if lifetime := expires.Sub(date); lifetime > 0 {
+			return lifetime, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// response reconstructs the stored response for req.
+func (s *stored) response(req *http.Request) (*http.Response, error) {
+	return http.ReadResponse(bufio.NewReader(bytes.NewReader(s.Response)), req)
+}
+
+func cacheKey(req *http.Request) string {
+	return req.URL.String()
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+// isCacheableStatus reports whether status is cacheable by default
+// (RFC 7231 section 6.1).
+func isCacheableStatus(status int) bool {
+	switch status {
+	case http.StatusOK, http.StatusNonAuthoritativeInfo, http.StatusNoContent,
+		http.StatusMultipleChoices, http.StatusMovedPermanently, http.StatusNotFound,
+		http.StatusMethodNotAllowed, http.StatusGone, http.StatusRequestURITooLong,
+		http.StatusNotImplemented:
+		return true
+	}
+	return false
+}
+
+// parseCacheControl splits the Cache-Control header into lower-cased
+// directives mapped to their (unquoted) arguments.
+func parseCacheControl(h http.Header) map[string]string {
+	cc := make(map[string]string)
+	for _, part := range headerList(h, "Cache-Control") {
+		name, value, _ := strings.Cut(part, "=")
+		cc[strings.ToLower(strings.TrimSpace(name))] = strings.Trim(strings.TrimSpace(value), `"`)
+	}
+	return cc
+}
+
+// headerList returns the comma-separated elements of every value of name.
+func headerList(h http.Header, name string) []string {
+	var out []string
+	for _, v := range h.Values(name) {
+		for _, part := range strings.Split(v, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				out = append(out, part)
+			}
+		}
+	}
+	return out
+}
+
+func headerSeconds(v string) time.Duration {
+	secs, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+	if err != nil || secs < 0 {
+		return 0
+	}
+	return time.Duration(secs) * time.Second
+}
@@ -0,0 +1,227 @@
+package httpcache
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// mapCache is a minimal Cache honouring TTLs against a fake clock.
+type mapCache struct {
+	mu    sync.Mutex
+	now   func() time.Time
+	items map[string]mapEntry
+}
+
+type mapEntry struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+func newMapCache(now func() time.Time) *mapCache {
+	return &mapCache{now: now, items: make(map[string]mapEntry)}
+}
+
+func (c *mapCache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.items[key]
+	if !ok || !c.now().Before(e.expiresAt) {
+		return nil, false
+	}
+	return e.value, true
+}
+
+func (c *mapCache) SetWithTTL(key string, value []byte, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.items[key] = mapEntry{value: value, expiresAt: c.now().Add(ttl)}
+}
+
+func (c *mapCache) Delete(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.items[key]
+	delete(c.items, key)
+	return ok
+}
+
+type fixture struct {
+	now    time.Time
+	hits   atomic.Int32
+	server *httptest.Server
+	client *http.Client
+	t      *Transport
+}
+
+func newFixture(t *testing.T, handler func(w http.ResponseWriter, r *http.Request)) *fixture {
+	f := &fixture{now: time.Unix(1_700_000_000, 0)}
+	f.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.hits.Add(1)
+		handler(w, r)
+	}))
+	t.Cleanup(f.server.Close)
+
+	clock := func() time.Time { return f.now }
+	f.t = NewTransport(newMapCache(clock))
+	f.t.Now = clock
+	f.client = f.t.Client()
+	return f
+}
+
+func (f *fixture) get(t *testing.T, path string, header ...string) (*http.Response, string) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, f.server.URL+path, nil)
+	if err != nil {
+		t.Fatalf("new request: %v", err)
+	}
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		t.Fatalf("get %s: %v", path, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read body: %v", err)
+	}
+	return resp, string(body)
+}
+
+func TestFreshResponseServedFromCache(t *testing.T) {
+	f := newFixture(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		io.WriteString(w, "hello")
+	})
+
+	resp, body := f.get(t, "/a")
+	if body != "hello" || resp.Header.Get(XFromCache) != "" {
+		t.Fatalf("first response: body=%q fromCache=%q", body, resp.Header.Get(XFromCache))
+	}
+
+	f.now = f.now.Add(30 * time.Second)
+	resp, body = f.get(t, "/a")
+	if body != "hello" || resp.Header.Get(XFromCache) != "1" {
+		t.Fatalf("second response: body=%q fromCache=%q", body, resp.Header.Get(XFromCache))
+	}
+	if age := resp.Header.Get("Age"); age != "30" {
+		t.Fatalf("expected Age 30, got %q", age)
+	}
+	if hits := f.hits.Load(); hits != 1 {
+		t.Fatalf("expected 1 upstream hit, got %d", hits)
+	}
+
+	f.now = f.now.Add(time.Minute)
+	f.get(t, "/a")
+	if hits := f.hits.Load(); hits != 2 {
+		t.Fatalf("expected refetch after expiry, got %d hits", hits)
+	}
+}
+
+func TestNoStoreAndMissingFreshnessAreNotCached(t *testing.T) {
+	f := newFixture(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/nostore" {
+			w.Header().Set("Cache-Control", "no-store, max-age=60")
+		}
+		io.WriteString(w, "x")
+	})
+
+	f.get(t, "/nostore")
+	f.get(t, "/nostore")
+	f.get(t, "/plain")
+	f.get(t, "/plain")
+	if hits := f.hits.Load(); hits != 4 {
+		t.Fatalf("expected 4 upstream hits, got %d", hits)
+	}
+}
+
+func TestRequestNoCacheBypassesStoredResponse(t *testing.T) {
+	f := newFixture(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		io.WriteString(w, "x")
+	})
+
+	f.get(t, "/a")
+	f.get(t, "/a", "Cache-Control", "no-cache")
+	if hits := f.hits.Load(); hits != 2 {
+		t.Fatalf("expected 2 upstream hits, got %d", hits)
+	}
+}
+
+func TestStaleResponseRevalidated(t *testing.T) {
+	f := newFixture(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=10")
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		io.WriteString(w, "body-v1")
+	})
+	f.t.StaleTTL = time.Hour
+
+	f.get(t, "/a")
+	f.now = f.now.Add(20 * time.Second)
+
+	resp, body := f.get(t, "/a")
+	if resp.StatusCode != http.StatusOK || body != "body-v1" {
+		t.Fatalf("expected cached 200 body-v1, got %d %q", resp.StatusCode, body)
+	}
+	if resp.Header.Get(XFromCache) != "1" {
+		t.Fatalf("expected revalidated response to be marked as from cache")
+	}
+	if hits := f.hits.Load(); hits != 2 {
+		t.Fatalf("expected conditional request upstream, got %d hits", hits)
+	}
+
+	// The 304 refreshed the entry, so it is fresh again.
+	f.now = f.now.Add(5 * time.Second)
+	f.get(t, "/a")
+	if hits := f.hits.Load(); hits != 2 {
+		t.Fatalf("expected refreshed entry to be served, got %d hits", hits)
+	}
+}
+
+func TestUnsafeMethodInvalidates(t *testing.T) {
+	f := newFixture(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		io.WriteString(w, "x")
+	})
+
+	f.get(t, "/a")
+	resp, err := f.client.Post(f.server.URL+"/a", "text/plain", nil)
+	if err != nil {
+		t.Fatalf("post: %v", err)
+	}
+	resp.Body.Close()
+
+	f.get(t, "/a")
+	if hits := f.hits.Load(); hits != 3 {
+		t.Fatalf("expected refetch after POST, got %d hits", hits)
+	}
+}
+
+func TestVaryMismatchIsMiss(t *testing.T) {
+	f := newFixture(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Header().Set("Vary", "Accept-Language")
+		io.WriteString(w, r.Header.Get("Accept-Language"))
+	})
+
+	f.get(t, "/a", "Accept-Language", "en")
+	_, body := f.get(t, "/a", "Accept-Language", "en")
+	if body != "en" || f.hits.Load() != 1 {
+		t.Fatalf("expected cached en, got %q after %d hits", body, f.hits.Load())
+	}
+
+	_, body = f.get(t, "/a", "Accept-Language", "fr")
+	if body != "fr" || f.hits.Load() != 2 {
+		t.Fatalf("expected upstream fr, got %q after %d hits", body, f.hits.Load())
+	}
+}
@@ -0,0 +1,139 @@
+// Package metrics is the observability hook shared by the cache
+// implementations.
+//
+// Applications export cache metrics very differently (Prometheus,
+// OpenTelemetry, statsd), so caches report events to a Recorder instead of
+// keeping counters in any particular format. A cache given no Recorder uses
+// Nop.
+package metrics
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Recorder receives a cache's events. Caches call it while holding their own
+// locks, so its methods must be safe for concurrent use, must return quickly
+// and must not call back into the cache.
+type Recorder interface {
+	// Hit records a Get that found a live entry.
+	Hit()
+	// Miss records a Get that found no live entry, including one that found
+	// an expired entry.
+	Miss()
+	// Eviction records an entry removed to make room for another.
+	Eviction()
+	// Expiration records an expired entry removed, whether by a lookup or a
+	// sweep.
+	Expiration()
+	// SweepDuration records how long one pass of the cache's expiry sweep
+	// took, measured on the cache's clock.
+	SweepDuration(d time.Duration)
+}
+
+// Nop is the Recorder that discards every event.
+type Nop struct{}
+
+func (Nop) Hit()                        {}
+func (Nop) Miss()                       {}
+func (Nop) Eviction()                   {}
+func (Nop) Expiration()                 {}
+func (Nop) SweepDuration(time.Duration) {}
+
+// OrNop returns r, or Nop if r is nil.
+func OrNop(r Recorder) Recorder {
+	if r == nil {
+		return Nop{}
+	}
+	return r
+}
+
+// Tee returns a Recorder that passes every event to each of rs in order.
+func Tee(rs ...Recorder) Recorder {
+	return tee(rs)
+}
+
+type tee []Recorder
+
+func (t tee) Hit() {
+	for _, r := range t {
+		r.Hit()
+	}
+}
+
+func (t tee) Miss() {
+	for _, r := range t {
+		r.Miss()
+	}
+}
+
+func (t tee) Eviction() {
+	for _, r := range t {
+		r.Eviction()
+	}
+}
+
+func (t tee) Expiration() {
+	for _, r := range t {
+		r.Expiration()
+	}
+}
+
+func (t tee) SweepDuration(d time.Duration) {
+	for _, r := range t {
+		r.SweepDuration(d)
+	}
+}
+
+// Counters is a Recorder that counts events. Its zero value is ready to use.
+type Counters struct {
+	hits, misses, evictions, expirations, sweeps atomic.Int64
+	sweepTime                                    atomic.Int64
+}
+
+func (c *Counters) Hit()        { c.hits.Add(1) }
+func (c *Counters) Miss()       { c.misses.Add(1) }
+func (c *Counters) Eviction()   { c.evictions.Add(1) }
+func (c *Counters) Expiration() { c.expirations.Add(1) }
+
+func (c *Counters) SweepDuration(d time.Duration) {
+	c.sweeps.Add(1)
+	c.sweepTime.Add(int64(d))
+}
+
+// Snapshot is the state of a Counters at one moment.
+type Snapshot struct {
+	Hits, Misses, Evictions, Expirations int64
+	// Sweeps is the number of sweeps recorded and SweepTime their total
+	// duration.
+	Sweeps    int64
+	SweepTime time.Duration
+}
+
+// Snapshot returns the current counts. Each count is read atomically, but
+// events recorded concurrently may be reflected in some counts and not
+// others.
+func (c *Counters) Snapshot() Snapshot {
+	return Snapshot{
+		Hits:        c.hits.Load(),
+		Misses:      c.misses.Load(),
+		Evictions:   c.evictions.Load(),
+		Expirations: c.expirations.Load(),
+		Sweeps:      c.sweeps.Load(),
+		SweepTime:   time.Duration(c.sweepTime.Load()),
+	}
+}
+
+// Reset zeroes the counts and returns their values before the reset. Like
+// Snapshot it is not atomic across counts, so an event recorded concurrently
+// may be counted in the returned Snapshot or after the reset.
+func (c *Counters) Reset() Snapshot {
+	return Snapshot{
+		Hits:        c.hits.Swap(0),
+		Misses:      c.misses.Swap(0),
+		Evictions:   c.evictions.Swap(0),
+		Expirations: c.expirations.Swap(0),
+		Sweeps:      c.sweeps.Swap(0),
+		SweepTime:   time.Duration(c.sweepTime.Swap(0)),
+	}
+}
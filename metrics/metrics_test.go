@@ -0,0 +1,73 @@
+package metrics
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestOrNop(t *testing.T) {
+	if _, ok := OrNop(nil).(Nop); !ok {
+		t.Fatal("OrNop(nil) is not Nop")
+	}
+	c := &Counters{}
+	if OrNop(c) != Recorder(c) {
+		t.Fatal("OrNop replaced a non-nil recorder")
+	}
+}
+
+func TestCounters(t *testing.T) {
+	var c Counters
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.Hit()
+			c.Hit()
+			c.Miss()
+			c.Eviction()
+			c.Expiration()
+			c.SweepDuration(time.Millisecond)
+		}()
+	}
+	wg.Wait()
+
+	want := Snapshot{Hits: 8, Misses: 4, Evictions: 4, Expirations: 4, Sweeps: 4, SweepTime: 4 * time.Millisecond}
+	if got := c.Snapshot(); got != want {
+		t.Fatalf("Snapshot() = %+v, want %+v", got, want)
+	}
+}
+
+func TestCountersReset(t *testing.T) {
+	var c Counters
+	c.Hit()
+	c.Miss()
+	c.SweepDuration(time.Second)
+
+	want := Snapshot{Hits: 1, Misses: 1, Sweeps: 1, SweepTime: time.Second}
+	if got := c.Reset(); got != want {
+		t.Fatalf("Reset() = %+v, want %+v", got, want)
+	}
+	if got := c.Snapshot(); got != (Snapshot{}) {
+		t.Fatalf("Snapshot() after Reset = %+v, want zero", got)
+	}
+}
+
+func TestTee(t *testing.T) {
+	var a, b Counters
+	r := Tee(&a, Nop{}, &b)
+	r.Hit()
+	r.Miss()
+	r.Eviction()
+	r.Expiration()
+	r.SweepDuration(time.Millisecond)
+
+	want := Snapshot{Hits: 1, Misses: 1, Evictions: 1, Expirations: 1, Sweeps: 1, SweepTime: time.Millisecond}
+	if got := a.Snapshot(); got != want {
+		t.Fatalf("first recorder got %+v, want %+v", got, want)
+	}
+	if got := b.Snapshot(); got != want {
+		t.Fatalf("last recorder got %+v, want %+v", got, want)
+	}
+}
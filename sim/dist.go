@@ -0,0 +1,108 @@
+package sim
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// keySampler draws key indices in [0, n). Index 0 is the most popular key.
+type keySampler struct {
+	// cdf is the cumulative Zipf weight of each rank; nil means uniform.
+	cdf []float64
+	n   int
+}
+
+// newKeySampler returns a sampler over n keys where the key of rank k is drawn
+// with probability proportional to 1/(k+1)^skew. A skew of zero is uniform.
+func newKeySampler(n int, skew float64) *keySampler {
+	if skew == 0 {
+		return &keySampler{n: n}
+	}
+	cdf := make([]float64, n)
+	sum := 0.0
+	for k := range cdf {
+		sum += 1 / math.Pow(float64(k+1), skew)
+		cdf[k] = sum
+	}
+	return &keySampler{cdf: cdf, n: n}
+}
+
+func (s *keySampler) sample(r *rand.Rand) int {
+	if s.cdf == nil {
+		return r.Intn(s.n)
+	}
+	u := r.Float64() * s.cdf[len(s.cdf)-1]
+	i := sort.SearchFloat64s(s.cdf, u)
+	if i == len(s.cdf) {
+		i--
+	}
+	return i
+}
+
+// TTLKind selects how TTLs are drawn for writes.
+type TTLKind string
+
+const (
+	// TTLNone writes every entry with Set, which uses the cache's default
+	// TTL. The simulator builds every cache with a zero default, so entries
+	// never expire. A zero TTL is never passed to SetWithTTL, since several
+	// agents treat an explicit zero TTL as already expired.
+	TTLNone TTLKind = "none"
+	// TTLFixed writes every entry with TTL.Mean.
+	TTLFixed TTLKind = "fixed"
+	// TTLUniform draws TTLs uniformly from [TTL.Min, TTL.Max].
+	TTLUniform TTLKind = "uniform"
+	// TTLExponential draws TTLs from an exponential distribution with mean
+	// TTL.Mean, capped at TTL.Max when it is positive.
+	TTLExponential TTLKind = "exponential"
+)
+
+// TTLDist describes the distribution of TTLs used for writes.
+type TTLDist struct {
+	Kind TTLKind       `json:"kind"`
+	Min  time.Duration `json:"min,omitempty"`
+	Max  time.Duration `json:"max,omitempty"`
+	Mean time.Duration `json:"mean,omitempty"`
+}
+
+func (d TTLDist) validate() error {
+	switch d.Kind {
+	case "", TTLNone:
+		return nil
+	case TTLFixed, TTLExponential:
+		if d.Mean <= 0 {
+			return fmt.Errorf("sim: %s TTL needs a positive mean", d.Kind)
+		}
+		return nil
+	case TTLUniform:
+		if d.Min <= 0 || d.Max < d.Min {
+			return fmt.Errorf("sim: uniform TTL needs 0 < min <= max")
+		}
+		return nil
+	default:
+		return fmt.Errorf("sim: unknown TTL kind %q", d.Kind)
+	}
+}
+
+func (d TTLDist) sample(r *rand.Rand) time.Duration {
+	switch d.Kind {
+	case TTLFixed:
+		return d.Mean
+	case TTLUniform:
+		return d.Min + time.Duration(r.Int63n(int64(d.Max-d.Min)+1))
+	case TTLExponential:
+		ttl := time.Duration(r.ExpFloat64() * float64(d.Mean))
+		if ttl <= 0 {
+			ttl = 1
+		}
+		if d.Max > 0 && ttl > d.Max {
+			ttl = d.Max
+		}
+		return ttl
+	default:
+		return 0
+	}
+}
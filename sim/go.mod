@@ -0,0 +1,52 @@
+module github.com/rselbach/agent-comparison/sim
+
+go 1.25.1
+
+require (
+	github.com/rselbach/agent-comparison/cache v0.0.0
+	github.com/rselbach/agent-comparison/clock v0.0.0
+)
+
+require (
+	agent10 v0.0.0 // indirect
+	agent11 v0.0.0 // indirect
+	agent9 v0.0.0 // indirect
+	github.com/gemini/lrucache v0.0.0 // indirect
+	github.com/opencode/lru v0.0.0 // indirect
+	github.com/rselbach/agent-comparison/flight v0.0.0 // indirect
+	github.com/rselbach/agent-comparison/metrics v0.0.0 // indirect
+	github.com/rselbach/agent-comparison/trie v0.0.0 // indirect
+	github.com/rselbach/agent12 v0.0.0 // indirect
+	github.com/rselbach/agent13 v0.0.0 // indirect
+	github.com/rselbach/agent14 v0.0.0 // indirect
+	github.com/rselbach/agent15 v0.0.0 // indirect
+	github.com/rselbach/agent5 v0.0.0 // indirect
+	github.com/rselbach/agent7 v0.0.0 // indirect
+	github.com/rselbach/agent8 v0.0.0 // indirect
+	github.com/rselbach/cc/lrucache v0.0.0 // indirect
+	github.com/rselbach/lrucache v0.0.0 // indirect
+	lru v0.0.0 // indirect
+)
+
+replace (
+	agent10 => ../agent10
+	agent11 => ../agent11
+	agent9 => ../agent9
+	github.com/gemini/lrucache => ../agent3/lrucache
+	github.com/opencode/lru => ../agent4
+	github.com/rselbach/agent-comparison/cache => ../cache
+	github.com/rselbach/agent-comparison/clock => ../clock
+	github.com/rselbach/agent-comparison/flight => ../flight
+	github.com/rselbach/agent-comparison/metrics => ../metrics
+	github.com/rselbach/agent-comparison/trie => ../trie
+	github.com/rselbach/agent12 => ../agent12
+	github.com/rselbach/agent13 => ../agent13
+	github.com/rselbach/agent14 => ../agent14
+	github.com/rselbach/agent15 => ../agent15
+	github.com/rselbach/agent5 => ../agent5
+	github.com/rselbach/agent7 => ../agent7
+	github.com/rselbach/agent8 => ../agent8
+	github.com/rselbach/cc/lrucache => ../agent1
+	github.com/rselbach/lrucache => ../agent6
+	lru => ../agent2
+)
@@ -0,0 +1,205 @@
+// Package sim runs reproducible workload simulations against the agent
+// caches for capacity planning.
+//
+// A simulation replays the same seeded stream of operations against every
+// implementation from cache.Implementations at every capacity in a sweep.
+// Keys are drawn from a uniform or Zipf distribution, writes carry TTLs drawn
+// from a configurable distribution, and time is virtual: each cache is built
+// on a clock.Fake that advances by a fixed step per operation, so expiry
+// behaves identically from run to run regardless of how fast the host is.
+// Background sweeps are disabled with clock.Synchronous, so whether an expired
+// entry is reclaimed before a capacity eviction never depends on goroutine
+// scheduling.
+// Agents with a single cache-wide TTL ignore the sampled TTLs. Results are
+// returned as a Report that encodes to JSON for plotting.
+package sim
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"strconv"
+	"time"
+
+	"github.com/rselbach/agent-comparison/cache"
+	"github.com/rselbach/agent-comparison/clock"
+)
+
+// Config parameterises a simulation.
+type Config struct {
+	// Seed makes the operation stream reproducible.
+	Seed int64 `json:"seed"`
+	// KeySpace is the number of distinct keys.
+	KeySpace int `json:"key_space"`
+	// Skew is the Zipf exponent of key popularity. Zero is uniform.
+	Skew float64 `json:"skew"`
+	// WriteRatio is the fraction of operations that are writes.
+	WriteRatio float64 `json:"write_ratio"`
+	// FillOnMiss writes the key after a read miss, as a cache-aside caller
+	// would. These fills count as writes in the results.
+	FillOnMiss bool `json:"fill_on_miss"`
+	// TTL is the distribution of TTLs for writes.
+	TTL TTLDist `json:"ttl"`
+	// Capacities is the capacity sweep; each implementation runs once per
+	// capacity.
+	Capacities []int `json:"capacities"`
+	// Warmup operations are applied before measuring.
+	Warmup int `json:"warmup"`
+	// Ops is the number of measured operations.
+	Ops int `json:"ops"`
+	// Step is how far the virtual clock advances per operation. Zero means
+	// one millisecond.
+	Step time.Duration `json:"step"`
+	// ValueSize is the length of stored values in bytes. Zero means 64.
+	ValueSize int `json:"value_size"`
+}
+
+func (c Config) validate() error {
+	switch {
+	case c.KeySpace <= 0:
+		return errors.New("sim: key space must be positive")
+	case c.Skew < 0:
+		return errors.New("sim: skew must not be negative")
+	case c.WriteRatio < 0 || c.WriteRatio > 1:
+		return errors.New("sim: write ratio must be in [0, 1]")
+	case len(c.Capacities) == 0:
+		return errors.New("sim: no capacities to sweep")
+	case c.Warmup < 0 || c.Ops <= 0:
+		return errors.New("sim: ops must be positive and warmup not negative")
+	case c.Step < 0 || c.ValueSize < 0:
+		return errors.New("sim: step and value size must not be negative")
+	}
+	for _, capacity := range c.Capacities {
+		if capacity <= 0 {
+			return fmt.Errorf("sim: invalid capacity %d", capacity)
+		}
+	}
+	return c.TTL.validate()
+}
+
+// Result holds the measurements for one implementation at one capacity.
+// Counts cover measured operations only.
+type Result struct {
+	Implementation string        `json:"implementation"`
+	Capacity       int           `json:"capacity"`
+	Reads          int           `json:"reads"`
+	Hits           int           `json:"hits"`
+	Misses         int           `json:"misses"`
+	Writes         int           `json:"writes"`
+	HitRatio       float64       `json:"hit_ratio"`
+	Elapsed        time.Duration `json:"elapsed_ns"`
+	NsPerOp        float64       `json:"ns_per_op"`
+}
+
+// Report is the outcome of Run.
+type Report struct {
+	Config  Config   `json:"config"`
+	Results []Result `json:"results"`
+}
+
+// WriteJSON writes r to w as indented JSON.
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// Run simulates cfg against each implementation at each capacity, in order,
+// and returns the results. Every run sees the same operation stream. Pass
+// cache.Implementations[[]byte]() to cover every agent.
+func Run(cfg Config, impls []cache.Implementation[[]byte]) (*Report, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	if cfg.Step == 0 {
+		cfg.Step = time.Millisecond
+	}
+	if cfg.ValueSize == 0 {
+		cfg.ValueSize = 64
+	}
+
+	keys := make([]string, cfg.KeySpace)
+	for i := range keys {
+		keys[i] = "k" + strconv.Itoa(i)
+	}
+	w := &workload{
+		cfg:     cfg,
+		keys:    keys,
+		sampler: newKeySampler(cfg.KeySpace, cfg.Skew),
+		value:   make([]byte, cfg.ValueSize),
+	}
+
+	report := &Report{Config: cfg}
+	for _, impl := range impls {
+		for _, capacity := range cfg.Capacities {
+			report.Results = append(report.Results, w.run(impl, capacity))
+		}
+	}
+	return report, nil
+}
+
+// workload is the state shared by every run of a simulation.
+type workload struct {
+	cfg     Config
+	keys    []string
+	sampler *keySampler
+	value   []byte
+}
+
+func (w *workload) run(impl cache.Implementation[[]byte], capacity int) Result {
+	clk := clock.NewFake(time.Unix(0, 0))
+	c := impl.NewWithClock(capacity, 0, clock.Synchronous(clk))
+	defer c.Close()
+	r := rand.New(rand.NewSource(w.cfg.Seed))
+
+	var res Result
+	for i := 0; i < w.cfg.Warmup; i++ {
+		w.step(c, r, &res)
+		clk.Advance(w.cfg.Step)
+	}
+
+	res = Result{Implementation: impl.Name, Capacity: capacity}
+	start := time.Now()
+	for i := 0; i < w.cfg.Ops; i++ {
+		w.step(c, r, &res)
+		clk.Advance(w.cfg.Step)
+	}
+	res.Elapsed = time.Since(start)
+
+	res.NsPerOp = float64(res.Elapsed.Nanoseconds()) / float64(w.cfg.Ops)
+	if res.Reads > 0 {
+		res.HitRatio = float64(res.Hits) / float64(res.Reads)
+	}
+	return res
+}
+
+// step applies one operation and records it in res. It draws the same random
+// numbers whatever the outcome, so every run sees an identical operation
+// stream even when implementations hit and miss differently.
+func (w *workload) step(c cache.Cache[string, []byte], r *rand.Rand, res *Result) {
+	key := w.keys[w.sampler.sample(r)]
+	write := r.Float64() < w.cfg.WriteRatio
+	ttl := w.cfg.TTL.sample(r)
+
+	if !write {
+		res.Reads++
+		if _, ok := c.Get(key); ok {
+			res.Hits++
+			return
+		}
+		res.Misses++
+		if !w.cfg.FillOnMiss {
+			return
+		}
+	}
+	res.Writes++
+	if ttl > 0 {
+		c.SetWithTTL(key, w.value, ttl)
+	} else {
+		// Several agents treat an explicit zero TTL as already expired, so
+		// "never expires" goes through Set and the cache's zero default.
+		c.Set(key, w.value)
+	}
+}
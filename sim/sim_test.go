@@ -0,0 +1,173 @@
+package sim
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/rselbach/agent-comparison/cache"
+)
+
+// only returns the registered implementations with the given names.
+func only(names ...string) []cache.Implementation[[]byte] {
+	var impls []cache.Implementation[[]byte]
+	for _, impl := range cache.Implementations[[]byte]() {
+		for _, name := range names {
+			if impl.Name == name {
+				impls = append(impls, impl)
+			}
+		}
+	}
+	return impls
+}
+
+func baseConfig() Config {
+	return Config{
+		Seed:       1,
+		KeySpace:   1000,
+		Skew:       1.1,
+		WriteRatio: 0.1,
+		FillOnMiss: true,
+		Capacities: []int{10, 100, 1000},
+		Warmup:     1000,
+		Ops:        20000,
+	}
+}
+
+func TestRunIsReproducible(t *testing.T) {
+	cfg := baseConfig()
+	// Span several minutes of virtual time with expiring entries, so a
+	// cache's background sweep would have fired during the run.
+	cfg.TTL = TTLDist{Kind: TTLExponential, Mean: 5 * time.Second}
+	cfg.Step = 10 * time.Millisecond
+	a, err := Run(cfg, cache.Implementations[[]byte]())
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := Run(cfg, cache.Implementations[[]byte]())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range a.Results {
+		ra, rb := a.Results[i], b.Results[i]
+		if ra.Hits != rb.Hits || ra.Reads != rb.Reads || ra.Writes != rb.Writes {
+			t.Fatalf("run %d differs: %+v vs %+v", i, ra, rb)
+		}
+	}
+}
+
+func TestCapacitySweep(t *testing.T) {
+	impls := cache.Implementations[[]byte]()
+	report, err := Run(baseConfig(), impls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Results) != 3*len(impls) {
+		t.Fatalf("expected %d results, got %d", 3*len(impls), len(report.Results))
+	}
+	for i, res := range report.Results {
+		if res.Reads+res.Writes-res.Misses != 20000 {
+			t.Fatalf("result %d: counts do not add up: %+v", i, res)
+		}
+		if i%3 > 0 && res.HitRatio <= report.Results[i-1].HitRatio {
+			t.Fatalf("hit ratio did not grow with capacity: %+v", report.Results[i-1:i+1])
+		}
+	}
+	for i := 2; i < len(report.Results); i += 3 {
+		if res := report.Results[i]; res.HitRatio < 0.9 {
+			t.Fatalf("%s holding every key should mostly hit, got %v", res.Implementation, res.HitRatio)
+		}
+	}
+}
+
+func TestTTLLowersHitRatio(t *testing.T) {
+	cfg := baseConfig()
+	cfg.Capacities = []int{1000}
+	// agent5, agent6 and agent8 have one cache-wide TTL and ignore the
+	// sampled ones, so use agents that honour per-entry TTLs.
+	impls := only("agent2", "agent11")
+	forever, err := Run(cfg, impls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.TTL = TTLDist{Kind: TTLFixed, Mean: 50 * time.Millisecond}
+	short, err := Run(cfg, impls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, res := range short.Results {
+		if res.HitRatio >= forever.Results[i].HitRatio {
+			t.Fatalf("%s: expected expiry to cost hits: %v >= %v", res.Implementation, res.HitRatio, forever.Results[i].HitRatio)
+		}
+	}
+}
+
+func TestTTLDistributions(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	uniform := TTLDist{Kind: TTLUniform, Min: time.Second, Max: 2 * time.Second}
+	capped := TTLDist{Kind: TTLExponential, Mean: time.Second, Max: 3 * time.Second}
+	for i := 0; i < 1000; i++ {
+		if ttl := uniform.sample(r); ttl < time.Second || ttl > 2*time.Second {
+			t.Fatalf("uniform TTL out of range: %v", ttl)
+		}
+		if ttl := capped.sample(r); ttl <= 0 || ttl > 3*time.Second {
+			t.Fatalf("exponential TTL out of range: %v", ttl)
+		}
+	}
+}
+
+func TestKeySamplerSkew(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	s := newKeySampler(100, 1.5)
+	counts := make([]int, 100)
+	for i := 0; i < 10000; i++ {
+		counts[s.sample(r)]++
+	}
+	if counts[0] <= counts[10] || counts[10] <= counts[99] {
+		t.Fatalf("expected popularity to fall with rank: %d, %d, %d", counts[0], counts[10], counts[99])
+	}
+}
+
+func TestInvalidConfig(t *testing.T) {
+	for name, mutate := range map[string]func(*Config){
+		"key space":   func(c *Config) { c.KeySpace = 0 },
+		"skew":        func(c *Config) { c.Skew = -1 },
+		"write ratio": func(c *Config) { c.WriteRatio = 1.5 },
+		"capacities":  func(c *Config) { c.Capacities = nil },
+		"capacity":    func(c *Config) { c.Capacities = []int{0} },
+		"ops":         func(c *Config) { c.Ops = 0 },
+		"ttl kind":    func(c *Config) { c.TTL.Kind = "gamma" },
+		"ttl bounds":  func(c *Config) { c.TTL = TTLDist{Kind: TTLUniform, Min: 2, Max: 1} },
+	} {
+		cfg := baseConfig()
+		mutate(&cfg)
+		if _, err := Run(cfg, only("agent2")); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestReportJSON(t *testing.T) {
+	cfg := baseConfig()
+	cfg.TTL = TTLDist{Kind: TTLExponential, Mean: time.Second}
+	report, err := Run(cfg, only("agent2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := report.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var decoded Report
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Config.TTL.Kind != TTLExponential || len(decoded.Results) != 3 {
+		t.Fatalf("round trip lost data: %+v", decoded)
+	}
+	if decoded.Results[1].Hits != report.Results[1].Hits {
+		t.Fatalf("hits mismatch: %d vs %d", decoded.Results[1].Hits, report.Results[1].Hits)
+	}
+}
@@ -0,0 +1,3 @@
+module github.com/rselbach/agent-comparison/trie
+
+go 1.21
@@ -0,0 +1,86 @@
+// Package trie is the byte-wise prefix index shared by the caches that can
+// delete keys by prefix or pattern, so finding the keys under a prefix costs
+// time proportional to the matches rather than to the whole cache.
+package trie
+
+// Trie maps string keys to values of type V. The zero value is an empty trie
+// ready to use. It is not safe for concurrent use; caches call it under their
+// own locks.
+type Trie[V any] struct {
+	root node[V]
+}
+
+type node[V any] struct {
+	children map[byte]*node[V]
+	value    V
+	ok       bool
+}
+
+// Insert stores value under key, replacing any value already there.
+func (t *Trie[V]) Insert(key string, value V) {
+	n := &t.root
+	for i := 0; i < len(key); i++ {
+		child, ok := n.children[key[i]]
+		if !ok {
+			if n.children == nil {
+				n.children = make(map[byte]*node[V])
+			}
+			child = &node[V]{}
+			n.children[key[i]] = child
+		}
+		n = child
+	}
+	n.value = value
+	n.ok = true
+}
+
+// Remove deletes key and prunes the nodes that no longer lead to any key.
+func (t *Trie[V]) Remove(key string) {
+	path := make([]*node[V], 0, len(key)+1)
+	n := &t.root
+	path = append(path, n)
+	for i := 0; i < len(key); i++ {
+		child, ok := n.children[key[i]]
+		if !ok {
+			return
+		}
+		n = child
+		path = append(path, n)
+	}
+	var zero V
+	n.value, n.ok = zero, false
+
+	for i := len(path) - 1; i > 0; i-- {
+		if path[i].ok || len(path[i].children) > 0 {
+			return
+		}
+		delete(path[i-1].children, key[i-1])
+	}
+}
+
+// Collect returns the values of every key that starts with prefix, in no
+// particular order.
+func (t *Trie[V]) Collect(prefix string) []V {
+	n := &t.root
+	for i := 0; i < len(prefix); i++ {
+		child, ok := n.children[prefix[i]]
+		if !ok {
+			return nil
+		}
+		n = child
+	}
+
+	var out []V
+	stack := []*node[V]{n}
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if n.ok {
+			out = append(out, n.value)
+		}
+		for _, child := range n.children {
+			stack = append(stack, child)
+		}
+	}
+	return out
+}
@@ -0,0 +1,61 @@
+package trie
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCollect(t *testing.T) {
+	var tr Trie[int]
+	tr.Insert("user:1", 1)
+	tr.Insert("user:10", 10)
+	tr.Insert("user:2", 2)
+	tr.Insert("session:1", 3)
+	tr.Insert("", 0)
+
+	for _, tc := range []struct {
+		prefix string
+		want   []int
+	}{
+		{"user:1", []int{1, 10}},
+		{"user:", []int{1, 2, 10}},
+		{"session", []int{3}},
+		{"missing", nil},
+		{"", []int{0, 1, 2, 3, 10}},
+	} {
+		got := tr.Collect(tc.prefix)
+		slices.Sort(got)
+		if !slices.Equal(got, tc.want) {
+			t.Errorf("Collect(%q) = %v, want %v", tc.prefix, got, tc.want)
+		}
+	}
+}
+
+func TestInsertReplaces(t *testing.T) {
+	var tr Trie[int]
+	tr.Insert("a", 1)
+	tr.Insert("a", 2)
+	if got := tr.Collect("a"); !slices.Equal(got, []int{2}) {
+		t.Fatalf("Collect(a) = %v, want [2]", got)
+	}
+}
+
+func TestRemovePrunes(t *testing.T) {
+	var tr Trie[int]
+	tr.Insert("ab", 1)
+	tr.Insert("abcd", 2)
+
+	tr.Remove("abcd")
+	if got := tr.Collect("a"); !slices.Equal(got, []int{1}) {
+		t.Fatalf("Collect(a) = %v, want [1]", got)
+	}
+	if n := tr.root.children['a'].children['b']; len(n.children) != 0 {
+		t.Fatalf("expected the nodes below ab to be pruned, got %v", n.children)
+	}
+
+	tr.Remove("ab")
+	tr.Remove("missing")
+	if len(tr.root.children) != 0 {
+		t.Fatalf("expected an empty trie, got %v", tr.root.children)
+	}
+}